@@ -2,10 +2,12 @@
 package all
 
 import (
+	"github.com/TSMC-Uber/server/app/services/tuber-api/v1/handlers/alertgrp"
 	"github.com/TSMC-Uber/server/app/services/tuber-api/v1/handlers/authgrp"
 	"github.com/TSMC-Uber/server/app/services/tuber-api/v1/handlers/drivergrp"
 	"github.com/TSMC-Uber/server/app/services/tuber-api/v1/handlers/healthgrp"
 	"github.com/TSMC-Uber/server/app/services/tuber-api/v1/handlers/locationgrp"
+	"github.com/TSMC-Uber/server/app/services/tuber-api/v1/handlers/reportgrp"
 	"github.com/TSMC-Uber/server/app/services/tuber-api/v1/handlers/tripgrp"
 	"github.com/TSMC-Uber/server/app/services/tuber-api/v1/handlers/usergrp"
 	"github.com/TSMC-Uber/server/app/services/tuber-api/v1/handlers/wsgrp"
@@ -27,9 +29,10 @@ func (add) Add(app *web.App, cfg v1.APIMuxConfig) {
 		Log: cfg.Log,
 	})
 	authgrp.Routes(app, authgrp.Config{
-		Log:  cfg.Log,
-		Auth: cfg.Auth,
-		DB:   cfg.DB,
+		Log:        cfg.Log,
+		Auth:       cfg.Auth,
+		DB:         cfg.DB,
+		RateLimits: cfg.RateLimits,
 	})
 	usergrp.Routes(app, usergrp.Config{
 		Log:  cfg.Log,
@@ -42,18 +45,32 @@ func (add) Add(app *web.App, cfg v1.APIMuxConfig) {
 		DB:   cfg.DB,
 	})
 	tripgrp.Routes(app, tripgrp.Config{
+		Log:        cfg.Log,
+		Auth:       cfg.Auth,
+		DB:         cfg.DB,
+		RateLimits: cfg.RateLimits,
+		Dispatcher: cfg.LocationDispatcher,
+	})
+	alertgrp.Routes(app, alertgrp.Config{
 		Log:  cfg.Log,
 		Auth: cfg.Auth,
 		DB:   cfg.DB,
 	})
-	locationgrp.Routes(app, locationgrp.Config{
+	reportgrp.Routes(app, reportgrp.Config{
 		Log:  cfg.Log,
 		Auth: cfg.Auth,
 		DB:   cfg.DB,
 	})
-	wsgrp.Routes(app, wsgrp.Config{
+	locationgrp.Routes(app, locationgrp.Config{
 		Log:  cfg.Log,
 		Auth: cfg.Auth,
 		DB:   cfg.DB,
 	})
+	wsgrp.Routes(app, wsgrp.Config{
+		Log:        cfg.Log,
+		Auth:       cfg.Auth,
+		DB:         cfg.DB,
+		Origins:    cfg.Origins,
+		RateLimits: cfg.RateLimits,
+	})
 }
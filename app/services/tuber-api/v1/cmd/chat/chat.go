@@ -23,8 +23,10 @@ func (add) Add(app *web.App, cfg v1.APIMuxConfig) {
 		Svc: "chat",
 	})
 	wsgrp.Routes(app, wsgrp.Config{
-		Log:  cfg.Log,
-		Auth: cfg.Auth,
-		DB:   cfg.DB,
+		Log:        cfg.Log,
+		Auth:       cfg.Auth,
+		DB:         cfg.DB,
+		Origins:    cfg.Origins,
+		RateLimits: cfg.RateLimits,
 	})
 }
@@ -2,25 +2,32 @@ package cmd
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"runtime"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/TSMC-Uber/server/app/services/tuber-api/v1/config"
 	"github.com/TSMC-Uber/server/business/core/locationws"
+	"github.com/TSMC-Uber/server/business/core/locationws/stores/locationwsbus"
+	"github.com/TSMC-Uber/server/business/core/locationws/stores/locationwsdb"
 	"github.com/TSMC-Uber/server/business/sys/cachedb"
 	"github.com/TSMC-Uber/server/business/sys/database"
 	"github.com/TSMC-Uber/server/business/sys/mail"
 	"github.com/TSMC-Uber/server/business/sys/mq"
+	"github.com/TSMC-Uber/server/business/sys/ratelimit"
 	v1 "github.com/TSMC-Uber/server/business/web/v1"
 	"github.com/TSMC-Uber/server/business/web/v1/auth"
 	"github.com/TSMC-Uber/server/business/web/v1/debug"
 
+	"github.com/TSMC-Uber/server/foundation/keystore"
 	"github.com/TSMC-Uber/server/foundation/logger"
+	"github.com/TSMC-Uber/server/foundation/vault"
 	"github.com/TSMC-Uber/server/foundation/web"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
@@ -102,10 +109,6 @@ func run(ctx context.Context, log *logger.Logger, build string, routes string, r
 		mq.Close()
 	}()
 
-	// -------------------------------------------------------------------------
-	// Room Dispatcher
-	locationws.NewRoomsDispatcher()
-
 	// -------------------------------------------------------------------------
 	// Database Support
 
@@ -150,10 +153,64 @@ func run(ctx context.Context, log *logger.Logger, build string, routes string, r
 		cachedb.Replica.Close()
 	}()
 
+	// Google is always accepted, a generic OpenID Connect provider is added
+	// when its issuer is configured.
+	google := auth.GoogleProvider(splitList(cfg.Auth.Audience)...)
+	google.RequireNonce = cfg.Auth.RequireNonce
+
+	providers := []auth.Provider{google}
+	if cfg.Auth.OIDC.Issuer != "" {
+		providers = append(providers, auth.Provider{
+			Name:         cfg.Auth.OIDC.Name,
+			Issuers:      []string{cfg.Auth.OIDC.Issuer},
+			Audiences:    splitList(cfg.Auth.OIDC.Audience),
+			JWKSURL:      cfg.Auth.OIDC.JWKSURL,
+			RequireNonce: cfg.Auth.OIDC.RequireNonce,
+		})
+	}
+
+	// Access tokens are signed with keys from Vault when its address is
+	// configured, or else from the keys folder. Without either access tokens
+	// are disabled.
+	var keyLookup auth.KeyLookup
+	switch {
+	case cfg.Vault.Address != "":
+		vlt, err := vault.New(vault.Config{
+			Address:   cfg.Vault.Address,
+			Token:     cfg.Vault.Token,
+			MountPath: cfg.Vault.MountPath,
+		})
+		if err != nil {
+			return fmt.Errorf("constructing vault: %w", err)
+		}
+		keyLookup = vlt
+
+	case cfg.Auth.KeysFolder != "":
+		ks, err := keystore.NewFS(os.DirFS(cfg.Auth.KeysFolder))
+		if err != nil {
+			return fmt.Errorf("reading keys: %w", err)
+		}
+		keyLookup = ks
+	}
+
+	sameSite, err := auth.ParseSameSite(cfg.Auth.Cookie.SameSite)
+	if err != nil {
+		return fmt.Errorf("parsing cookie config: %w", err)
+	}
+
 	authCfg := auth.Config{
-		Log: log,
-		DB:  db,
-		// KeyLookup: vault,
+		Log:            log,
+		DB:             db,
+		KeyLookup:      keyLookup,
+		ActiveKID:      cfg.Auth.ActiveKID,
+		AccessTokenTTL: cfg.Auth.AccessTokenTTL,
+		Issuer:         cfg.Auth.Issuer,
+		Providers:      providers,
+		Cookie: auth.CookieConfig{
+			Domain:   cfg.Auth.Cookie.Domain,
+			Secure:   cfg.Auth.Cookie.Secure,
+			SameSite: sameSite,
+		},
 	}
 
 	auth, err := auth.New(authCfg)
@@ -178,6 +235,36 @@ func run(ctx context.Context, log *logger.Logger, build string, routes string, r
 
 	tracer := traceProvider.Tracer("service")
 
+	// -------------------------------------------------------------------------
+	// Room Dispatcher
+
+	dispatcher := locationws.NewRoomsDispatcher(log, locationwsbus.NewBus(log), locationws.Config{
+		Shards:            cfg.Location.Shards,
+		QueueSize:         cfg.Location.QueueSize,
+		SlowClientTimeout: cfg.Location.SlowClientTimeout,
+	})
+	defer func() {
+		log.Info(ctx, "shutdown", "status", "stopping room dispatcher")
+		dispatcher.Close()
+	}()
+
+	expvar.Publish("locationws", expvar.Func(func() any {
+		return dispatcher.Stats()
+	}))
+
+	// -------------------------------------------------------------------------
+	// Trace Recorder
+
+	recorder := locationws.NewRecorder(log, locationwsdb.NewStore(log, db), locationws.RecorderConfig{
+		BatchSize:     cfg.Location.Trace.BatchSize,
+		FlushInterval: cfg.Location.Trace.FlushInterval,
+		Retention:     cfg.Location.Trace.Retention,
+	})
+	defer func() {
+		log.Info(ctx, "shutdown", "status", "stopping trace recorder")
+		recorder.Close()
+	}()
+
 	// -------------------------------------------------------------------------
 	// Start Debug Service
 
@@ -197,12 +284,25 @@ func run(ctx context.Context, log *logger.Logger, build string, routes string, r
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 
+	rateLimits, err := ratelimit.ParsePolicies(cfg.RateLimit.Policies)
+	if err != nil {
+		return fmt.Errorf("parsing rate limits: %w", err)
+	}
+
 	cfgMux := v1.APIMuxConfig{
-		Shutdown: shutdown,
-		Log:      log,
-		Auth:     auth,
-		DB:       db,
-		Tracer:   tracer,
+		Shutdown:   shutdown,
+		Log:        log,
+		Auth:       auth,
+		DB:         db,
+		Tracer:     tracer,
+		Origins:    splitList(cfg.Web.AllowedOrigins),
+		RateLimits: rateLimits,
+
+		TrustedProxies: splitList(cfg.Web.TrustedProxies),
+
+		LocationDispatcher:      dispatcher,
+		LocationRecorder:        recorder,
+		LocationStreamRetention: cfg.Location.StreamRetention,
 	}
 
 	apiMux := v1.APIMux(cfgMux, routeAdder)
@@ -290,3 +390,14 @@ func startTracing(reporterURI string, serviceName string, probability float64) (
 
 	return traceProvider, nil
 }
+
+// splitList splits a comma separated configuration value.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
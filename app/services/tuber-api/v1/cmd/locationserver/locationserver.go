@@ -26,7 +26,14 @@ func (add) Add(app *web.App, cfg v1.APIMuxConfig) {
 		Auth: cfg.Auth,
 	})
 	locationwsgrp.Routes(app, locationwsgrp.Config{
-		Log:  cfg.Log,
-		Auth: cfg.Auth,
+		Log:        cfg.Log,
+		Auth:       cfg.Auth,
+		DB:         cfg.DB,
+		Origins:    cfg.Origins,
+		RateLimits: cfg.RateLimits,
+		Dispatcher: cfg.LocationDispatcher,
+		Recorder:   cfg.LocationRecorder,
+
+		StreamRetention: cfg.LocationStreamRetention,
 	})
 }
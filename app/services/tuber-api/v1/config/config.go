@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -15,6 +18,8 @@ type config struct {
 		ShutdownTimeout time.Duration
 		APIHost         string
 		DebugHost       string
+		AllowedOrigins  string
+		TrustedProxies  string
 	}
 	DB struct {
 		User         string
@@ -45,8 +50,44 @@ type config struct {
 		ServiceName string
 		Probability float64
 	}
+	RateLimit struct {
+		Policies string
+	}
+	Location struct {
+		Shards            int
+		QueueSize         int
+		SlowClientTimeout time.Duration
+		StreamRetention   time.Duration
+		Trace             struct {
+			BatchSize     int
+			FlushInterval time.Duration
+			Retention     time.Duration
+		}
+	}
+	Vault struct {
+		Address   string
+		Token     string
+		MountPath string
+	}
 	Auth struct {
-		Audience string
+		Audience       string
+		RequireNonce   bool
+		Issuer         string
+		ActiveKID      string
+		KeysFolder     string
+		AccessTokenTTL time.Duration
+		OIDC           struct {
+			Name         string
+			Issuer       string
+			Audience     string
+			JWKSURL      string
+			RequireNonce bool
+		}
+		Cookie struct {
+			Domain   string
+			Secure   bool
+			SameSite string
+		}
 	}
 }
 
@@ -60,6 +101,8 @@ func New() (config, error) {
 	vConfig.SetDefault("Web.ShutdownTimeout", time.Second*5)
 	vConfig.SetDefault("Web.APIHost", "0.0.0.0:3000")
 	vConfig.SetDefault("Web.DebugHost", "0.0.0.0:4000")
+	vConfig.SetDefault("Web.AllowedOrigins", "http://localhost:5173")
+	vConfig.SetDefault("Web.TrustedProxies", "")
 
 	// Set DB defaults.
 	vConfig.SetDefault("DB.User", "postgres")
@@ -90,6 +133,36 @@ func New() (config, error) {
 
 	// Set Auth defaults.
 	vConfig.SetDefault("Auth.Audience", "")
+	vConfig.SetDefault("Auth.RequireNonce", false)
+	vConfig.SetDefault("Auth.Issuer", "tuber project")
+	vConfig.SetDefault("Auth.ActiveKID", "")
+	vConfig.SetDefault("Auth.KeysFolder", "")
+	vConfig.SetDefault("Auth.AccessTokenTTL", time.Minute*5)
+	vConfig.SetDefault("Auth.OIDC.Name", "oidc")
+	vConfig.SetDefault("Auth.OIDC.Issuer", "")
+	vConfig.SetDefault("Auth.OIDC.Audience", "")
+	vConfig.SetDefault("Auth.OIDC.JWKSURL", "")
+	vConfig.SetDefault("Auth.OIDC.RequireNonce", false)
+	vConfig.SetDefault("Auth.Cookie.Domain", "localhost")
+	vConfig.SetDefault("Auth.Cookie.Secure", false)
+	vConfig.SetDefault("Auth.Cookie.SameSite", "lax")
+
+	// Set RateLimit defaults.
+	vConfig.SetDefault("RateLimit.Policies", "login=10/1m,join=10/1m,chat=30/10s,location=10/1s")
+
+	// Set Location defaults.
+	vConfig.SetDefault("Location.Shards", 32)
+	vConfig.SetDefault("Location.QueueSize", 4)
+	vConfig.SetDefault("Location.SlowClientTimeout", time.Second*5)
+	vConfig.SetDefault("Location.StreamRetention", 0)
+	vConfig.SetDefault("Location.Trace.BatchSize", 100)
+	vConfig.SetDefault("Location.Trace.FlushInterval", time.Second*5)
+	vConfig.SetDefault("Location.Trace.Retention", time.Hour*24*30)
+
+	// Set Vault defaults.
+	vConfig.SetDefault("Vault.Address", "")
+	vConfig.SetDefault("Vault.Token", "")
+	vConfig.SetDefault("Vault.MountPath", "secret")
 
 	// Enable environment variable overriding for all.
 	vConfig.AutomaticEnv()
@@ -97,7 +170,33 @@ func New() (config, error) {
 	// since our config is nested, we need to set the defaults for the nested structs
 	vConfig.BindEnv("Web.APIHost", "API_HOST")
 	vConfig.BindEnv("Web.DebugHost", "DEBUG_HOST")
+	vConfig.BindEnv("Web.AllowedOrigins", "ALLOWED_ORIGINS")
+	vConfig.BindEnv("Web.TrustedProxies", "TRUSTED_PROXIES")
 	vConfig.BindEnv("Auth.Audience", "AUTH_AUDIENCE")
+	vConfig.BindEnv("Auth.RequireNonce", "AUTH_REQUIRE_NONCE")
+	vConfig.BindEnv("Auth.Issuer", "AUTH_ISSUER")
+	vConfig.BindEnv("Auth.ActiveKID", "AUTH_ACTIVE_KID")
+	vConfig.BindEnv("Auth.KeysFolder", "AUTH_KEYS_FOLDER")
+	vConfig.BindEnv("Auth.AccessTokenTTL", "AUTH_ACCESS_TOKEN_TTL")
+	vConfig.BindEnv("RateLimit.Policies", "RATE_LIMITS")
+	vConfig.BindEnv("Location.Shards", "LOCATION_SHARDS")
+	vConfig.BindEnv("Location.QueueSize", "LOCATION_QUEUE_SIZE")
+	vConfig.BindEnv("Location.SlowClientTimeout", "LOCATION_SLOW_CLIENT_TIMEOUT")
+	vConfig.BindEnv("Location.StreamRetention", "LOCATION_STREAM_RETENTION")
+	vConfig.BindEnv("Location.Trace.BatchSize", "LOCATION_TRACE_BATCH_SIZE")
+	vConfig.BindEnv("Location.Trace.FlushInterval", "LOCATION_TRACE_FLUSH_INTERVAL")
+	vConfig.BindEnv("Location.Trace.Retention", "LOCATION_TRACE_RETENTION")
+	vConfig.BindEnv("Vault.Address", "VAULT_ADDRESS")
+	vConfig.BindEnv("Vault.Token", "VAULT_TOKEN")
+	vConfig.BindEnv("Vault.MountPath", "VAULT_MOUNT_PATH")
+	vConfig.BindEnv("Auth.OIDC.Name", "AUTH_OIDC_NAME")
+	vConfig.BindEnv("Auth.OIDC.Issuer", "AUTH_OIDC_ISSUER")
+	vConfig.BindEnv("Auth.OIDC.Audience", "AUTH_OIDC_AUDIENCE")
+	vConfig.BindEnv("Auth.OIDC.JWKSURL", "AUTH_OIDC_JWKS_URL")
+	vConfig.BindEnv("Auth.OIDC.RequireNonce", "AUTH_OIDC_REQUIRE_NONCE")
+	vConfig.BindEnv("Auth.Cookie.Domain", "AUTH_COOKIE_DOMAIN")
+	vConfig.BindEnv("Auth.Cookie.Secure", "AUTH_COOKIE_SECURE")
+	vConfig.BindEnv("Auth.Cookie.SameSite", "AUTH_COOKIE_SAMESITE")
 
 	conf := &config{}
 	// Unmarshal the config into the conf struct.
@@ -105,9 +204,72 @@ func New() (config, error) {
 		return *conf, err
 	}
 
+	if err := validateProxies(conf.Web.TrustedProxies); err != nil {
+		return *conf, err
+	}
+
+	origins, err := normalizeOrigins(conf.Web.AllowedOrigins)
+	if err != nil {
+		return *conf, err
+	}
+	conf.Web.AllowedOrigins = origins
+
 	// test API_HOST, get from env
 	fmt.Println("API_HOST: ", vConfig.GetString("API_HOST"))
 	fmt.Println("DEBUG_HOST: ", vConfig.GetString("DEBUG_HOST"))
 
 	return *conf, nil
 }
+
+// validateProxies checks every item of the comma separated list of trusted
+// proxies is an IP or a CIDR.
+func validateProxies(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if _, _, err := net.ParseCIDR(item); err == nil {
+			continue
+		}
+		if net.ParseIP(item) == nil {
+			return fmt.Errorf("invalid trusted proxy %q, expected an IP or a CIDR", item)
+		}
+	}
+
+	return nil
+}
+
+// normalizeOrigins checks the comma separated list of allowed origins holds
+// at least one origin, every one a scheme and host like
+// "https://tuber.example.com" or "*" for any, and returns it lower cased
+// without trailing slashes, the form browsers send.
+func normalizeOrigins(value string) (string, error) {
+	var origins []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if item == "*" {
+			origins = append(origins, item)
+			continue
+		}
+
+		u, err := url.Parse(item)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Contains(u.Host, "*") || u.User != nil ||
+			(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+			return "", fmt.Errorf("invalid allowed origin %q, expected <scheme>://<host>[:<port>] or *", item)
+		}
+
+		origins = append(origins, strings.ToLower(u.Scheme+"://"+u.Host))
+	}
+
+	if len(origins) == 0 {
+		return "", fmt.Errorf("no allowed origin, set ALLOWED_ORIGINS")
+	}
+
+	return strings.Join(origins, ","), nil
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeOrigins(t *testing.T) {
+	origins, err := normalizeOrigins(" HTTPS://Tuber.example.com/ , http://localhost:5173,,*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "https://tuber.example.com,http://localhost:5173,*", origins)
+
+	for _, value := range []string{"", " , ", "tuber.example.com", "localhost:5173", "ftp://tuber.example.com", "https://", "https://tuber.example.com/app", "https://tuber.example.com?x=1", "https://*.example.com"} {
+		_, err := normalizeOrigins(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestValidateProxies(t *testing.T) {
+	assert.NoError(t, validateProxies(""))
+	assert.NoError(t, validateProxies("10.0.0.1, 10.0.0.0/8,::1"))
+	assert.Error(t, validateProxies("10.0.0.0/33"))
+	assert.Error(t, validateProxies("proxy.example.com"))
+}
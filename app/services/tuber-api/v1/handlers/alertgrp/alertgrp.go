@@ -0,0 +1,166 @@
+// Package alertgrp maintains the group of handlers for alert access.
+package alertgrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/TSMC-Uber/server/business/core/alert"
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/web/v1/auth"
+	"github.com/TSMC-Uber/server/business/web/v1/response"
+	"github.com/TSMC-Uber/server/foundation/web"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handlers manages the set of alert endpoints.
+type Handlers struct {
+	alert *alert.Core
+}
+
+// New constructs a handlers for route access.
+func New(alert *alert.Core) *Handlers {
+	return &Handlers{
+		alert: alert,
+	}
+}
+
+// @Summary raise an alert on a trip
+// @Schemes
+// @Description Create will raise an SOS alert on a trip in progress and notify the other participants right away
+// @Tags alert
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param id path string true "Trip ID"
+// @Param body body AppNewAlert true "New Alert"
+// @Success 201 {object} AppAlert "Alert successfully raised"
+// @Failure 400 "Bad Request"
+// @Failure 403 "Forbidden"
+// @Failure 404 "Not Found"
+// @Failure 409 "Trip is not in progress"
+// @Failure 500 "Internal Server Error"
+// @Router /trips/{id}/alerts [post]
+func (h *Handlers) Create(ctx context.Context, c *gin.Context) error {
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	var app AppNewAlert
+	if err := web.Decode(c, &app); err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	if err := app.Validate(); err != nil {
+		return err
+	}
+
+	na := toCoreNewAlert(app)
+	na.SenderID = auth.GetUserID(ctx)
+
+	alrt, err := h.alert.Create(ctx, tripID, na)
+	if err != nil {
+		return alertError(err, tripID)
+	}
+
+	return web.Respond(ctx, c.Writer, toAppAlert(alrt), http.StatusCreated)
+}
+
+// @Summary get the alerts of a trip
+// @Schemes
+// @Description QueryByTripID will query the alerts raised on a trip, newest first
+// @Tags alert
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param id path string true "Trip ID"
+// @Success 200 {array} AppAlert "query alerts of a trip"
+// @Failure 403 "Forbidden"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /trips/{id}/alerts [get]
+func (h *Handlers) QueryByTripID(ctx context.Context, c *gin.Context) error {
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	alerts, err := h.alert.QueryByTripID(ctx, tripID, auth.GetUserID(ctx))
+	if err != nil {
+		return alertError(err, tripID)
+	}
+
+	return web.Respond(ctx, c.Writer, toAppAlerts(alerts), http.StatusOK)
+}
+
+// @Summary acknowledge or resolve an alert
+// @Schemes
+// @Description UpdateStatus will acknowledge or resolve an alert of a trip
+// @Tags alert
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param id path string true "Trip ID"
+// @Param alert_id path string true "Alert ID"
+// @Param body body AppUpdateAlert true "Alert Status"
+// @Success 200 {object} AppAlert "Alert successfully updated"
+// @Failure 400 "Bad Request"
+// @Failure 403 "Forbidden"
+// @Failure 404 "Not Found"
+// @Failure 409 "Alert cannot move to this status"
+// @Failure 500 "Internal Server Error"
+// @Router /trips/{id}/alerts/{alert_id} [put]
+func (h *Handlers) UpdateStatus(ctx context.Context, c *gin.Context) error {
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	alertID, err := uuid.Parse(c.Param("alert_id"))
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	var app AppUpdateAlert
+	if err := web.Decode(c, &app); err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	if err := app.Validate(); err != nil {
+		return err
+	}
+
+	alrt, err := h.alert.QueryByID(ctx, alertID)
+	if err != nil {
+		return alertError(err, tripID)
+	}
+
+	if alrt.TripID != tripID {
+		return response.NewError(alert.ErrNotFound, http.StatusNotFound)
+	}
+
+	alrt, err = h.alert.UpdateStatus(ctx, alrt, auth.GetUserID(ctx), app.Status)
+	if err != nil {
+		return alertError(err, tripID)
+	}
+
+	return web.Respond(ctx, c.Writer, toAppAlert(alrt), http.StatusOK)
+}
+
+// alertError maps the errors of the alert core to responses.
+func alertError(err error, tripID uuid.UUID) error {
+	switch {
+	case errors.Is(err, alert.ErrNotParticipant):
+		return response.NewError(err, http.StatusForbidden)
+	case errors.Is(err, alert.ErrNotFound), errors.Is(err, trip.ErrNotFound):
+		return response.NewError(err, http.StatusNotFound)
+	case errors.Is(err, alert.ErrTripNotInProgress), errors.Is(err, alert.ErrInvalidTransition):
+		return response.NewError(err, http.StatusConflict)
+	default:
+		return fmt.Errorf("alert: tripID[%s]: %w", tripID, err)
+	}
+}
@@ -0,0 +1,119 @@
+package alertgrp
+
+import (
+	"time"
+
+	"github.com/TSMC-Uber/server/business/core/alert"
+	"github.com/TSMC-Uber/server/business/sys/validate"
+)
+
+// AppAlert represents information about an individual alert.
+type AppAlert struct {
+	ID             string       `json:"id"`
+	TripID         string       `json:"trip_id"`
+	SenderID       string       `json:"sender_id"`
+	Comment        string       `json:"comment"`
+	Status         string       `json:"status"`
+	Location       *AppLocation `json:"location"`
+	AcknowledgedBy string       `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt string       `json:"acknowledged_at,omitempty"`
+	ResolvedBy     string       `json:"resolved_by,omitempty"`
+	ResolvedAt     string       `json:"resolved_at,omitempty"`
+	CreatedAt      string       `json:"created_at"`
+}
+
+// AppLocation is where the sender was when the alert was raised.
+type AppLocation struct {
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	RecordedAt string  `json:"recorded_at"`
+}
+
+func toAppAlert(alrt alert.Alert) AppAlert {
+	app := AppAlert{
+		ID:        alrt.ID.String(),
+		TripID:    alrt.TripID.String(),
+		SenderID:  alrt.SenderID.String(),
+		Comment:   alrt.Comment,
+		Status:    alrt.Status,
+		CreatedAt: alrt.CreatedAt.Format(time.RFC3339),
+	}
+
+	if alrt.Location != nil {
+		app.Location = &AppLocation{
+			Latitude:   alrt.Location.Latitude,
+			Longitude:  alrt.Location.Longitude,
+			RecordedAt: alrt.Location.RecordedAt.Format(time.RFC3339),
+		}
+	}
+	if alrt.AcknowledgedBy != nil {
+		app.AcknowledgedBy = alrt.AcknowledgedBy.String()
+	}
+	if alrt.AcknowledgedAt != nil {
+		app.AcknowledgedAt = alrt.AcknowledgedAt.Format(time.RFC3339)
+	}
+	if alrt.ResolvedBy != nil {
+		app.ResolvedBy = alrt.ResolvedBy.String()
+	}
+	if alrt.ResolvedAt != nil {
+		app.ResolvedAt = alrt.ResolvedAt.Format(time.RFC3339)
+	}
+
+	return app
+}
+
+func toAppAlerts(alerts []alert.Alert) []AppAlert {
+	items := make([]AppAlert, len(alerts))
+	for i, alrt := range alerts {
+		items[i] = toAppAlert(alrt)
+	}
+	return items
+}
+
+// =============================================================================
+
+// AppNewAlert contains information needed to raise an alert. The coordinates
+// are optional, the last location of the trip is used without them.
+type AppNewAlert struct {
+	Comment   string   `json:"comment"`
+	Latitude  *float64 `json:"latitude" validate:"required_with=Longitude,omitempty,gte=-90,lte=90"`
+	Longitude *float64 `json:"longitude" validate:"required_with=Latitude,omitempty,gte=-180,lte=180"`
+}
+
+func toCoreNewAlert(app AppNewAlert) alert.NewAlert {
+	na := alert.NewAlert{
+		Comment: app.Comment,
+	}
+
+	if app.Latitude != nil && app.Longitude != nil {
+		na.Location = &alert.Location{
+			Latitude:   *app.Latitude,
+			Longitude:  *app.Longitude,
+			RecordedAt: time.Now(),
+		}
+	}
+
+	return na
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppNewAlert) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AppUpdateAlert contains information needed to acknowledge or resolve an
+// alert.
+type AppUpdateAlert struct {
+	Status string `json:"status" validate:"required,oneof=acknowledged resolved"`
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppUpdateAlert) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+	return nil
+}
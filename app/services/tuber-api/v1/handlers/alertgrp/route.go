@@ -0,0 +1,42 @@
+package alertgrp
+
+import (
+	"net/http"
+
+	"github.com/TSMC-Uber/server/business/core/alert"
+	"github.com/TSMC-Uber/server/business/core/alert/stores/alertbus"
+	"github.com/TSMC-Uber/server/business/core/alert/stores/alertdb"
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/core/trip/stores/tripdb"
+	"github.com/TSMC-Uber/server/business/core/user"
+	"github.com/TSMC-Uber/server/business/core/user/stores/userdb"
+	"github.com/TSMC-Uber/server/business/web/v1/auth"
+	"github.com/TSMC-Uber/server/business/web/v1/mid"
+	"github.com/TSMC-Uber/server/foundation/logger"
+	"github.com/TSMC-Uber/server/foundation/web"
+	"github.com/jmoiron/sqlx"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log  *logger.Logger
+	Auth *auth.Auth
+	DB   *sqlx.DB
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	tripCore := trip.NewCore(tripdb.NewStore(cfg.Log, cfg.DB))
+	usrCore := user.NewCore(userdb.NewStore(cfg.Log, cfg.DB))
+	bus := alertbus.NewBus(cfg.Log)
+	alertCore := alert.NewCore(cfg.Log, alertdb.NewStore(cfg.Log, cfg.DB), bus, bus, tripCore, usrCore)
+
+	authen := mid.Authenticate(cfg.Auth)
+
+	hdl := New(alertCore)
+	app.Handle(http.MethodPost, version, "/trips/:id/alerts", hdl.Create, authen)
+	app.Handle(http.MethodGet, version, "/trips/:id/alerts", hdl.QueryByTripID, authen)
+	app.Handle(http.MethodPut, version, "/trips/:id/alerts/:alert_id", hdl.UpdateStatus, authen)
+}
@@ -3,8 +3,10 @@ package authgrp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/TSMC-Uber/server/business/core/auth"
 	"github.com/TSMC-Uber/server/business/core/user"
@@ -12,19 +14,22 @@ import (
 	"github.com/TSMC-Uber/server/business/web/v1/response"
 	"github.com/TSMC-Uber/server/foundation/web"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // Handlers manages the set of user endpoints.
 type Handlers struct {
-	auth *auth.Core
-	user *user.Core
+	auth    *auth.Core
+	user    *user.Core
+	webAuth *webauth.Auth
 }
 
 // New constructs a handlers for route access.
-func New(auth *auth.Core, user *user.Core) *Handlers {
+func New(auth *auth.Core, user *user.Core, webAuth *webauth.Auth) *Handlers {
 	return &Handlers{
-		auth: auth,
-		user: user,
+		auth:    auth,
+		user:    user,
+		webAuth: webAuth,
 	}
 }
 
@@ -35,55 +40,295 @@ func New(auth *auth.Core, user *user.Core) *Handlers {
 // @Accept json
 // @Produce json
 // @Param id_token header string true "ID Token"
+// @Param nonce header string false "Nonce issued with /auth/nonce, required by providers configured to"
+// @Param mode query string false "bearer to get the token in the body instead of a cookie"
 // @Success 201 {object} AppUser "User successfully logged in"
+// @Success 201 {object} AppLogin "User successfully logged in, in bearer mode"
 // @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 409 "Email is not unique"
 // @Failure 500 "Internal Server Error"
 // @Router /auth/login [post]
 // Login will add a user if they do not exist or update them if they do.
 func (h *Handlers) Login(ctx context.Context, c *gin.Context) error {
 	idToken := webauth.GetIDToken(ctx)
 
-	tokenInfo, err := h.auth.ParseIDToken(idToken)
+	nc, err := toCoreNewUser(idToken)
 	if err != nil {
 		return response.NewError(err, http.StatusBadRequest)
 	}
 
-	nc, err := toCoreNewUser(tokenInfo)
+	usr, err := h.user.UpsertByIdentity(ctx, toCoreNewIdentity(idToken), nc)
 	if err != nil {
+		if errors.Is(err, user.ErrUniqueEmail) {
+			return response.NewError(err, http.StatusConflict)
+		}
+		return fmt.Errorf("upsert: provider[%s] subject[%s]: %w", idToken.Provider, idToken.Subject, err)
+	}
+
+	sess, token, err := h.auth.Create(ctx, usr, toCoreNewSession(c))
+	if err != nil {
+		return fmt.Errorf("create session: userID[%s]: %w", usr.ID, err)
+	}
+
+	// clients that cannot use cookies send the token as a bearer token
+	if c.Query("mode") == "bearer" {
+		app := toAppLogin(usr, sess, token)
+
+		accessToken, expiresAt, err := h.webAuth.AccessToken(sess)
+		switch {
+		case err == nil:
+			app.AccessToken = accessToken
+			app.AccessTokenExpiresAt = expiresAt.Format(time.RFC3339)
+		case !errors.Is(err, webauth.ErrAccessTokensDisabled):
+			return fmt.Errorf("access token: sessionID[%s]: %w", sess.ID, err)
+		}
+
+		return web.Respond(ctx, c.Writer, app, http.StatusCreated)
+	}
+
+	if err := h.webAuth.SetSessionCookies(c.Writer, token, sess.ExpiresAt); err != nil {
+		return fmt.Errorf("set session cookies: %w", err)
+	}
+	return web.Respond(ctx, c.Writer, toAppUser(usr), http.StatusCreated)
+}
+
+// @Summary refresh an access token
+// @Schemes
+// @Description Refresh will exchange the token of a session for a new short lived access token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param refresh body AppRefresh true "Refresh token"
+// @Success 200 {object} AppAccessToken "Access token successfully issued"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 501 "Access tokens are not enabled"
+// @Failure 500 "Internal Server Error"
+// @Router /auth/token [post]
+func (h *Handlers) Refresh(ctx context.Context, c *gin.Context) error {
+	var app AppRefresh
+	if err := web.Decode(c, &app); err != nil {
 		return response.NewError(err, http.StatusBadRequest)
 	}
 
-	usr, err := h.user.UpsertByGoogleID(ctx, tokenInfo.Sub, nc)
+	if err := app.Validate(); err != nil {
+		return err
+	}
+
+	accessToken, expiresAt, err := h.webAuth.Refresh(ctx, app.RefreshToken)
+	if err != nil {
+		if errors.Is(err, webauth.ErrAccessTokensDisabled) {
+			return response.NewError(err, http.StatusNotImplemented)
+		}
+		return webauth.NewAuthError("refresh: failed: %s", err)
+	}
+
+	return web.Respond(ctx, c.Writer, toAppAccessToken(accessToken, expiresAt), http.StatusOK)
+}
+
+// @Summary issue a websocket ticket
+// @Schemes
+// @Description IssueTicket will issue a single use ticket to open a websocket connection with, valid for 30 seconds
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Success 201 {object} AppTicket "Ticket successfully issued"
+// @Failure 401 "Unauthorized"
+// @Failure 500 "Internal Server Error"
+// @Router /auth/tickets [post]
+func (h *Handlers) IssueTicket(ctx context.Context, c *gin.Context) error {
+	ticket, expiresAt, err := h.webAuth.IssueTicket(ctx, webauth.GetClaims(ctx))
 	if err != nil {
-		return fmt.Errorf("upsert: usr[%+v]: %w", usr, err)
+		return fmt.Errorf("issue ticket: %w", err)
 	}
 
-	// generate token
-	sessionToken, err := h.auth.GenerateSessionToken(usr.ID, 3600)
+	return web.Respond(ctx, c.Writer, toAppTicket(ticket, expiresAt), http.StatusCreated)
+}
+
+// @Summary issue a nonce
+// @Schemes
+// @Description IssueNonce will issue a single use nonce to ask the provider for an ID token with, valid for 10 minutes. The nonce is sent back with the ID token as the nonce header.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 201 {object} AppNonce "Nonce successfully issued"
+// @Failure 500 "Internal Server Error"
+// @Router /auth/nonce [post]
+func (h *Handlers) IssueNonce(ctx context.Context, c *gin.Context) error {
+	nonce, expiresAt, err := h.webAuth.IssueNonce(ctx)
 	if err != nil {
-		return fmt.Errorf("generate session token: %w", err)
+		return fmt.Errorf("issue nonce: %w", err)
 	}
 
-	// store session token to redis
-	err = h.auth.SetSessionToken(ctx, *sessionToken, usr)
+	return web.Respond(ctx, c.Writer, toAppNonce(nonce, expiresAt), http.StatusCreated)
+}
+
+// @Summary link an identity
+// @Schemes
+// @Description LinkIdentity will let the signed in user sign in with another provider as well
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param id_token header string true "ID Token"
+// @Param nonce header string false "Nonce issued with /auth/nonce, required by providers configured to"
+// @Success 204 "Identity successfully linked"
+// @Failure 401 "Unauthorized"
+// @Failure 409 "Identity is linked to another user"
+// @Failure 500 "Internal Server Error"
+// @Router /auth/identities [post]
+func (h *Handlers) LinkIdentity(ctx context.Context, c *gin.Context) error {
+	userID := webauth.GetUserID(ctx)
+	idToken := webauth.GetIDToken(ctx)
+
+	usr, err := h.user.QueryByID(ctx, userID)
 	if err != nil {
-		return fmt.Errorf("set session token: %w", err)
+		return fmt.Errorf("querybyid: userID[%s]: %w", userID, err)
 	}
-	// set cookie
-	c.SetCookie("token", sessionToken.Plaintext, 3600, "/", "localhost", false, true)
-	return web.Respond(ctx, c.Writer, toAppUser(usr), http.StatusCreated)
+
+	if err := h.user.LinkIdentity(ctx, usr, toCoreNewIdentity(idToken)); err != nil {
+		if errors.Is(err, user.ErrIdentityLinked) {
+			return response.NewError(err, http.StatusConflict)
+		}
+		return fmt.Errorf("linkidentity: userID[%s] provider[%s]: %w", userID, idToken.Provider, err)
+	}
+
+	return web.Respond(ctx, c.Writer, nil, http.StatusNoContent)
 }
 
-// Logout updates a user in the system.
+// @Summary logout
+// @Schemes
+// @Description Logout will sign the current session out
+// @Tags auth
+// @Param token header string true "Token"
+// @Success 204 "Successfully logged out"
+// @Failure 401 "Unauthorized"
+// @Failure 500 "Internal Server Error"
+// @Router /auth/logout [post]
 func (h *Handlers) Logout(ctx context.Context, c *gin.Context) error {
-	sessionToken := webauth.GetSessionToken(ctx)
-	// remove session token from redis
-	err := h.auth.RemoveSessionToken(ctx, sessionToken)
+	userID := webauth.GetUserID(ctx)
+
+	sessionID, err := uuid.Parse(webauth.GetClaims(ctx).ID)
+	if err != nil {
+		return fmt.Errorf("parse session id: %w", err)
+	}
+
+	if err := h.revokeSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+
+	h.webAuth.ClearSessionCookies(c.Writer)
+	return web.Respond(ctx, c.Writer, nil, http.StatusNoContent)
+}
+
+// @Summary get my sessions
+// @Schemes
+// @Description QuerySessions will list the devices the user is signed in on
+// @Tags auth
+// @Produce json
+// @Param token header string true "Token"
+// @Success 200 {object} []AppSession "Sessions successfully queried"
+// @Failure 401 "Unauthorized"
+// @Failure 500 "Internal Server Error"
+// @Router /auth/sessions [get]
+func (h *Handlers) QuerySessions(ctx context.Context, c *gin.Context) error {
+	userID := webauth.GetUserID(ctx)
+
+	sessions, err := h.auth.QueryByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("querybyuser: userID[%s]: %w", userID, err)
+	}
+
+	return web.Respond(ctx, c.Writer, toAppSessions(sessions, webauth.GetClaims(ctx).ID), http.StatusOK)
+}
+
+// @Summary revoke a session
+// @Schemes
+// @Description RevokeSession will sign one of the sessions of the user out
+// @Tags auth
+// @Param token header string true "Token"
+// @Param id path string true "Session ID"
+// @Success 204 "Session successfully revoked"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Session not found"
+// @Failure 500 "Internal Server Error"
+// @Router /auth/sessions/{id} [delete]
+func (h *Handlers) RevokeSession(ctx context.Context, c *gin.Context) error {
+	userID := webauth.GetUserID(ctx)
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	if err := h.revokeSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, c.Writer, nil, http.StatusNoContent)
+}
+
+// @Summary log out everywhere
+// @Schemes
+// @Description RevokeSessions will sign the user out of every session, the current one included
+// @Tags auth
+// @Param token header string true "Token"
+// @Success 204 "Sessions successfully revoked"
+// @Failure 401 "Unauthorized"
+// @Failure 500 "Internal Server Error"
+// @Router /auth/sessions [delete]
+func (h *Handlers) RevokeSessions(ctx context.Context, c *gin.Context) error {
+	userID := webauth.GetUserID(ctx)
+
+	if err := h.auth.RevokeAll(ctx, userID); err != nil {
+		return fmt.Errorf("revokeall: userID[%s]: %w", userID, err)
+	}
+
+	h.webAuth.ClearSessionCookies(c.Writer)
+	return web.Respond(ctx, c.Writer, nil, http.StatusNoContent)
+}
+
+// @Summary revoke the sessions of a user
+// @Schemes
+// @Description RevokeUserSessions will sign the given user out of every session
+// @Tags auth
+// @Param token header string true "Token"
+// @Param id path string true "User ID"
+// @Success 204 "Sessions successfully revoked"
+// @Failure 400 "Bad Request"
+// @Failure 401 "Unauthorized"
+// @Failure 403 "Forbidden"
+// @Failure 500 "Internal Server Error"
+// @Router /users/{id}/sessions [delete]
+func (h *Handlers) RevokeUserSessions(ctx context.Context, c *gin.Context) error {
+	userID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		return fmt.Errorf("remove session token: %w", err)
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	if err := h.auth.RevokeAll(ctx, userID); err != nil {
+		return fmt.Errorf("revokeall: userID[%s]: %w", userID, err)
+	}
+
+	return web.Respond(ctx, c.Writer, nil, http.StatusNoContent)
+}
+
+// revokeSession signs the session of the user out.
+func (h *Handlers) revokeSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) error {
+	sess, err := h.auth.QueryByID(ctx, userID, sessionID)
+	if err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			return response.NewError(err, http.StatusNotFound)
+		}
+		return fmt.Errorf("querybyid: userID[%s] sessionID[%s]: %w", userID, sessionID, err)
+	}
+
+	if err := h.auth.Revoke(ctx, sess); err != nil {
+		return fmt.Errorf("revoke: sessionID[%s]: %w", sessionID, err)
 	}
 
-	// remove cookie
-	c.SetCookie("token", "", -1, "/", "localhost", false, true)
 	return nil
 }
@@ -5,23 +5,31 @@ import (
 	"net/mail"
 	"time"
 
-	"github.com/TSMC-Uber/server/business/core/auth"
+	aauth "github.com/TSMC-Uber/server/business/core/auth"
 	"github.com/TSMC-Uber/server/business/core/user"
+	"github.com/TSMC-Uber/server/business/sys/validate"
+	"github.com/TSMC-Uber/server/business/web/v1/auth"
 	"github.com/TSMC-Uber/server/business/web/v1/mid"
+	"github.com/gin-gonic/gin"
 )
 
 type AppUser struct {
-	ID                 string `json:"id"`
-	Name               string `json:"name"`
-	Email              string `json:"email"`
-	ImageURL           string `json:"imageURL"`
-	Bio                string `json:"bio"`
-	AcceptNotification bool   `json:"acceptNotification"`
-	CreatedAt          string `json:"createdAt"`
-	UpdatedAt          string `json:"updatedAt"`
+	ID                 string   `json:"id"`
+	Name               string   `json:"name"`
+	Email              string   `json:"email"`
+	ImageURL           string   `json:"imageURL"`
+	Bio                string   `json:"bio"`
+	AcceptNotification bool     `json:"acceptNotification"`
+	Roles              []string `json:"roles"`
+	CreatedAt          string   `json:"createdAt"`
+	UpdatedAt          string   `json:"updatedAt"`
 }
 
 func toAppUser(usr user.User) AppUser {
+	roles := make([]string, len(usr.Roles))
+	for i, role := range usr.Roles {
+		roles[i] = role.Name()
+	}
 
 	return AppUser{
 		ID:                 usr.ID.String(),
@@ -30,23 +38,147 @@ func toAppUser(usr user.User) AppUser {
 		ImageURL:           usr.ImageURL,
 		Bio:                usr.Bio,
 		AcceptNotification: usr.AcceptNotification,
+		Roles:              roles,
 		CreatedAt:          usr.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:          usr.UpdatedAt.Format(time.RFC3339),
 	}
 }
 
-func toCoreNewUser(tokenInfo *auth.IDTokenInfo) (user.NewUser, error) {
+func toCoreNewUser(idToken auth.IDToken) (user.NewUser, error) {
 	// upsert user and get user id here
-	addr, err := mail.ParseAddress(tokenInfo.Email)
+	addr, err := mail.ParseAddress(idToken.Email)
 	if err != nil {
 		return user.NewUser{}, mid.WrapError(fmt.Errorf("parsing email: %w", err))
 	}
 	usr := user.NewUser{
-		Name:     tokenInfo.Name,
+		Name:     idToken.Name,
 		Email:    *addr,
-		ImageURL: tokenInfo.Picture,
-		Sub:      tokenInfo.Sub,
+		ImageURL: idToken.Picture,
 	}
 
 	return usr, nil
 }
+
+func toCoreNewIdentity(idToken auth.IDToken) user.NewIdentity {
+	return user.NewIdentity{
+		Provider:      idToken.Provider,
+		Subject:       idToken.Subject,
+		Email:         idToken.Email,
+		EmailVerified: idToken.EmailVerified,
+	}
+}
+
+// AppLogin is the login response for clients using bearer tokens. When
+// access tokens are enabled the token is the refresh token, to be exchanged
+// for access tokens at /auth/token.
+type AppLogin struct {
+	User                 AppUser `json:"user"`
+	Token                string  `json:"token"`
+	ExpiresAt            string  `json:"expiresAt"`
+	AccessToken          string  `json:"accessToken,omitempty"`
+	AccessTokenExpiresAt string  `json:"accessTokenExpiresAt,omitempty"`
+}
+
+func toAppLogin(usr user.User, sess aauth.Session, token string) AppLogin {
+	return AppLogin{
+		User:      toAppUser(usr),
+		Token:     token,
+		ExpiresAt: sess.ExpiresAt.Format(time.RFC3339),
+	}
+}
+
+// AppRefresh contains the refresh token to exchange for an access token.
+type AppRefresh struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppRefresh) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AppAccessToken is a signed access token.
+type AppAccessToken struct {
+	AccessToken string `json:"accessToken"`
+	ExpiresAt   string `json:"expiresAt"`
+}
+
+func toAppAccessToken(token string, expiresAt time.Time) AppAccessToken {
+	return AppAccessToken{
+		AccessToken: token,
+		ExpiresAt:   expiresAt.Format(time.RFC3339),
+	}
+}
+
+// AppTicket is a single use ticket to open a websocket connection with.
+type AppTicket struct {
+	Ticket    string `json:"ticket"`
+	ExpiresAt string `json:"expiresAt"`
+}
+
+func toAppTicket(ticket string, expiresAt time.Time) AppTicket {
+	return AppTicket{
+		Ticket:    ticket,
+		ExpiresAt: expiresAt.Format(time.RFC3339),
+	}
+}
+
+// AppNonce is a single use nonce to ask the provider for an ID token with.
+type AppNonce struct {
+	Nonce     string `json:"nonce"`
+	ExpiresAt string `json:"expiresAt"`
+}
+
+func toAppNonce(nonce string, expiresAt time.Time) AppNonce {
+	return AppNonce{
+		Nonce:     nonce,
+		ExpiresAt: expiresAt.Format(time.RFC3339),
+	}
+}
+
+// =============================================================================
+
+type AppSession struct {
+	ID         string `json:"id"`
+	Device     string `json:"device"`
+	UserAgent  string `json:"userAgent"`
+	IP         string `json:"ip"`
+	Current    bool   `json:"current"`
+	CreatedAt  string `json:"createdAt"`
+	LastSeenAt string `json:"lastSeenAt"`
+	ExpiresAt  string `json:"expiresAt"`
+}
+
+func toAppSession(sess aauth.Session, currentID string) AppSession {
+	return AppSession{
+		ID:         sess.ID.String(),
+		Device:     sess.Device,
+		UserAgent:  sess.UserAgent,
+		IP:         sess.IP,
+		Current:    sess.ID.String() == currentID,
+		CreatedAt:  sess.CreatedAt.Format(time.RFC3339),
+		LastSeenAt: sess.LastSeenAt.Format(time.RFC3339),
+		ExpiresAt:  sess.ExpiresAt.Format(time.RFC3339),
+	}
+}
+
+func toAppSessions(sessions []aauth.Session, currentID string) []AppSession {
+	items := make([]AppSession, len(sessions))
+	for i, sess := range sessions {
+		items[i] = toAppSession(sess, currentID)
+	}
+	return items
+}
+
+// toCoreNewSession describes the device the request comes from. Clients can
+// name the device with the `device` header.
+func toCoreNewSession(c *gin.Context) aauth.NewSession {
+	return aauth.NewSession{
+		Device:    c.Request.Header.Get("device"),
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+}
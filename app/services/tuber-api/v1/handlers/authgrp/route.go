@@ -4,9 +4,10 @@ import (
 	"net/http"
 
 	aauth "github.com/TSMC-Uber/server/business/core/auth"
-	"github.com/TSMC-Uber/server/business/core/auth/stores/authdb"
+	"github.com/TSMC-Uber/server/business/core/auth/stores/authredisdb"
 	"github.com/TSMC-Uber/server/business/core/user"
 	"github.com/TSMC-Uber/server/business/core/user/stores/userdb"
+	"github.com/TSMC-Uber/server/business/sys/ratelimit"
 	"github.com/TSMC-Uber/server/business/web/v1/auth"
 	"github.com/TSMC-Uber/server/business/web/v1/mid"
 	"github.com/TSMC-Uber/server/foundation/logger"
@@ -17,10 +18,11 @@ import (
 
 // Config contains all the mandatory systems required by handlers.
 type Config struct {
-	Log     *logger.Logger
-	Auth    *auth.Auth
-	DB      *sqlx.DB
-	RedisDB struct {
+	Log        *logger.Logger
+	Auth       *auth.Auth
+	DB         *sqlx.DB
+	RateLimits map[string]ratelimit.Policy
+	RedisDB    struct {
 		Master  *redis.Client
 		Replica *redis.Client
 	}
@@ -32,11 +34,22 @@ func Routes(app *web.App, cfg Config) {
 
 	userCore := user.NewCore(userdb.NewStore(cfg.Log, cfg.DB))
 
-	authCore := aauth.NewCore(authdb.NewStore(cfg.Log, cfg.DB))
-
-	authenGoogle := mid.AuthGoogle(cfg.Auth, authCore)
-
-	hdl := New(authCore, userCore)
-	app.Handle(http.MethodPost, version, "/auth/login", hdl.Login, authenGoogle)
-	app.Handle(http.MethodPost, version, "/auth/logout", hdl.Logout, authenGoogle)
+	authCore := aauth.NewCore(authredisdb.NewStore(cfg.Log))
+
+	authen := mid.Authenticate(cfg.Auth)
+	authenIDToken := mid.AuthIDToken(cfg.Auth)
+	adminOnly := mid.Authorize(cfg.Auth, user.RoleAdmin)
+	loginLimit := mid.RateLimit(cfg.Log, "login", cfg.RateLimits["login"])
+
+	hdl := New(authCore, userCore, cfg.Auth)
+	app.Handle(http.MethodPost, version, "/auth/nonce", hdl.IssueNonce, loginLimit)
+	app.Handle(http.MethodPost, version, "/auth/login", hdl.Login, loginLimit, authenIDToken)
+	app.Handle(http.MethodPost, version, "/auth/token", hdl.Refresh)
+	app.Handle(http.MethodPost, version, "/auth/logout", hdl.Logout, authen)
+	app.Handle(http.MethodPost, version, "/auth/tickets", hdl.IssueTicket, authen)
+	app.Handle(http.MethodPost, version, "/auth/identities", hdl.LinkIdentity, authen, authenIDToken)
+	app.Handle(http.MethodGet, version, "/auth/sessions", hdl.QuerySessions, authen)
+	app.Handle(http.MethodDelete, version, "/auth/sessions", hdl.RevokeSessions, authen)
+	app.Handle(http.MethodDelete, version, "/auth/sessions/:id", hdl.RevokeSession, authen)
+	app.Handle(http.MethodDelete, version, "/users/:id/sessions", hdl.RevokeUserSessions, authen, adminOnly)
 }
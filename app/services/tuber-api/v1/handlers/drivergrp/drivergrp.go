@@ -7,23 +7,33 @@ import (
 	"fmt"
 	"net/http"
 
+	aauth "github.com/TSMC-Uber/server/business/core/auth"
 	"github.com/TSMC-Uber/server/business/core/driver"
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/core/user"
 	"github.com/TSMC-Uber/server/business/web/v1/auth"
 	"github.com/TSMC-Uber/server/business/web/v1/paging"
 	"github.com/TSMC-Uber/server/business/web/v1/response"
 	"github.com/TSMC-Uber/server/foundation/web"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // Handlers manages the set of user endpoints.
 type Handlers struct {
 	driver *driver.Core
+	trip   *trip.Core
+	user   *user.Core
+	auth   *aauth.Core
 }
 
 // New constructs a handlers for route access.
-func New(driver *driver.Core) *Handlers {
+func New(driver *driver.Core, trip *trip.Core, user *user.Core, auth *aauth.Core) *Handlers {
 	return &Handlers{
 		driver: driver,
+		trip:   trip,
+		user:   user,
+		auth:   auth,
 	}
 }
 
@@ -58,6 +68,21 @@ func (h *Handlers) Create(ctx context.Context, c *gin.Context) error {
 		return fmt.Errorf("create: driver[%+v]: %w", driver, err)
 	}
 
+	// grant the driver role and apply it to the sessions of the user
+	usr, err := h.user.QueryByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("querybyid: userID[%s]: %w", userID, err)
+	}
+
+	usr, err = h.user.AddRole(ctx, usr, user.RoleDriver)
+	if err != nil {
+		return fmt.Errorf("addrole: userID[%s]: %w", userID, err)
+	}
+
+	if err := h.auth.RefreshUser(ctx, usr); err != nil {
+		return fmt.Errorf("refreshuser: userID[%s]: %w", userID, err)
+	}
+
 	return web.Respond(ctx, c.Writer, toAppDriver(driver), http.StatusCreated)
 }
 
@@ -132,6 +157,58 @@ func (h *Handlers) QueryByID(ctx context.Context, c *gin.Context) error {
 	return web.Respond(ctx, c.Writer, toAppDriver(qdriver), http.StatusOK)
 }
 
+// @Summary get the ratings of a driver
+// @Schemes
+// @Description QueryRatings will query the ratings left on the trips of a driver, newest first
+// @Tags driver
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Success 200 {object} AppDriverRating "Ratings successfully queried"
+// @Failure 400 "Bad Request"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /drivers/{id}/ratings [get]
+func (h *Handlers) QueryRatings(ctx context.Context, c *gin.Context) error {
+	id := c.Param("id")
+
+	driverID, err := uuid.Parse(id)
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	page, err := paging.ParseRequest(c.Request)
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.driver.QueryByID(ctx, id); err != nil {
+		switch {
+		case errors.Is(err, driver.ErrNotFound):
+			return response.NewError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("querybyid: id[%s]: %w", id, err)
+		}
+	}
+
+	ratings, err := h.trip.QueryRatingsByDriver(ctx, driverID, page.Number, page.RowsPerPage)
+	if err != nil {
+		return fmt.Errorf("queryratingsbydriver: id[%s]: %w", id, err)
+	}
+
+	items := make([]AppDriverRating, len(ratings))
+	for i, rating := range ratings {
+		items[i] = toAppDriverRating(rating)
+	}
+
+	total, err := h.trip.CountRatingsByDriver(ctx, driverID)
+	if err != nil {
+		return fmt.Errorf("countratingsbydriver: id[%s]: %w", id, err)
+	}
+
+	return web.Respond(ctx, c.Writer, paging.NewResponse(items, total, page.Number, page.RowsPerPage), http.StatusOK)
+}
+
 // @Summary add a driver to favorite
 // @Schemes
 // @Description AddFavorite will add a driver to favorite
@@ -2,6 +2,7 @@ package drivergrp
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/TSMC-Uber/server/business/core/driver"
 	"github.com/TSMC-Uber/server/business/sys/validate"
@@ -33,6 +34,14 @@ func parseFilter(r *http.Request) (driver.QueryFilter, error) {
 		filter.WithColor(color)
 	}
 
+	if minRating := values.Get("min_rating"); minRating != "" {
+		rating, err := strconv.ParseFloat(minRating, 64)
+		if err != nil {
+			return driver.QueryFilter{}, validate.NewFieldsError("min_rating", err)
+		}
+		filter.WithMinRating(rating)
+	}
+
 	if err := filter.Validate(); err != nil {
 		return driver.QueryFilter{}, err
 	}
@@ -4,31 +4,62 @@ import (
 	"time"
 
 	"github.com/TSMC-Uber/server/business/core/driver"
+	"github.com/TSMC-Uber/server/business/core/trip"
 )
 
 // AppDriver represents information about an individual location.
 type AppDriver struct {
-	UserID    string `json:"user_id"`
-	License   string `json:"license"`
-	Verified  bool   `json:"verified"`
-	Brand     string `json:"brand"`
-	Model     string `json:"model"`
-	Color     string `json:"color"`
-	Plate     string `json:"plate"`
-	CreatedAt string `json:"created_at"`
+	UserID        string  `json:"user_id"`
+	License       string  `json:"license"`
+	Verified      bool    `json:"verified"`
+	Brand         string  `json:"brand"`
+	Model         string  `json:"model"`
+	Color         string  `json:"color"`
+	Plate         string  `json:"plate"`
+	RatingAverage float64 `json:"rating_average"`
+	RatingCount   int     `json:"rating_count"`
+	CreatedAt     string  `json:"created_at"`
 }
 
 func toAppDriver(driver driver.Driver) AppDriver {
 
 	return AppDriver{
-		UserID:    driver.UserID.String(),
-		License:   driver.License,
-		Verified:  driver.Verified,
-		Brand:     driver.Brand,
-		Model:     driver.Model,
-		Color:     driver.Color,
-		Plate:     driver.Plate,
-		CreatedAt: driver.CreatedAt.Format(time.RFC3339),
+		UserID:        driver.UserID.String(),
+		License:       driver.License,
+		Verified:      driver.Verified,
+		Brand:         driver.Brand,
+		Model:         driver.Model,
+		Color:         driver.Color,
+		Plate:         driver.Plate,
+		RatingAverage: driver.RatingAverage,
+		RatingCount:   driver.RatingCount,
+		CreatedAt:     driver.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// AppDriverRating represents a rating left on a trip of a driver.
+type AppDriverRating struct {
+	ID                string `json:"id"`
+	TripID            string `json:"trip_id"`
+	CommenterID       string `json:"commenter_id"`
+	CommenterName     string `json:"commenter_name"`
+	CommenterImageURL string `json:"commenter_image_url"`
+	Comment           string `json:"comment"`
+	Rating            int    `json:"rating"`
+	CreatedAt         string `json:"created_at"`
+}
+
+func toAppDriverRating(rating trip.Rating) AppDriverRating {
+
+	return AppDriverRating{
+		ID:                rating.ID.String(),
+		TripID:            rating.TripID.String(),
+		CommenterID:       rating.CommenterID.String(),
+		CommenterName:     rating.CommenterName,
+		CommenterImageURL: rating.CommenterImageURL,
+		Comment:           rating.Comment,
+		Rating:            rating.Rating,
+		CreatedAt:         rating.CreatedAt.Format(time.RFC3339),
 	}
 }
 
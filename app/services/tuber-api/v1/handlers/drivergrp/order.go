@@ -10,9 +10,11 @@ import (
 )
 
 var orderByFields = map[string]struct{}{
-	driver.OrderByBrand: {},
-	driver.OrderByModel: {},
-	driver.OrderByColor: {},
+	driver.OrderByBrand:         {},
+	driver.OrderByModel:         {},
+	driver.OrderByColor:         {},
+	driver.OrderByRatingAverage: {},
+	driver.OrderByRatingCount:   {},
 }
 
 var orderByFieldsFavoriteDriver = map[string]struct{}{
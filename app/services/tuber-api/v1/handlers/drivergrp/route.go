@@ -3,8 +3,14 @@ package drivergrp
 import (
 	"net/http"
 
+	aauth "github.com/TSMC-Uber/server/business/core/auth"
+	"github.com/TSMC-Uber/server/business/core/auth/stores/authredisdb"
 	"github.com/TSMC-Uber/server/business/core/driver"
 	"github.com/TSMC-Uber/server/business/core/driver/stores/driverdb"
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/core/trip/stores/tripdb"
+	"github.com/TSMC-Uber/server/business/core/user"
+	"github.com/TSMC-Uber/server/business/core/user/stores/userdb"
 	"github.com/TSMC-Uber/server/business/web/v1/auth"
 	"github.com/TSMC-Uber/server/business/web/v1/mid"
 	"github.com/TSMC-Uber/server/foundation/logger"
@@ -31,13 +37,17 @@ func Routes(app *web.App, cfg Config) {
 	// usrCore := user.NewCore(cfg.Log, envCore, usercache.NewStore(cfg.Log, userdb.NewStore(cfg.Log, cfg.DB)))
 
 	driverCore := driver.NewCore(driverdb.NewStore(cfg.Log, cfg.DB))
+	tripCore := trip.NewCore(tripdb.NewStore(cfg.Log, cfg.DB))
+	usrCore := user.NewCore(userdb.NewStore(cfg.Log, cfg.DB))
+	authCore := aauth.NewCore(authredisdb.NewStore(cfg.Log))
 
 	authen := mid.Authenticate(cfg.Auth)
 
-	hdl := New(driverCore)
+	hdl := New(driverCore, tripCore, usrCore, authCore)
 	app.Handle(http.MethodPost, version, "/drivers", hdl.Create, authen)
 	app.Handle(http.MethodGet, version, "/drivers", hdl.Query)
 	app.Handle(http.MethodGet, version, "/drivers/:id", hdl.QueryByID)
+	app.Handle(http.MethodGet, version, "/drivers/:id/ratings", hdl.QueryRatings)
 	app.Handle(http.MethodGet, version, "/favorite-drivers", hdl.QueryFavorite, authen)
 	app.Handle(http.MethodPost, version, "/favorite-drivers/:id", hdl.AddFavorite, authen)
 }
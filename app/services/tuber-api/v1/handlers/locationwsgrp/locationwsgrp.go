@@ -2,107 +2,141 @@ package locationwsgrp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/TSMC-Uber/server/business/core/locationws"
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/sys/ratelimit"
+	"github.com/TSMC-Uber/server/business/web/v1/auth"
+	"github.com/TSMC-Uber/server/business/web/v1/response"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
 type Handlers struct {
 	locationws *locationws.Core
+	trip       *trip.Core
+	upgrader   websocket.Upgrader
+	limit      ratelimit.Policy
 }
 
-// New constructs a handlers for route access.
-func New(locationws *locationws.Core) *Handlers {
+// New constructs a handlers for route access. Only connections from the
+// allowed origins are upgraded, and the locations a driver publishes are
+// limited by the policy.
+func New(locationws *locationws.Core, trip *trip.Core, checkOrigin func(r *http.Request) bool, limit ratelimit.Policy) *Handlers {
 	return &Handlers{
 		locationws: locationws,
+		trip:       trip,
+		limit:      limit,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: checkOrigin,
+		},
 	}
 }
 
-var upgrader = websocket.Upgrader{
-	// TODO: check origin
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
-
+// DriverWebSocketHandler lets the driver of the trip publish its locations.
+// Like passengers, it first gets the last location of the trip, or the
+// locations after last_seen when it resumes a stream.
 func (h *Handlers) DriverWebSocketHandler(ctx context.Context, c *gin.Context) error {
-	// userID := auth.GetUserID(c)
-	// temp fix for testing, will remove later when auth is implemented
-	// we need to implement a middleware to get user info from DB
-	// if userID == uuid.Nil {
-	// 	id, err := uuid.Parse("00000000-0000-0000-0000-000000000000")
-	// 	if err != nil {
-	// 		return fmt.Errorf("parse uuid: %w", err)
-	// 	}
-
-	// 	userID = id
-	// }
+	qtrip, err := h.queryTrip(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	if qtrip.DriverID != auth.GetUserID(ctx) {
+		return response.NewError(errors.New("user is not the driver of the trip"), http.StatusForbidden)
+	}
+
+	lastSeen, err := parseLastSeen(c)
+	if err != nil {
+		return err
+	}
 
 	// upgrade get request to websocket protocol
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		return fmt.Errorf("upgrade: %w", err)
 	}
 	defer conn.Close()
 
-	tripID := c.Request.URL.Query().Get("trip_id")
-	if tripID == "" {
-		conn.WriteMessage(websocket.TextMessage, []byte("No trip specified"))
-		return fmt.Errorf("no trip specified")
+	if err := h.locationws.ServeClient(ctx, conn, qtrip.ID.String(), qtrip.DriverID, true, h.limit, lastSeen); err != nil {
+		return fmt.Errorf("serve client: tripID[%s]: %w", qtrip.ID, err)
+	}
+	return nil
+}
+
+// PassengerWebSocketHandler lets the accepted passengers of the trip follow
+// the locations of the driver. A passenger reconnecting can pass the stream
+// id of the last location it saw as last_seen to get every location after it.
+func (h *Handlers) PassengerWebSocketHandler(ctx context.Context, c *gin.Context) error {
+	qtrip, err := h.queryTrip(ctx, c)
+	if err != nil {
+		return err
 	}
 
-	// TODO: check if user is driver for this trip
+	userID := auth.GetUserID(ctx)
 
-	_, err = locationws.ServeClient(
-		conn,
-		tripID,
-		true,
-	)
+	ok, err := h.trip.IsParticipant(ctx, qtrip, userID)
 	if err != nil {
-		return fmt.Errorf("serve client: %w", err)
+		return fmt.Errorf("isparticipant: tripID[%s]: %w", qtrip.ID, err)
+	}
+	if !ok {
+		return response.NewError(errors.New("user is not a participant of the trip"), http.StatusForbidden)
 	}
-	return nil
-}
 
-func (h *Handlers) PassengerWebSocketHandler(ctx context.Context, c *gin.Context) error {
-	// userID := auth.GetUserID(c)
-	// temp fix for testing, will remove later when auth is implemented
-	// we need to implement a middleware to get user info from DB
-	// if userID == uuid.Nil {
-	// 	id, err := uuid.Parse("00000000-0000-0000-0000-000000000000")
-	// 	if err != nil {
-	// 		return fmt.Errorf("parse uuid: %w", err)
-	// 	}
-
-	// 	userID = id
-	// }
+	lastSeen, err := parseLastSeen(c)
+	if err != nil {
+		return err
+	}
 
 	// upgrade get request to websocket protocol
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		return fmt.Errorf("upgrade: %w", err)
 	}
 	defer conn.Close()
 
-	tripID := c.Request.URL.Query().Get("trip_id")
-	if tripID == "" {
-		conn.WriteMessage(websocket.TextMessage, []byte("No trip specified"))
-		return fmt.Errorf("no trip specified")
+	if err := h.locationws.ServeClient(ctx, conn, qtrip.ID.String(), userID, false, ratelimit.Policy{}, lastSeen); err != nil {
+		return fmt.Errorf("serve client: tripID[%s]: %w", qtrip.ID, err)
 	}
 
-	// TODO: check if user is passenger for this trip
+	return nil
+}
 
-	_, err = locationws.ServeClient(
-		conn,
-		tripID,
-		false,
-	)
+// queryTrip returns the trip named by the trip_id query parameter.
+func (h *Handlers) queryTrip(ctx context.Context, c *gin.Context) (trip.TripView, error) {
+	tripID, err := uuid.Parse(c.Query("trip_id"))
 	if err != nil {
-		return fmt.Errorf("serve client: %w", err)
+		return trip.TripView{}, response.NewError(fmt.Errorf("parse trip id: %w", err), http.StatusBadRequest)
 	}
 
-	return nil
+	qtrip, err := h.trip.QueryByID(ctx, tripID)
+	if err != nil {
+		switch {
+		case errors.Is(err, trip.ErrNotFound):
+			return trip.TripView{}, response.NewError(err, http.StatusNotFound)
+		default:
+			return trip.TripView{}, fmt.Errorf("querybyid: tripID[%s]: %w", tripID, err)
+		}
+	}
+
+	return qtrip, nil
+}
+
+// parseLastSeen returns the stream id given as the last_seen query
+// parameter, empty when there is none.
+func parseLastSeen(c *gin.Context) (string, error) {
+	lastSeen := c.Query("last_seen")
+	if lastSeen == "" {
+		return "", nil
+	}
+
+	if err := locationws.ValidateStreamID(lastSeen); err != nil {
+		return "", response.NewError(fmt.Errorf("parse last seen: %w", err), http.StatusBadRequest)
+	}
+
+	return lastSeen, nil
 }
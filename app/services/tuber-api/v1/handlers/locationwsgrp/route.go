@@ -2,27 +2,44 @@ package locationwsgrp
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/TSMC-Uber/server/business/core/locationws"
+	"github.com/TSMC-Uber/server/business/core/locationws/stores/locationwsdb"
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/core/trip/stores/tripdb"
+	"github.com/TSMC-Uber/server/business/sys/ratelimit"
 	"github.com/TSMC-Uber/server/business/web/v1/auth"
+	"github.com/TSMC-Uber/server/business/web/v1/mid"
 	"github.com/TSMC-Uber/server/foundation/logger"
 	"github.com/TSMC-Uber/server/foundation/web"
+	"github.com/jmoiron/sqlx"
 )
 
 type Config struct {
-	Log  *logger.Logger
-	Auth *auth.Auth
+	Log        *logger.Logger
+	Auth       *auth.Auth
+	DB         *sqlx.DB
+	Origins    []string
+	RateLimits map[string]ratelimit.Policy
+	Dispatcher *locationws.RoomsDispatcher
+	Recorder   *locationws.Recorder
+
+	// StreamRetention is how long the locations are kept for clients to
+	// resume from, zero keeps none.
+	StreamRetention time.Duration
 }
 
 // Routes adds specific routes for this group.
 func Routes(app *web.App, cfg Config) {
 	const version = "v1"
 
-	locationwsCore := locationws.NewCore(cfg.Log)
+	locationwsCore := locationws.NewCore(cfg.Log, locationwsdb.NewStore(cfg.Log, cfg.DB), cfg.Dispatcher, cfg.Recorder, cfg.StreamRetention)
+	tripCore := trip.NewCore(tripdb.NewStore(cfg.Log, cfg.DB))
 
-	// authen := mid.Authenticate(cfg.Auth)
+	authen := mid.Authenticate(cfg.Auth)
 
-	hdl := New(locationwsCore)
-	app.Handle(http.MethodGet, version, "/ws/driver", hdl.DriverWebSocketHandler)
-	app.Handle(http.MethodGet, version, "/ws/passenger", hdl.PassengerWebSocketHandler)
+	hdl := New(locationwsCore, tripCore, web.CheckOrigin(cfg.Origins), cfg.RateLimits["location"])
+	app.Handle(http.MethodGet, version, "/ws/driver", hdl.DriverWebSocketHandler, authen)
+	app.Handle(http.MethodGet, version, "/ws/passenger", hdl.PassengerWebSocketHandler, authen)
 }
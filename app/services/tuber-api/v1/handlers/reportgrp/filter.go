@@ -0,0 +1,49 @@
+package reportgrp
+
+import (
+	"net/http"
+
+	"github.com/TSMC-Uber/server/business/core/report"
+	"github.com/TSMC-Uber/server/business/sys/validate"
+	"github.com/google/uuid"
+)
+
+func parseFilter(r *http.Request) (report.QueryFilter, error) {
+	values := r.URL.Query()
+
+	var filter report.QueryFilter
+
+	if status := values.Get("status"); status != "" {
+		filter.WithStatus(status)
+	}
+
+	if tripID := values.Get("trip_id"); tripID != "" {
+		id, err := uuid.Parse(tripID)
+		if err != nil {
+			return report.QueryFilter{}, validate.NewFieldsError("trip_id", err)
+		}
+		filter.WithTripID(id)
+	}
+
+	if defendantID := values.Get("defendant_id"); defendantID != "" {
+		id, err := uuid.Parse(defendantID)
+		if err != nil {
+			return report.QueryFilter{}, validate.NewFieldsError("defendant_id", err)
+		}
+		filter.WithDefendantID(id)
+	}
+
+	if assigneeID := values.Get("assignee_id"); assigneeID != "" {
+		id, err := uuid.Parse(assigneeID)
+		if err != nil {
+			return report.QueryFilter{}, validate.NewFieldsError("assignee_id", err)
+		}
+		filter.WithAssigneeID(id)
+	}
+
+	if err := filter.Validate(); err != nil {
+		return report.QueryFilter{}, err
+	}
+
+	return filter, nil
+}
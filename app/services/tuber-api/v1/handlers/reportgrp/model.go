@@ -0,0 +1,170 @@
+package reportgrp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/core/report"
+	"github.com/TSMC-Uber/server/business/sys/validate"
+	"github.com/google/uuid"
+)
+
+// AppReport represents information about an individual report.
+type AppReport struct {
+	ID            string `json:"id"`
+	TripID        string `json:"trip_id"`
+	ComplainantID string `json:"complainant_id"`
+	DefendantID   string `json:"defendant_id"`
+	Comment       string `json:"comment"`
+	Status        string `json:"status"`
+	AssigneeID    string `json:"assignee_id,omitempty"`
+	Resolution    string `json:"resolution,omitempty"`
+	Suspended     bool   `json:"suspended"`
+	ResolvedBy    string `json:"resolved_by,omitempty"`
+	ResolvedAt    string `json:"resolved_at,omitempty"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+}
+
+func toAppReport(rpt report.Report) AppReport {
+	app := AppReport{
+		ID:            rpt.ID.String(),
+		TripID:        rpt.TripID.String(),
+		ComplainantID: rpt.ComplainantID.String(),
+		DefendantID:   rpt.DefendantID.String(),
+		Comment:       rpt.Comment,
+		Status:        rpt.Status,
+		Resolution:    rpt.Resolution,
+		Suspended:     rpt.Suspended,
+		CreatedAt:     rpt.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:     rpt.UpdatedAt.Format(time.RFC3339),
+	}
+
+	if rpt.AssigneeID != nil {
+		app.AssigneeID = rpt.AssigneeID.String()
+	}
+	if rpt.ResolvedBy != nil {
+		app.ResolvedBy = rpt.ResolvedBy.String()
+	}
+	if rpt.ResolvedAt != nil {
+		app.ResolvedAt = rpt.ResolvedAt.Format(time.RFC3339)
+	}
+
+	return app
+}
+
+// AppReportDetails is a report together with the notes left on it.
+type AppReportDetails struct {
+	AppReport
+	Notes []AppNote `json:"notes"`
+}
+
+// AppNote represents a remark left on a report.
+type AppNote struct {
+	ID        string `json:"id"`
+	AuthorID  string `json:"author_id"`
+	Note      string `json:"note"`
+	CreatedAt string `json:"created_at"`
+}
+
+func toAppNote(note report.Note) AppNote {
+	return AppNote{
+		ID:        note.ID.String(),
+		AuthorID:  note.AuthorID.String(),
+		Note:      note.Note,
+		CreatedAt: note.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func toAppReportDetails(rpt report.Report, notes []report.Note) AppReportDetails {
+	items := make([]AppNote, len(notes))
+	for i, note := range notes {
+		items[i] = toAppNote(note)
+	}
+
+	return AppReportDetails{
+		AppReport: toAppReport(rpt),
+		Notes:     items,
+	}
+}
+
+// =============================================================================
+
+// AppNewReport contains information needed to report another participant of
+// a trip.
+type AppNewReport struct {
+	DefendantID string `json:"defendant_id" validate:"required,uuid"`
+	Comment     string `json:"comment" validate:"required"`
+}
+
+func toCoreNewReport(app AppNewReport) (report.NewReport, error) {
+	defendantID, err := uuid.Parse(app.DefendantID)
+	if err != nil {
+		return report.NewReport{}, fmt.Errorf("parse defendantID: %w", err)
+	}
+
+	nr := report.NewReport{
+		DefendantID: defendantID,
+		Comment:     app.Comment,
+	}
+
+	return nr, nil
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppNewReport) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AppTriageReport contains the optional note left when taking a report.
+type AppTriageReport struct {
+	Note string `json:"note"`
+}
+
+// AppNewNote contains information needed to leave a note on a report.
+type AppNewNote struct {
+	Note string `json:"note" validate:"required"`
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppNewNote) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AppResolveReport contains information needed to close a report. Without
+// suspend_days a suspension has no end.
+type AppResolveReport struct {
+	Status      string `json:"status" validate:"required,oneof=resolved dismissed"`
+	Resolution  string `json:"resolution" validate:"required"`
+	Suspend     bool   `json:"suspend"`
+	SuspendDays *int   `json:"suspend_days" validate:"omitempty,gte=1"`
+}
+
+func toCoreResolution(app AppResolveReport) report.Resolution {
+	res := report.Resolution{
+		Status:     app.Status,
+		Resolution: app.Resolution,
+		Suspend:    app.Suspend,
+	}
+
+	if app.SuspendDays != nil {
+		d := time.Duration(*app.SuspendDays) * 24 * time.Hour
+		res.SuspendFor = &d
+	}
+
+	return res
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppResolveReport) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+// Package reportgrp maintains the group of handlers for report access.
+package reportgrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/TSMC-Uber/server/business/core/report"
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/core/user"
+	"github.com/TSMC-Uber/server/business/web/v1/auth"
+	"github.com/TSMC-Uber/server/business/web/v1/paging"
+	"github.com/TSMC-Uber/server/business/web/v1/response"
+	"github.com/TSMC-Uber/server/foundation/web"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handlers manages the set of report endpoints.
+type Handlers struct {
+	report *report.Core
+}
+
+// New constructs a handlers for route access.
+func New(report *report.Core) *Handlers {
+	return &Handlers{
+		report: report,
+	}
+}
+
+// @Summary report a participant of a trip
+// @Schemes
+// @Description Create will report another participant of the same trip to the moderators
+// @Tags report
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param id path string true "Trip ID"
+// @Param body body AppNewReport true "New Report"
+// @Success 201 {object} AppReport "Report successfully filed"
+// @Failure 400 "Bad Request"
+// @Failure 403 "Forbidden"
+// @Failure 404 "Not Found"
+// @Failure 409 "Already reported"
+// @Failure 500 "Internal Server Error"
+// @Router /trips/{id}/reports [post]
+func (h *Handlers) Create(ctx context.Context, c *gin.Context) error {
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	var app AppNewReport
+	if err := web.Decode(c, &app); err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	if err := app.Validate(); err != nil {
+		return err
+	}
+
+	nr, err := toCoreNewReport(app)
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+	nr.ComplainantID = auth.GetUserID(ctx)
+
+	rpt, err := h.report.Create(ctx, tripID, nr)
+	if err != nil {
+		return reportError(err)
+	}
+
+	return web.Respond(ctx, c.Writer, toAppReport(rpt), http.StatusCreated)
+}
+
+// @Summary get reports
+// @Schemes
+// @Description Query will query the moderation queue, oldest reports first
+// @Tags report
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param page query int false "Page"
+// @Param rows query int false "Rows"
+// @Param status query string false "Status"
+// @Param trip_id query string false "Trip ID"
+// @Param defendant_id query string false "Defendant ID"
+// @Param assignee_id query string false "Assignee ID"
+// @Success 200 {object} AppReport "Reports successfully queried"
+// @Failure 400 "Bad Request"
+// @Failure 403 "Forbidden"
+// @Failure 500 "Internal Server Error"
+// @Router /reports [get]
+func (h *Handlers) Query(ctx context.Context, c *gin.Context) error {
+	if err := h.requireModerator(ctx); err != nil {
+		return err
+	}
+
+	page, err := paging.ParseRequest(c.Request)
+	if err != nil {
+		return err
+	}
+
+	filter, err := parseFilter(c.Request)
+	if err != nil {
+		return err
+	}
+
+	orderBy, err := parseOrder(c.Request)
+	if err != nil {
+		return err
+	}
+
+	reports, err := h.report.Query(ctx, filter, orderBy, page.Number, page.RowsPerPage)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	items := make([]AppReport, len(reports))
+	for i, rpt := range reports {
+		items[i] = toAppReport(rpt)
+	}
+
+	total, err := h.report.Count(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("count: %w", err)
+	}
+
+	return web.Respond(ctx, c.Writer, paging.NewResponse(items, total, page.Number, page.RowsPerPage), http.StatusOK)
+}
+
+// @Summary get report by id
+// @Schemes
+// @Description QueryByID will query a report together with its notes
+// @Tags report
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param id path string true "Report ID"
+// @Success 200 {object} AppReportDetails "query report"
+// @Failure 400 "Bad Request"
+// @Failure 403 "Forbidden"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /reports/{id} [get]
+func (h *Handlers) QueryByID(ctx context.Context, c *gin.Context) error {
+	if err := h.requireModerator(ctx); err != nil {
+		return err
+	}
+
+	rpt, err := h.queryReport(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	notes, err := h.report.QueryNotes(ctx, rpt.ID)
+	if err != nil {
+		return fmt.Errorf("querynotes: %w", err)
+	}
+
+	return web.Respond(ctx, c.Writer, toAppReportDetails(rpt, notes), http.StatusOK)
+}
+
+// @Summary triage a report
+// @Schemes
+// @Description Triage will assign the report to the calling moderator and start the review
+// @Tags report
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param id path string true "Report ID"
+// @Param body body AppTriageReport false "Triage Note"
+// @Success 200 {object} AppReport "Report successfully triaged"
+// @Failure 400 "Bad Request"
+// @Failure 403 "Forbidden"
+// @Failure 404 "Not Found"
+// @Failure 409 "Report cannot move to this status"
+// @Failure 500 "Internal Server Error"
+// @Router /reports/{id}/triage [put]
+func (h *Handlers) Triage(ctx context.Context, c *gin.Context) error {
+	if err := h.requireModerator(ctx); err != nil {
+		return err
+	}
+
+	var app AppTriageReport
+	if c.Request.ContentLength != 0 {
+		if err := web.Decode(c, &app); err != nil {
+			return response.NewError(err, http.StatusBadRequest)
+		}
+	}
+
+	rpt, err := h.queryReport(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	rpt, err = h.report.Triage(ctx, rpt, auth.GetUserID(ctx), app.Note)
+	if err != nil {
+		return reportError(err)
+	}
+
+	return web.Respond(ctx, c.Writer, toAppReport(rpt), http.StatusOK)
+}
+
+// @Summary leave a note on a report
+// @Schemes
+// @Description CreateNote will leave a moderator note on a report
+// @Tags report
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param id path string true "Report ID"
+// @Param body body AppNewNote true "New Note"
+// @Success 201 {object} AppNote "Note successfully created"
+// @Failure 400 "Bad Request"
+// @Failure 403 "Forbidden"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /reports/{id}/notes [post]
+func (h *Handlers) CreateNote(ctx context.Context, c *gin.Context) error {
+	if err := h.requireModerator(ctx); err != nil {
+		return err
+	}
+
+	var app AppNewNote
+	if err := web.Decode(c, &app); err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	if err := app.Validate(); err != nil {
+		return err
+	}
+
+	rpt, err := h.queryReport(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	note, err := h.report.AddNote(ctx, rpt, auth.GetUserID(ctx), app.Note)
+	if err != nil {
+		return fmt.Errorf("addnote: %w", err)
+	}
+
+	return web.Respond(ctx, c.Writer, toAppNote(note), http.StatusCreated)
+}
+
+// @Summary resolve a report
+// @Schemes
+// @Description Resolve will resolve or dismiss a report, optionally suspending the defendant
+// @Tags report
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param id path string true "Report ID"
+// @Param body body AppResolveReport true "Resolution"
+// @Success 200 {object} AppReport "Report successfully resolved"
+// @Failure 400 "Bad Request"
+// @Failure 403 "Forbidden"
+// @Failure 404 "Not Found"
+// @Failure 409 "Report cannot move to this status"
+// @Failure 500 "Internal Server Error"
+// @Router /reports/{id}/resolve [put]
+func (h *Handlers) Resolve(ctx context.Context, c *gin.Context) error {
+	if err := h.requireModerator(ctx); err != nil {
+		return err
+	}
+
+	var app AppResolveReport
+	if err := web.Decode(c, &app); err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	if err := app.Validate(); err != nil {
+		return err
+	}
+
+	rpt, err := h.queryReport(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	rpt, err = h.report.Resolve(ctx, rpt, auth.GetUserID(ctx), toCoreResolution(app))
+	if err != nil {
+		return reportError(err)
+	}
+
+	return web.Respond(ctx, c.Writer, toAppReport(rpt), http.StatusOK)
+}
+
+// requireModerator rejects callers that do not work the moderation queue.
+// Admins always do.
+func (h *Handlers) requireModerator(ctx context.Context) error {
+	if auth.GetClaims(ctx).HasRole(user.RoleAdmin) {
+		return nil
+	}
+
+	ok, err := h.report.IsModerator(ctx, auth.GetUserID(ctx))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return response.NewError(report.ErrNotModerator, http.StatusForbidden)
+	}
+
+	return nil
+}
+
+// queryReport loads the report named in the path.
+func (h *Handlers) queryReport(ctx context.Context, c *gin.Context) (report.Report, error) {
+	reportID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return report.Report{}, response.NewError(err, http.StatusBadRequest)
+	}
+
+	rpt, err := h.report.QueryByID(ctx, reportID)
+	if err != nil {
+		return report.Report{}, reportError(err)
+	}
+
+	return rpt, nil
+}
+
+// reportError maps the errors of the report core to responses.
+func reportError(err error) error {
+	switch {
+	case errors.Is(err, report.ErrReportSelf):
+		return response.NewError(err, http.StatusBadRequest)
+	case errors.Is(err, report.ErrNotParticipant):
+		return response.NewError(err, http.StatusForbidden)
+	case errors.Is(err, report.ErrNotFound), errors.Is(err, trip.ErrNotFound):
+		return response.NewError(err, http.StatusNotFound)
+	case errors.Is(err, report.ErrAlreadyReported), errors.Is(err, report.ErrInvalidTransition):
+		return response.NewError(err, http.StatusConflict)
+	default:
+		return fmt.Errorf("report: %w", err)
+	}
+}
@@ -0,0 +1,43 @@
+package reportgrp
+
+import (
+	"net/http"
+
+	"github.com/TSMC-Uber/server/business/core/report"
+	"github.com/TSMC-Uber/server/business/core/report/stores/reportdb"
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/core/trip/stores/tripdb"
+	"github.com/TSMC-Uber/server/business/core/user"
+	"github.com/TSMC-Uber/server/business/core/user/stores/userdb"
+	"github.com/TSMC-Uber/server/business/web/v1/auth"
+	"github.com/TSMC-Uber/server/business/web/v1/mid"
+	"github.com/TSMC-Uber/server/foundation/logger"
+	"github.com/TSMC-Uber/server/foundation/web"
+	"github.com/jmoiron/sqlx"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log  *logger.Logger
+	Auth *auth.Auth
+	DB   *sqlx.DB
+}
+
+// Routes adds specific routes for this group.
+func Routes(app *web.App, cfg Config) {
+	const version = "v1"
+
+	tripCore := trip.NewCore(tripdb.NewStore(cfg.Log, cfg.DB))
+	usrCore := user.NewCore(userdb.NewStore(cfg.Log, cfg.DB))
+	reportCore := report.NewCore(reportdb.NewStore(cfg.Log, cfg.DB), tripCore, usrCore)
+
+	authen := mid.Authenticate(cfg.Auth)
+
+	hdl := New(reportCore)
+	app.Handle(http.MethodPost, version, "/trips/:id/reports", hdl.Create, authen)
+	app.Handle(http.MethodGet, version, "/reports", hdl.Query, authen)
+	app.Handle(http.MethodGet, version, "/reports/:id", hdl.QueryByID, authen)
+	app.Handle(http.MethodPut, version, "/reports/:id/triage", hdl.Triage, authen)
+	app.Handle(http.MethodPost, version, "/reports/:id/notes", hdl.CreateNote, authen)
+	app.Handle(http.MethodPut, version, "/reports/:id/resolve", hdl.Resolve, authen)
+}
@@ -2,7 +2,9 @@ package tripgrp
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
@@ -72,6 +74,34 @@ func parseFilter(r *http.Request) (trip.QueryFilter, error) {
 		filter.WithEndStartDate(t)
 	}
 
+	pickup, err := parseGeoPoint(values, "pickup")
+	if err != nil {
+		return trip.QueryFilter{}, err
+	}
+	if pickup != nil {
+		filter.WithPickup(pickup.Lat, pickup.Lon)
+	}
+
+	dropoff, err := parseGeoPoint(values, "dropoff")
+	if err != nil {
+		return trip.QueryFilter{}, err
+	}
+	if dropoff != nil {
+		filter.WithDropoff(dropoff.Lat, dropoff.Lon)
+	}
+
+	if radius := values.Get("radius"); radius != "" {
+		r, err := strconv.ParseFloat(radius, 64)
+		if err != nil {
+			return trip.QueryFilter{}, validate.NewFieldsError("radius", err)
+		}
+		filter.WithRadius(r)
+	}
+
+	if filter.IsGeoSearch() && filter.Radius == nil {
+		filter.WithRadius(trip.DefaultSearchRadius)
+	}
+
 	if err := filter.Validate(); err != nil {
 		return trip.QueryFilter{}, err
 	}
@@ -79,15 +109,46 @@ func parseFilter(r *http.Request) (trip.QueryFilter, error) {
 	return filter, nil
 }
 
+// parseGeoPoint reads the <prefix>_lat and <prefix>_lon query parameters,
+// both of which must be provided together.
+func parseGeoPoint(values url.Values, prefix string) (*trip.GeoPoint, error) {
+	latField := prefix + "_lat"
+	lonField := prefix + "_lon"
+
+	lat, lon := values.Get(latField), values.Get(lonField)
+	if lat == "" && lon == "" {
+		return nil, nil
+	}
+
+	if lat == "" {
+		return nil, validate.NewFieldsError(latField, fmt.Errorf("%s is required with %s", latField, lonField))
+	}
+	if lon == "" {
+		return nil, validate.NewFieldsError(lonField, fmt.Errorf("%s is required with %s", lonField, latField))
+	}
+
+	latValue, err := strconv.ParseFloat(lat, 64)
+	if err != nil {
+		return nil, validate.NewFieldsError(latField, err)
+	}
+
+	lonValue, err := strconv.ParseFloat(lon, 64)
+	if err != nil {
+		return nil, validate.NewFieldsError(lonField, err)
+	}
+
+	return &trip.GeoPoint{Lat: latValue, Lon: lonValue}, nil
+}
+
 func parseFilterByUser(r *http.Request) (trip.QueryFilterByUser, error) {
 	values := r.URL.Query()
 
 	var filter trip.QueryFilterByUser
 
 	if status := values.Get("status"); status != "" {
-		// status should only have 3 values: "not_start", "in_trip", "finished"
-		if status != "not_start" && status != "in_trip" && status != "finished" {
-			return trip.QueryFilterByUser{}, errors.New("status should only have 3 values: not_start, in_trip, finished")
+		// status should only have 4 values: "not_start", "in_trip", "finished", "cancelled"
+		if status != trip.TripStatusNotStarted && status != trip.TripStatusIn && status != trip.TripStatusFinished && status != trip.TripStatusCancelled {
+			return trip.QueryFilterByUser{}, errors.New("status should only have 4 values: not_start, in_trip, finished, cancelled")
 		}
 		filter.WithStatus(status)
 	}
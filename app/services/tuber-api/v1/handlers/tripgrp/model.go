@@ -1,8 +1,10 @@
 package tripgrp
 
 import (
+	"encoding/xml"
 	"time"
 
+	"github.com/TSMC-Uber/server/business/core/locationws"
 	"github.com/TSMC-Uber/server/business/core/trip"
 	"github.com/TSMC-Uber/server/business/sys/validate"
 	"github.com/google/uuid"
@@ -35,10 +37,11 @@ func toAppTrip(trip trip.Trip) AppTrip {
 }
 
 type AppNewTripLocation struct {
-	Name    string  `json:"name" binding:"required"`
-	PlaceID string  `json:"place_id" binding:"required"`
-	Lat     float64 `json:"lat" binding:"required"`
-	Lon     float64 `json:"lon" binding:"required"`
+	Name           string  `json:"name" binding:"required"`
+	PlaceID        string  `json:"place_id" binding:"required"`
+	Lat            float64 `json:"lat" binding:"required"`
+	Lon            float64 `json:"lon" binding:"required"`
+	PlannedArrival string  `json:"planned_arrival"`
 }
 
 type AppNewTrip struct {
@@ -56,15 +59,25 @@ func toCoreNewTrip(app AppNewTrip) (trip.NewTrip, error) {
 		return trip.NewTrip{}, err
 	}
 
-	// construct mid
+	// construct mid, in the order the driver will stop at them
 	mid := []trip.TripLocation{}
 	for _, appMid := range app.Mid {
-		mid = append(mid, trip.TripLocation{
+		tripLocation := trip.TripLocation{
 			Name:    appMid.Name,
 			PlaceID: appMid.PlaceID,
 			Lat:     appMid.Lat,
 			Lon:     appMid.Lon,
-		})
+		}
+
+		if appMid.PlannedArrival != "" {
+			plannedArrival, err := time.Parse(time.RFC3339, appMid.PlannedArrival)
+			if err != nil {
+				return trip.NewTrip{}, err
+			}
+			tripLocation.PlannedArrival = &plannedArrival
+		}
+
+		mid = append(mid, tripLocation)
 	}
 
 	trip := trip.NewTrip{
@@ -89,20 +102,37 @@ func toCoreNewTrip(app AppNewTrip) (trip.NewTrip, error) {
 }
 
 type AppUpdateTrip struct {
-	PassengerLimit int    `json:"passenger_limit"`
-	Status         string `json:"status"`
+	PassengerLimit *int    `json:"passenger_limit" validate:"omitempty,gte=1"`
+	Status         *string `json:"status" validate:"omitempty,oneof=not_start in_trip finished cancelled"`
+	StartTime      *string `json:"start_time"`
 }
 
 func toCoreUpdateTrip(app AppUpdateTrip) (trip.UpdateTrip, error) {
 
 	trip := trip.UpdateTrip{
-		PassengerLimit: &app.PassengerLimit,
-		Status:         &app.Status,
+		PassengerLimit: app.PassengerLimit,
+		Status:         app.Status,
+	}
+
+	if app.StartTime != nil {
+		startTime, err := time.Parse(time.RFC3339, *app.StartTime)
+		if err != nil {
+			return trip, err
+		}
+		trip.StartTime = &startTime
 	}
 
 	return trip, nil
 }
 
+// Validate checks the data in the model is considered clean.
+func (app AppUpdateTrip) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+	return nil
+}
+
 // Validate checks the data in the model is considered clean.
 func (app AppNewTrip) Validate() error {
 	if err := validate.Check(app); err != nil {
@@ -117,6 +147,7 @@ type AppTripPassenger struct {
 	SourceID      string `json:"source_id"`
 	DestinationID string `json:"destination_id"`
 	Status        string `json:"status"`
+	StatusReason  string `json:"status_reason,omitempty"`
 	CreatedAt     string `json:"createdAt"`
 }
 
@@ -128,6 +159,7 @@ func toAppTripPassenger(tripPassenger trip.TripPassenger) AppTripPassenger {
 		SourceID:      tripPassenger.SourceID.String(),
 		DestinationID: tripPassenger.DestinationID.String(),
 		Status:        tripPassenger.Status,
+		StatusReason:  tripPassenger.StatusReason,
 		CreatedAt:     tripPassenger.CreatedAt.Format(time.RFC3339),
 	}
 }
@@ -184,24 +216,35 @@ type AppTripView struct {
 }
 
 type AppTripLocation struct {
-	ID      string
-	Name    string
-	PlaceID string
-	Lat     float64
-	Lon     float64
+	ID             string
+	Name           string
+	PlaceID        string
+	Lat            float64
+	Lon            float64
+	PlannedArrival string `json:",omitempty"`
+}
+
+func toAppTripLocation(tripLocation trip.TripLocation) AppTripLocation {
+	appTripLocation := AppTripLocation{
+		ID:      tripLocation.ID.String(),
+		Name:    tripLocation.Name,
+		PlaceID: tripLocation.PlaceID,
+		Lat:     tripLocation.Lat,
+		Lon:     tripLocation.Lon,
+	}
+
+	if tripLocation.PlannedArrival != nil {
+		appTripLocation.PlannedArrival = tripLocation.PlannedArrival.Format(time.RFC3339)
+	}
+
+	return appTripLocation
 }
 
 func toAppTripView(tripView trip.TripView) AppTripView {
 	// convert mid to AppTripLocation
 	mid := []AppTripLocation{}
 	for _, tripLocation := range tripView.Mid {
-		mid = append(mid, AppTripLocation{
-			ID:      tripLocation.ID.String(),
-			Name:    tripLocation.Name,
-			PlaceID: tripLocation.PlaceID,
-			Lat:     tripLocation.Lat,
-			Lon:     tripLocation.Lon,
-		})
+		mid = append(mid, toAppTripLocation(tripLocation))
 	}
 
 	return AppTripView{
@@ -237,6 +280,10 @@ type AppPassengerDetails struct {
 	PassengerName        string  `json:"passenger_name"`
 	PassengerImageURL    string  `json:"passenger_image_url"`
 	PassengerStatus      string  `json:"passenger_status"`
+	PassengerReason      string  `json:"passenger_status_reason,omitempty"`
+	PassengerRating      float64 `json:"passenger_rating_average"`
+	PassengerRatingCount int     `json:"passenger_rating_count"`
+	CompletedTrips       int     `json:"passenger_completed_trips"`
 	SourceName           string  `json:"source_name"`
 	SourcePlaceID        string  `json:"source_place_id"`
 	SourceLatitude       float64 `json:"source_latitude"`
@@ -278,6 +325,10 @@ func toAppPassengerDetails(passengerDetails []trip.PassengerDetails) []AppPassen
 			PassengerName:        passengerDetail.PassengerName,
 			PassengerImageURL:    passengerDetail.PassengerImageURL,
 			PassengerStatus:      passengerDetail.PassengerStatus,
+			PassengerReason:      passengerDetail.PassengerReason,
+			PassengerRating:      passengerDetail.PassengerReputation.RatingAverage,
+			PassengerRatingCount: passengerDetail.PassengerReputation.RatingCount,
+			CompletedTrips:       passengerDetail.PassengerReputation.CompletedTrips,
 			SourceName:           passengerDetail.SourceName,
 			SourcePlaceID:        passengerDetail.SourcePlaceID,
 			SourceLatitude:       passengerDetail.SourceLatitude,
@@ -318,27 +369,44 @@ func toAppTripDetails(tripDetails trip.TripDetails) AppTripDetails {
 
 // =============================================================================
 type AppRating struct {
-	ID          string `json:"id"`
-	TripID      string `json:"trip_id"`
-	CommenterID string `json:"commenter_id"`
-	Comment     string `json:"comment"`
-	Rating      int    `json:"rating"`
-	CreatedAt   string `json:"createdAt"`
+	ID                string `json:"id"`
+	TripID            string `json:"trip_id"`
+	RateeID           string `json:"ratee_id"`
+	CommenterID       string `json:"commenter_id"`
+	CommenterName     string `json:"commenter_name"`
+	CommenterImageURL string `json:"commenter_image_url"`
+	Comment           string `json:"comment"`
+	Rating            int    `json:"rating"`
+	CreatedAt         string `json:"createdAt"`
 }
 
 func toAppRating(rating trip.Rating) AppRating {
 
 	return AppRating{
-		ID:          rating.ID.String(),
-		TripID:      rating.TripID.String(),
-		CommenterID: rating.CommenterID.String(),
-		Comment:     rating.Comment,
-		Rating:      rating.Rating,
-		CreatedAt:   rating.CreatedAt.Format(time.RFC3339),
+		ID:                rating.ID.String(),
+		TripID:            rating.TripID.String(),
+		RateeID:           rating.RateeID.String(),
+		CommenterID:       rating.CommenterID.String(),
+		CommenterName:     rating.CommenterName,
+		CommenterImageURL: rating.CommenterImageURL,
+		Comment:           rating.Comment,
+		Rating:            rating.Rating,
+		CreatedAt:         rating.CreatedAt.Format(time.RFC3339),
 	}
 }
 
+func toAppRatings(ratings []trip.Rating) []AppRating {
+	items := make([]AppRating, len(ratings))
+	for i, rating := range ratings {
+		items[i] = toAppRating(rating)
+	}
+	return items
+}
+
+// AppNewRating contains information needed to rate a trip. Passengers can
+// leave out the ratee to rate the driver, the driver has to name the passenger.
 type AppNewRating struct {
+	RateeID string `json:"ratee_id"`
 	Rating  int    `json:"rating" binding:"required"`
 	Comment string `json:"comment"`
 }
@@ -349,5 +417,159 @@ func toCoreNewRating(app AppNewRating) (trip.NewRating, error) {
 		Comment: app.Comment,
 	}
 
+	if app.RateeID != "" {
+		rateeID, err := uuid.Parse(app.RateeID)
+		if err != nil {
+			return trip.NewRating{}, validate.NewFieldsError("ratee_id", err)
+		}
+		rating.RateeID = rateeID
+	}
+
 	return rating, nil
 }
+
+// =============================================================================
+
+type AppStop struct {
+	Sequence       int             `json:"sequence"`
+	Kind           string          `json:"kind"`
+	PassengerID    string          `json:"passenger_id,omitempty"`
+	Location       AppTripLocation `json:"location"`
+	PlannedArrival string          `json:"planned_arrival,omitempty"`
+	Distance       float64         `json:"distance"`
+}
+
+type AppRoute struct {
+	TripID        string    `json:"trip_id"`
+	Stops         []AppStop `json:"stops"`
+	TotalDistance float64   `json:"total_distance"`
+}
+
+func toAppRoute(route trip.Route) AppRoute {
+	stops := make([]AppStop, len(route.Stops))
+	for i, stop := range route.Stops {
+		stops[i] = AppStop{
+			Sequence: stop.Sequence,
+			Kind:     stop.Kind,
+			Location: toAppTripLocation(stop.Location),
+			Distance: stop.Distance,
+		}
+		if stop.PassengerID != nil {
+			stops[i].PassengerID = stop.PassengerID.String()
+		}
+		if stop.PlannedArrival != nil {
+			stops[i].PlannedArrival = stop.PlannedArrival.Format(time.RFC3339)
+		}
+	}
+
+	return AppRoute{
+		TripID:        route.TripID.String(),
+		Stops:         stops,
+		TotalDistance: route.TotalDistance,
+	}
+}
+
+// AppTrace is the trace of a trip as a GeoJSON feature, a line string with
+// the time of every point in its properties.
+type AppTrace struct {
+	Type       string             `json:"type"`
+	Geometry   AppTraceGeometry   `json:"geometry"`
+	Properties AppTraceProperties `json:"properties"`
+}
+
+type AppTraceGeometry struct {
+	Type        string       `json:"type"`
+	Coordinates [][2]float64 `json:"coordinates"`
+}
+
+type AppTraceProperties struct {
+	TripID   string   `json:"trip_id"`
+	Distance float64  `json:"distance"`
+	Finished bool     `json:"finished"`
+	Times    []string `json:"times"`
+}
+
+func toAppTrace(trace locationws.Trace) AppTrace {
+	coordinates := make([][2]float64, len(trace.Points))
+	times := make([]string, len(trace.Points))
+	for i, point := range trace.Points {
+		coordinates[i] = [2]float64{point.Longitude, point.Latitude}
+		times[i] = point.RecordedAt.Format(time.RFC3339)
+	}
+
+	return AppTrace{
+		Type: "Feature",
+		Geometry: AppTraceGeometry{
+			Type:        "LineString",
+			Coordinates: coordinates,
+		},
+		Properties: AppTraceProperties{
+			TripID:   trace.TripID.String(),
+			Distance: trace.Distance,
+			Finished: trace.Finished,
+			Times:    times,
+		},
+	}
+}
+
+// AppGPX is the trace of a trip as a GPX 1.1 document with a single track.
+type AppGPX struct {
+	XMLName xml.Name    `xml:"http://www.topografix.com/GPX/1/1 gpx"`
+	Version string      `xml:"version,attr"`
+	Creator string      `xml:"creator,attr"`
+	Track   AppGPXTrack `xml:"trk"`
+}
+
+type AppGPXTrack struct {
+	Name    string        `xml:"name"`
+	Segment []AppGPXPoint `xml:"trkseg>trkpt"`
+}
+
+type AppGPXPoint struct {
+	Latitude  float64 `xml:"lat,attr"`
+	Longitude float64 `xml:"lon,attr"`
+	Time      string  `xml:"time"`
+}
+
+func toAppGPX(trace locationws.Trace) AppGPX {
+	points := make([]AppGPXPoint, len(trace.Points))
+	for i, point := range trace.Points {
+		points[i] = AppGPXPoint{
+			Latitude:  point.Latitude,
+			Longitude: point.Longitude,
+			Time:      point.RecordedAt.UTC().Format(time.RFC3339),
+		}
+	}
+
+	return AppGPX{
+		Version: "1.1",
+		Creator: "tuber",
+		Track: AppGPXTrack{
+			Name:    trace.TripID.String(),
+			Segment: points,
+		},
+	}
+}
+
+// AppUnread is how many chat messages of a trip are unread.
+type AppUnread struct {
+	TripID string `json:"trip_id"`
+	Unread int    `json:"unread"`
+}
+
+// toAppUnread returns the unread messages of the trips that were counted, in
+// the order of the trips.
+func toAppUnread(trips []trip.UserTrip, unread map[uuid.UUID]int) []AppUnread {
+	items := make([]AppUnread, 0, len(unread))
+	for _, trp := range trips {
+		n, ok := unread[trp.TripID]
+		if !ok {
+			continue
+		}
+		items = append(items, AppUnread{
+			TripID: trp.TripID.String(),
+			Unread: n,
+		})
+	}
+	return items
+}
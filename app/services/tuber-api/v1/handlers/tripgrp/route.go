@@ -3,10 +3,15 @@ package tripgrp
 import (
 	"net/http"
 
+	"github.com/TSMC-Uber/server/business/core/locationws"
+	"github.com/TSMC-Uber/server/business/core/locationws/stores/locationwsdb"
 	"github.com/TSMC-Uber/server/business/core/trip"
 	"github.com/TSMC-Uber/server/business/core/trip/stores/tripdb"
 	"github.com/TSMC-Uber/server/business/core/user"
 	"github.com/TSMC-Uber/server/business/core/user/stores/userdb"
+	"github.com/TSMC-Uber/server/business/core/ws"
+	"github.com/TSMC-Uber/server/business/core/ws/stores/wsdb"
+	"github.com/TSMC-Uber/server/business/sys/ratelimit"
 	"github.com/TSMC-Uber/server/business/web/v1/auth"
 	"github.com/TSMC-Uber/server/business/web/v1/mid"
 	"github.com/TSMC-Uber/server/foundation/logger"
@@ -16,10 +21,12 @@ import (
 )
 
 type Config struct {
-	Log     *logger.Logger
-	Auth    *auth.Auth
-	DB      *sqlx.DB
-	RedisDB struct {
+	Log        *logger.Logger
+	Auth       *auth.Auth
+	DB         *sqlx.DB
+	RateLimits map[string]ratelimit.Policy
+	Dispatcher *locationws.RoomsDispatcher
+	RedisDB    struct {
 		Master  *redis.Client
 		Replica *redis.Client
 	}
@@ -34,19 +41,36 @@ func Routes(app *web.App, cfg Config) {
 
 	tripCore := trip.NewCore(tripdb.NewStore(cfg.Log, cfg.DB))
 	usrCore := user.NewCore(userdb.NewStore(cfg.Log, cfg.DB))
+	chatCore := ws.NewCore(wsdb.NewStore(cfg.Log, cfg.DB), tripCore, usrCore, cfg.RateLimits["chat"])
+	tripCore.Subscribe(chatCore.TripEvents(cfg.Log))
+
+	// Only the traces are served here, the location streams are served by
+	// locationwsgrp. The dispatcher kicks the passengers leaving a trip from
+	// the streams.
+	locationCore := locationws.NewCore(cfg.Log, locationwsdb.NewStore(cfg.Log, cfg.DB), cfg.Dispatcher, nil, 0)
+	tripCore.Subscribe(locationCore.TripEvents())
+
 	authen := mid.Authenticate(cfg.Auth)
+	driverOnly := mid.Authorize(cfg.Auth, user.RoleDriver)
+	joinLimit := mid.RateLimit(cfg.Log, "join", cfg.RateLimits["join"])
 
-	hdl := New(tripCore, usrCore)
+	hdl := New(tripCore, usrCore, chatCore, locationCore)
 	app.Handle(http.MethodGet, version, "/trips", hdl.Query)
 	app.Handle(http.MethodGet, version, "/trips/:id", hdl.QueryByID)
-	app.Handle(http.MethodPost, version, "/trips", hdl.Create, authen)
+	app.Handle(http.MethodPost, version, "/trips", hdl.Create, authen, driverOnly)
 	app.Handle(http.MethodPut, version, "/trips/:id", hdl.Update, authen)
 	// app.Handle(http.MethodPost, version, "/trips/join", hdl.Join)
 	// app.Handle(http.MethodDelete, version, "/users/:id", hdl.Delete)
 	app.Handle(http.MethodGet, version, "/trips/my", hdl.QueryMyTrip, authen)
-	app.Handle(http.MethodPost, version, "/trips/:id/join", hdl.Join, authen)
+	app.Handle(http.MethodGet, version, "/trips/my/unread", hdl.QueryMyUnread, authen)
+	app.Handle(http.MethodPost, version, "/trips/:id/join", hdl.Join, authen, joinLimit)
+	app.Handle(http.MethodDelete, version, "/trips/:id/join", hdl.Withdraw, authen)
 
+	app.Handle(http.MethodGet, version, "/trips/:id/route", hdl.QueryRoute, authen)
+	app.Handle(http.MethodGet, version, "/trips/:id/trace", hdl.QueryTrace, authen)
 	app.Handle(http.MethodGet, version, "/trips/:id/passengers", hdl.QueryPassengers, authen)
 	app.Handle(http.MethodPut, version, "/trips/:id/passengers/:passenger_id", hdl.UpdatePassengerStatus, authen)
+	app.Handle(http.MethodDelete, version, "/trips/:id/passengers/:passenger_id", hdl.RemovePassenger, authen)
 	app.Handle(http.MethodPost, version, "/trips/:id/rating", hdl.CreateRating, authen)
+	app.Handle(http.MethodGet, version, "/trips/:id/ratings", hdl.QueryRatings)
 }
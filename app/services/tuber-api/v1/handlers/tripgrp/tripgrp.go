@@ -3,12 +3,18 @@ package tripgrp
 
 import (
 	"context"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/TSMC-Uber/server/business/core/locationws"
 	"github.com/TSMC-Uber/server/business/core/trip"
 	"github.com/TSMC-Uber/server/business/core/user"
+	"github.com/TSMC-Uber/server/business/core/ws"
 	"github.com/TSMC-Uber/server/business/web/v1/auth"
 	"github.com/TSMC-Uber/server/business/web/v1/paging"
 	"github.com/TSMC-Uber/server/business/web/v1/response"
@@ -19,15 +25,19 @@ import (
 
 // Handlers manages the set of user endpoints.
 type Handlers struct {
-	trip *trip.Core
-	user *user.Core
+	trip     *trip.Core
+	user     *user.Core
+	chat     *ws.Core
+	location *locationws.Core
 }
 
 // New constructs a handlers for route access.
-func New(trip *trip.Core, user *user.Core) *Handlers {
+func New(trip *trip.Core, user *user.Core, chat *ws.Core, location *locationws.Core) *Handlers {
 	return &Handlers{
-		trip: trip,
-		user: user,
+		trip:     trip,
+		user:     user,
+		chat:     chat,
+		location: location,
 	}
 }
 
@@ -41,10 +51,15 @@ func New(trip *trip.Core, user *user.Core) *Handlers {
 // @Param body body AppNewTrip true "New Trip"
 // @Success 201 {object} AppTrip "Trip successfully created"
 // @Failure 400 "Bad Request"
+// @Failure 403 "User is suspended"
 // @Failure 500 "Internal Server Error"
 // @Router /trips [post]
 func (h *Handlers) Create(ctx context.Context, c *gin.Context) error {
 	userID := auth.GetUserID(ctx)
+	if err := h.requireActive(ctx, userID); err != nil {
+		return err
+	}
+
 	var app AppNewTrip
 	// Validate the request.
 	if err := web.Decode(c, &app); err != nil {
@@ -62,6 +77,11 @@ func (h *Handlers) Create(ctx context.Context, c *gin.Context) error {
 		return fmt.Errorf("create: trip[%+v]: %w", trip, err)
 	}
 
+	// The trip is stored, failing the request now would have the driver
+	// create it twice. A room that could not be created is created when it
+	// is first used, see ws.Core.Authorize.
+	h.chat.CreateTripRoom(ctx, trip.ID)
+
 	// get driver's email
 	qdriver, err := h.user.QueryByID(ctx, trip.DriverID)
 	if err != nil {
@@ -92,6 +112,7 @@ func (h *Handlers) Create(ctx context.Context, c *gin.Context) error {
 // @Param body body AppUpdateTrip true "Update Trip"
 // @Success 200 {object} AppTrip "Trip successfully updated"
 // @Failure 400 "Bad Request"
+// @Failure 409 "Illegal status transition, the start time of a started trip, or a passenger limit below the accepted passengers"
 // @Failure 500 "Internal Server Error"
 // @Router /trips/{id} [put]
 func (h *Handlers) Update(ctx context.Context, c *gin.Context) error {
@@ -102,12 +123,16 @@ func (h *Handlers) Update(ctx context.Context, c *gin.Context) error {
 		return err
 	}
 
+	if err := app.Validate(); err != nil {
+		return err
+	}
+
 	tripID := uuid.Must(uuid.Parse(c.Param("id")))
 
 	qtrip, err := h.trip.QueryByID(ctx, tripID)
 	if err != nil {
 		switch {
-		case errors.Is(err, user.ErrNotFound):
+		case errors.Is(err, trip.ErrNotFound):
 			return response.NewError(err, http.StatusNotFound)
 		default:
 			return fmt.Errorf("querybyid: tripID[%s]: %w", tripID, err)
@@ -124,12 +149,26 @@ func (h *Handlers) Update(ctx context.Context, c *gin.Context) error {
 		return response.NewError(err, http.StatusBadRequest)
 	}
 
-	trip, err := h.trip.Update(ctx, qtrip, ut)
+	utrip, err := h.trip.Update(ctx, userID, qtrip, ut)
 	if err != nil {
-		return fmt.Errorf("update: tripID[%s] ut[%+v]: %w", tripID, ut, err)
+		switch {
+		case errors.Is(err, trip.ErrInvalidPassengerLimit):
+			return response.NewError(err, http.StatusBadRequest)
+		case trip.IsStatusTransitionError(err), errors.Is(err, trip.ErrTripStarted), errors.Is(err, trip.ErrPassengerLimitTooLow):
+			return response.NewError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("update: tripID[%s] ut[%+v]: %w", tripID, ut, err)
+		}
 	}
 
-	return web.Respond(ctx, c.Writer, toAppTrip(trip), http.StatusOK)
+	// cancelling the trip cancelled its passengers
+	if utrip.Status == trip.TripStatusCancelled && qtrip.Status != trip.TripStatusCancelled {
+		if err := h.kickCancelled(ctx, qtrip); err != nil {
+			return err
+		}
+	}
+
+	return web.Respond(ctx, c.Writer, toAppTrip(utrip), http.StatusOK)
 }
 
 // @Summary get all trips
@@ -138,6 +177,11 @@ func (h *Handlers) Update(ctx context.Context, c *gin.Context) error {
 // @Tags trip
 // @Accept json
 // @Produce json
+// @Param pickup_lat query number false "Pickup latitude"
+// @Param pickup_lon query number false "Pickup longitude"
+// @Param dropoff_lat query number false "Drop-off latitude"
+// @Param dropoff_lon query number false "Drop-off longitude"
+// @Param radius query number false "Search radius in meters"
 // @Success 200 {object} AppTrip "query trips"
 // @Failure 400 "Bad Request"
 // @Failure 500 "Internal Server Error"
@@ -220,6 +264,50 @@ func (h *Handlers) QueryMyTrip(ctx context.Context, c *gin.Context) error {
 	return web.Respond(ctx, c.Writer, paging.NewResponse(qtrip, len(qtrip), page.Number, page.RowsPerPage), http.StatusOK)
 }
 
+// @Summary get unread chat messages of my trips
+// @Schemes
+// @Description QueryMyUnread will count the chat messages of my trips sent since I last read the chat, for the trips of the same page of my trips. Only trips I drive or was accepted on are counted.
+// @Tags trip
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param status query string false "Status"
+// @Param is_driver query bool false "Is Driver"
+// @Success 200 {array} AppUnread "unread messages by trip"
+// @Failure 400 "Bad Request"
+// @Failure 500 "Internal Server Error"
+// @Router /trips/my/unread [get]
+func (h *Handlers) QueryMyUnread(ctx context.Context, c *gin.Context) error {
+	id := auth.GetUserID(ctx)
+
+	page, err := paging.ParseRequest(c.Request)
+	if err != nil {
+		return err
+	}
+
+	filter, err := parseFilterByUser(c.Request)
+	if err != nil {
+		return err
+	}
+
+	orderBy, err := parseOrder(c.Request)
+	if err != nil {
+		return err
+	}
+
+	trips, err := h.trip.QueryMyTrip(ctx, id, filter, orderBy, page.Number, page.RowsPerPage)
+	if err != nil {
+		return fmt.Errorf("querymytrip: id[%s]: %w", id, err)
+	}
+
+	unread, err := h.chat.CountUnread(ctx, id, trips)
+	if err != nil {
+		return fmt.Errorf("countunread: id[%s]: %w", id, err)
+	}
+
+	return web.Respond(ctx, c.Writer, toAppUnread(trips, unread), http.StatusOK)
+}
+
 // @Summary get trips by id
 // @Schemes
 // @Description QueryByID will query trips by id
@@ -257,11 +345,17 @@ func (h *Handlers) QueryByID(ctx context.Context, c *gin.Context) error {
 // @Param id path string true "Trip ID"
 // @Success 200 {object} AppTrip "Trip successfully joined"
 // @Failure 400 "Bad Request"
+// @Failure 403 "User is suspended"
+// @Failure 409 "Trip cannot be joined"
 // @Failure 500 "Internal Server Error"
 // @Router /trips/{id}/join [post]
 func (h *Handlers) Join(ctx context.Context, c *gin.Context) error {
 	tripID := uuid.Must(uuid.Parse(c.Param("id")))
 	userID := auth.GetUserID(ctx)
+	if err := h.requireActive(ctx, userID); err != nil {
+		return err
+	}
+
 	var app AppNewTripPassenger
 	// Validate the request.
 	if err := web.Decode(c, &app); err != nil {
@@ -276,10 +370,16 @@ func (h *Handlers) Join(ctx context.Context, c *gin.Context) error {
 
 	tripPassenger, err := h.trip.Join(ctx, tripID, ntp)
 	if err != nil {
-		if errors.Is(err, user.ErrUniqueEmail) {
+		switch {
+		case errors.Is(err, trip.ErrNotFound):
+			return response.NewError(err, http.StatusNotFound)
+		case errors.Is(err, trip.ErrJoinOwnTrip),
+			errors.Is(err, trip.ErrTripNotJoinable),
+			errors.Is(err, trip.ErrAlreadyJoined):
 			return response.NewError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("join: tripPassenger[%+v]: %w", tripPassenger, err)
 		}
-		return fmt.Errorf("join: tripPassenger[%+v]: %w", tripPassenger, err)
 	}
 
 	return web.Respond(ctx, c.Writer, toAppTripPassenger(tripPassenger), http.StatusCreated)
@@ -297,14 +397,313 @@ func (h *Handlers) UpdatePassengerStatus(ctx context.Context, c *gin.Context) er
 		return response.NewError(err, http.StatusBadRequest)
 	}
 
+	if app.Status != trip.StatusAccepted && app.Status != trip.StatusRejected {
+		return response.NewError(errors.New("status should only have 2 values: accepted, rejected"), http.StatusBadRequest)
+	}
+
+	qtrip, err := h.trip.QueryByID(ctx, tripID)
+	if err != nil {
+		switch {
+		case errors.Is(err, trip.ErrNotFound):
+			return response.NewError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("querybyid: tripID[%s]: %w", tripID, err)
+		}
+	}
+
+	// only the driver of the trip can accept or reject passengers
+	if qtrip.DriverID != auth.GetUserID(ctx) {
+		return response.NewError(errors.New("user is not the driver of the trip"), http.StatusForbidden)
+	}
+
 	tripPassenger, err := h.trip.UpdatePassengerStatus(ctx, tripID, passengerID, app.Status)
 	if err != nil {
-		return fmt.Errorf("accept: tripPassenger[%+v]: %w", tripPassenger, err)
+		switch {
+		case errors.Is(err, trip.ErrNotFound), errors.Is(err, trip.ErrPassengerNotFound):
+			return response.NewError(err, http.StatusNotFound)
+		case errors.Is(err, trip.ErrTripFull):
+			return response.NewError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("accept: tripPassenger[%+v]: %w", tripPassenger, err)
+		}
+	}
+
+	if tripPassenger.Status != trip.StatusAccepted {
+		if err := h.kick(ctx, tripID, passengerID); err != nil {
+			return err
+		}
+	}
+
+	return web.Respond(ctx, c.Writer, toAppTripPassenger(tripPassenger), http.StatusOK)
+}
+
+// @Summary withdraw from a trip
+// @Schemes
+// @Description Withdraw will cancel a join request or give up an accepted seat
+// @Tags trip
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param id path string true "Trip ID"
+// @Param reason query string false "Reason"
+// @Success 200 {object} AppTripPassenger "Passenger successfully withdrawn"
+// @Failure 404 "Not Found"
+// @Failure 409 "Passenger cannot withdraw"
+// @Failure 500 "Internal Server Error"
+// @Router /trips/{id}/join [delete]
+func (h *Handlers) Withdraw(ctx context.Context, c *gin.Context) error {
+	tripID := uuid.Must(uuid.Parse(c.Param("id")))
+	userID := auth.GetUserID(ctx)
+
+	tripPassenger, err := h.trip.Withdraw(ctx, tripID, userID, c.Query("reason"))
+	if err != nil {
+		return leaveError(err, tripID, userID)
+	}
+
+	if err := h.kick(ctx, tripID, userID); err != nil {
+		return err
 	}
 
 	return web.Respond(ctx, c.Writer, toAppTripPassenger(tripPassenger), http.StatusOK)
 }
 
+// @Summary remove a passenger from a trip
+// @Schemes
+// @Description RemovePassenger will remove an accepted passenger from a trip
+// @Tags trip
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param id path string true "Trip ID"
+// @Param passenger_id path string true "Passenger ID"
+// @Param reason query string false "Reason"
+// @Success 200 {object} AppTripPassenger "Passenger successfully removed"
+// @Failure 403 "Forbidden"
+// @Failure 404 "Not Found"
+// @Failure 409 "Passenger cannot be removed"
+// @Failure 500 "Internal Server Error"
+// @Router /trips/{id}/passengers/{passenger_id} [delete]
+func (h *Handlers) RemovePassenger(ctx context.Context, c *gin.Context) error {
+	tripID := uuid.Must(uuid.Parse(c.Param("id")))
+	passengerID := uuid.Must(uuid.Parse(c.Param("passenger_id")))
+
+	qtrip, err := h.trip.QueryByID(ctx, tripID)
+	if err != nil {
+		switch {
+		case errors.Is(err, trip.ErrNotFound):
+			return response.NewError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("querybyid: tripID[%s]: %w", tripID, err)
+		}
+	}
+
+	// only the driver of the trip can remove passengers
+	if qtrip.DriverID != auth.GetUserID(ctx) {
+		return response.NewError(errors.New("user is not the driver of the trip"), http.StatusForbidden)
+	}
+
+	tripPassenger, err := h.trip.RemovePassenger(ctx, tripID, passengerID, c.Query("reason"))
+	if err != nil {
+		return leaveError(err, tripID, passengerID)
+	}
+
+	if err := h.kick(ctx, tripID, passengerID); err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, c.Writer, toAppTripPassenger(tripPassenger), http.StatusOK)
+}
+
+// kick disconnects the passenger from the chat room and the location stream
+// of the trip.
+func (h *Handlers) kick(ctx context.Context, tripID uuid.UUID, passengerID uuid.UUID) error {
+	// the room of the trip has the id of the trip
+	if err := h.chat.Kick(ctx, tripID, passengerID); err != nil {
+		return fmt.Errorf("kick chat: tripID[%s] passengerID[%s]: %w", tripID, passengerID, err)
+	}
+
+	if err := h.location.Kick(ctx, tripID, passengerID); err != nil {
+		return fmt.Errorf("kick location: tripID[%s] passengerID[%s]: %w", tripID, passengerID, err)
+	}
+
+	return nil
+}
+
+// kickCancelled disconnects the cancelled passengers of the trip from its
+// chat room and location stream.
+func (h *Handlers) kickCancelled(ctx context.Context, qtrip trip.TripView) error {
+	details, err := h.trip.QueryPassengers(ctx, qtrip.ID)
+	if err != nil {
+		return fmt.Errorf("querypassengers: tripID[%s]: %w", qtrip.ID, err)
+	}
+
+	for _, passenger := range details.PassengerDetails {
+		if passenger.PassengerID == qtrip.DriverID || passenger.PassengerStatus != trip.StatusCancelled {
+			continue
+		}
+		if err := h.kick(ctx, qtrip.ID, passenger.PassengerID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// leaveError maps the errors of a passenger leaving a trip to responses.
+func leaveError(err error, tripID uuid.UUID, passengerID uuid.UUID) error {
+	switch {
+	case errors.Is(err, trip.ErrNotFound), errors.Is(err, trip.ErrPassengerNotFound):
+		return response.NewError(err, http.StatusNotFound)
+	case errors.Is(err, trip.ErrCutoffPassed), errors.Is(err, trip.ErrPassengerNotActive):
+		return response.NewError(err, http.StatusConflict)
+	default:
+		return fmt.Errorf("leave: tripID[%s] passengerID[%s]: %w", tripID, passengerID, err)
+	}
+}
+
+// @Summary get the route of a trip
+// @Schemes
+// @Description QueryRoute will query the ordered stops of a trip with cumulative distances in meters
+// @Tags trip
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param id path string true "Trip ID"
+// @Success 200 {object} AppRoute "query route of a trip"
+// @Failure 403 "Forbidden"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /trips/{id}/route [get]
+func (h *Handlers) QueryRoute(ctx context.Context, c *gin.Context) error {
+	tripID := uuid.Must(uuid.Parse(c.Param("id")))
+
+	qtrip, err := h.trip.QueryByID(ctx, tripID)
+	if err != nil {
+		switch {
+		case errors.Is(err, trip.ErrNotFound):
+			return response.NewError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("querybyid: tripID[%s]: %w", tripID, err)
+		}
+	}
+
+	// the route holds the pickup points of the passengers
+	ok, err := h.trip.IsParticipant(ctx, qtrip, auth.GetUserID(ctx))
+	if err != nil {
+		return fmt.Errorf("isparticipant: tripID[%s]: %w", tripID, err)
+	}
+	if !ok {
+		return response.NewError(errors.New("user is not a participant of the trip"), http.StatusForbidden)
+	}
+
+	route, err := h.trip.QueryRoute(ctx, tripID)
+	if err != nil {
+		switch {
+		case errors.Is(err, trip.ErrNotFound):
+			return response.NewError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("queryroute: tripID[%s]: %w", tripID, err)
+		}
+	}
+
+	return web.Respond(ctx, c.Writer, toAppRoute(route), http.StatusOK)
+}
+
+// defaultTolerance is how far off the trace its points can be dropped, in
+// meters, when the request does not say.
+const defaultTolerance = 5.0
+
+// @Summary get the trace of a trip
+// @Schemes
+// @Description QueryTrace will return the path the driver of a trip took as a GeoJSON feature, or as GPX with format=gpx. Points at most tolerance meters off the path are dropped (default 5, 0 keeps them all). Only the participants of the trip and admins can see it.
+// @Tags trip
+// @Produce json
+// @Produce application/gpx+xml
+// @Param token header string true "Token"
+// @Param id path string true "Trip ID"
+// @Param format query string false "geojson or gpx"
+// @Param tolerance query number false "Tolerance in meters"
+// @Success 200 {object} AppTrace "trace of a trip"
+// @Failure 400 "Bad Request"
+// @Failure 403 "Forbidden"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /trips/{id}/trace [get]
+func (h *Handlers) QueryTrace(ctx context.Context, c *gin.Context) error {
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return response.NewError(fmt.Errorf("parse trip id: %w", err), http.StatusBadRequest)
+	}
+
+	format := c.DefaultQuery("format", "geojson")
+	if format != "geojson" && format != "gpx" {
+		return response.NewError(fmt.Errorf("format %q is not supported", format), http.StatusBadRequest)
+	}
+
+	tolerance := defaultTolerance
+	if v := c.Query("tolerance"); v != "" {
+		tolerance, err = strconv.ParseFloat(v, 64)
+		if err != nil || tolerance < 0 || math.IsInf(tolerance, 0) {
+			return response.NewError(fmt.Errorf("tolerance %q is not a distance", v), http.StatusBadRequest)
+		}
+	}
+
+	qtrip, err := h.trip.QueryByID(ctx, tripID)
+	if err != nil {
+		switch {
+		case errors.Is(err, trip.ErrNotFound):
+			return response.NewError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("querybyid: tripID[%s]: %w", tripID, err)
+		}
+	}
+
+	if !auth.GetClaims(ctx).HasRole(user.RoleAdmin) {
+		ok, err := h.trip.IsParticipant(ctx, qtrip, auth.GetUserID(ctx))
+		if err != nil {
+			return fmt.Errorf("isparticipant: tripID[%s]: %w", tripID, err)
+		}
+		if !ok {
+			return response.NewError(errors.New("user is not a participant of the trip"), http.StatusForbidden)
+		}
+	}
+
+	trace, err := h.location.QueryTrace(ctx, tripID, tolerance)
+	if err != nil {
+		switch {
+		case errors.Is(err, locationws.ErrNoTrace):
+			return response.NewError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("querytrace: tripID[%s]: %w", tripID, err)
+		}
+	}
+
+	if format == "gpx" {
+		return respondGPX(ctx, c.Writer, toAppGPX(trace))
+	}
+
+	return web.Respond(ctx, c.Writer, toAppTrace(trace), http.StatusOK)
+}
+
+// respondGPX sends the track to the client as a GPX document.
+func respondGPX(ctx context.Context, w http.ResponseWriter, gpx AppGPX) error {
+	data, err := xml.Marshal(gpx)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	web.SetStatusCode(ctx, http.StatusOK)
+
+	w.Header().Set("Content-Type", "application/gpx+xml")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(append([]byte(xml.Header), data...)); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
+
 // @Summary get all passengers of a trip
 // @Schemes
 // @Description QueryPassengers will query passengers of a trip
@@ -342,7 +741,11 @@ func (h *Handlers) QueryPassengers(ctx context.Context, c *gin.Context) error {
 // @Param token header string true "Token"
 // @Param body body AppNewRating true "New Rating"
 // @Success 201 {object} AppRating "Rating successfully created"
+// @Param id path string true "Trip ID"
 // @Failure 400 "Bad Request"
+// @Failure 403 "Forbidden"
+// @Failure 404 "Not Found"
+// @Failure 409 "Conflict"
 // @Failure 500 "Internal Server Error"
 // @Router /trips/{id}/rating [post]
 func (h *Handlers) CreateRating(ctx context.Context, c *gin.Context) error {
@@ -362,8 +765,64 @@ func (h *Handlers) CreateRating(ctx context.Context, c *gin.Context) error {
 
 	rating, err := h.trip.CreateRating(ctx, tripID, nr)
 	if err != nil {
-		return fmt.Errorf("create: rating[%+v]: %w", rating, err)
+		switch {
+		case errors.Is(err, trip.ErrInvalidRating), errors.Is(err, trip.ErrInvalidRatee):
+			return response.NewError(err, http.StatusBadRequest)
+		case errors.Is(err, trip.ErrNotParticipant):
+			return response.NewError(err, http.StatusForbidden)
+		case errors.Is(err, trip.ErrNotFound):
+			return response.NewError(err, http.StatusNotFound)
+		case errors.Is(err, trip.ErrTripNotFinished), errors.Is(err, trip.ErrAlreadyRated):
+			return response.NewError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("create: rating[%+v]: %w", rating, err)
+		}
 	}
 
 	return web.Respond(ctx, c.Writer, toAppRating(rating), http.StatusCreated)
 }
+
+// @Summary get the ratings of a trip
+// @Schemes
+// @Description QueryRatings will query the ratings of a trip, newest first
+// @Tags trip
+// @Accept json
+// @Produce json
+// @Param id path string true "Trip ID"
+// @Success 200 {array} AppRating "query ratings of a trip"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /trips/{id}/ratings [get]
+func (h *Handlers) QueryRatings(ctx context.Context, c *gin.Context) error {
+	tripID := uuid.Must(uuid.Parse(c.Param("id")))
+
+	if _, err := h.trip.QueryByID(ctx, tripID); err != nil {
+		switch {
+		case errors.Is(err, trip.ErrNotFound):
+			return response.NewError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("querybyid: tripID[%s]: %w", tripID, err)
+		}
+	}
+
+	ratings, err := h.trip.QueryRatings(ctx, tripID)
+	if err != nil {
+		return fmt.Errorf("queryratings: tripID[%s]: %w", tripID, err)
+	}
+
+	return web.Respond(ctx, c.Writer, toAppRatings(ratings), http.StatusOK)
+}
+
+// requireActive rejects users a moderator suspended from taking part in trips.
+func (h *Handlers) requireActive(ctx context.Context, userID uuid.UUID) error {
+	usr, err := h.user.QueryByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("querybyid: userID[%s]: %w", userID, err)
+	}
+
+	if usr.IsSuspended(time.Now()) {
+		return response.NewError(user.ErrSuspended, http.StatusForbidden)
+	}
+
+	return nil
+}
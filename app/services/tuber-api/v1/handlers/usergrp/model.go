@@ -12,17 +12,22 @@ import (
 
 // AppUser represents information about an individual user.
 type AppUser struct {
-	ID                 string `json:"id"`
-	Name               string `json:"name"`
-	Email              string `json:"email"`
-	ImageURL           string `json:"imageURL"`
-	Bio                string `json:"bio"`
-	AcceptNotification bool   `json:"acceptNotification"`
-	CreatedAt          string `json:"createdAt"`
-	UpdatedAt          string `json:"updatedAt"`
+	ID                 string   `json:"id"`
+	Name               string   `json:"name"`
+	Email              string   `json:"email"`
+	ImageURL           string   `json:"imageURL"`
+	Bio                string   `json:"bio"`
+	AcceptNotification bool     `json:"acceptNotification"`
+	Roles              []string `json:"roles"`
+	CreatedAt          string   `json:"createdAt"`
+	UpdatedAt          string   `json:"updatedAt"`
 }
 
 func toAppUser(usr user.User) AppUser {
+	roles := make([]string, len(usr.Roles))
+	for i, role := range usr.Roles {
+		roles[i] = role.Name()
+	}
 
 	return AppUser{
 		ID:                 usr.ID.String(),
@@ -31,6 +36,7 @@ func toAppUser(usr user.User) AppUser {
 		ImageURL:           usr.ImageURL,
 		Bio:                usr.Bio,
 		AcceptNotification: usr.AcceptNotification,
+		Roles:              roles,
 		CreatedAt:          usr.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:          usr.UpdatedAt.Format(time.RFC3339),
 	}
@@ -35,11 +35,13 @@ func Routes(app *web.App, cfg Config) {
 	usrCore := user.NewCore(userdb.NewStore(cfg.Log, cfg.DB))
 
 	authen := mid.Authenticate(cfg.Auth)
+	adminOnly := mid.Authorize(cfg.Auth, user.RoleAdmin)
+	selfOrAdmin := mid.AuthorizeUser(cfg.Auth)
 
 	hdl := New(usrCore)
-	app.Handle(http.MethodGet, version, "/users", hdl.Query)
+	app.Handle(http.MethodGet, version, "/users", hdl.Query, authen, adminOnly)
 	app.Handle(http.MethodGet, version, "/users/:id", hdl.QueryByID)
 	app.Handle(http.MethodPost, version, "/users", hdl.Create)
-	app.Handle(http.MethodPut, version, "/users/:id", hdl.Update, authen)
-	app.Handle(http.MethodDelete, version, "/users/:id", hdl.Delete, authen)
+	app.Handle(http.MethodPut, version, "/users/:id", hdl.Update, authen, selfOrAdmin)
+	app.Handle(http.MethodDelete, version, "/users/:id", hdl.Delete, authen, selfOrAdmin)
 }
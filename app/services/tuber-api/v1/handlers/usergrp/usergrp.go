@@ -72,6 +72,7 @@ func (h *Handlers) Create(ctx context.Context, c *gin.Context) error {
 // @Param token header string true "Token"
 // @Success 200 {object} AppUser "User successfully updated"
 // @Failure 400 "Bad Request"
+// @Failure 403 "Forbidden"
 // @Failure 500 "Internal Server Error"
 // @Router /users/{id} [put]
 func (h *Handlers) Update(ctx context.Context, c *gin.Context) error {
@@ -80,9 +81,10 @@ func (h *Handlers) Update(ctx context.Context, c *gin.Context) error {
 		return err
 	}
 
-	// userID := auth.GetUserID(ctx)
-	// temp get from url "/users/:id" -> uuid
-	userID := uuid.Must(uuid.Parse(c.Param("id")))
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
 
 	usr, err := h.user.QueryByID(ctx, userID)
 	if err != nil {
@@ -117,11 +119,14 @@ func (h *Handlers) Update(ctx context.Context, c *gin.Context) error {
 // @Param token header string true "Token"
 // @Success 204 "No Content"
 // @Failure 400 "Bad Request"
+// @Failure 403 "Forbidden"
 // @Failure 500 "Internal Server Error"
 // @Router /users/{id} [delete]
 func (h *Handlers) Delete(ctx context.Context, c *gin.Context) error {
-	// userID := auth.GetUserID(ctx)
-	userID := uuid.Must(uuid.Parse(c.Param("id")))
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
 
 	usr, err := h.user.QueryByID(ctx, userID)
 	if err != nil {
@@ -146,8 +151,10 @@ func (h *Handlers) Delete(ctx context.Context, c *gin.Context) error {
 // @Tags user
 // @Accept json
 // @Produce json
+// @Param token header string true "Token"
 // @Success 200 {object} AppUser "query users"
 // @Failure 400 "Bad Request"
+// @Failure 403 "Forbidden"
 // @Failure 500 "Internal Server Error"
 // @Router /users [get]
 func (h *Handlers) Query(ctx context.Context, c *gin.Context) error {
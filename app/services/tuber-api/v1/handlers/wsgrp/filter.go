@@ -0,0 +1,46 @@
+package wsgrp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/TSMC-Uber/server/business/core/ws"
+	"github.com/TSMC-Uber/server/business/sys/validate"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultRows = 50
+	maxRows     = 100
+)
+
+// parseFilter parses the cursor of a page of messages: the messages before
+// the `before` message, up to `rows` of them.
+func parseFilter(r *http.Request) (ws.QueryFilter, int, error) {
+	values := r.URL.Query()
+
+	var filter ws.QueryFilter
+
+	if before := values.Get("before"); before != "" {
+		id, err := uuid.Parse(before)
+		if err != nil {
+			return ws.QueryFilter{}, 0, validate.NewFieldsError("before", err)
+		}
+		filter.Before = &id
+	}
+
+	rows := defaultRows
+	if v := values.Get("rows"); v != "" {
+		var err error
+		rows, err = strconv.Atoi(v)
+		if err != nil {
+			return ws.QueryFilter{}, 0, validate.NewFieldsError("rows", err)
+		}
+		if rows < 1 || rows > maxRows {
+			return ws.QueryFilter{}, 0, validate.NewFieldsError("rows", errors.New("must be between 1 and 100"))
+		}
+	}
+
+	return filter, rows, nil
+}
@@ -1,7 +1,101 @@
 package wsgrp
 
-// ChatMessage represents a chat message with a user ID.
-type ChatMessage struct {
-	UserID  string `json:"user_id"`
-	Message string `json:"message"`
+import (
+	"time"
+
+	"github.com/TSMC-Uber/server/business/core/ws"
+	"github.com/google/uuid"
+)
+
+// AppChatMessage represents a message sent to a chat room. System messages
+// are posted about the trip, they have an event and no user.
+type AppChatMessage struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Event     string `json:"event,omitempty"`
+	RoomID    string `json:"room_id"`
+	TripID    string `json:"trip_id"`
+	UserID    string `json:"user_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	ImageURL  string `json:"image_url,omitempty"`
+	Message   string `json:"message"`
+	CreatedAt string `json:"created_at"`
+}
+
+func toAppChatMessage(msg ws.ChatMessage) AppChatMessage {
+	app := AppChatMessage{
+		ID:        msg.ID.String(),
+		Type:      msg.Type,
+		Event:     msg.Event,
+		RoomID:    msg.RoomID.String(),
+		TripID:    msg.TripID.String(),
+		Username:  msg.Username,
+		ImageURL:  msg.ImageURL,
+		Message:   msg.Message,
+		CreatedAt: msg.CreatedAt.Format(time.RFC3339),
+	}
+
+	if msg.UserID != uuid.Nil {
+		app.UserID = msg.UserID.String()
+	}
+
+	return app
+}
+
+// AppChatMessages is a page of the messages of a room, oldest first. Before
+// is the cursor of the page of older messages, empty when there are none.
+type AppChatMessages struct {
+	Items  []AppChatMessage `json:"items"`
+	Before string           `json:"before,omitempty"`
+}
+
+func toAppChatMessages(msgs []ws.ChatMessage, rows int) AppChatMessages {
+	items := make([]AppChatMessage, len(msgs))
+	for i, msg := range msgs {
+		items[i] = toAppChatMessage(msg)
+	}
+
+	app := AppChatMessages{
+		Items: items,
+	}
+
+	// a full page means there may be older messages
+	if len(msgs) == rows {
+		app.Before = msgs[0].ID.String()
+	}
+
+	return app
+}
+
+// =============================================================================
+
+// AppRoom represents a chat room of a trip. The room of the trip itself has
+// no passenger_id, the room of a prospective passenger with the driver has.
+type AppRoom struct {
+	ID          string `json:"id"`
+	TripID      string `json:"trip_id"`
+	PassengerID string `json:"passenger_id,omitempty"`
+	CreatedAt   string `json:"created_at"`
+}
+
+func toAppRoom(room ws.Room) AppRoom {
+	app := AppRoom{
+		ID:        room.ID.String(),
+		TripID:    room.TripID.String(),
+		CreatedAt: room.CreatedAt.Format(time.RFC3339),
+	}
+
+	if room.PassengerID != nil {
+		app.PassengerID = room.PassengerID.String()
+	}
+
+	return app
+}
+
+func toAppRooms(rooms []ws.Room) []AppRoom {
+	items := make([]AppRoom, len(rooms))
+	for i, room := range rooms {
+		items[i] = toAppRoom(room)
+	}
+	return items
 }
@@ -3,11 +3,15 @@ package wsgrp
 import (
 	"net/http"
 
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/core/trip/stores/tripdb"
 	"github.com/TSMC-Uber/server/business/core/user"
 	"github.com/TSMC-Uber/server/business/core/user/stores/userdb"
 	"github.com/TSMC-Uber/server/business/core/ws"
 	"github.com/TSMC-Uber/server/business/core/ws/stores/wsdb"
+	"github.com/TSMC-Uber/server/business/sys/ratelimit"
 	"github.com/TSMC-Uber/server/business/web/v1/auth"
+	"github.com/TSMC-Uber/server/business/web/v1/mid"
 	"github.com/TSMC-Uber/server/foundation/logger"
 	"github.com/TSMC-Uber/server/foundation/web"
 	"github.com/jmoiron/sqlx"
@@ -15,10 +19,12 @@ import (
 )
 
 type Config struct {
-	Log     *logger.Logger
-	Auth    *auth.Auth
-	DB      *sqlx.DB
-	RedisDB struct {
+	Log        *logger.Logger
+	Auth       *auth.Auth
+	DB         *sqlx.DB
+	Origins    []string
+	RateLimits map[string]ratelimit.Policy
+	RedisDB    struct {
 		Master  *redis.Client
 		Replica *redis.Client
 	}
@@ -30,11 +36,16 @@ func Routes(app *web.App, cfg Config) {
 
 	// envCore := event.NewCore(cfg.Log)
 
-	wsCore := ws.NewCore(wsdb.NewStore(cfg.Log, cfg.DB))
+	tripCore := trip.NewCore(tripdb.NewStore(cfg.Log, cfg.DB))
 	usrCore := user.NewCore(userdb.NewStore(cfg.Log, cfg.DB))
+	wsCore := ws.NewCore(wsdb.NewStore(cfg.Log, cfg.DB), tripCore, usrCore, cfg.RateLimits["chat"])
 
-	// authen := mid.Authenticate(cfg.Auth)
+	authen := mid.Authenticate(cfg.Auth)
 
-	hdl := New(wsCore, usrCore)
-	app.Handle(http.MethodGet, version, "/chat/ws", hdl.Connect)
+	hdl := New(wsCore, usrCore, web.CheckOrigin(cfg.Origins))
+	app.Handle(http.MethodGet, version, "/chat/ws", hdl.Connect, authen)
+	app.Handle(http.MethodGet, version, "/chat/rooms/:id/messages", hdl.QueryMessages, authen)
+	app.Handle(http.MethodGet, version, "/trips/:id/messages", hdl.QueryMessages, authen)
+	app.Handle(http.MethodPost, version, "/trips/:id/rooms", hdl.OpenRoom, authen)
+	app.Handle(http.MethodGet, version, "/trips/:id/rooms", hdl.QueryRooms, authen)
 }
@@ -2,88 +2,201 @@ package wsgrp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/TSMC-Uber/server/business/core/trip"
 	"github.com/TSMC-Uber/server/business/core/user"
 	"github.com/TSMC-Uber/server/business/core/ws"
-	"github.com/TSMC-Uber/server/business/sys/cachedb"
+	"github.com/TSMC-Uber/server/business/web/v1/auth"
+	"github.com/TSMC-Uber/server/business/web/v1/response"
+	"github.com/TSMC-Uber/server/foundation/web"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
-
 type Handlers struct {
-	ws   *ws.Core
-	user *user.Core
+	ws       *ws.Core
+	user     *user.Core
+	upgrader websocket.Upgrader
 }
 
-// New constructs a handlers for route access.
-func New(ws *ws.Core, user *user.Core) *Handlers {
+// New constructs a handlers for route access. Only connections from the
+// allowed origins are upgraded.
+func New(ws *ws.Core, user *user.Core, checkOrigin func(r *http.Request) bool) *Handlers {
 	return &Handlers{
 		ws:   ws,
 		user: user,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: checkOrigin,
+		},
 	}
 }
 
-// Create adds a new trip to the system.
+// Connect joins a chat room, only its members can join it. The room of a
+// trip has the id of the trip. The latest messages are replayed, a client
+// reconnecting can pass the id of the last message it saw as last_seen to
+// get every message sent after it.
 func (h *Handlers) Connect(ctx context.Context, c *gin.Context) error {
-	// get user id from query param
+	roomID, err := uuid.Parse(c.Query("room"))
+	if err != nil {
+		return response.NewError(fmt.Errorf("parse room: %w", err), http.StatusBadRequest)
+	}
+
+	var lastSeen *uuid.UUID
+	if v := c.Query("last_seen"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return response.NewError(fmt.Errorf("parse last seen: %w", err), http.StatusBadRequest)
+		}
+		lastSeen = &id
+	}
 
-	userID, err := uuid.Parse(c.Request.URL.Query().Get("user_id"))
+	room, err := h.authorize(ctx, roomID)
 	if err != nil {
-		return fmt.Errorf("parse user id: %w", err)
+		return err
 	}
 
-	// get user from DB
-	user, err := h.user.QueryByID(ctx, userID)
+	user, err := h.user.QueryByID(ctx, auth.GetUserID(ctx))
 	if err != nil {
 		return fmt.Errorf("query user by id: %w", err)
 	}
 
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		return fmt.Errorf("upgrade: %w", err)
 	}
 	defer conn.Close()
 
-	roomName := c.Request.URL.Query().Get("room")
-	if roomName == "" {
-		conn.WriteMessage(websocket.TextMessage, []byte("No room specified"))
-		return fmt.Errorf("no room specified")
+	if err := h.ws.Serve(ctx, user, room, lastSeen, conn); err != nil {
+		return fmt.Errorf("serve: roomID[%s]: %w", roomID, err)
+	}
+
+	return nil
+}
+
+// @Summary query the messages of a chat room
+// @Schemes
+// @Description QueryMessages will return the latest messages of a chat room, oldest first. Older messages are paged with the before cursor. The room of a trip has the id of the trip.
+// @Tags chat
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param id path string true "Room ID"
+// @Param before query string false "ID of the message to page before"
+// @Param rows query int false "Number of messages, at most 100 (default 50)"
+// @Success 200 {object} AppChatMessages "messages of the room"
+// @Failure 400 "Bad Request"
+// @Failure 403 "Forbidden"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /chat/rooms/{id}/messages [get]
+// @Router /trips/{id}/messages [get]
+func (h *Handlers) QueryMessages(ctx context.Context, c *gin.Context) error {
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return response.NewError(fmt.Errorf("parse room id: %w", err), http.StatusBadRequest)
 	}
 
-	streamName := "chatstream:" + roomName
-	channelName := "chatroom:" + roomName
+	filter, rows, err := parseFilter(c.Request)
+	if err != nil {
+		return err
+	}
 
-	// Send chat history to the new client from the Stream
-	if err = h.ws.SendChatHistory(ctx, streamName, channelName, conn); err != nil {
-		return fmt.Errorf("send chat history: %w", err)
+	if _, err := h.authorize(ctx, roomID); err != nil {
+		return err
 	}
 
-	// Subscribe to the Redis channel for real-time updates
-	pubsub := cachedb.Subscribe(ctx, channelName)
-	defer pubsub.Close()
-	ch := pubsub.Channel()
+	msgs, err := h.ws.QueryByRoom(ctx, roomID, filter, rows)
+	if err != nil {
+		return fmt.Errorf("query: roomID[%s]: %w", roomID, err)
+	}
 
-	// you can create this user object if you want to temp close auth
-	// user := user.User{}
-	// Receive messages from WebSocket, send to Redis Stream, and publish to Redis channel
-	go h.ws.ReceiveChatMessages(ctx, user, streamName, channelName, conn, ch)
+	return web.Respond(ctx, c.Writer, toAppChatMessages(msgs, rows), http.StatusOK)
+}
 
-	// Receive real-time messages from the Redis channel and send to WebSocket
-	for msg := range ch {
-		err := conn.WriteMessage(websocket.TextMessage, []byte(msg.Payload))
-		if err != nil {
-			return fmt.Errorf("write message: %w", err)
+// @Summary open a chat room with the driver
+// @Schemes
+// @Description OpenRoom will open a chat room between a prospective passenger and the driver of a trip, to ask questions before joining. Opening it again returns the same room.
+// @Tags chat
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param id path string true "Trip ID"
+// @Success 200 {object} AppRoom "Chat room opened"
+// @Failure 400 "Bad Request"
+// @Failure 404 "Not Found"
+// @Failure 409 "Trip can no longer be joined"
+// @Failure 500 "Internal Server Error"
+// @Router /trips/{id}/rooms [post]
+func (h *Handlers) OpenRoom(ctx context.Context, c *gin.Context) error {
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return response.NewError(fmt.Errorf("parse trip id: %w", err), http.StatusBadRequest)
+	}
+
+	room, err := h.ws.OpenRoom(ctx, tripID, auth.GetUserID(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, trip.ErrNotFound):
+			return response.NewError(err, http.StatusNotFound)
+		case errors.Is(err, trip.ErrJoinOwnTrip), errors.Is(err, trip.ErrTripNotJoinable):
+			return response.NewError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("open room: tripID[%s]: %w", tripID, err)
 		}
 	}
 
-	return nil
+	return web.Respond(ctx, c.Writer, toAppRoom(room), http.StatusOK)
+}
+
+// @Summary query the chat rooms of a trip
+// @Schemes
+// @Description QueryRooms will return the chat rooms of prospective passengers of a trip. The driver gets all of them, anybody else only their own.
+// @Tags chat
+// @Accept json
+// @Produce json
+// @Param token header string true "Token"
+// @Param id path string true "Trip ID"
+// @Success 200 {array} AppRoom "chat rooms of the trip"
+// @Failure 400 "Bad Request"
+// @Failure 404 "Not Found"
+// @Failure 500 "Internal Server Error"
+// @Router /trips/{id}/rooms [get]
+func (h *Handlers) QueryRooms(ctx context.Context, c *gin.Context) error {
+	tripID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return response.NewError(fmt.Errorf("parse trip id: %w", err), http.StatusBadRequest)
+	}
+
+	rooms, err := h.ws.QueryRooms(ctx, tripID, auth.GetUserID(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, trip.ErrNotFound):
+			return response.NewError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("query rooms: tripID[%s]: %w", tripID, err)
+		}
+	}
+
+	return web.Respond(ctx, c.Writer, toAppRooms(rooms), http.StatusOK)
+}
+
+// authorize returns the room when the user is one of its members.
+func (h *Handlers) authorize(ctx context.Context, roomID uuid.UUID) (ws.Room, error) {
+	room, err := h.ws.Authorize(ctx, roomID, auth.GetUserID(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, ws.ErrRoomNotFound), errors.Is(err, trip.ErrNotFound):
+			return ws.Room{}, response.NewError(err, http.StatusNotFound)
+		case errors.Is(err, ws.ErrNotMember):
+			return ws.Room{}, response.NewError(err, http.StatusForbidden)
+		default:
+			return ws.Room{}, fmt.Errorf("authorize: roomID[%s]: %w", roomID, err)
+		}
+	}
+
+	return room, nil
 }
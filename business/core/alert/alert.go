@@ -0,0 +1,260 @@
+// Package alert provides the core business API for the safety alerts (SOS)
+// raised during a trip.
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/core/user"
+	"github.com/TSMC-Uber/server/foundation/logger"
+	"github.com/google/uuid"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound          = errors.New("alert not found")
+	ErrTripNotInProgress = errors.New("alerts can only be raised while the trip is in progress")
+	ErrNotParticipant    = errors.New("user is not a participant of the trip")
+	ErrInvalidTransition = errors.New("alert cannot move to this status")
+	ErrNoLocation        = errors.New("no location known for the trip")
+)
+
+var (
+	StatusOpen         = "open"
+	StatusAcknowledged = "acknowledged"
+	StatusResolved     = "resolved"
+)
+
+// transitions lists the statuses an alert can move to from each status.
+var transitions = map[string][]string{
+	StatusOpen:         {StatusAcknowledged, StatusResolved},
+	StatusAcknowledged: {StatusResolved},
+}
+
+// Storer interface declares the behavior this package needs to perists and
+// retrieve data.
+type Storer interface {
+	Create(ctx context.Context, alert Alert) error
+	UpdateStatus(ctx context.Context, alert Alert, from string) error
+	QueryByID(ctx context.Context, alertID uuid.UUID) (Alert, error)
+	QueryByTripID(ctx context.Context, tripID uuid.UUID) ([]Alert, error)
+}
+
+// Notifier interface declares the behavior this package needs to fan an
+// alert out to the people who have to act on it.
+type Notifier interface {
+	Publish(ctx context.Context, alert Alert) error
+	Email(ctx context.Context, alert Alert, recipients []string) error
+}
+
+// Locator interface declares the behavior this package needs to find where
+// a trip was last seen. It returns ErrNoLocation when nothing is known.
+type Locator interface {
+	LastLocation(ctx context.Context, tripID uuid.UUID) (Location, error)
+}
+
+// Core manages the set of APIs for alert access.
+type Core struct {
+	log      *logger.Logger
+	storer   Storer
+	notifier Notifier
+	locator  Locator
+	trip     *trip.Core
+	user     *user.Core
+}
+
+// NewCore constructs a core for alert api access.
+func NewCore(log *logger.Logger, storer Storer, notifier Notifier, locator Locator, trip *trip.Core, user *user.Core) *Core {
+	return &Core{
+		log:      log,
+		storer:   storer,
+		notifier: notifier,
+		locator:  locator,
+		trip:     trip,
+		user:     user,
+	}
+}
+
+// Create raises an alert on a trip in progress and fans it out right away.
+// The alert is stored before it is fanned out, and a failing fan-out does not
+// fail the alert.
+func (c *Core) Create(ctx context.Context, tripID uuid.UUID, na NewAlert) (Alert, error) {
+	trp, err := c.participantTrip(ctx, tripID, na.SenderID)
+	if err != nil {
+		return Alert{}, err
+	}
+
+	if trp.Status != trip.TripStatusIn {
+		return Alert{}, ErrTripNotInProgress
+	}
+
+	location := na.Location
+	if location == nil {
+		last, err := c.locator.LastLocation(ctx, tripID)
+		switch {
+		case err == nil:
+			location = &last
+		case !errors.Is(err, ErrNoLocation):
+			// an alert must go out even when the position is unknown
+			c.log.Error(ctx, "alert: lastlocation", "tripID", tripID, "msg", err)
+		}
+	}
+
+	alert := Alert{
+		ID:        uuid.New(),
+		TripID:    tripID,
+		SenderID:  na.SenderID,
+		Comment:   na.Comment,
+		Status:    StatusOpen,
+		Location:  location,
+		CreatedAt: time.Now(),
+	}
+
+	if err := c.storer.Create(ctx, alert); err != nil {
+		return Alert{}, fmt.Errorf("create: %w", err)
+	}
+
+	c.publish(ctx, alert)
+
+	recipients, err := c.recipients(ctx, trp, na.SenderID)
+	if err != nil {
+		c.log.Error(ctx, "alert: recipients", "alertID", alert.ID, "msg", err)
+		return alert, nil
+	}
+
+	if err := c.notifier.Email(ctx, alert, recipients); err != nil {
+		c.log.Error(ctx, "alert: email", "alertID", alert.ID, "msg", err)
+	}
+
+	return alert, nil
+}
+
+// UpdateStatus acknowledges or resolves an alert on behalf of a participant
+// of the trip.
+func (c *Core) UpdateStatus(ctx context.Context, alert Alert, userID uuid.UUID, status string) (Alert, error) {
+	if _, err := c.participantTrip(ctx, alert.TripID, userID); err != nil {
+		return Alert{}, err
+	}
+
+	if !canTransition(alert.Status, status) {
+		return Alert{}, ErrInvalidTransition
+	}
+
+	from := alert.Status
+	now := time.Now()
+
+	alert.Status = status
+	switch status {
+	case StatusAcknowledged:
+		alert.AcknowledgedBy = &userID
+		alert.AcknowledgedAt = &now
+	case StatusResolved:
+		alert.ResolvedBy = &userID
+		alert.ResolvedAt = &now
+	}
+
+	if err := c.storer.UpdateStatus(ctx, alert, from); err != nil {
+		return Alert{}, fmt.Errorf("updatestatus: alertID[%s]: %w", alert.ID, err)
+	}
+
+	c.publish(ctx, alert)
+
+	return alert, nil
+}
+
+// QueryByID gets the specified alert from the database.
+func (c *Core) QueryByID(ctx context.Context, alertID uuid.UUID) (Alert, error) {
+	alert, err := c.storer.QueryByID(ctx, alertID)
+	if err != nil {
+		return Alert{}, fmt.Errorf("query: alertID[%s]: %w", alertID, err)
+	}
+
+	return alert, nil
+}
+
+// QueryByTripID retrieves the alerts of a trip for one of its participants,
+// newest first.
+func (c *Core) QueryByTripID(ctx context.Context, tripID uuid.UUID, userID uuid.UUID) ([]Alert, error) {
+	if _, err := c.participantTrip(ctx, tripID, userID); err != nil {
+		return nil, err
+	}
+
+	alerts, err := c.storer.QueryByTripID(ctx, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("query: tripID[%s]: %w", tripID, err)
+	}
+
+	return alerts, nil
+}
+
+// =============================================================================
+
+// participantTrip returns the trip when the user is its driver or one of its
+// accepted passengers.
+func (c *Core) participantTrip(ctx context.Context, tripID uuid.UUID, userID uuid.UUID) (trip.TripView, error) {
+	trp, err := c.trip.QueryByID(ctx, tripID)
+	if err != nil {
+		return trip.TripView{}, fmt.Errorf("querytrip: tripID[%s]: %w", tripID, err)
+	}
+
+	ok, err := c.trip.IsParticipant(ctx, trp, userID)
+	if err != nil {
+		return trip.TripView{}, fmt.Errorf("isparticipant: tripID[%s]: %w", tripID, err)
+	}
+	if !ok {
+		return trip.TripView{}, ErrNotParticipant
+	}
+
+	return trp, nil
+}
+
+// recipients returns the email addresses of the other participants of the
+// trip.
+func (c *Core) recipients(ctx context.Context, trp trip.TripView, senderID uuid.UUID) ([]string, error) {
+	details, err := c.trip.QueryPassengers(ctx, trp.ID)
+	if err != nil {
+		return nil, fmt.Errorf("querypassengers: %w", err)
+	}
+
+	userIDs := []uuid.UUID{trp.DriverID}
+	for _, passenger := range details.PassengerDetails {
+		if passenger.PassengerID == trp.DriverID || passenger.PassengerStatus != trip.StatusAccepted {
+			continue
+		}
+		userIDs = append(userIDs, passenger.PassengerID)
+	}
+
+	users, err := c.user.QueryByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("querybyids: %w", err)
+	}
+
+	recipients := make([]string, 0, len(users))
+	for _, usr := range users {
+		if usr.ID == senderID {
+			continue
+		}
+		recipients = append(recipients, usr.Email.Address)
+	}
+
+	return recipients, nil
+}
+
+func (c *Core) publish(ctx context.Context, alert Alert) {
+	if err := c.notifier.Publish(ctx, alert); err != nil {
+		c.log.Error(ctx, "alert: publish", "alertID", alert.ID, "msg", err)
+	}
+}
+
+func canTransition(from string, to string) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package alert
+
+import (
+	"context"
+	"io"
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/core/user"
+	"github.com/TSMC-Uber/server/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mocks struct {
+	storer   *MockStorer
+	notifier *MockNotifier
+	locator  *MockLocator
+	trip     *trip.MockStorer
+	user     *user.MockStorer
+}
+
+func newTestCore() (*Core, mocks) {
+	m := mocks{
+		storer:   new(MockStorer),
+		notifier: new(MockNotifier),
+		locator:  new(MockLocator),
+		trip:     new(trip.MockStorer),
+		user:     new(user.MockStorer),
+	}
+
+	log := logger.New(io.Discard, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+	core := NewCore(log, m.storer, m.notifier, m.locator, trip.NewCore(m.trip), user.NewCore(m.user))
+
+	return core, m
+}
+
+func TestCreate(t *testing.T) {
+	core, m := newTestCore()
+
+	tripID := uuid.New()
+	driverID := uuid.New()
+	passengerID := uuid.New()
+	last := Location{Latitude: 25.03, Longitude: 121.56, RecordedAt: time.Now()}
+
+	m.trip.On("QueryByID", mock.Anything, tripID).Return(trip.TripView{ID: tripID, DriverID: driverID, Status: trip.TripStatusIn}, nil)
+	m.trip.On("QueryPassenger", mock.Anything, tripID, passengerID).Return(trip.TripPassenger{Status: trip.StatusAccepted}, nil)
+	m.trip.On("QueryPassengers", mock.Anything, tripID).Return(trip.TripDetails{
+		PassengerDetails: []trip.PassengerDetails{
+			{PassengerID: driverID, PassengerStatus: trip.StatusAccepted},
+			{PassengerID: passengerID, PassengerStatus: trip.StatusAccepted},
+		},
+	}, nil)
+	m.user.On("QueryByIDs", mock.Anything, []uuid.UUID{driverID, passengerID}).Return([]user.User{
+		{ID: driverID, Email: mail.Address{Address: "driver@example.com"}},
+		{ID: passengerID, Email: mail.Address{Address: "passenger@example.com"}},
+	}, nil)
+	m.locator.On("LastLocation", mock.Anything, tripID).Return(last, nil)
+	m.storer.On("Create", mock.Anything, mock.AnythingOfType("Alert")).Return(nil)
+	m.notifier.On("Publish", mock.Anything, mock.AnythingOfType("Alert")).Return(nil)
+	m.notifier.On("Email", mock.Anything, mock.AnythingOfType("Alert"), []string{"driver@example.com"}).Return(nil)
+
+	alert, err := core.Create(context.Background(), tripID, NewAlert{SenderID: passengerID, Comment: "help"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusOpen, alert.Status)
+	assert.Equal(t, passengerID, alert.SenderID)
+	assert.Equal(t, &last, alert.Location)
+	m.storer.AssertExpectations(t)
+	m.notifier.AssertExpectations(t)
+}
+
+func TestCreateWithoutLocation(t *testing.T) {
+	core, m := newTestCore()
+
+	tripID := uuid.New()
+	driverID := uuid.New()
+
+	m.trip.On("QueryByID", mock.Anything, tripID).Return(trip.TripView{ID: tripID, DriverID: driverID, Status: trip.TripStatusIn}, nil)
+	m.trip.On("QueryPassengers", mock.Anything, tripID).Return(trip.TripDetails{}, nil)
+	m.user.On("QueryByIDs", mock.Anything, []uuid.UUID{driverID}).Return([]user.User{{ID: driverID}}, nil)
+	m.locator.On("LastLocation", mock.Anything, tripID).Return(Location{}, ErrNoLocation)
+	m.storer.On("Create", mock.Anything, mock.AnythingOfType("Alert")).Return(nil)
+	m.notifier.On("Publish", mock.Anything, mock.AnythingOfType("Alert")).Return(nil)
+	m.notifier.On("Email", mock.Anything, mock.AnythingOfType("Alert"), []string{}).Return(nil)
+
+	alert, err := core.Create(context.Background(), tripID, NewAlert{SenderID: driverID})
+
+	assert.NoError(t, err)
+	assert.Nil(t, alert.Location)
+	m.storer.AssertExpectations(t)
+}
+
+func TestCreateRejected(t *testing.T) {
+	tripID := uuid.New()
+	driverID := uuid.New()
+
+	tests := []struct {
+		name   string
+		sender uuid.UUID
+		status string
+		want   error
+	}{
+		{name: "not started", sender: driverID, status: trip.TripStatusNotStarted, want: ErrTripNotInProgress},
+		{name: "finished", sender: driverID, status: trip.TripStatusFinished, want: ErrTripNotInProgress},
+		{name: "stranger", sender: uuid.New(), status: trip.TripStatusIn, want: ErrNotParticipant},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, m := newTestCore()
+
+			m.trip.On("QueryByID", mock.Anything, tripID).Return(trip.TripView{ID: tripID, DriverID: driverID, Status: tt.status}, nil)
+			m.trip.On("QueryPassenger", mock.Anything, tripID, tt.sender).Return(trip.TripPassenger{}, trip.ErrPassengerNotFound)
+
+			_, err := core.Create(context.Background(), tripID, NewAlert{SenderID: tt.sender})
+
+			assert.ErrorIs(t, err, tt.want)
+			m.storer.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestUpdateStatus(t *testing.T) {
+	core, m := newTestCore()
+
+	tripID := uuid.New()
+	driverID := uuid.New()
+	alert := Alert{ID: uuid.New(), TripID: tripID, SenderID: uuid.New(), Status: StatusOpen}
+
+	m.trip.On("QueryByID", mock.Anything, tripID).Return(trip.TripView{ID: tripID, DriverID: driverID, Status: trip.TripStatusIn}, nil)
+	m.storer.On("UpdateStatus", mock.Anything, mock.AnythingOfType("Alert"), StatusOpen).Return(nil)
+	m.notifier.On("Publish", mock.Anything, mock.AnythingOfType("Alert")).Return(nil)
+
+	updated, err := core.UpdateStatus(context.Background(), alert, driverID, StatusAcknowledged)
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusAcknowledged, updated.Status)
+	assert.Equal(t, &driverID, updated.AcknowledgedBy)
+	assert.NotNil(t, updated.AcknowledgedAt)
+	m.storer.AssertExpectations(t)
+	m.notifier.AssertExpectations(t)
+}
+
+func TestUpdateStatusInvalidTransition(t *testing.T) {
+	core, m := newTestCore()
+
+	tripID := uuid.New()
+	driverID := uuid.New()
+	alert := Alert{ID: uuid.New(), TripID: tripID, Status: StatusResolved}
+
+	m.trip.On("QueryByID", mock.Anything, tripID).Return(trip.TripView{ID: tripID, DriverID: driverID, Status: trip.TripStatusFinished}, nil)
+
+	_, err := core.UpdateStatus(context.Background(), alert, driverID, StatusAcknowledged)
+
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	m.storer.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,55 @@
+package alert
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockStorer struct {
+	mock.Mock
+}
+
+func (m *MockStorer) Create(ctx context.Context, alert Alert) error {
+	args := m.Called(ctx, alert)
+	return args.Error(0)
+}
+
+func (m *MockStorer) UpdateStatus(ctx context.Context, alert Alert, from string) error {
+	args := m.Called(ctx, alert, from)
+	return args.Error(0)
+}
+
+func (m *MockStorer) QueryByID(ctx context.Context, alertID uuid.UUID) (Alert, error) {
+	args := m.Called(ctx, alertID)
+	return args.Get(0).(Alert), args.Error(1)
+}
+
+func (m *MockStorer) QueryByTripID(ctx context.Context, tripID uuid.UUID) ([]Alert, error) {
+	args := m.Called(ctx, tripID)
+	return args.Get(0).([]Alert), args.Error(1)
+}
+
+type MockNotifier struct {
+	mock.Mock
+}
+
+func (m *MockNotifier) Publish(ctx context.Context, alert Alert) error {
+	args := m.Called(ctx, alert)
+	return args.Error(0)
+}
+
+func (m *MockNotifier) Email(ctx context.Context, alert Alert, recipients []string) error {
+	args := m.Called(ctx, alert, recipients)
+	return args.Error(0)
+}
+
+type MockLocator struct {
+	mock.Mock
+}
+
+func (m *MockLocator) LastLocation(ctx context.Context, tripID uuid.UUID) (Location, error) {
+	args := m.Called(ctx, tripID)
+	return args.Get(0).(Location), args.Error(1)
+}
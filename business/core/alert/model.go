@@ -0,0 +1,37 @@
+package alert
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Alert represents a safety alert raised during a trip.
+type Alert struct {
+	ID             uuid.UUID
+	TripID         uuid.UUID
+	SenderID       uuid.UUID
+	Comment        string
+	Status         string
+	Location       *Location
+	AcknowledgedBy *uuid.UUID
+	AcknowledgedAt *time.Time
+	ResolvedBy     *uuid.UUID
+	ResolvedAt     *time.Time
+	CreatedAt      time.Time
+}
+
+// Location is where the sender was when the alert was raised.
+type Location struct {
+	Latitude   float64
+	Longitude  float64
+	RecordedAt time.Time
+}
+
+// NewAlert contains information needed to raise an alert. When the location
+// is left out the last location of the trip stream is used.
+type NewAlert struct {
+	SenderID uuid.UUID
+	Comment  string
+	Location *Location
+}
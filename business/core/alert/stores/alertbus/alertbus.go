@@ -0,0 +1,134 @@
+// Package alertbus fans alerts out over redis and the email queue, and looks
+// up the last location a trip published on its location stream.
+package alertbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/core/alert"
+	"github.com/TSMC-Uber/server/business/core/locationws"
+	"github.com/TSMC-Uber/server/business/sys/cachedb"
+	"github.com/TSMC-Uber/server/business/sys/mail"
+	"github.com/TSMC-Uber/server/foundation/logger"
+	"github.com/google/uuid"
+)
+
+// ChannelAll is the redis pub-sub-channel every alert is published on.
+const ChannelAll = "alerts"
+
+// Channel returns the redis pub-sub-channel the alerts of a trip are
+// published on.
+func Channel(tripID uuid.UUID) string {
+	return ChannelAll + ":" + tripID.String()
+}
+
+// Bus manages the set of APIs for alert fan-out.
+type Bus struct {
+	log *logger.Logger
+}
+
+// NewBus constructs the api for alert fan-out.
+func NewBus(log *logger.Logger) *Bus {
+	return &Bus{
+		log: log,
+	}
+}
+
+// Publish sends the alert to the subscribers of its trip and of all alerts.
+func (b *Bus) Publish(ctx context.Context, alrt alert.Alert) error {
+	msg, err := json.Marshal(toEvent(alrt))
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := cachedb.Publish(ctx, Channel(alrt.TripID), string(msg)); err != nil {
+		return fmt.Errorf("publish: tripID[%s]: %w", alrt.TripID, err)
+	}
+
+	if err := cachedb.Publish(ctx, ChannelAll, string(msg)); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	return nil
+}
+
+// Email queues an email about the alert for the recipients.
+func (b *Bus) Email(ctx context.Context, alrt alert.Alert, recipients []string) error {
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "An SOS alert was raised on trip %s at %s.\r\n", alrt.TripID, alrt.CreatedAt.Format(time.RFC1123))
+	if alrt.Comment != "" {
+		fmt.Fprintf(&body, "Message: %s\r\n", alrt.Comment)
+	}
+	if alrt.Location != nil {
+		fmt.Fprintf(&body, "Last known location: https://www.google.com/maps?q=%f,%f\r\n", alrt.Location.Latitude, alrt.Location.Longitude)
+	}
+
+	email := mail.Email{
+		To:      recipients,
+		Subject: "SOS alert on your trip",
+		Body:    body.String(),
+	}
+
+	if err := mail.Enqueue(email); err != nil {
+		return fmt.Errorf("enqueue: %w", err)
+	}
+
+	return nil
+}
+
+// LastLocation returns the last location published on the trip stream.
+func (b *Bus) LastLocation(ctx context.Context, tripID uuid.UUID) (alert.Location, error) {
+	last, err := locationws.QueryLastLocation(ctx, tripID.String())
+	if err != nil {
+		if errors.Is(err, locationws.ErrNoLocation) {
+			return alert.Location{}, alert.ErrNoLocation
+		}
+		return alert.Location{}, fmt.Errorf("querylastlocation: tripID[%s]: %w", tripID, err)
+	}
+
+	return alert.Location{
+		Latitude:   last.Latitude,
+		Longitude:  last.Longitude,
+		RecordedAt: last.ServerTime.In(time.Local),
+	}, nil
+}
+
+// =============================================================================
+
+type event struct {
+	ID        string    `json:"id"`
+	TripID    string    `json:"trip_id"`
+	SenderID  string    `json:"sender_id"`
+	Comment   string    `json:"comment"`
+	Status    string    `json:"status"`
+	Latitude  *float64  `json:"latitude,omitempty"`
+	Longitude *float64  `json:"longitude,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func toEvent(alrt alert.Alert) event {
+	evt := event{
+		ID:        alrt.ID.String(),
+		TripID:    alrt.TripID.String(),
+		SenderID:  alrt.SenderID.String(),
+		Comment:   alrt.Comment,
+		Status:    alrt.Status,
+		CreatedAt: alrt.CreatedAt,
+	}
+
+	if alrt.Location != nil {
+		evt.Latitude = &alrt.Location.Latitude
+		evt.Longitude = &alrt.Location.Longitude
+	}
+
+	return evt
+}
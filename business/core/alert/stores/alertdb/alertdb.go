@@ -0,0 +1,152 @@
+// Package alertdb contains alert related CRUD functionality.
+package alertdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+
+	"github.com/TSMC-Uber/server/business/core/alert"
+	"github.com/TSMC-Uber/server/business/sys/database"
+	"github.com/TSMC-Uber/server/foundation/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// alertColumns are the columns selected when reading alerts.
+var alertColumns = []string{
+	"id",
+	"trip_id",
+	"sender_id",
+	"comment",
+	"status",
+	"ST_Y(lat_lon::geometry) AS latitude",
+	"ST_X(lat_lon::geometry) AS longitude",
+	"located_at",
+	"acknowledged_by",
+	"acknowledged_at",
+	"resolved_by",
+	"resolved_at",
+	"created_at",
+}
+
+// Store manages the set of APIs for alert database access.
+type Store struct {
+	log *logger.Logger
+	db  *sqlx.DB
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create inserts a new alert into the database.
+func (s *Store) Create(ctx context.Context, alrt alert.Alert) error {
+	dbAlert := toDBAlert(alrt)
+
+	var latLon any
+	if dbAlert.Latitude.Valid && dbAlert.Longitude.Valid {
+		latLon = sq.Expr("ST_SetSRID(ST_MakePoint(?, ?), 4326)", dbAlert.Longitude.Float64, dbAlert.Latitude.Float64)
+	}
+
+	sql, args, err := sq.
+		Insert("alert").
+		Columns("id", "trip_id", "sender_id", "comment", "status", "lat_lon", "located_at", "created_at").
+		Values(dbAlert.ID, dbAlert.TripID, dbAlert.SenderID, dbAlert.Comment, dbAlert.Status, latLon, dbAlert.LocatedAt, dbAlert.CreatedAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	if err := database.ExecContext(ctx, s.log, s.db, sql, args); err != nil {
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateStatus moves the alert to its new status, as long as nobody moved it
+// away from the status it was read in.
+func (s *Store) UpdateStatus(ctx context.Context, alrt alert.Alert, from string) error {
+	dbAlert := toDBAlert(alrt)
+
+	sql, args, err := sq.
+		Update("alert").
+		Set("status", dbAlert.Status).
+		Set("acknowledged_by", dbAlert.AcknowledgedBy).
+		Set("acknowledged_at", dbAlert.AcknowledgedAt).
+		Set("resolved_by", dbAlert.ResolvedBy).
+		Set("resolved_at", dbAlert.ResolvedAt).
+		Where(sq.Eq{"id": dbAlert.ID}).
+		Where(sq.Eq{"status": from}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rowsaffected: %w", err)
+	}
+	if rows == 0 {
+		return alert.ErrInvalidTransition
+	}
+
+	return nil
+}
+
+// QueryByID gets the specified alert from the database.
+func (s *Store) QueryByID(ctx context.Context, alertID uuid.UUID) (alert.Alert, error) {
+	sql, args, err := sq.Select(alertColumns...).
+		From("alert").
+		Where(sq.Eq{"id": alertID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return alert.Alert{}, fmt.Errorf("tosql: %w", err)
+	}
+
+	var dbAlert dbAlert
+	if err := database.GetContext(ctx, s.log, s.db, sql, args, &dbAlert); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return alert.Alert{}, fmt.Errorf("namedquerystruct: %w", alert.ErrNotFound)
+		}
+		return alert.Alert{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreAlert(dbAlert), nil
+}
+
+// QueryByTripID retrieves the alerts of a trip from the database, newest
+// first.
+func (s *Store) QueryByTripID(ctx context.Context, tripID uuid.UUID) ([]alert.Alert, error) {
+	sql, args, err := sq.Select(alertColumns...).
+		From("alert").
+		Where(sq.Eq{"trip_id": tripID}).
+		OrderBy("created_at DESC").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("tosql: %w", err)
+	}
+
+	var dbAlerts []dbAlert
+	if err := database.QueryContext(ctx, s.log, s.db, sql, args, &dbAlerts); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreAlertSlice(dbAlerts), nil
+}
@@ -0,0 +1,99 @@
+package alertdb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/core/alert"
+	"github.com/google/uuid"
+)
+
+type dbAlert struct {
+	ID             uuid.UUID       `db:"id"`
+	TripID         uuid.UUID       `db:"trip_id"`
+	SenderID       uuid.UUID       `db:"sender_id"`
+	Comment        sql.NullString  `db:"comment"`
+	Status         string          `db:"status"`
+	Latitude       sql.NullFloat64 `db:"latitude"`
+	Longitude      sql.NullFloat64 `db:"longitude"`
+	LocatedAt      sql.NullTime    `db:"located_at"`
+	AcknowledgedBy uuid.NullUUID   `db:"acknowledged_by"`
+	AcknowledgedAt sql.NullTime    `db:"acknowledged_at"`
+	ResolvedBy     uuid.NullUUID   `db:"resolved_by"`
+	ResolvedAt     sql.NullTime    `db:"resolved_at"`
+	CreatedAt      time.Time       `db:"created_at"`
+}
+
+func toDBAlert(alrt alert.Alert) dbAlert {
+	dbAlert := dbAlert{
+		ID:        alrt.ID,
+		TripID:    alrt.TripID,
+		SenderID:  alrt.SenderID,
+		Comment:   sql.NullString{String: alrt.Comment, Valid: alrt.Comment != ""},
+		Status:    alrt.Status,
+		CreatedAt: alrt.CreatedAt.UTC(),
+	}
+
+	if alrt.Location != nil {
+		dbAlert.Latitude = sql.NullFloat64{Float64: alrt.Location.Latitude, Valid: true}
+		dbAlert.Longitude = sql.NullFloat64{Float64: alrt.Location.Longitude, Valid: true}
+		dbAlert.LocatedAt = sql.NullTime{Time: alrt.Location.RecordedAt.UTC(), Valid: true}
+	}
+	if alrt.AcknowledgedBy != nil {
+		dbAlert.AcknowledgedBy = uuid.NullUUID{UUID: *alrt.AcknowledgedBy, Valid: true}
+	}
+	if alrt.AcknowledgedAt != nil {
+		dbAlert.AcknowledgedAt = sql.NullTime{Time: alrt.AcknowledgedAt.UTC(), Valid: true}
+	}
+	if alrt.ResolvedBy != nil {
+		dbAlert.ResolvedBy = uuid.NullUUID{UUID: *alrt.ResolvedBy, Valid: true}
+	}
+	if alrt.ResolvedAt != nil {
+		dbAlert.ResolvedAt = sql.NullTime{Time: alrt.ResolvedAt.UTC(), Valid: true}
+	}
+
+	return dbAlert
+}
+
+func toCoreAlert(dbAlert dbAlert) alert.Alert {
+	alrt := alert.Alert{
+		ID:        dbAlert.ID,
+		TripID:    dbAlert.TripID,
+		SenderID:  dbAlert.SenderID,
+		Comment:   dbAlert.Comment.String,
+		Status:    dbAlert.Status,
+		CreatedAt: dbAlert.CreatedAt.In(time.Local),
+	}
+
+	if dbAlert.Latitude.Valid && dbAlert.Longitude.Valid {
+		alrt.Location = &alert.Location{
+			Latitude:   dbAlert.Latitude.Float64,
+			Longitude:  dbAlert.Longitude.Float64,
+			RecordedAt: dbAlert.LocatedAt.Time.In(time.Local),
+		}
+	}
+	if dbAlert.AcknowledgedBy.Valid {
+		alrt.AcknowledgedBy = &dbAlert.AcknowledgedBy.UUID
+	}
+	if dbAlert.AcknowledgedAt.Valid {
+		acknowledgedAt := dbAlert.AcknowledgedAt.Time.In(time.Local)
+		alrt.AcknowledgedAt = &acknowledgedAt
+	}
+	if dbAlert.ResolvedBy.Valid {
+		alrt.ResolvedBy = &dbAlert.ResolvedBy.UUID
+	}
+	if dbAlert.ResolvedAt.Valid {
+		resolvedAt := dbAlert.ResolvedAt.Time.In(time.Local)
+		alrt.ResolvedAt = &resolvedAt
+	}
+
+	return alrt
+}
+
+func toCoreAlertSlice(dbAlerts []dbAlert) []alert.Alert {
+	alerts := make([]alert.Alert, len(dbAlerts))
+	for i, dbAlert := range dbAlerts {
+		alerts[i] = toCoreAlert(dbAlert)
+	}
+	return alerts
+}
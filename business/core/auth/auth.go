@@ -1,6 +1,6 @@
-// Package user provides an example of a core business API. Right now these
-// calls are just wrapping the data/data layer. But at some point you will
-// want auditing or something that isn't specific to the data/store layer.
+// Package auth provides the core business API for user sessions. A session
+// is created when a user signs in on a device and lives until it is idle for
+// too long, reaches its maximum lifetime or is revoked.
 package auth
 
 import (
@@ -9,125 +9,223 @@ import (
 	"crypto/sha256"
 	"encoding/base32"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/TSMC-Uber/server/business/core/user"
-	"github.com/TSMC-Uber/server/business/sys/cachedb"
-	"github.com/golang-jwt/jwt/v4"
-
 	"github.com/google/uuid"
 )
 
-// Set of error variables for CRUD operations.
-var ()
+// Set of error variables for session operations.
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionExpired  = errors.New("session expired")
+)
+
+const (
+	// IdleTimeout is how long a session lives without being used.
+	IdleTimeout = 24 * time.Hour
+
+	// MaxLifetime is how long a session lives at most, however often it is
+	// used.
+	MaxLifetime = 30 * 24 * time.Hour
+
+	// touchInterval bounds how often using a session writes its last seen
+	// time and pushes its expiry back.
+	touchInterval = time.Minute
+)
 
 // Storer interface declares the behavior this package needs to perists and
-// retrieve data.
+// retrieve data. Update fails with ErrSessionNotFound when the session does
+// not exist anymore, and keeps the expiry of the session for a zero ttl.
 type Storer interface {
-	Login(ctx context.Context, idToken string) (string, error)
-	Logout(ctx context.Context, sessionToken string) error
-}
-
-type RedisStorer interface {
-	Get(ctx context.Context, key string) (string, error)
-	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Create(ctx context.Context, sess Session, ttl time.Duration) error
+	Update(ctx context.Context, sess Session, ttl time.Duration) error
+	Delete(ctx context.Context, sess Session) error
+	DeleteByUser(ctx context.Context, userID uuid.UUID) error
+	QueryByTokenHash(ctx context.Context, tokenHash string) (Session, error)
+	QueryByID(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) (Session, error)
+	QueryByUser(ctx context.Context, userID uuid.UUID) ([]Session, error)
 }
 
-// Core manages the set of APIs for user access.
+// Core manages the set of APIs for session access.
 type Core struct {
 	storer Storer
+	now    func() time.Time
 }
 
-// NewCore constructs a core for user api access.
+// NewCore constructs a core for session api access.
 func NewCore(storer Storer) *Core {
 	return &Core{
 		storer: storer,
+		now:    time.Now,
 	}
 }
 
-// GenerateToken generates a signed JWT token string representing the user Claims.
-func (c *Core) GenerateSessionToken(userID uuid.UUID, ttl time.Duration) (*SessionToken, error) {
-	// Create a Token instance containing the user ID, expiry, and scope information. // Notice that we add the provided ttl (time-to-live) duration parameter to the // current time to get the expiry time?
-	token := &SessionToken{
-		UserID: userID,
-		Expiry: time.Now().Add(ttl),
+// Create signs the user in on a new session and returns it with the token
+// identifying it.
+func (c *Core) Create(ctx context.Context, usr user.User, ns NewSession) (Session, string, error) {
+	token, tokenHash, err := generateToken()
+	if err != nil {
+		return Session{}, "", fmt.Errorf("generate token: %w", err)
 	}
-	// Initialize a zero-valued byte slice with a length of 16 bytes.
-	randomBytes := make([]byte, 16)
-	// Use the Read() function from the crypto/rand package to fill the byte slice with
-	// random bytes from your operating system's CSPRNG. This will return an error if
-	// the CSPRNG fails to function correctly.
-	_, err := rand.Read(randomBytes)
+
+	now := c.now()
+
+	sess := Session{
+		ID:         uuid.New(),
+		TokenHash:  tokenHash,
+		UserID:     usr.ID,
+		Name:       usr.Name,
+		ImageURL:   usr.ImageURL,
+		Roles:      usr.Roles,
+		Device:     ns.Device,
+		UserAgent:  ns.UserAgent,
+		IP:         ns.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(MaxLifetime),
+	}
+
+	if err := c.storer.Create(ctx, sess, ttl(sess, now)); err != nil {
+		return Session{}, "", fmt.Errorf("create: %w", err)
+	}
+
+	return sess, token, nil
+}
+
+// Authenticate returns the session identified by the token. Using a session
+// pushes its expiry back, up to its maximum lifetime.
+func (c *Core) Authenticate(ctx context.Context, token string) (Session, error) {
+	sess, err := c.storer.QueryByTokenHash(ctx, hashToken(token))
 	if err != nil {
-		return nil, err
+		return Session{}, fmt.Errorf("query: %w", err)
 	}
-	// Encode the byte slice to a base-32-encoded string and assign it to the token
-	// Plaintext field. This will be the token string that we send to the user in their
-	// welcome email. They will look similar to this: //
-	// Y3QMGX3PJ3WLRL2YRTQGQ6KRHU //
-	// Note that by default base-32 strings may be padded at the end with the =
-	// character. We don't need this padding character for the purpose of our tokens, so
-	// we use the WithPadding(base32.NoPadding) method in the line below to omit them. token.Plaintext = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
-	token.Plaintext = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
-	// Generate a SHA-256 hash of the plaintext token string. This will be the value
-	// that we store in the `hash` field of our database table. Note that the
-	// sha256.Sum256() function returns an *array* of length 32, so to make it easier to // work with we convert it to a slice using the [:] operator before storing it.
-	hash := sha256.Sum256([]byte(token.Plaintext))
-	token.Hash = hex.EncodeToString(hash[:])
-	return token, nil
+
+	now := c.now()
+
+	if !now.Before(sess.ExpiresAt) {
+		if err := c.storer.Delete(ctx, sess); err != nil {
+			return Session{}, fmt.Errorf("delete: sessionID[%s]: %w", sess.ID, err)
+		}
+		return Session{}, ErrSessionExpired
+	}
+
+	if now.Sub(sess.LastSeenAt) >= touchInterval {
+		sess.LastSeenAt = now
+
+		// The update fails when the session was revoked in the meantime.
+		if err := c.storer.Update(ctx, sess, ttl(sess, now)); err != nil {
+			return Session{}, fmt.Errorf("update: sessionID[%s]: %w", sess.ID, err)
+		}
+	}
+
+	return sess, nil
 }
 
-func (c *Core) SetSessionToken(ctx context.Context, sessionToken SessionToken, user user.User) error {
-	userSessionInfo := userSessionInfo{
-		ID:       user.ID,       // replace 'user.ID' with actual user ID
-		Name:     user.Name,     // replace 'user.Name' with actual user name
-		ImageURL: user.ImageURL, // replace 'user.ImageURL' with actual image URL
+// QueryByID returns the session of the user with the given id.
+func (c *Core) QueryByID(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) (Session, error) {
+	sess, err := c.storer.QueryByID(ctx, userID, sessionID)
+	if err != nil {
+		return Session{}, fmt.Errorf("query: userID[%s] sessionID[%s]: %w", userID, sessionID, err)
 	}
 
-	jsonUserInfo, err := json.Marshal(userSessionInfo)
+	return sess, nil
+}
+
+// QueryByUser returns the sessions of the user, most recently used first.
+func (c *Core) QueryByUser(ctx context.Context, userID uuid.UUID) ([]Session, error) {
+	sessions, err := c.storer.QueryByUser(ctx, userID)
 	if err != nil {
-		return fmt.Errorf("json marshal: %w", err)
+		return nil, fmt.Errorf("query: userID[%s]: %w", userID, err)
 	}
-	if err := cachedb.Set(ctx, sessionToken.Hash, jsonUserInfo, time.Until(sessionToken.Expiry)); err != nil {
-		return fmt.Errorf("set session token: %w", err)
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+
+	return sessions, nil
+}
+
+// Revoke signs the session out.
+func (c *Core) Revoke(ctx context.Context, sess Session) error {
+	if err := c.storer.Delete(ctx, sess); err != nil {
+		return fmt.Errorf("delete: sessionID[%s]: %w", sess.ID, err)
 	}
 
 	return nil
 }
 
-func (c *Core) RemoveSessionToken(ctx context.Context, sessionToken string) error {
-	if err := cachedb.Remove(ctx, sessionToken); err != nil {
-		return fmt.Errorf("remove session token: %w", err)
+// RevokeAll signs the user out of every session.
+func (c *Core) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	if err := c.storer.DeleteByUser(ctx, userID); err != nil {
+		return fmt.Errorf("deletebyuser: userID[%s]: %w", userID, err)
 	}
 
 	return nil
 }
 
-func (c *Core) GetSessionToken(ctx context.Context, sessionToken string) (uuid.UUID, error) {
-	userInfo, err := cachedb.Get(ctx, sessionToken)
+// RefreshUser rewrites the user information stored with the sessions of the
+// user, keeping their expiry, so a change of roles applies right away.
+func (c *Core) RefreshUser(ctx context.Context, usr user.User) error {
+	sessions, err := c.storer.QueryByUser(ctx, usr.ID)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("get session token: %w", err)
+		return fmt.Errorf("query: userID[%s]: %w", usr.ID, err)
 	}
 
-	var userSessionInfo userSessionInfo
-	if err := json.Unmarshal([]byte(userInfo), &userSessionInfo); err != nil {
-		return uuid.Nil, fmt.Errorf("json unmarshal: %w", err)
+	for _, sess := range sessions {
+		sess.Name = usr.Name
+		sess.ImageURL = usr.ImageURL
+		sess.Roles = usr.Roles
+
+		if err := c.storer.Update(ctx, sess, 0); err != nil {
+			if errors.Is(err, ErrSessionNotFound) {
+				continue
+			}
+			return fmt.Errorf("update: sessionID[%s]: %w", sess.ID, err)
+		}
 	}
 
-	return uuid.Parse(userSessionInfo.ID.String())
+	return nil
 }
 
-func (c *Core) ParseIDToken(idToken string) (*IDTokenInfo, error) {
-	token, _, err := new(jwt.Parser).ParseUnverified(idToken, &IDTokenInfo{})
-	if err != nil {
-		return nil, fmt.Errorf("parse unverified: %w", err)
+// =============================================================================
+
+// ttl returns how long the session lives if it is not used again.
+func ttl(sess Session, now time.Time) time.Duration {
+	left := sess.ExpiresAt.Sub(now)
+	if left < IdleTimeout {
+		return left
 	}
-	tokenInfo, ok := token.Claims.(*IDTokenInfo)
-	if !ok {
-		return nil, fmt.Errorf("token claims: %w", err)
+	return IdleTimeout
+}
+
+// generateToken generates a random token and the hash it is stored under.
+func generateToken() (string, string, error) {
+	// Initialize a zero-valued byte slice with a length of 16 bytes.
+	randomBytes := make([]byte, 16)
+	// Use the Read() function from the crypto/rand package to fill the byte slice with
+	// random bytes from your operating system's CSPRNG. This will return an error if
+	// the CSPRNG fails to function correctly.
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", "", err
 	}
+	// Encode the byte slice to a base-32-encoded string. They will look similar to
+	// this: Y3QMGX3PJ3WLRL2YRTQGQ6KRHU
+	// Note that by default base-32 strings may be padded at the end with the =
+	// character. We don't need this padding character for the purpose of our tokens, so
+	// we use the WithPadding(base32.NoPadding) method in the line below to omit them.
+	token := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+
+	return token, hashToken(token), nil
+}
 
-	return tokenInfo, nil
+// hashToken returns the SHA-256 hash of the token, which is what we store so a
+// leak of the store does not leak usable tokens.
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
 }
@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/core/user"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestCore(now time.Time) (*Core, *MockStorer) {
+	storer := new(MockStorer)
+
+	core := NewCore(storer)
+	core.now = func() time.Time { return now }
+
+	return core, storer
+}
+
+func TestCreate(t *testing.T) {
+	now := time.Now()
+	core, storer := newTestCore(now)
+
+	usr := user.User{ID: uuid.New(), Name: "Rider", Roles: []user.Role{user.RoleRider}}
+	ns := NewSession{Device: "phone", UserAgent: "test-agent", IP: "10.0.0.1"}
+
+	storer.On("Create", mock.Anything, mock.AnythingOfType("auth.Session"), IdleTimeout).Return(nil)
+
+	sess, token, err := core.Create(context.Background(), usr, ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, token, 26)
+	assert.Equal(t, hashToken(token), sess.TokenHash)
+	assert.Equal(t, usr.ID, sess.UserID)
+	assert.Equal(t, usr.Roles, sess.Roles)
+	assert.Equal(t, "phone", sess.Device)
+	assert.Equal(t, "10.0.0.1", sess.IP)
+	assert.Equal(t, now.Add(MaxLifetime), sess.ExpiresAt)
+	storer.AssertExpectations(t)
+}
+
+func TestAuthenticateSlidesExpiry(t *testing.T) {
+	now := time.Now()
+	core, storer := newTestCore(now)
+
+	sess := Session{
+		ID:         uuid.New(),
+		TokenHash:  hashToken("token"),
+		UserID:     uuid.New(),
+		CreatedAt:  now.Add(-time.Hour),
+		LastSeenAt: now.Add(-time.Hour),
+		ExpiresAt:  now.Add(MaxLifetime - time.Hour),
+	}
+
+	touched := sess
+	touched.LastSeenAt = now
+
+	storer.On("QueryByTokenHash", mock.Anything, sess.TokenHash).Return(sess, nil)
+	storer.On("Update", mock.Anything, touched, IdleTimeout).Return(nil)
+
+	got, err := core.Authenticate(context.Background(), "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, touched, got)
+	storer.AssertExpectations(t)
+}
+
+func TestAuthenticateCappedByMaxLifetime(t *testing.T) {
+	now := time.Now()
+	core, storer := newTestCore(now)
+
+	sess := Session{
+		ID:         uuid.New(),
+		TokenHash:  hashToken("token"),
+		UserID:     uuid.New(),
+		LastSeenAt: now.Add(-time.Hour),
+		ExpiresAt:  now.Add(time.Hour),
+	}
+
+	storer.On("QueryByTokenHash", mock.Anything, sess.TokenHash).Return(sess, nil)
+	storer.On("Update", mock.Anything, mock.AnythingOfType("auth.Session"), time.Hour).Return(nil)
+
+	_, err := core.Authenticate(context.Background(), "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storer.AssertExpectations(t)
+}
+
+func TestAuthenticateRecentlyUsed(t *testing.T) {
+	now := time.Now()
+	core, storer := newTestCore(now)
+
+	sess := Session{
+		ID:         uuid.New(),
+		TokenHash:  hashToken("token"),
+		UserID:     uuid.New(),
+		LastSeenAt: now.Add(-time.Second),
+		ExpiresAt:  now.Add(time.Hour),
+	}
+
+	storer.On("QueryByTokenHash", mock.Anything, sess.TokenHash).Return(sess, nil)
+
+	_, err := core.Authenticate(context.Background(), "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storer.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthenticateExpired(t *testing.T) {
+	now := time.Now()
+	core, storer := newTestCore(now)
+
+	sess := Session{
+		ID:         uuid.New(),
+		TokenHash:  hashToken("token"),
+		UserID:     uuid.New(),
+		LastSeenAt: now.Add(-time.Hour),
+		ExpiresAt:  now.Add(-time.Second),
+	}
+
+	storer.On("QueryByTokenHash", mock.Anything, sess.TokenHash).Return(sess, nil)
+	storer.On("Delete", mock.Anything, sess).Return(nil)
+
+	_, err := core.Authenticate(context.Background(), "token")
+	assert.ErrorIs(t, err, ErrSessionExpired)
+	storer.AssertExpectations(t)
+}
+
+func TestAuthenticateRevoked(t *testing.T) {
+	now := time.Now()
+	core, storer := newTestCore(now)
+
+	sess := Session{
+		ID:         uuid.New(),
+		TokenHash:  hashToken("token"),
+		UserID:     uuid.New(),
+		LastSeenAt: now.Add(-time.Hour),
+		ExpiresAt:  now.Add(time.Hour),
+	}
+
+	// revoked before the lookup
+	storer.On("QueryByTokenHash", mock.Anything, hashToken("revoked")).Return(Session{}, ErrSessionNotFound)
+
+	_, err := core.Authenticate(context.Background(), "revoked")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	// revoked between the lookup and the update
+	storer.On("QueryByTokenHash", mock.Anything, sess.TokenHash).Return(sess, nil)
+	storer.On("Update", mock.Anything, mock.Anything, mock.Anything).Return(ErrSessionNotFound)
+
+	_, err = core.Authenticate(context.Background(), "token")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestRefreshUser(t *testing.T) {
+	now := time.Now()
+	core, storer := newTestCore(now)
+
+	usr := user.User{ID: uuid.New(), Name: "Driver", Roles: []user.Role{user.RoleRider, user.RoleDriver}}
+
+	sessions := []Session{
+		{ID: uuid.New(), TokenHash: "hash-1", UserID: usr.ID, Roles: []user.Role{user.RoleRider}},
+		{ID: uuid.New(), TokenHash: "hash-2", UserID: usr.ID, Roles: []user.Role{user.RoleRider}},
+	}
+
+	storer.On("QueryByUser", mock.Anything, usr.ID).Return(sessions, nil)
+	storer.On("Update", mock.Anything, mock.MatchedBy(func(sess Session) bool {
+		return sess.TokenHash == "hash-1" && user.HasRole(sess.Roles, user.RoleDriver)
+	}), time.Duration(0)).Return(nil)
+	storer.On("Update", mock.Anything, mock.MatchedBy(func(sess Session) bool {
+		return sess.TokenHash == "hash-2"
+	}), time.Duration(0)).Return(ErrSessionNotFound)
+
+	if err := core.RefreshUser(context.Background(), usr); err != nil {
+		t.Fatal(err)
+	}
+
+	storer.AssertNumberOfCalls(t, "Update", 2)
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockStorer struct {
+	mock.Mock
+}
+
+func (m *MockStorer) Create(ctx context.Context, sess Session, ttl time.Duration) error {
+	args := m.Called(ctx, sess, ttl)
+	return args.Error(0)
+}
+
+func (m *MockStorer) Update(ctx context.Context, sess Session, ttl time.Duration) error {
+	args := m.Called(ctx, sess, ttl)
+	return args.Error(0)
+}
+
+func (m *MockStorer) Delete(ctx context.Context, sess Session) error {
+	args := m.Called(ctx, sess)
+	return args.Error(0)
+}
+
+func (m *MockStorer) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockStorer) QueryByTokenHash(ctx context.Context, tokenHash string) (Session, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(Session), args.Error(1)
+}
+
+func (m *MockStorer) QueryByID(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) (Session, error) {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Get(0).(Session), args.Error(1)
+}
+
+func (m *MockStorer) QueryByUser(ctx context.Context, userID uuid.UUID) ([]Session, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]Session), args.Error(1)
+}
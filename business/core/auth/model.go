@@ -3,49 +3,30 @@ package auth
 import (
 	"time"
 
-	"github.com/golang-jwt/jwt/v4"
+	"github.com/TSMC-Uber/server/business/core/user"
 	"github.com/google/uuid"
 )
 
-type SessionToken struct {
-	Plaintext string
-	Hash      string
-	UserID    uuid.UUID
-	Expiry    time.Time
-	Scope     string
+// Session is a device a user is signed in on. The token identifying the
+// session is only known to the device, we keep its hash.
+type Session struct {
+	ID         uuid.UUID
+	TokenHash  string
+	UserID     uuid.UUID
+	Name       string
+	ImageURL   string
+	Roles      []user.Role
+	Device     string
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
 }
 
-type NewSessionToken struct {
-	Hash   []byte
-	UserID uuid.UUID
-	Expiry time.Time
-	Scope  string
-}
-
-type IDTokenInfo struct {
-	Iss string `json:"iss"`
-	// userId
-	Sub string `json:"sub"`
-	Azp string `json:"azp"`
-	// clientId
-	Aud string `json:"aud"`
-	Iat int64  `json:"iat"`
-	// expired time
-	Exp int64 `json:"exp"`
-
-	Email         string `json:"email"`
-	EmailVerified bool   `json:"email_verified"`
-	AtHash        string `json:"at_hash"`
-	Name          string `json:"name"`
-	GivenName     string `json:"given_name"`
-	FamilyName    string `json:"family_name"`
-	Picture       string `json:"picture"`
-	Local         string `json:"locale"`
-	jwt.StandardClaims
-}
-
-type userSessionInfo struct {
-	ID       uuid.UUID `json:"id"`
-	Name     string    `json:"name"`
-	ImageURL string    `json:"image_url"`
+// NewSession contains information about the device signing in.
+type NewSession struct {
+	Device    string
+	UserAgent string
+	IP        string
 }
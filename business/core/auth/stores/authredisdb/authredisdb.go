@@ -1,48 +1,201 @@
-// Package authredisdb contains redis related Set and Get functionality.
+// Package authredisdb contains session related functionality backed by redis.
+// A session is stored under the hash of its token, and the sessions of a user
+// are indexed in a redis hash from session id to token hash.
 package authredisdb
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/TSMC-Uber/server/business/core/auth"
+	"github.com/TSMC-Uber/server/business/sys/cachedb"
+	"github.com/TSMC-Uber/server/foundation/logger"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
-	"go.uber.org/zap"
 )
 
-type redisDB struct {
-	Master  *redis.Client
-	Replica *redis.Client
-}
-
-// Store manages the set of APIs for user database access.
+// Store manages the set of APIs for session access.
 type Store struct {
-	log     *zap.SugaredLogger
-	redisdb redisDB
+	log *logger.Logger
 }
 
 // NewStore constructs the api for data access.
-func NewStore(log *zap.SugaredLogger, redisdb redisDB) *Store {
+func NewStore(log *logger.Logger) *Store {
 	return &Store{
-		log:     log,
-		redisdb: redisdb,
+		log: log,
+	}
+}
+
+// Create stores a new session and adds it to the index of its user.
+func (s *Store) Create(ctx context.Context, sess auth.Session, ttl time.Duration) error {
+	data, err := json.Marshal(toDBSession(sess))
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	err = cachedb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, sessionKey(sess.TokenHash), data, ttl)
+		pipe.HSet(ctx, userKey(sess.UserID), sess.ID.String(), sess.TokenHash)
+
+		// Sessions share the same maximum lifetime, so the newest session is
+		// the one to outlive the others.
+		pipe.ExpireAt(ctx, userKey(sess.UserID), sess.ExpiresAt)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
 	}
+
+	return nil
 }
 
-func (s *Store) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
-	err := s.redisdb.Master.Set(ctx, key, value, expiration).Err()
+// Update replaces a session that still exists.
+func (s *Store) Update(ctx context.Context, sess auth.Session, ttl time.Duration) error {
+	data, err := json.Marshal(toDBSession(sess))
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if ttl == 0 {
+		ttl = redis.KeepTTL
+	}
+
+	ok, err := cachedb.SetXX(ctx, sessionKey(sess.TokenHash), data, ttl)
+	if err != nil {
+		return fmt.Errorf("setxx: %w", err)
+	}
+	if !ok {
+		return auth.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// Delete removes a session and its entry in the index of its user.
+func (s *Store) Delete(ctx context.Context, sess auth.Session) error {
+	err := cachedb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, sessionKey(sess.TokenHash))
+		pipe.HDel(ctx, userKey(sess.UserID), sess.ID.String())
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteByUser removes every session of the user.
+func (s *Store) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	index, err := cachedb.HGetAll(ctx, userKey(userID))
+	if err != nil {
+		return fmt.Errorf("hgetall: %w", err)
+	}
+
+	keys := []string{userKey(userID)}
+	for _, tokenHash := range index {
+		keys = append(keys, sessionKey(tokenHash))
+	}
+
+	err = cachedb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, keys...)
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("set session token: %w", err)
+		return fmt.Errorf("delete: %w", err)
 	}
 
 	return nil
 }
 
-func (s *Store) Get(ctx context.Context, key string) (string, error) {
-	val, err := s.redisdb.Replica.Get(ctx, key).Result()
+// QueryByTokenHash gets the session stored under the token hash. Sessions are
+// read from the master so a revoked session is not served by a lagging
+// replica.
+func (s *Store) QueryByTokenHash(ctx context.Context, tokenHash string) (auth.Session, error) {
+	data, err := cachedb.GetPrimary(ctx, sessionKey(tokenHash))
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return auth.Session{}, auth.ErrSessionNotFound
+		}
+		return auth.Session{}, fmt.Errorf("get: %w", err)
+	}
+
+	return toCoreSession(tokenHash, data)
+}
+
+// QueryByID gets the session of the user with the given id.
+func (s *Store) QueryByID(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) (auth.Session, error) {
+	tokenHash, err := cachedb.HGet(ctx, userKey(userID), sessionID.String())
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return auth.Session{}, auth.ErrSessionNotFound
+		}
+		return auth.Session{}, fmt.Errorf("hget: %w", err)
+	}
+
+	return s.QueryByTokenHash(ctx, tokenHash)
+}
+
+// QueryByUser gets the sessions of the user. Index entries of sessions that
+// expired are removed on the way.
+func (s *Store) QueryByUser(ctx context.Context, userID uuid.UUID) ([]auth.Session, error) {
+	index, err := cachedb.HGetAll(ctx, userKey(userID))
 	if err != nil {
-		return "", fmt.Errorf("get session token: %w", err)
+		return nil, fmt.Errorf("hgetall: %w", err)
+	}
+
+	if len(index) == 0 {
+		return nil, nil
 	}
 
-	return val, nil
+	sessionIDs := make([]string, 0, len(index))
+	tokenHashes := make([]string, 0, len(index))
+	keys := make([]string, 0, len(index))
+	for sessionID, tokenHash := range index {
+		sessionIDs = append(sessionIDs, sessionID)
+		tokenHashes = append(tokenHashes, tokenHash)
+		keys = append(keys, sessionKey(tokenHash))
+	}
+
+	vals, err := cachedb.MGet(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("mget: %w", err)
+	}
+
+	var sessions []auth.Session
+	var expired []string
+	for i, val := range vals {
+		data, ok := val.(string)
+		if !ok {
+			expired = append(expired, sessionIDs[i])
+			continue
+		}
+
+		sess, err := toCoreSession(tokenHashes[i], data)
+		if err != nil {
+			return nil, fmt.Errorf("sessionID[%s]: %w", sessionIDs[i], err)
+		}
+		sessions = append(sessions, sess)
+	}
+
+	if len(expired) > 0 {
+		if err := cachedb.HDel(ctx, userKey(userID), expired...); err != nil {
+			s.log.Error(ctx, "authredisdb: remove expired sessions", "userID", userID, "msg", err)
+		}
+	}
+
+	return sessions, nil
+}
+
+// =============================================================================
+
+func sessionKey(tokenHash string) string {
+	return "session:" + tokenHash
+}
+
+func userKey(userID uuid.UUID) string {
+	return "session:user:" + userID.String()
 }
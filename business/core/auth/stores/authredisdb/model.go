@@ -0,0 +1,67 @@
+package authredisdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/core/auth"
+	"github.com/TSMC-Uber/server/business/core/user"
+	"github.com/google/uuid"
+)
+
+// dbSession represent the structure we need for moving data
+// between the app and redis.
+type dbSession struct {
+	ID         uuid.UUID   `json:"session_id"`
+	UserID     uuid.UUID   `json:"id"`
+	Name       string      `json:"name"`
+	ImageURL   string      `json:"image_url"`
+	Roles      []user.Role `json:"roles"`
+	Device     string      `json:"device"`
+	UserAgent  string      `json:"user_agent"`
+	IP         string      `json:"ip"`
+	CreatedAt  time.Time   `json:"created_at"`
+	LastSeenAt time.Time   `json:"last_seen_at"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+}
+
+func toDBSession(sess auth.Session) dbSession {
+	return dbSession{
+		ID:         sess.ID,
+		UserID:     sess.UserID,
+		Name:       sess.Name,
+		ImageURL:   sess.ImageURL,
+		Roles:      sess.Roles,
+		Device:     sess.Device,
+		UserAgent:  sess.UserAgent,
+		IP:         sess.IP,
+		CreatedAt:  sess.CreatedAt.UTC(),
+		LastSeenAt: sess.LastSeenAt.UTC(),
+		ExpiresAt:  sess.ExpiresAt.UTC(),
+	}
+}
+
+func toCoreSession(tokenHash string, data string) (auth.Session, error) {
+	var dbSess dbSession
+	if err := json.Unmarshal([]byte(data), &dbSess); err != nil {
+		return auth.Session{}, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	sess := auth.Session{
+		ID:         dbSess.ID,
+		TokenHash:  tokenHash,
+		UserID:     dbSess.UserID,
+		Name:       dbSess.Name,
+		ImageURL:   dbSess.ImageURL,
+		Roles:      dbSess.Roles,
+		Device:     dbSess.Device,
+		UserAgent:  dbSess.UserAgent,
+		IP:         dbSess.IP,
+		CreatedAt:  dbSess.CreatedAt.In(time.Local),
+		LastSeenAt: dbSess.LastSeenAt.In(time.Local),
+		ExpiresAt:  dbSess.ExpiresAt.In(time.Local),
+	}
+
+	return sess, nil
+}
@@ -8,10 +8,11 @@ import (
 )
 
 type QueryFilter struct {
-	DriverID *uuid.UUID `validate:"omitempty"`
-	Brand    *string    `validate:"omitempty"`
-	Model    *string    `validate:"omitempty"`
-	Color    *string    `validate:"omitempty"`
+	DriverID  *uuid.UUID `validate:"omitempty"`
+	Brand     *string    `validate:"omitempty"`
+	Model     *string    `validate:"omitempty"`
+	Color     *string    `validate:"omitempty"`
+	MinRating *float64   `validate:"omitempty,gte=1,lte=5"`
 }
 
 // Validate checks the data in the model is considered clean.
@@ -42,6 +43,11 @@ func (qf *QueryFilter) WithColor(color string) {
 	qf.Color = &color
 }
 
+// WithMinRating sets the MinRating field of the QueryFilter value.
+func (qf *QueryFilter) WithMinRating(minRating float64) {
+	qf.MinRating = &minRating
+}
+
 type QueryFilterFavoriteDriver struct {
 	UserID   *uuid.UUID `validate:"omitempty"`
 	DriverID *uuid.UUID `validate:"omitempty"`
@@ -15,6 +15,10 @@ type Driver struct {
 	Color     string    `json:"color"`
 	Plate     string    `json:"plate"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// aggregated from the ratings of the trips of the driver
+	RatingAverage float64 `json:"rating_average"`
+	RatingCount   int     `json:"rating_count"`
 }
 
 type NewDriver struct {
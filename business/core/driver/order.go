@@ -9,6 +9,9 @@ const (
 	OrderByBrand = "brand"
 	OrderByModel = "model"
 	OrderByColor = "color"
+
+	OrderByRatingAverage = "rating_average"
+	OrderByRatingCount   = "rating_count"
 )
 
 var DefaultOrderByFavoriteDriver = order.NewBy(OrderByBrandFavoriteDriver, order.ASC)
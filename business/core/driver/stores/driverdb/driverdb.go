@@ -65,6 +65,8 @@ func (s *Store) Query(ctx context.Context, filter driver.QueryFilter, orderBy or
 		"color",
 		"plate",
 		"driver_created_at",
+		"rating_average",
+		"rating_count",
 	).From("driver_view")
 
 	builder = s.applyFilter(builder, filter)
@@ -79,13 +81,13 @@ func (s *Store) Query(ctx context.Context, filter driver.QueryFilter, orderBy or
 	builder = builder.Limit(uint64(rowsPerPage)).Offset(uint64((pageNumber - 1) * rowsPerPage))
 
 	// Convert the builder to SQL and args
-	sql, _, err := builder.ToSql()
+	sql, args, err := builder.PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("tosql: %w", err)
 	}
 
 	var dbDrivers []dbDriver
-	if err := database.QueryContext(ctx, s.log, s.db, sql, nil, &dbDrivers); err != nil {
+	if err := database.QueryContext(ctx, s.log, s.db, sql, args, &dbDrivers); err != nil {
 		return nil, fmt.Errorf("namedqueryslice: %w", err)
 	}
 
@@ -99,7 +101,7 @@ func (s *Store) Count(ctx context.Context, filter driver.QueryFilter) (int, erro
 	builder = s.applyFilter(builder, filter)
 
 	// Convert the builder to SQL and args
-	sql, _, err := builder.ToSql()
+	sql, args, err := builder.PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
 		return 0, fmt.Errorf("tosql: %w", err)
 	}
@@ -107,7 +109,7 @@ func (s *Store) Count(ctx context.Context, filter driver.QueryFilter) (int, erro
 	var count struct {
 		Count int `db:"count"`
 	}
-	if err = database.GetContext(ctx, s.log, s.db, sql, nil, &count); err != nil {
+	if err = database.GetContext(ctx, s.log, s.db, sql, args, &count); err != nil {
 		return 0, fmt.Errorf("getcontext: %w", err)
 	}
 
@@ -127,6 +129,8 @@ func (s *Store) QueryByID(ctx context.Context, driverID string) (driver.Driver,
 		"color",
 		"plate",
 		"driver_created_at",
+		"rating_average",
+		"rating_count",
 	).From("driver_view").
 		Where(sq.Eq{"id": driverID}).
 		PlaceholderFormat(sq.Dollar).
@@ -189,7 +193,7 @@ func (s *Store) QueryFavorite(ctx context.Context, userID uuid.UUID, filter driv
 	).
 		From("favorite_driver_view")
 
-	builder = s.applyFilter(builder, filter)
+	builder = s.applyFilterFavoriteDriver(builder, filter)
 
 	orderByClause, err := orderByClauseFavoriteDriver(orderBy)
 	if err != nil {
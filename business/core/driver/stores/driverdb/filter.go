@@ -7,19 +7,43 @@ import (
 
 func (s *Store) applyFilter(builder squirrel.SelectBuilder, filter driver.QueryFilter) squirrel.SelectBuilder {
 	if filter.DriverID != nil {
-		builder = builder.Where(squirrel.Eq{"driver.user_id": *filter.DriverID})
+		builder = builder.Where(squirrel.Eq{"id": *filter.DriverID})
 	}
 
 	if filter.Brand != nil {
-		builder = builder.Where(squirrel.Eq{"driver.brand": *filter.Brand})
+		builder = builder.Where(squirrel.Eq{"brand": *filter.Brand})
 	}
 
 	if filter.Model != nil {
-		builder = builder.Where(squirrel.Eq{"driver.model": *filter.Model})
+		builder = builder.Where(squirrel.Eq{"model": *filter.Model})
 	}
 
 	if filter.Color != nil {
-		builder = builder.Where(squirrel.Eq{"driver.color": *filter.Color})
+		builder = builder.Where(squirrel.Eq{"color": *filter.Color})
+	}
+
+	if filter.MinRating != nil {
+		builder = builder.Where(squirrel.GtOrEq{"rating_average": *filter.MinRating})
+	}
+
+	return builder
+}
+
+func (s *Store) applyFilterFavoriteDriver(builder squirrel.SelectBuilder, filter driver.QueryFilter) squirrel.SelectBuilder {
+	if filter.DriverID != nil {
+		builder = builder.Where(squirrel.Eq{"driver_id": *filter.DriverID})
+	}
+
+	if filter.Brand != nil {
+		builder = builder.Where(squirrel.Eq{"driver_brand": *filter.Brand})
+	}
+
+	if filter.Model != nil {
+		builder = builder.Where(squirrel.Eq{"driver_model": *filter.Model})
+	}
+
+	if filter.Color != nil {
+		builder = builder.Where(squirrel.Eq{"driver_color": *filter.Color})
 	}
 
 	return builder
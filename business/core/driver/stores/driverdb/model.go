@@ -18,6 +18,9 @@ type dbDriver struct {
 	Color     string    `db:"color"`
 	Plate     string    `db:"plate"`
 	CreatedAt time.Time `db:"driver_created_at"`
+
+	RatingAverage float64 `db:"rating_average"`
+	RatingCount   int     `db:"rating_count"`
 }
 
 func toDBDriver(driver driver.Driver) dbDriver {
@@ -44,6 +47,9 @@ func toCoreDriver(dbDriver dbDriver) driver.Driver {
 		Color:     dbDriver.Color,
 		Plate:     dbDriver.Plate,
 		CreatedAt: dbDriver.CreatedAt.In(time.Local),
+
+		RatingAverage: dbDriver.RatingAverage,
+		RatingCount:   dbDriver.RatingCount,
 	}
 
 	return trip
@@ -11,6 +11,9 @@ var orderByFields = map[string]string{
 	driver.OrderByBrand: "brand",
 	driver.OrderByModel: "model",
 	driver.OrderByColor: "color",
+
+	driver.OrderByRatingAverage: "rating_average",
+	driver.OrderByRatingCount:   "rating_count",
 }
 
 func orderByClause(orderBy order.By) (string, error) {
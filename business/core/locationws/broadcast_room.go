@@ -2,128 +2,167 @@ package locationws
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"errors"
+	"sync"
+	"time"
 
-	"github.com/TSMC-Uber/server/business/sys/cachedb"
+	"github.com/TSMC-Uber/server/foundation/logger"
 )
 
-const (
-	// Intend to block the join/leave operations.
-	roomParticChannelBufferSize = 0
-)
-
-type RoomChannel struct {
-	Joining chan *Client
-	Leaving chan *Client
-	// This channel would be closed when the room is closed
-	Closed chan struct{}
-}
-
-func (c *RoomChannel) IsClosed() bool {
-	select {
-	case <-c.Closed:
-		return true
-	default:
-		return false
-	}
-}
-
-func (c *RoomChannel) Close() {
-	close(c.Closed)
-}
-
-// BroadcastRoom maintains the set of active clients and broadcasts messages from the redis pub-sub-channel to them.
+// BroadcastRoom maintains the set of active clients of a trip and broadcasts
+// the messages published on the trip to them. The dispatcher opens and closes
+// it, see RoomsDispatcher.
 type BroadcastRoom struct {
-	id      string // Redis pub-sub-channel name
-	clients map[*Client]bool
-	channel *RoomChannel
+	id      string
+	mu      sync.RWMutex
+	clients map[*Client]struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	// slowTimeout is how long the queue of a client can stay full.
+	slowTimeout time.Duration
+	counters    *counters
+
+	// done is closed once the room stopped broadcasting.
+	done chan struct{}
 }
 
-func newBroadcastRoom(id string) *BroadcastRoom {
+func newBroadcastRoom(id string, slowTimeout time.Duration, counters *counters) *BroadcastRoom {
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &BroadcastRoom{
-		id:      id,
-		clients: make(map[*Client]bool),
-		channel: &RoomChannel{
-			Joining: make(chan *Client, roomParticChannelBufferSize),
-			Leaving: make(chan *Client, roomParticChannelBufferSize),
-			Closed:  make(chan struct{}),
-		},
+		id:          id,
+		clients:     make(map[*Client]struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+		slowTimeout: slowTimeout,
+		counters:    counters,
+		done:        make(chan struct{}),
 	}
 }
 
+// GetClientCount returns the number of clients in the room.
 func (r *BroadcastRoom) GetClientCount() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	return len(r.clients)
 }
 
-// Handles the corresponding interactions from channels.
-// This room will automatically close when the last client leaves.
-func (r *BroadcastRoom) run(ctx context.Context) {
-	defer func() {
-		r.close()
-	}()
-
-	pubSub := cachedb.Subscribe(ctx, r.id)
-	defer func() {
-		err := pubSub.Unsubscribe(ctx, r.id)
-		if err != nil {
-			log.Println("failed to unsubscribe from redis pub-sub-channel", err)
-		}
-	}()
+// run broadcasts the messages published on the trip and disconnects the
+// users kicked from it until the room is closed. When the room cannot
+// subscribe its clients are disconnected, so they reconnect to a new room.
+func (r *BroadcastRoom) run(log *logger.Logger, broker Broker) {
+	defer close(r.done)
 
-	channel := r.channel
-	redisChannel := pubSub.Channel()
-	for {
-		if channel.IsClosed() {
-			return
-		}
+	messages, err := broker.Subscribe(r.ctx, r.id)
+	if err != nil {
+		log.Error(r.ctx, "locationws: subscribe", "tripID", r.id, "ERROR", err)
+		r.disconnectAll()
+		return
+	}
 
+	kicks, err := broker.Subscribe(r.ctx, kickTopic(r.id))
+	if err != nil {
+		log.Error(r.ctx, "locationws: subscribe kicks", "tripID", r.id, "ERROR", err)
+		r.disconnectAll()
+		return
+	}
+
+	for {
 		select {
-		case client := <-channel.Joining:
-			r.clients[client] = true
-		case client := <-channel.Leaving:
-			r.remove(client)
-		case <-ctx.Done():
+		case <-r.ctx.Done():
 			return
-		case message, ok := <-redisChannel:
-			fmt.Println("message: ", message.Payload)
+
+		case message, ok := <-messages:
 			if !ok {
 				return
 			}
+			r.broadcast(message)
 
-			for client := range r.clients {
-				if err := SendMessage(client, message.Payload); err != nil {
-					log.Println("failed to send message to client", err)
-					r.remove(client)
-				}
+		case userID, ok := <-kicks:
+			if !ok {
+				return
 			}
+			r.kick(userID)
 		}
 	}
 }
 
-func (r *BroadcastRoom) PublishMessage(ctx context.Context, message string) error {
-	return cachedb.Publish(ctx, r.id, message)
+// broadcast queues the message for every client of the room without waiting
+// for any of them, so a stalled client does not hold the others back. A
+// client that stays behind is disconnected.
+func (r *BroadcastRoom) broadcast(message string) {
+	r.mu.RLock()
+	clients := make([]*Client, 0, len(r.clients))
+	for client := range r.clients {
+		clients = append(clients, client)
+	}
+	r.mu.RUnlock()
+
+	for _, client := range clients {
+		conflated, err := client.send(message, r.slowTimeout)
+		switch {
+		case errors.Is(err, ErrSlowClient):
+			r.counters.slowClients.Add(1)
+			r.counters.dropped.Add(1)
+			client.disconnect()
+		case err != nil:
+			r.counters.dropped.Add(1)
+		case conflated:
+			r.counters.conflated.Add(1)
+		}
+	}
 }
 
-// remove remove the given client when it is joined. And close this room when the last client been removed.
-func (r *BroadcastRoom) remove(client *Client) {
-	if _, ok := r.clients[client]; !ok {
-		return
+// add adds the client, reporting whether it was not in the room yet.
+func (r *BroadcastRoom) add(client *Client) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.clients[client]; ok {
+		return false
 	}
+	r.clients[client] = struct{}{}
+
+	return true
+}
 
+// remove removes the client, reporting whether it was in the room and how
+// many clients are left.
+func (r *BroadcastRoom) remove(client *Client) (bool, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.clients[client]
 	delete(r.clients, client)
 
-	if len(r.clients) == 0 {
-		r.close()
+	return ok, len(r.clients)
+}
+
+// kick disconnects the clients of the user.
+func (r *BroadcastRoom) kick(userID string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for client := range r.clients {
+		if client.userID == userID {
+			client.disconnect()
+		}
 	}
 }
 
-// close do corresponding cleanups (when it is necessary)
-func (r *BroadcastRoom) close() {
-	if r.channel.IsClosed() {
-		return
+// disconnectAll disconnects every client of the room.
+func (r *BroadcastRoom) disconnectAll() {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for client := range r.clients {
+		client.disconnect()
 	}
+}
 
-	r.channel.Close()
-	clear(r.clients)
+// close stops the room from broadcasting, closing it again does nothing.
+func (r *BroadcastRoom) close() {
+	r.cancel()
 }
@@ -3,140 +3,134 @@ package locationws
 import (
 	"errors"
 	"fmt"
-	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+// Set of errors returned when a message cannot be sent to a client.
+var (
+	ErrClientClosed = errors.New("client is disconnected")
+	ErrSlowClient   = errors.New("client is too slow")
+)
+
+// Client is a websocket connection following the locations of a trip.
 type Client struct {
 	*websocket.Conn
+	userID        string
 	pingTicker    *time.Ticker
 	messageToSend chan string
-	broadcastRoom *BroadcastRoom
+
+	// room is the room the client joined, only the goroutine serving the
+	// client joins and leaves.
+	room *BroadcastRoom
+
+	// fullSince is when the queue of the client filled up, in unix nano
+	// seconds, or zero while it is not full.
+	fullSince atomic.Int64
+
+	// closed is closed once the client is disconnected.
+	closed    chan struct{}
+	closeOnce sync.Once
 }
 
-// sendLoop is a loop to send messages to the websocket connection
-func (c *Client) sendLoop() {
-	defer c.Close()
+func newClient(baseConn *websocket.Conn, userID string, queueSize int) *Client {
+	return &Client{
+		Conn:          baseConn,
+		userID:        userID,
+		pingTicker:    time.NewTicker(pingPeriod),
+		messageToSend: make(chan string, queueSize),
+		closed:        make(chan struct{}),
+	}
+}
 
+// sendLoop writes the messages of the room and the pings to the connection
+// until the client is disconnected or a write fails.
+func (c *Client) sendLoop() error {
 	for {
-		fmt.Println("sendLoop")
 		select {
+		case <-c.closed:
+			return nil
+
 		case <-c.pingTicker.C:
 			c.updateWriteDeadline()
-			err := c.WriteMessage(websocket.PingMessage, nil)
-			if err != nil {
-				log.Printf("failed to write ping message to the client %v", err)
-				return
+			if err := c.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return fmt.Errorf("write ping: %w", err)
 			}
+
 		case msg := <-c.messageToSend:
-			fmt.Println("receive msg from messageToSend")
+			c.fullSince.Store(0)
 			c.updateWriteDeadline()
-			err := c.WriteMessage(websocket.TextMessage, []byte(msg))
-			if err != nil {
-				log.Printf("failed to write message to the client %v", err)
-				return
+			if err := c.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+				return fmt.Errorf("write message: %w", err)
 			}
 		}
 	}
 }
 
-func (c *Client) receiveLoop() {
-	defer c.Close()
-
-	for {
-		fmt.Println("receiveLoop")
-		msgType, msg, err := c.ReadMessage()
-		fmt.Println("msg:", string(msg))
-		if err != nil {
-			fmt.Println("err:", err)
-			return // Usually caused by a normal client disconnection
-		}
-
-		fmt.Println("57")
-		c.updateReadDeadline()
-
-		if msgType == websocket.TextMessage { // only handle text message
-			err := HandleRequest(c, msg)
-			if err != nil {
-				log.Printf("failed to handle request %v", err)
-			}
-			continue
-		}
+// send queues the message without waiting for the client. When the queue is
+// full the oldest message is dropped for the new one, since only the newest
+// location matters, and send reports it conflated. A client whose queue stays
+// full for longer than the timeout is too slow, send returns ErrSlowClient.
+func (c *Client) send(msg string, slowTimeout time.Duration) (bool, error) {
+	select {
+	case <-c.closed:
+		return false, ErrClientClosed
+	default:
 	}
-}
-
-func (c *Client) updateReadDeadline() {
-	_ = c.SetReadDeadline(time.Now().Add(readMaxWait)) // ignore error
-}
 
-func (c *Client) updateWriteDeadline() {
-	_ = c.SetWriteDeadline(time.Now().Add(writeMaxWait)) // ignore error
-}
+	select {
+	case c.messageToSend <- msg:
+		return false, nil
+	default:
+	}
 
-func newClient(baseConn *websocket.Conn) *Client {
-	return &Client{
-		Conn:          baseConn,
-		pingTicker:    time.NewTicker(pingPeriod),
-		messageToSend: make(chan string, msgChannelBufferSize),
+	now := time.Now().UnixNano()
+	switch since := c.fullSince.Load(); {
+	case since == 0:
+		c.fullSince.CompareAndSwap(0, now)
+	case time.Duration(now-since) > slowTimeout:
+		return false, ErrSlowClient
 	}
-}
 
-func (c *Client) listenRoomClose(closeChan chan struct{}) {
+	// Only the room sends to the client, so once the oldest message is taken
+	// there is room for the new one.
 	select {
-	case <-closeChan:
-		c.Close()
-		return
+	case <-c.messageToSend:
+	default:
 	}
-}
-
-func (c *Client) leaveRoom() error {
-	broadcastRoom := c.broadcastRoom
-	if broadcastRoom == nil {
-		return nil
+	select {
+	case c.messageToSend <- msg:
+	default:
 	}
 
-	// If the room channel is already closed, then we don't need to send the
-	// `Leaving` channel.
-	if !broadcastRoom.channel.IsClosed() {
-		timer := time.NewTimer(roomResponseTimeout)
-		defer timer.Stop()
+	return true, nil
+}
 
-		select {
-		case broadcastRoom.channel.Leaving <- c:
-		case <-broadcastRoom.channel.Closed:
-		case <-timer.C:
-			return errors.New("leave from broadcast room timeout")
-		}
+// disconnect stops serving the client, disconnecting it again does nothing.
+func (c *Client) disconnect() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+}
+
+// writeJSON writes the value to the connection, only before the send loop
+// runs since a connection has one writer.
+func (c *Client) writeJSON(v any) error {
+	c.updateWriteDeadline()
+	if err := c.WriteJSON(v); err != nil {
+		return fmt.Errorf("write json: %w", err)
 	}
 
-	c.broadcastRoom = nil
 	return nil
 }
 
-func (c *Client) doJoin(
-	tripID string,
-) error {
-	fmt.Println("doJoin")
-	if c.broadcastRoom != nil {
-		err := c.leaveRoom()
-		if err != nil {
-			return err
-		}
-	}
-
-	timer := time.NewTimer(roomResponseTimeout)
-	defer timer.Stop()
+func (c *Client) updateReadDeadline() {
+	_ = c.SetReadDeadline(time.Now().Add(readMaxWait)) // ignore error
+}
 
-	broadcastRoom := AcquireBroadcastRoom(tripID)
-	fmt.Println("AcquireBroadcastRoom Success")
-	select {
-	case broadcastRoom.channel.Joining <- c:
-		c.broadcastRoom = broadcastRoom
-		go c.listenRoomClose(broadcastRoom.channel.Closed)
-		return nil
-	case <-timer.C:
-		return errors.New("join to broadcast room timeout")
-	}
+func (c *Client) updateWriteDeadline() {
+	_ = c.SetWriteDeadline(time.Now().Add(writeMaxWait)) // ignore error
 }
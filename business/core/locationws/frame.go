@@ -0,0 +1,158 @@
+package locationws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Version is the version of the location frames the server sends. Drivers
+// can still send the frames without a version, see decodeFrame.
+const Version = 1
+
+// Set of errors a location frame is rejected with.
+var (
+	ErrInvalidFrame       = errors.New("frame is invalid")
+	ErrUnsupportedVersion = errors.New("frame version is not supported")
+	ErrOutOfOrder         = errors.New("frame is out of order")
+	ErrImplausibleSpeed   = errors.New("frame is too far from the last one")
+)
+
+const (
+	// maxSpeed is the fastest a driver is believed to move, in meters per
+	// second, a frame further from the last one is taken as spoofed.
+	maxSpeed = 70.0
+
+	// maxClockSkew is how far in the future of the server the device time of
+	// a frame can be.
+	maxClockSkew = time.Minute
+
+	// earthRadius is the mean radius of the earth, in meters.
+	earthRadius = 6371000.0
+)
+
+// position holds the coordinates of a frame as the driver sent them, so a
+// missing coordinate is not taken for a zero one.
+type position struct {
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Latitute  *float64 `json:"latitute"`
+}
+
+// decodeFrame decodes and validates a frame the driver of the trip sent at
+// now. Frames without a version are the legacy ones, a position with the
+// latitude as latitute and no sequence number or device time; they are read
+// as a frame received at now.
+func decodeFrame(data []byte, tripID string, now time.Time) (Frame, error) {
+	var frm Frame
+	if err := json.Unmarshal(data, &frm); err != nil {
+		return Frame{}, fmt.Errorf("%w: not a json object", ErrInvalidFrame)
+	}
+
+	var pos position
+	if err := json.Unmarshal(data, &pos); err != nil {
+		return Frame{}, fmt.Errorf("%w: not a json object", ErrInvalidFrame)
+	}
+
+	switch frm.Version {
+	case 0:
+		if pos.Latitude == nil {
+			pos.Latitude = pos.Latitute
+		}
+		if pos.Latitude == nil || pos.Longitude == nil {
+			return Frame{}, fmt.Errorf("%w: latitute or longitude is missing", ErrInvalidFrame)
+		}
+		frm.Latitude = *pos.Latitude
+		frm.Seq = 0
+		frm.DeviceTime = now
+
+	case Version:
+		if pos.Latitude == nil || pos.Longitude == nil {
+			return Frame{}, fmt.Errorf("%w: latitude or longitude is missing", ErrInvalidFrame)
+		}
+		if frm.Seq < 1 {
+			return Frame{}, fmt.Errorf("%w: seq must be positive", ErrInvalidFrame)
+		}
+		if frm.DeviceTime.IsZero() {
+			return Frame{}, fmt.Errorf("%w: device_time is missing", ErrInvalidFrame)
+		}
+		if frm.DeviceTime.After(now.Add(maxClockSkew)) {
+			return Frame{}, fmt.Errorf("%w: device_time is in the future", ErrInvalidFrame)
+		}
+
+	default:
+		return Frame{}, ErrUnsupportedVersion
+	}
+
+	if frm.TripID != "" && frm.TripID != tripID {
+		return Frame{}, fmt.Errorf("%w: trip_id is not the trip of the stream", ErrInvalidFrame)
+	}
+
+	if err := validatePosition(frm); err != nil {
+		return Frame{}, err
+	}
+
+	frm.Version = Version
+	frm.TripID = tripID
+	frm.ServerTime = now
+	frm.StreamID = ""
+
+	lat := frm.Latitude
+	frm.Latitute = &lat
+
+	return frm, nil
+}
+
+// validatePosition checks the position and its readings are in range.
+func validatePosition(frm Frame) error {
+	switch {
+	case math.IsNaN(frm.Latitude) || frm.Latitude < -90 || frm.Latitude > 90:
+		return fmt.Errorf("%w: latitude is out of range", ErrInvalidFrame)
+	case math.IsNaN(frm.Longitude) || frm.Longitude < -180 || frm.Longitude > 180:
+		return fmt.Errorf("%w: longitude is out of range", ErrInvalidFrame)
+	case frm.Accuracy != nil && !(*frm.Accuracy >= 0):
+		return fmt.Errorf("%w: accuracy is negative", ErrInvalidFrame)
+	case frm.Heading != nil && !(*frm.Heading >= 0 && *frm.Heading < 360):
+		return fmt.Errorf("%w: heading is out of range", ErrInvalidFrame)
+	case frm.Speed != nil && !(*frm.Speed >= 0 && *frm.Speed <= maxSpeed):
+		return fmt.Errorf("%w: speed is out of range", ErrInvalidFrame)
+	}
+
+	return nil
+}
+
+// validateNext checks the frame can follow the last frame accepted from the
+// driver: its sequence number and device time move forward, and the driver
+// could have covered the distance between them in the time.
+func validateNext(prev Frame, next Frame) error {
+	if next.Seq != 0 && prev.Seq != 0 && next.Seq <= prev.Seq {
+		return fmt.Errorf("%w: seq %d after %d", ErrOutOfOrder, next.Seq, prev.Seq)
+	}
+
+	elapsed := next.DeviceTime.Sub(prev.DeviceTime)
+	if elapsed < 0 {
+		return fmt.Errorf("%w: device_time goes back", ErrOutOfOrder)
+	}
+
+	dist := distance(prev, next)
+	if dist > maxSpeed*math.Max(elapsed.Seconds(), 1) {
+		return fmt.Errorf("%w: %.0fm in %s", ErrImplausibleSpeed, dist, elapsed)
+	}
+
+	return nil
+}
+
+// distance returns the great-circle distance between the positions of the
+// frames, in meters.
+func distance(a Frame, b Frame) float64 {
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
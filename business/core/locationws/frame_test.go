@@ -0,0 +1,134 @@
+package locationws
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testTripID = "5cf37266-3473-4006-984f-9325122678b7"
+
+func TestDecodeFrame(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	frm, err := decodeFrame([]byte(`{"v":1,"seq":3,"latitude":25.03,"longitude":121.56,"accuracy":5,"heading":90,"speed":12.5,"device_time":"2024-01-02T03:04:04Z"}`), testTripID, now)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+
+	assert.Equal(t, Version, frm.Version)
+	assert.Equal(t, testTripID, frm.TripID)
+	assert.Equal(t, int64(3), frm.Seq)
+	assert.Equal(t, 25.03, frm.Latitude)
+	assert.Equal(t, 121.56, frm.Longitude)
+	assert.Equal(t, 12.5, *frm.Speed)
+	assert.Equal(t, now.Add(-time.Second), frm.DeviceTime)
+	assert.Equal(t, now, frm.ServerTime)
+	assert.Equal(t, 25.03, *frm.Latitute)
+}
+
+func TestDecodeLegacyFrame(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	frm, err := decodeFrame([]byte(`{"latitute":25.03,"longitude":121.56}`), testTripID, now)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+
+	assert.Equal(t, Version, frm.Version)
+	assert.Equal(t, int64(0), frm.Seq)
+	assert.Equal(t, 25.03, frm.Latitude)
+	assert.Equal(t, now, frm.DeviceTime)
+	assert.Equal(t, now, frm.ServerTime)
+}
+
+func TestDecodeInvalidFrame(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := map[string]struct {
+		frame string
+		err   error
+	}{
+		"not json":         {`location`, ErrInvalidFrame},
+		"version":          {`{"v":2,"seq":1,"latitude":1,"longitude":1,"device_time":"2024-01-02T03:04:05Z"}`, ErrUnsupportedVersion},
+		"no seq":           {`{"v":1,"latitude":1,"longitude":1,"device_time":"2024-01-02T03:04:05Z"}`, ErrInvalidFrame},
+		"no device time":   {`{"v":1,"seq":1,"latitude":1,"longitude":1}`, ErrInvalidFrame},
+		"future":           {`{"v":1,"seq":1,"latitude":1,"longitude":1,"device_time":"2024-01-02T04:04:05Z"}`, ErrInvalidFrame},
+		"other trip":       {`{"v":1,"seq":1,"trip_id":"other","latitude":1,"longitude":1,"device_time":"2024-01-02T03:04:05Z"}`, ErrInvalidFrame},
+		"latitude":         {`{"v":1,"seq":1,"latitude":91,"longitude":1,"device_time":"2024-01-02T03:04:05Z"}`, ErrInvalidFrame},
+		"longitude":        {`{"v":1,"seq":1,"latitude":1,"longitude":-181,"device_time":"2024-01-02T03:04:05Z"}`, ErrInvalidFrame},
+		"accuracy":         {`{"v":1,"seq":1,"latitude":1,"longitude":1,"accuracy":-1,"device_time":"2024-01-02T03:04:05Z"}`, ErrInvalidFrame},
+		"heading":          {`{"v":1,"seq":1,"latitude":1,"longitude":1,"heading":360,"device_time":"2024-01-02T03:04:05Z"}`, ErrInvalidFrame},
+		"speed":            {`{"v":1,"seq":1,"latitude":1,"longitude":1,"speed":100,"device_time":"2024-01-02T03:04:05Z"}`, ErrInvalidFrame},
+		"no latitude":      {`{"v":1,"seq":1,"longitude":1,"device_time":"2024-01-02T03:04:05Z"}`, ErrInvalidFrame},
+		"no longitude":     {`{"v":1,"seq":1,"latitude":1,"device_time":"2024-01-02T03:04:05Z"}`, ErrInvalidFrame},
+		"no coordinates":   {`{"v":1,"seq":1,"device_time":"2024-01-02T03:04:05Z"}`, ErrInvalidFrame},
+		"v1 latitute":      {`{"v":1,"seq":1,"latitute":1,"longitude":1,"device_time":"2024-01-02T03:04:05Z"}`, ErrInvalidFrame},
+		"legacy empty":     {`{}`, ErrInvalidFrame},
+		"legacy no lat":    {`{"longitude":1}`, ErrInvalidFrame},
+		"legacy no lng":    {`{"latitute":1}`, ErrInvalidFrame},
+		"legacy latitude":  {`{"latitute":-91,"longitude":1}`, ErrInvalidFrame},
+		"legacy longitude": {`{"latitute":1,"longitude":200}`, ErrInvalidFrame},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodeFrame([]byte(tt.frame), testTripID, now)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestDecodeZeroFrame(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// A position on null island is still a position.
+	frm, err := decodeFrame([]byte(`{"v":1,"seq":1,"latitude":0,"longitude":0,"device_time":"2024-01-02T03:04:05Z"}`), testTripID, now)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	assert.Zero(t, frm.Latitude)
+
+	frm, err = decodeFrame([]byte(`{"latitude":25.03,"longitude":121.56}`), testTripID, now)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	assert.Equal(t, 25.03, frm.Latitude)
+}
+
+func TestValidateNext(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	prev := Frame{Seq: 10, Latitude: 25.0330, Longitude: 121.5654, DeviceTime: start}
+
+	// About 111m north, 10s later.
+	next := Frame{Seq: 11, Latitude: 25.0340, Longitude: 121.5654, DeviceTime: start.Add(10 * time.Second)}
+	assert.NoError(t, validateNext(prev, next))
+
+	replayed := next
+	replayed.Seq = 10
+	assert.ErrorIs(t, validateNext(prev, replayed), ErrOutOfOrder)
+
+	back := next
+	back.DeviceTime = start.Add(-time.Second)
+	assert.ErrorIs(t, validateNext(prev, back), ErrOutOfOrder)
+
+	// About 11km in 10s.
+	jump := next
+	jump.Latitude = 25.1330
+	assert.ErrorIs(t, validateNext(prev, jump), ErrImplausibleSpeed)
+
+	// Legacy frames have no sequence number.
+	legacy := next
+	legacy.Seq = 0
+	assert.NoError(t, validateNext(prev, legacy))
+}
+
+func TestDistance(t *testing.T) {
+	a := Frame{Latitude: 25.0330, Longitude: 121.5654}
+	b := Frame{Latitude: 25.0430, Longitude: 121.5654}
+
+	assert.Equal(t, "1112", fmt.Sprintf("%.0f", distance(a, b)))
+	assert.Zero(t, distance(a, a))
+}
@@ -5,35 +5,66 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
 	"time"
 
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/sys/cachedb"
+	"github.com/TSMC-Uber/server/business/sys/ratelimit"
+	"github.com/TSMC-Uber/server/foundation/logger"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+)
+
+// Set of error variables for location streaming.
+var (
+	ErrNoLocation      = errors.New("no location known for the trip")
+	ErrInvalidStreamID = errors.New("stream id is invalid")
+	ErrNoTrace         = errors.New("no trace known for the trip")
 )
 
 // Storer interface declares the behavior this package needs to perists and
 // retrieve data.
 type Storer interface {
-	//Todo: Mongo DB
+	CreatePoints(ctx context.Context, frms []Frame) error
+	CreateTrace(ctx context.Context, tripID uuid.UUID) error
+	RefreshTrace(ctx context.Context, tripID uuid.UUID) error
+	QueryTrace(ctx context.Context, tripID uuid.UUID, tolerance float64) (Trace, error)
+	DeletePoints(ctx context.Context, before time.Time) error
 }
 
-// Core manages the set of APIs for user access.
+// Core manages the set of APIs for location streaming and the traces of the
+// trips.
 type Core struct {
-	storer Storer
+	log        *logger.Logger
+	storer     Storer
+	dispatcher *RoomsDispatcher
+	recorder   *Recorder
+
+	// retention is how long the locations of a trip are kept on its stream,
+	// zero keeps no stream.
+	retention time.Duration
 }
 
-// NewCore constructs a core for user api access.
-func NewCore(storer Storer) *Core {
+// NewCore constructs a core for location streaming, the clients of a trip
+// share the room the dispatcher keeps for it and the locations of the drivers
+// are stored by the recorder. With a retention the locations are also added
+// to a redis stream of the trip, trimmed to the retention, so clients can
+// resume from the last one they saw. A core only serving traces needs neither
+// a dispatcher nor a recorder.
+func NewCore(log *logger.Logger, storer Storer, dispatcher *RoomsDispatcher, recorder *Recorder, retention time.Duration) *Core {
 	return &Core{
-		storer: storer,
+		log:        log,
+		storer:     storer,
+		dispatcher: dispatcher,
+		recorder:   recorder,
+		retention:  retention,
 	}
 }
 
 const (
-	// The upper bound on the total number of senders:
-	// 1. the response for the websocket request
-	// 2. the broadcast from the room
-	msgChannelBufferSize = 2
-
 	// Time allowed to write a message to the peer.
 	writeMaxWait = 20 * time.Second
 
@@ -44,20 +75,19 @@ const (
 	// Time allowed to read the next message from the peer.
 	readMaxWait = pingPeriod + pingTimeout
 
-	roomResponseTimeout = 2 * time.Minute
+	// How long the last location of a trip is kept after the stream stops.
+	lastLocationTTL = 30 * time.Minute
 )
 
-// ServeClient creates a new websocket connection and handles the corresponding interactions.
-func ServeClient(
-	baseConn *websocket.Conn,
-	tripID string,
-	isDriver bool,
-) (*Client, error) {
-	client := newClient(baseConn)
-
-	client.SetCloseHandler(func(int, string) error {
-		return nil
-	})
+// ServeClient joins the connection of the user to the room of the trip and
+// streams the locations published on the trip to it until the client goes
+// away or the user is kicked, see Kick. The
+// client first gets the last location of the trip, or with a stream every
+// location after lastSeen when it gives one. The locations a driver publishes
+// are limited by the policy.
+func (c *Core) ServeClient(ctx context.Context, conn *websocket.Conn, tripID string, userID uuid.UUID, isDriver bool, limit ratelimit.Policy, lastSeen string) error {
+	client := c.dispatcher.newClient(conn, userID.String())
+	defer client.pingTicker.Stop()
 
 	client.updateReadDeadline()
 	client.SetPongHandler(func(string) error {
@@ -65,64 +95,282 @@ func ServeClient(
 		return nil
 	})
 
-	err := client.doJoin(tripID)
-	if err != nil {
-		return nil, err
+	c.dispatcher.Join(tripID, client)
+	defer c.dispatcher.Leave(client)
+
+	// The room queues what is published meanwhile, so nothing falls in
+	// between. Clients drop what they get twice by its stream id.
+	if err := c.sendSnapshot(ctx, client, tripID, lastSeen); err != nil {
+		return fmt.Errorf("send snapshot: %w", err)
+	}
+
+	// Read every client so pongs and closes are handled, only the locations
+	// of the driver are published. The read fails once the caller closes
+	// the connection, which ends the goroutine.
+	go c.receiveLoop(ctx, client, tripID, isDriver, limit)
+
+	return client.sendLoop()
+}
+
+// Kick disconnects the user from the location stream of the trip on every
+// instance, for a passenger who is no longer on the trip.
+func (c *Core) Kick(ctx context.Context, tripID uuid.UUID, userID uuid.UUID) error {
+	if err := c.dispatcher.Kick(ctx, tripID.String(), userID.String()); err != nil {
+		return fmt.Errorf("kick: %w", err)
 	}
 
+	return nil
+}
+
+// receiveLoop reads the frames of the client until the connection fails,
+// then disconnects the client. The frames of the driver that are invalid or
+// do not follow the last one accepted are dropped, the first one follows the
+// last location of the trip.
+func (c *Core) receiveLoop(ctx context.Context, client *Client, tripID string, isDriver bool, limit ratelimit.Policy) {
+	defer client.disconnect()
+
+	var prev *Frame
 	if isDriver {
-		go client.receiveLoop()
+		prev = c.lastAccepted(ctx, tripID)
 	}
 
-	// go client.sendLoop()
 	for {
-		select {
-		case <-client.pingTicker.C:
-			client.updateWriteDeadline()
-			err := client.WriteMessage(websocket.PingMessage, nil)
-			if err != nil {
-				fmt.Printf("failed to write ping message to the client %v", err)
-				// return
-			}
-		case msg := <-client.messageToSend:
-			client.updateWriteDeadline()
-			err := client.WriteMessage(websocket.TextMessage, []byte(msg))
-			if err != nil {
-				fmt.Printf("failed to write message to the client %v", err)
-				// return
-			}
+		msgType, msg, err := client.ReadMessage()
+		if err != nil {
+			return // Usually caused by a normal client disconnection
+		}
+
+		client.updateReadDeadline()
+
+		if !isDriver || msgType != websocket.TextMessage {
+			continue
+		}
+
+		// Locations over the limit are dropped, the next one will do.
+		res, err := ratelimit.Allow(ctx, "location:trip:"+tripID, limit)
+		if err != nil {
+			c.log.Error(ctx, "locationws: allow", "tripID", tripID, "ERROR", err)
+		}
+		if err == nil && !res.Allowed {
+			continue
+		}
+
+		frm, err := decodeFrame(msg, tripID, time.Now().UTC())
+		if err == nil && prev != nil {
+			err = validateNext(*prev, frm)
+		}
+		if err != nil {
+			c.dispatcher.counters.rejected.Add(1)
+			c.log.Warn(ctx, "locationws: reject frame", "tripID", tripID, "ERROR", err)
+			continue
+		}
+		prev = &frm
+
+		if c.recorder != nil && !c.recorder.Record(frm) {
+			c.log.Warn(ctx, "locationws: record queue full", "tripID", tripID)
+		}
+
+		if err := c.publish(ctx, frm); err != nil {
+			c.log.Error(ctx, "locationws: publish", "tripID", tripID, "ERROR", err)
 		}
 	}
+}
 
-	// return client, nil
+// lastAccepted returns the last location of the trip, for the frames of a
+// driver reconnecting to follow, or nil when there is none. The sequence
+// numbers start over with the connection, so it has none.
+func (c *Core) lastAccepted(ctx context.Context, tripID string) *Frame {
+	last, err := QueryLastLocation(ctx, tripID)
+	if err != nil {
+		if !errors.Is(err, ErrNoLocation) {
+			c.log.Error(ctx, "locationws: querylastlocation", "tripID", tripID, "ERROR", err)
+		}
+		return nil
+	}
+
+	// The locations kept before the frames had a device time.
+	if last.DeviceTime.IsZero() {
+		return nil
+	}
+	last.Seq = 0
+
+	return &last
 }
 
-func SendMessage(c *Client, msg string) error {
-	timer := time.NewTimer(writeMaxWait)
-	defer timer.Stop()
+// publish sends the location to the clients of the trip and keeps it as the
+// last location of the trip, and on the stream of the trip when there is one.
+func (c *Core) publish(ctx context.Context, frm Frame) error {
+	if c.retention > 0 {
+		streamID, err := c.appendStream(ctx, frm)
+		if err != nil {
+			return fmt.Errorf("append stream: %w", err)
+		}
+		frm.StreamID = streamID
+	}
+
+	msg, err := json.Marshal(frm)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
 
-	select {
-	case c.messageToSend <- msg:
+	if err := c.dispatcher.Publish(ctx, frm.TripID, string(msg)); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	if err := cachedb.Set(ctx, lastLocationKey(frm.TripID), msg, lastLocationTTL); err != nil {
+		return fmt.Errorf("set: %w", err)
+	}
+
+	return nil
+}
+
+// appendStream adds the location to the stream of the trip, dropping the
+// locations older than the retention, and returns its stream id.
+func (c *Core) appendStream(ctx context.Context, frm Frame) (string, error) {
+	data, err := json.Marshal(frm)
+	if err != nil {
+		return "", fmt.Errorf("marshal: %w", err)
+	}
+
+	key := streamKey(frm.TripID)
+	minID := strconv.FormatInt(frm.ServerTime.Add(-c.retention).UnixMilli(), 10)
+
+	var cmd *redis.StringCmd
+	err = cachedb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		cmd = pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: key,
+			MinID:  minID,
+			Approx: true,
+			Values: map[string]interface{}{"location": data},
+		})
+		pipe.Expire(ctx, key, c.retention)
 		return nil
-	case <-timer.C:
-		return errors.New("failed to write into message-to-send channel on time")
+	})
+	if err != nil {
+		return "", err
 	}
+
+	return cmd.Val(), nil
 }
 
-func HandleRequest(c *Client, plainReq []byte) error {
-	var req Location
-	err := json.Unmarshal(plainReq, &req)
+// sendSnapshot writes the locations the client missed to the connection, the
+// ones on the stream after lastSeen when the client resumes, or else the last
+// location of the trip.
+func (c *Core) sendSnapshot(ctx context.Context, client *Client, tripID string, lastSeen string) error {
+	if lastSeen != "" && c.retention > 0 {
+		xMessages, err := cachedb.XRange(ctx, streamKey(tripID), "("+lastSeen, "+")
+		if err != nil {
+			return fmt.Errorf("xrange: %w", err)
+		}
+
+		for _, xMessage := range xMessages {
+			data, ok := xMessage.Values["location"].(string)
+			if !ok {
+				continue
+			}
+
+			var frm Frame
+			if err := json.Unmarshal([]byte(data), &frm); err != nil {
+				return fmt.Errorf("unmarshal: %w", err)
+			}
+			frm.StreamID = xMessage.ID
+
+			if err := client.writeJSON(frm); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	last, err := QueryLastLocation(ctx, tripID)
 	if err != nil {
-		return err
+		if errors.Is(err, ErrNoLocation) {
+			return nil
+		}
+		return fmt.Errorf("querylastlocation: %w", err)
 	}
 
-	ctx := context.Background()
-	msg, err := json.Marshal(req)
+	return client.writeJSON(last)
+}
+
+// QueryLastLocation returns the last location published on the trip.
+func QueryLastLocation(ctx context.Context, tripID string) (Frame, error) {
+	val, err := cachedb.Get(ctx, lastLocationKey(tripID))
 	if err != nil {
-		return err
+		if errors.Is(err, redis.Nil) {
+			return Frame{}, ErrNoLocation
+		}
+		return Frame{}, fmt.Errorf("get: %w", err)
 	}
 
-	roomsDispatcher.broadcastRoomMap[c.broadcastRoom.id].PublishMessage(ctx, string(msg))
+	var last Frame
+	if err := json.Unmarshal([]byte(val), &last); err != nil {
+		return Frame{}, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	// The locations kept before the frames had a version.
+	if last.Version == 0 && last.Latitute != nil {
+		last.Latitude = *last.Latitute
+	}
+
+	return last, nil
+}
+
+// metersPerDegree is about how many meters a degree of latitude spans.
+const metersPerDegree = 111320.0
+
+// QueryTrace returns the path the driver of the trip took, simplified so that
+// the points dropped are at most tolerance meters off it.
+func (c *Core) QueryTrace(ctx context.Context, tripID uuid.UUID, tolerance float64) (Trace, error) {
+	trace, err := c.storer.QueryTrace(ctx, tripID, tolerance/metersPerDegree)
+	if err != nil {
+		return Trace{}, fmt.Errorf("query: tripID[%s]: %w", tripID, err)
+	}
+
+	return trace, nil
+}
+
+// CreateTrace stores the path of the trip through the locations stored for
+// it, replacing the one stored before.
+func (c *Core) CreateTrace(ctx context.Context, tripID uuid.UUID) error {
+	if err := c.storer.CreateTrace(ctx, tripID); err != nil {
+		return fmt.Errorf("create: tripID[%s]: %w", tripID, err)
+	}
+
+	return nil
+}
+
+// TripEvents returns the handler storing the path of a trip once it
+// finished. The points the recorder still holds then are added to the path
+// as they are stored, see Recorder.
+func (c *Core) TripEvents() trip.EventHandler {
+	return func(ctx context.Context, evt trip.Event) {
+		if evt.Type != trip.EventFinished {
+			return
+		}
+
+		if err := c.CreateTrace(ctx, evt.TripID); err != nil {
+			c.log.Error(ctx, "locationws: trip event", "tripID", evt.TripID, "event", evt.Type, "ERROR", err)
+		}
+	}
+}
+
+// ValidateStreamID checks the id is a redis stream id, as clients resume from.
+func ValidateStreamID(id string) error {
+	if !streamIDRegex.MatchString(id) {
+		return ErrInvalidStreamID
+	}
 
 	return nil
 }
+
+var streamIDRegex = regexp.MustCompile(`^[0-9]+-[0-9]+$`)
+
+func lastLocationKey(tripID string) string {
+	return "location:last:" + tripID
+}
+
+func streamKey(tripID string) string {
+	return "location:stream:" + tripID
+}
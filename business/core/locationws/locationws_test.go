@@ -0,0 +1,17 @@
+package locationws
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateStreamID(t *testing.T) {
+	for _, id := range []string{"0-0", "1700000000000-0", "1700000000000-12"} {
+		assert.NoError(t, ValidateStreamID(id), id)
+	}
+
+	for _, id := range []string{"", "1700000000000", "-1", "1-", "abc-1", "1-1-1", "1 -1", "$", "+"} {
+		assert.ErrorIs(t, ValidateStreamID(id), ErrInvalidStreamID, id)
+	}
+}
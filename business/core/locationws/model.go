@@ -1,6 +1,47 @@
 package locationws
 
-type Location struct {
-	Latitute  float64 `json:"latitute"`
-	Longitude float64 `json:"longitude"`
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Frame is a location of the driver of a trip. Drivers send the sequence
+// number, the position and its accuracy, heading and speed as their device
+// reads them, and the server adds the trip id, its time and, with a stream,
+// the stream id clients resume from.
+type Frame struct {
+	Version    int       `json:"v"`
+	TripID     string    `json:"trip_id"`
+	Seq        int64     `json:"seq"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	Accuracy   *float64  `json:"accuracy,omitempty"`
+	Heading    *float64  `json:"heading,omitempty"`
+	Speed      *float64  `json:"speed,omitempty"`
+	DeviceTime time.Time `json:"device_time"`
+	ServerTime time.Time `json:"server_time"`
+	StreamID   string    `json:"stream_id,omitempty"`
+
+	// Latitute is the misspelled latitude of the frames without a version.
+	// The server still sends it while clients migrate to latitude.
+	Latitute *float64 `json:"latitute,omitempty"`
+}
+
+// Trace is the path the driver of a trip took. Finished is set once the path
+// was stored as the trip finished, a trace of a trip still running is built
+// from the locations stored so far.
+type Trace struct {
+	TripID   uuid.UUID
+	Points   []TracePoint
+	Distance float64
+	Finished bool
+}
+
+// TracePoint is a point of the path of a trip, with the device time of the
+// location it was published at.
+type TracePoint struct {
+	Latitude   float64
+	Longitude  float64
+	RecordedAt time.Time
 }
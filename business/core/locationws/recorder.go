@@ -0,0 +1,191 @@
+package locationws
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/TSMC-Uber/server/foundation/logger"
+	"github.com/google/uuid"
+)
+
+// Set of defaults of the recorder config.
+const (
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = 5 * time.Second
+	DefaultRecordQueue   = 1000
+	DefaultPurgeInterval = time.Hour
+)
+
+// RecorderConfig holds how the recorder batches the locations it stores and
+// how long it keeps them.
+type RecorderConfig struct {
+	// BatchSize is how many locations are stored at once.
+	BatchSize int
+
+	// FlushInterval is how long a location waits for its batch to fill up.
+	FlushInterval time.Duration
+
+	// QueueSize is how many locations wait to be stored, the ones recorded
+	// while the queue is full are dropped.
+	QueueSize int
+
+	// Retention is how long the locations are kept, zero keeps them for good.
+	Retention time.Duration
+
+	// PurgeInterval is how often the locations past the retention are
+	// removed.
+	PurgeInterval time.Duration
+}
+
+// Recorder stores the locations of the drivers as the points of the traces of
+// their trips, in batches so the database is not written once per location,
+// and removes the points past the retention. A trip usually finishes while
+// its last points wait in a batch, so the path of every trip in a batch is
+// stored again once the batch is, if the trip finished.
+type Recorder struct {
+	log     *logger.Logger
+	storer  Storer
+	cfg     RecorderConfig
+	frames  chan Frame
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewRecorder constructs a recorder and starts storing the locations it is
+// given, the settings of the config left zero take their default.
+func NewRecorder(log *logger.Logger, storer Storer, cfg RecorderConfig) *Recorder {
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultFlushInterval
+	}
+	if cfg.QueueSize < 1 {
+		cfg.QueueSize = DefaultRecordQueue
+	}
+	if cfg.PurgeInterval <= 0 {
+		cfg.PurgeInterval = DefaultPurgeInterval
+	}
+
+	r := Recorder{
+		log:     log,
+		storer:  storer,
+		cfg:     cfg,
+		frames:  make(chan Frame, cfg.QueueSize),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go r.run()
+
+	return &r
+}
+
+// Record queues the location to be stored without waiting, reporting whether
+// it was queued.
+func (r *Recorder) Record(frm Frame) bool {
+	select {
+	case r.frames <- frm:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stores the locations still queued and stops the recorder.
+func (r *Recorder) Close() {
+	r.once.Do(func() {
+		close(r.stop)
+	})
+	<-r.stopped
+}
+
+// run stores the queued locations once a batch is full or has waited long
+// enough, and removes the old ones, until the recorder is closed.
+func (r *Recorder) run() {
+	defer close(r.stopped)
+
+	flush := time.NewTicker(r.cfg.FlushInterval)
+	defer flush.Stop()
+
+	purge := time.NewTicker(r.cfg.PurgeInterval)
+	defer purge.Stop()
+
+	batch := make([]Frame, 0, r.cfg.BatchSize)
+	for {
+		select {
+		case frm := <-r.frames:
+			batch = append(batch, frm)
+			if len(batch) >= r.cfg.BatchSize {
+				batch = r.flush(batch)
+			}
+
+		case <-flush.C:
+			batch = r.flush(batch)
+
+		case <-purge.C:
+			r.purge()
+
+		case <-r.stop:
+			for {
+				select {
+				case frm := <-r.frames:
+					batch = append(batch, frm)
+					if len(batch) >= r.cfg.BatchSize {
+						batch = r.flush(batch)
+					}
+				default:
+					r.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush stores the batch and returns it emptied. A batch that cannot be
+// stored is dropped, the trace only misses some points.
+func (r *Recorder) flush(batch []Frame) []Frame {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx := context.Background()
+	if err := r.storer.CreatePoints(ctx, batch); err != nil {
+		r.log.Error(ctx, "locationws: store points", "points", len(batch), "ERROR", err)
+		return batch[:0]
+	}
+
+	refreshed := make(map[string]struct{})
+	for _, frm := range batch {
+		if _, ok := refreshed[frm.TripID]; ok {
+			continue
+		}
+		refreshed[frm.TripID] = struct{}{}
+
+		tripID, err := uuid.Parse(frm.TripID)
+		if err != nil {
+			continue
+		}
+
+		if err := r.storer.RefreshTrace(ctx, tripID); err != nil {
+			r.log.Error(ctx, "locationws: refresh trace", "tripID", tripID, "ERROR", err)
+		}
+	}
+
+	return batch[:0]
+}
+
+// purge removes the locations past the retention.
+func (r *Recorder) purge() {
+	if r.cfg.Retention <= 0 {
+		return
+	}
+
+	ctx := context.Background()
+	if err := r.storer.DeletePoints(ctx, time.Now().Add(-r.cfg.Retention)); err != nil {
+		r.log.Error(ctx, "locationws: purge points", "ERROR", err)
+	}
+}
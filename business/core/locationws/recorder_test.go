@@ -0,0 +1,216 @@
+package locationws
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStorer keeps the batches of points stored, the paths of the trips and
+// the purges in memory. Like the database, the path of a trip is only stored
+// again for new points once the trip finished.
+type fakeStorer struct {
+	mu       sync.Mutex
+	batches  [][]Frame
+	points   map[string][]Frame
+	traces   map[uuid.UUID][]Frame
+	finished map[uuid.UUID]bool
+	purged   chan time.Time
+}
+
+func newFakeStorer() *fakeStorer {
+	return &fakeStorer{
+		points:   make(map[string][]Frame),
+		traces:   make(map[uuid.UUID][]Frame),
+		finished: make(map[uuid.UUID]bool),
+		purged:   make(chan time.Time, 16),
+	}
+}
+
+func (s *fakeStorer) CreatePoints(ctx context.Context, frms []Frame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches = append(s.batches, append([]Frame(nil), frms...))
+	for _, frm := range frms {
+		s.points[frm.TripID] = append(s.points[frm.TripID], frm)
+	}
+
+	return nil
+}
+
+func (s *fakeStorer) CreateTrace(ctx context.Context, tripID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if points := s.points[tripID.String()]; len(points) >= 2 && len(points) >= len(s.traces[tripID]) {
+		s.traces[tripID] = append([]Frame(nil), points...)
+	}
+
+	return nil
+}
+
+func (s *fakeStorer) RefreshTrace(ctx context.Context, tripID uuid.UUID) error {
+	s.mu.Lock()
+	finished := s.finished[tripID]
+	s.mu.Unlock()
+
+	if !finished {
+		return nil
+	}
+
+	return s.CreateTrace(ctx, tripID)
+}
+
+func (s *fakeStorer) QueryTrace(ctx context.Context, tripID uuid.UUID, tolerance float64) (Trace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	points, ok := s.traces[tripID]
+	if !ok {
+		return Trace{}, ErrNoTrace
+	}
+
+	trace := Trace{TripID: tripID, Finished: true}
+	for _, frm := range points {
+		trace.Points = append(trace.Points, TracePoint{Latitude: frm.Latitude, Longitude: frm.Longitude, RecordedAt: frm.DeviceTime})
+	}
+
+	return trace, nil
+}
+
+func (s *fakeStorer) DeletePoints(ctx context.Context, before time.Time) error {
+	s.purged <- before
+	return nil
+}
+
+func (s *fakeStorer) sizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sizes := make([]int, len(s.batches))
+	for i, batch := range s.batches {
+		sizes[i] = len(batch)
+	}
+
+	return sizes
+}
+
+func TestRecorderBatches(t *testing.T) {
+	storer := newFakeStorer()
+	r := NewRecorder(nil, storer, RecorderConfig{
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		QueueSize:     10,
+	})
+
+	for seq := int64(1); seq <= 5; seq++ {
+		if !r.Record(Frame{TripID: testTripID, Seq: seq}) {
+			t.Fatalf("frame %d not queued", seq)
+		}
+	}
+
+	// The last frame is stored on close.
+	r.Close()
+	r.Close()
+
+	assert.Equal(t, []int{2, 2, 1}, storer.sizes())
+	assert.Equal(t, int64(5), storer.batches[2][0].Seq)
+}
+
+func TestRecorderFlushInterval(t *testing.T) {
+	storer := newFakeStorer()
+	r := NewRecorder(nil, storer, RecorderConfig{
+		BatchSize:     100,
+		FlushInterval: 10 * time.Millisecond,
+	})
+	defer r.Close()
+
+	r.Record(Frame{TripID: testTripID, Seq: 1})
+
+	deadline := time.After(time.Second)
+	for len(storer.sizes()) == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("frame not stored")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	assert.Equal(t, []int{1}, storer.sizes())
+}
+
+func TestRecorderPurge(t *testing.T) {
+	storer := newFakeStorer()
+	r := NewRecorder(nil, storer, RecorderConfig{
+		Retention:     time.Hour,
+		PurgeInterval: 10 * time.Millisecond,
+	})
+	defer r.Close()
+
+	select {
+	case before := <-storer.purged:
+		assert.WithinDuration(t, time.Now().Add(-time.Hour), before, time.Second)
+	case <-time.After(time.Second):
+		t.Fatal("points not purged")
+	}
+}
+
+func TestRecorderQueueFull(t *testing.T) {
+	storer := newFakeStorer()
+	r := NewRecorder(nil, storer, RecorderConfig{QueueSize: 1})
+	r.Close()
+
+	// Nothing reads the queue once the recorder is closed.
+	assert.True(t, r.Record(Frame{Seq: 1}))
+	assert.False(t, r.Record(Frame{Seq: 2}))
+}
+
+func TestTraceLatePoints(t *testing.T) {
+	storer := newFakeStorer()
+	r := NewRecorder(nil, storer, RecorderConfig{
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	})
+	core := NewCore(nil, storer, nil, r, 0)
+
+	tripID := uuid.MustParse(testTripID)
+	for seq := int64(1); seq <= 3; seq++ {
+		r.Record(Frame{TripID: testTripID, Seq: seq})
+	}
+
+	// Wait for the first batch, the last point is still held by the recorder.
+	deadline := time.After(time.Second)
+	for len(storer.sizes()) == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("first batch not stored")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	// The trip finishes before the recorder stored its last point.
+	storer.mu.Lock()
+	storer.finished[tripID] = true
+	storer.mu.Unlock()
+	core.TripEvents()(context.Background(), trip.Event{Type: trip.EventFinished, TripID: tripID})
+
+	trace, err := core.QueryTrace(context.Background(), tripID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, trace.Points, 2)
+
+	r.Close()
+
+	trace, err = core.QueryTrace(context.Background(), tripID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, trace.Points, 3)
+}
@@ -1,32 +1,222 @@
 package locationws
 
-import "context"
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
 
-var roomsDispatcher *RoomsDispatcher
+	"github.com/TSMC-Uber/server/foundation/logger"
+	"github.com/gorilla/websocket"
+)
 
+// Set of defaults of the dispatcher config.
+const (
+	DefaultShards            = 32
+	DefaultQueueSize         = 4
+	DefaultSlowClientTimeout = 5 * time.Second
+)
+
+// Config holds how the dispatcher spreads the rooms and treats the clients
+// that cannot keep up.
+type Config struct {
+	// Shards is how many shards the rooms are spread over.
+	Shards int
+
+	// QueueSize is how many messages are queued for a client, the oldest is
+	// dropped for a new one when the queue is full.
+	QueueSize int
+
+	// SlowClientTimeout is how long the queue of a client can stay full
+	// before the client is disconnected.
+	SlowClientTimeout time.Duration
+}
+
+// Broker interface declares the behavior this package needs to fan the
+// locations of a trip out to the clients on every instance.
+type Broker interface {
+	Publish(ctx context.Context, tripID string, message string) error
+
+	// Subscribe returns the messages published on the trip until the
+	// context is done, the channel is closed then.
+	Subscribe(ctx context.Context, tripID string) (<-chan string, error)
+}
+
+// Stats holds the number of rooms open and clients connected on this
+// instance, and how many messages the clients did not get: conflated ones
+// were replaced by a newer message before they were written, dropped ones
+// were meant for a client that was disconnected. SlowClients is how many
+// clients were disconnected for not keeping up, and Rejected how many frames
+// of the drivers were invalid.
+type Stats struct {
+	Rooms       int64 `json:"rooms"`
+	Clients     int64 `json:"clients"`
+	Conflated   int64 `json:"conflated"`
+	Dropped     int64 `json:"dropped"`
+	SlowClients int64 `json:"slow_clients"`
+	Rejected    int64 `json:"rejected"`
+}
+
+// counters holds the counts of the messages the clients did not get, shared
+// by the rooms of a dispatcher, and of the frames rejected.
+type counters struct {
+	conflated   atomic.Int64
+	dropped     atomic.Int64
+	slowClients atomic.Int64
+	rejected    atomic.Int64
+}
+
+// RoomsDispatcher keeps the broadcast room of every trip with clients on this
+// instance. A room is opened for the first client of a trip and closed when
+// the last one leaves. The rooms are sharded by trip id, so the clients of
+// different trips rarely wait on each other.
 type RoomsDispatcher struct {
-	broadcastRoomMap map[string]*BroadcastRoom
+	log      *logger.Logger
+	broker   Broker
+	cfg      Config
+	shards   []*shard
+	rooms    atomic.Int64
+	clients  atomic.Int64
+	counters counters
 }
 
-func NewRoomsDispatcher() *RoomsDispatcher {
-	roomsDispatcher = &RoomsDispatcher{
-		broadcastRoomMap: make(map[string]*BroadcastRoom),
+// shard holds the rooms of the trips hashed to it.
+type shard struct {
+	mu    sync.Mutex
+	rooms map[string]*BroadcastRoom
+}
+
+// NewRoomsDispatcher constructs a dispatcher, the settings of the config left
+// zero take their default.
+func NewRoomsDispatcher(log *logger.Logger, broker Broker, cfg Config) *RoomsDispatcher {
+	if cfg.Shards < 1 {
+		cfg.Shards = DefaultShards
+	}
+	if cfg.QueueSize < 1 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.SlowClientTimeout <= 0 {
+		cfg.SlowClientTimeout = DefaultSlowClientTimeout
+	}
+
+	d := RoomsDispatcher{
+		log:    log,
+		broker: broker,
+		cfg:    cfg,
+		shards: make([]*shard, cfg.Shards),
+	}
+
+	for i := range d.shards {
+		d.shards[i] = &shard{
+			rooms: make(map[string]*BroadcastRoom),
+		}
 	}
-	return roomsDispatcher
+
+	return &d
+}
+
+// newClient returns a client of the user on the connection queuing as many
+// messages as the dispatcher is configured to.
+func (d *RoomsDispatcher) newClient(conn *websocket.Conn, userID string) *Client {
+	return newClient(conn, userID, d.cfg.QueueSize)
 }
 
-func AcquireBroadcastRoom(id string) *BroadcastRoom {
-	if _, ok := roomsDispatcher.broadcastRoomMap[id]; !ok {
-		roomsDispatcher.broadcastRoomMap[id] = newBroadcastRoom(id)
-		ctx, cancel := context.WithCancel(context.Background())
-		go roomsDispatcher.broadcastRoomMap[id].run(ctx)
-		go roomsDispatcher.listenRoomClosed(roomsDispatcher.broadcastRoomMap[id], cancel)
+// Join adds the client to the room of the trip, opening the room when the
+// client is the first one.
+func (d *RoomsDispatcher) Join(tripID string, client *Client) {
+	s := d.shard(tripID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, ok := s.rooms[tripID]
+	if !ok {
+		room = newBroadcastRoom(tripID, d.cfg.SlowClientTimeout, &d.counters)
+		s.rooms[tripID] = room
+		d.rooms.Add(1)
+
+		go room.run(d.log, d.broker)
+	}
+
+	if room.add(client) {
+		d.clients.Add(1)
+	}
+	client.room = room
+}
+
+// Leave removes the client from its room, closing the room when the client
+// was the last one. Leaving twice does nothing.
+func (d *RoomsDispatcher) Leave(client *Client) {
+	room := client.room
+	if room == nil {
+		return
+	}
+
+	s := d.shard(room.id)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed, left := room.remove(client)
+	if removed {
+		d.clients.Add(-1)
+	}
+	client.room = nil
+
+	if left == 0 && s.rooms[room.id] == room {
+		delete(s.rooms, room.id)
+		d.rooms.Add(-1)
+		room.close()
 	}
-	return roomsDispatcher.broadcastRoomMap[id]
 }
 
-func (d *RoomsDispatcher) listenRoomClosed(room *BroadcastRoom, cancel context.CancelFunc) {
-	<-room.channel.Closed
-	cancel()
-	delete(d.broadcastRoomMap, room.id)
+// Publish sends the message to the clients of the trip on every instance.
+func (d *RoomsDispatcher) Publish(ctx context.Context, tripID string, message string) error {
+	return d.broker.Publish(ctx, tripID, message)
+}
+
+// Kick disconnects the clients of the user from the room of the trip on every
+// instance.
+func (d *RoomsDispatcher) Kick(ctx context.Context, tripID string, userID string) error {
+	return d.broker.Publish(ctx, kickTopic(tripID), userID)
+}
+
+// Stats returns the counts of the rooms, clients, undelivered messages and
+// rejected frames.
+func (d *RoomsDispatcher) Stats() Stats {
+	return Stats{
+		Rooms:       d.rooms.Load(),
+		Clients:     d.clients.Load(),
+		Conflated:   d.counters.conflated.Load(),
+		Dropped:     d.counters.dropped.Load(),
+		SlowClients: d.counters.slowClients.Load(),
+		Rejected:    d.counters.rejected.Load(),
+	}
+}
+
+// Close disconnects every client and closes every room, for shutdown.
+func (d *RoomsDispatcher) Close() {
+	for _, s := range d.shards {
+		s.mu.Lock()
+		for id, room := range s.rooms {
+			room.disconnectAll()
+			room.close()
+			delete(s.rooms, id)
+			d.rooms.Add(-1)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// kickTopic is where the users kicked from the trip are published.
+func kickTopic(tripID string) string {
+	return tripID + ":kick"
+}
+
+// shard returns the shard of the trip.
+func (d *RoomsDispatcher) shard(tripID string) *shard {
+	h := fnv.New32a()
+	h.Write([]byte(tripID))
+	return d.shards[h.Sum32()%uint32(len(d.shards))]
 }
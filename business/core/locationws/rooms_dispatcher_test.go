@@ -0,0 +1,362 @@
+package locationws
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBroker fans the messages out in memory.
+type fakeBroker struct {
+	mu   sync.Mutex
+	subs map[string][]chan string
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{
+		subs: make(map[string][]chan string),
+	}
+}
+
+func (b *fakeBroker) Publish(ctx context.Context, tripID string, message string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, ch := range b.subs[tripID] {
+		select {
+		case ch <- message:
+		default:
+		}
+	}
+
+	return nil
+}
+
+func (b *fakeBroker) Subscribe(ctx context.Context, tripID string) (<-chan string, error) {
+	ch := make(chan string, 16)
+
+	b.mu.Lock()
+	b.subs[tripID] = append(b.subs[tripID], ch)
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		subs := b.subs[tripID]
+		for i := range subs {
+			if subs[i] == ch {
+				b.subs[tripID] = append(subs[:i], subs[i+1:]...)
+				break
+			}
+		}
+		if len(b.subs[tripID]) == 0 {
+			delete(b.subs, tripID)
+		}
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+func (b *fakeBroker) subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subs)
+}
+
+// newTestClient returns a client without a connection, the messages sent to
+// it are passed on to received.
+func newTestClient(t *testing.T, received chan<- string) *Client {
+	client := newClient(nil, "", DefaultQueueSize)
+	client.pingTicker.Stop()
+
+	go func() {
+		for {
+			select {
+			case <-client.closed:
+				return
+			case msg := <-client.messageToSend:
+				if received != nil {
+					received <- msg
+				}
+			}
+		}
+	}()
+	t.Cleanup(client.disconnect)
+
+	return client
+}
+
+func TestJoinLeave(t *testing.T) {
+	d := NewRoomsDispatcher(nil, newFakeBroker(), Config{Shards: 4})
+
+	c1 := newTestClient(t, nil)
+	c2 := newTestClient(t, nil)
+	c3 := newTestClient(t, nil)
+
+	d.Join("trip-1", c1)
+	d.Join("trip-1", c2)
+	d.Join("trip-2", c3)
+	assert.Equal(t, Stats{Rooms: 2, Clients: 3}, d.Stats())
+	assert.Same(t, c1.room, c2.room)
+
+	room := c1.room
+	d.Leave(c1)
+	d.Leave(c1)
+	assert.Equal(t, Stats{Rooms: 2, Clients: 2}, d.Stats())
+
+	d.Leave(c2)
+	assert.Equal(t, Stats{Rooms: 1, Clients: 1}, d.Stats())
+
+	select {
+	case <-room.done:
+	case <-time.After(time.Second):
+		t.Fatal("room still running after the last client left")
+	}
+
+	d.Leave(c3)
+	assert.Equal(t, Stats{}, d.Stats())
+}
+
+func TestPublish(t *testing.T) {
+	d := NewRoomsDispatcher(nil, newFakeBroker(), Config{Shards: 4})
+
+	received := make(chan string, 1)
+	d.Join("trip-1", newTestClient(t, received))
+	d.Join("trip-2", newTestClient(t, nil))
+
+	// The room subscribes in the background, publish until it is listening.
+	deadline := time.After(time.Second)
+	for {
+		if err := d.Publish(context.Background(), "trip-1", "hello"); err != nil {
+			t.Fatalf("publish: %s", err)
+		}
+
+		select {
+		case msg := <-received:
+			assert.Equal(t, "hello", msg)
+			return
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("message not received")
+		}
+	}
+}
+
+func TestKick(t *testing.T) {
+	d := NewRoomsDispatcher(nil, newFakeBroker(), Config{Shards: 4})
+
+	kicked := newTestClient(t, nil)
+	kicked.userID = "user-1"
+	d.Join("trip-1", kicked)
+
+	other := newTestClient(t, nil)
+	other.userID = "user-2"
+	d.Join("trip-1", other)
+
+	elsewhere := newTestClient(t, nil)
+	elsewhere.userID = "user-1"
+	d.Join("trip-2", elsewhere)
+
+	// The room subscribes in the background, kick until it is listening.
+	deadline := time.After(time.Second)
+	for {
+		if err := d.Kick(context.Background(), "trip-1", "user-1"); err != nil {
+			t.Fatalf("kick: %s", err)
+		}
+
+		select {
+		case <-kicked.closed:
+			select {
+			case <-other.closed:
+				t.Fatal("other user disconnected")
+			case <-elsewhere.closed:
+				t.Fatal("user disconnected from another trip")
+			default:
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("client not kicked")
+		}
+	}
+}
+
+func TestRejoinAfterClose(t *testing.T) {
+	d := NewRoomsDispatcher(nil, newFakeBroker(), Config{Shards: 1})
+
+	c1 := newTestClient(t, nil)
+	d.Join("trip-1", c1)
+	first := c1.room
+	d.Leave(c1)
+
+	c2 := newTestClient(t, nil)
+	d.Join("trip-1", c2)
+	assert.NotSame(t, first, c2.room)
+	assert.Equal(t, Stats{Rooms: 1, Clients: 1}, d.Stats())
+
+	d.Leave(c2)
+}
+
+func TestClose(t *testing.T) {
+	d := NewRoomsDispatcher(nil, newFakeBroker(), Config{Shards: 4})
+
+	client := newTestClient(t, nil)
+	d.Join("trip-1", client)
+	room := client.room
+
+	d.Close()
+
+	select {
+	case <-client.closed:
+	case <-time.After(time.Second):
+		t.Fatal("client not disconnected")
+	}
+
+	select {
+	case <-room.done:
+	case <-time.After(time.Second):
+		t.Fatal("room still running after close")
+	}
+
+	// The client leaves after the dispatcher closed its room.
+	d.Leave(client)
+	assert.Equal(t, Stats{}, d.Stats())
+}
+
+func TestConcurrentJoinLeavePublish(t *testing.T) {
+	broker := newFakeBroker()
+	d := NewRoomsDispatcher(nil, broker, Config{Shards: 8})
+
+	const trips = 16
+	const clients = 64
+	const rounds = 50
+
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			tripID := fmt.Sprintf("trip-%d", i%trips)
+			for r := 0; r < rounds; r++ {
+				client := newTestClient(t, nil)
+				d.Join(tripID, client)
+				if err := d.Publish(context.Background(), tripID, "location"); err != nil {
+					t.Errorf("publish: %s", err)
+				}
+				_ = d.Stats()
+				d.Leave(client)
+				client.disconnect()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	stats := d.Stats()
+	assert.Equal(t, int64(0), stats.Rooms)
+	assert.Equal(t, int64(0), stats.Clients)
+
+	// Every room stops and unsubscribes once its last client left.
+	deadline := time.After(time.Second)
+	for broker.subscribers() != 0 {
+		select {
+		case <-deadline:
+			t.Fatalf("%d trips still subscribed", broker.subscribers())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestSendConflates(t *testing.T) {
+	client := newClient(nil, "", 2)
+	client.pingTicker.Stop()
+
+	for _, msg := range []string{"1", "2"} {
+		conflated, err := client.send(msg, time.Minute)
+		if err != nil {
+			t.Fatalf("send: %s", err)
+		}
+		assert.False(t, conflated)
+	}
+
+	conflated, err := client.send("3", time.Minute)
+	if err != nil {
+		t.Fatalf("send: %s", err)
+	}
+	assert.True(t, conflated)
+
+	assert.Equal(t, "2", <-client.messageToSend)
+	assert.Equal(t, "3", <-client.messageToSend)
+}
+
+func TestSendSlowClient(t *testing.T) {
+	client := newClient(nil, "", 1)
+	client.pingTicker.Stop()
+
+	if _, err := client.send("1", 10*time.Millisecond); err != nil {
+		t.Fatalf("send: %s", err)
+	}
+	if _, err := client.send("2", 10*time.Millisecond); err != nil {
+		t.Fatalf("send: %s", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	_, err := client.send("3", 10*time.Millisecond)
+	assert.ErrorIs(t, err, ErrSlowClient)
+
+	// A client catching up is no longer slow.
+	<-client.messageToSend
+	client.fullSince.Store(0)
+	_, err = client.send("4", 10*time.Millisecond)
+	assert.NoError(t, err)
+
+	client.disconnect()
+	_, err = client.send("5", 10*time.Millisecond)
+	assert.ErrorIs(t, err, ErrClientClosed)
+}
+
+func TestStalledClient(t *testing.T) {
+	d := NewRoomsDispatcher(nil, newFakeBroker(), Config{
+		Shards:            1,
+		QueueSize:         1,
+		SlowClientTimeout: 50 * time.Millisecond,
+	})
+
+	// The stalled client never reads its queue.
+	stalled := newClient(nil, "", 1)
+	stalled.pingTicker.Stop()
+	d.Join("trip-1", stalled)
+
+	received := make(chan string, 100)
+	d.Join("trip-1", newTestClient(t, received))
+
+	deadline := time.After(time.Second)
+	for i := 0; ; i++ {
+		if err := d.Publish(context.Background(), "trip-1", fmt.Sprint(i)); err != nil {
+			t.Fatalf("publish: %s", err)
+		}
+
+		select {
+		case <-stalled.closed:
+			stats := d.Stats()
+			assert.Equal(t, int64(1), stats.SlowClients)
+			assert.NotZero(t, stats.Conflated)
+			assert.NotZero(t, len(received), "the other client got no message")
+			d.Leave(stalled)
+			return
+		case <-deadline:
+			t.Fatal("stalled client not disconnected")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Package locationwsbus fans the locations of a trip out over redis, so the
+// clients following the trip on every instance get them.
+package locationwsbus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/TSMC-Uber/server/business/sys/cachedb"
+	"github.com/TSMC-Uber/server/foundation/logger"
+)
+
+// Bus manages the set of APIs for location fan-out.
+type Bus struct {
+	log *logger.Logger
+}
+
+// NewBus constructs the api for location fan-out.
+func NewBus(log *logger.Logger) *Bus {
+	return &Bus{
+		log: log,
+	}
+}
+
+// Publish sends the message to the subscribers of the trip. The trip id is
+// the redis pub-sub-channel name.
+func (b *Bus) Publish(ctx context.Context, tripID string, message string) error {
+	if err := cachedb.Publish(ctx, tripID, message); err != nil {
+		return fmt.Errorf("publish: tripID[%s]: %w", tripID, err)
+	}
+
+	return nil
+}
+
+// Subscribe returns the messages published on the trip until the context is
+// done.
+func (b *Bus) Subscribe(ctx context.Context, tripID string) (<-chan string, error) {
+	pubsub := cachedb.Subscribe(ctx, tripID)
+
+	// Wait for the confirmation, so a failed subscription is reported.
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("subscribe: tripID[%s]: %w", tripID, err)
+	}
+
+	messages := make(chan string)
+
+	go func() {
+		defer close(messages)
+		defer func() {
+			if err := pubsub.Close(); err != nil {
+				b.log.Error(context.Background(), "locationwsbus: close", "tripID", tripID, "ERROR", err)
+			}
+		}()
+
+		ch := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				select {
+				case messages <- msg.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return messages, nil
+}
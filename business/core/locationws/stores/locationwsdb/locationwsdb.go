@@ -1,15 +1,177 @@
+// Package locationwsdb contains the trace related CRUD functionality.
 package locationwsdb
 
-import "github.com/TSMC-Uber/server/foundation/logger"
+import (
+	"context"
+	"fmt"
+	"time"
 
-// Store manages the set of APIs for user database access.
+	sq "github.com/Masterminds/squirrel"
+	"github.com/TSMC-Uber/server/business/core/locationws"
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/sys/database"
+	"github.com/TSMC-Uber/server/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// traceLine is the path through the points of a trip, in the order of their
+// device time, the measure of every point is its device time in seconds since
+// the epoch.
+const traceLine = `ST_SetSRID(ST_MakeLine(
+	ST_MakePointM(ST_X(lat_lon::geometry), ST_Y(lat_lon::geometry), EXTRACT(EPOCH FROM device_time))
+	ORDER BY device_time, seq
+), 4326)`
+
+// insertTrace stores the path of the trip $1 from its points, replacing the
+// one stored before unless that one has more points: the path is built again
+// as late points come in, and a build that saw fewer points, or one after
+// the points were purged, must not win.
+const insertTrace = `
+INSERT INTO trip_trace (trip_id, path, points, started_at, finished_at)
+SELECT trip_id, ` + traceLine + `::geography, COUNT(*), MIN(device_time), MAX(device_time)
+FROM trip_trace_point
+WHERE trip_id = $1 %s
+GROUP BY trip_id
+HAVING COUNT(*) >= 2
+ON CONFLICT (trip_id) DO UPDATE
+SET path = EXCLUDED.path,
+	points = EXCLUDED.points,
+	started_at = EXCLUDED.started_at,
+	finished_at = EXCLUDED.finished_at,
+	created_at = CURRENT_TIMESTAMP
+WHERE trip_trace.points <= EXCLUDED.points`
+
+// createTraceQuery stores the path of the trip $1.
+var createTraceQuery = fmt.Sprintf(insertTrace, "")
+
+// refreshTraceQuery stores the path of the trip $1 only when the trip has the
+// status $2, finished.
+var refreshTraceQuery = fmt.Sprintf(insertTrace, "AND EXISTS (SELECT 1 FROM trip WHERE id = $1 AND status = $2)")
+
+// traceQuery selects the points of the path of the trip $1 simplified with
+// the tolerance $2, in degrees. The path stored when the trip finished is
+// used, or else the path through the points stored so far. Every point
+// carries the length of the whole path, in meters.
+const traceQuery = `
+WITH line AS (
+	SELECT path::geometry AS path, true AS finished
+	FROM trip_trace
+	WHERE trip_id = $1
+	UNION ALL
+	SELECT ` + traceLine + `, false
+	FROM trip_trace_point
+	WHERE trip_id = $1
+		AND NOT EXISTS (SELECT 1 FROM trip_trace WHERE trip_id = $1)
+	HAVING COUNT(*) >= 2
+)
+SELECT ST_Y(point.geom) AS lat,
+	ST_X(point.geom) AS lon,
+	to_timestamp(ST_M(point.geom)) AT TIME ZONE 'UTC' AS recorded_at,
+	ST_Length(line.path::geography) AS distance,
+	line.finished
+FROM line, LATERAL ST_DumpPoints(ST_Simplify(line.path, $2)) AS point
+ORDER BY point.path`
+
+// Store manages the set of APIs for trace database access.
 type Store struct {
 	log *logger.Logger
+	db  *sqlx.DB
 }
 
 // NewStore constructs the api for data access.
-func NewStore(log *logger.Logger) *Store {
+func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
 	return &Store{
 		log: log,
+		db:  db,
 	}
 }
+
+// CreatePoints inserts the locations into the points of their trips.
+func (s *Store) CreatePoints(ctx context.Context, frms []locationws.Frame) error {
+	if len(frms) == 0 {
+		return nil
+	}
+
+	builder := sq.
+		Insert("trip_trace_point").
+		Columns("trip_id", "seq", "lat_lon", "accuracy", "heading", "speed", "device_time", "server_time")
+
+	for _, frm := range frms {
+		dbPoint := toDBPoint(frm)
+		builder = builder.Values(
+			dbPoint.TripID,
+			dbPoint.Seq,
+			sq.Expr("ST_SetSRID(ST_MakePoint(?, ?), 4326)", dbPoint.Longitude, dbPoint.Latitude),
+			dbPoint.Accuracy,
+			dbPoint.Heading,
+			dbPoint.Speed,
+			dbPoint.DeviceTime,
+			dbPoint.ServerTime,
+		)
+	}
+
+	sql, args, err := builder.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	if err := database.ExecContext(ctx, s.log, s.db, sql, args); err != nil {
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	return nil
+}
+
+// CreateTrace stores the path of the trip through its points. A trip with
+// less than two points has no path.
+func (s *Store) CreateTrace(ctx context.Context, tripID uuid.UUID) error {
+	if err := database.ExecContext(ctx, s.log, s.db, createTraceQuery, []any{tripID}); err != nil {
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	return nil
+}
+
+// RefreshTrace stores the path of the trip again when the trip finished, for
+// the points stored after it did.
+func (s *Store) RefreshTrace(ctx context.Context, tripID uuid.UUID) error {
+	if err := database.ExecContext(ctx, s.log, s.db, refreshTraceQuery, []any{tripID, trip.TripStatusFinished}); err != nil {
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	return nil
+}
+
+// QueryTrace retrieves the path of the trip simplified with the tolerance, in
+// degrees.
+func (s *Store) QueryTrace(ctx context.Context, tripID uuid.UUID, tolerance float64) (locationws.Trace, error) {
+	var dbPoints []dbTracePoint
+	if err := database.QueryContext(ctx, s.log, s.db, traceQuery, []any{tripID, tolerance}, &dbPoints); err != nil {
+		return locationws.Trace{}, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	if len(dbPoints) == 0 {
+		return locationws.Trace{}, locationws.ErrNoTrace
+	}
+
+	return toCoreTrace(tripID, dbPoints), nil
+}
+
+// DeletePoints removes the points received before the time.
+func (s *Store) DeletePoints(ctx context.Context, before time.Time) error {
+	sql, args, err := sq.
+		Delete("trip_trace_point").
+		Where(sq.Lt{"server_time": before.UTC()}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	if err := database.ExecContext(ctx, s.log, s.db, sql, args); err != nil {
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	return nil
+}
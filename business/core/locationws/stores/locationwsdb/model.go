@@ -0,0 +1,71 @@
+package locationwsdb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/core/locationws"
+	"github.com/google/uuid"
+)
+
+type dbPoint struct {
+	TripID     string          `db:"trip_id"`
+	Seq        int64           `db:"seq"`
+	Latitude   float64         `db:"lat"`
+	Longitude  float64         `db:"lon"`
+	Accuracy   sql.NullFloat64 `db:"accuracy"`
+	Heading    sql.NullFloat64 `db:"heading"`
+	Speed      sql.NullFloat64 `db:"speed"`
+	DeviceTime time.Time       `db:"device_time"`
+	ServerTime time.Time       `db:"server_time"`
+}
+
+func toDBPoint(frm locationws.Frame) dbPoint {
+	return dbPoint{
+		TripID:     frm.TripID,
+		Seq:        frm.Seq,
+		Latitude:   frm.Latitude,
+		Longitude:  frm.Longitude,
+		Accuracy:   toNullFloat64(frm.Accuracy),
+		Heading:    toNullFloat64(frm.Heading),
+		Speed:      toNullFloat64(frm.Speed),
+		DeviceTime: frm.DeviceTime.UTC(),
+		ServerTime: frm.ServerTime.UTC(),
+	}
+}
+
+func toNullFloat64(v *float64) sql.NullFloat64 {
+	if v == nil {
+		return sql.NullFloat64{}
+	}
+
+	return sql.NullFloat64{Float64: *v, Valid: true}
+}
+
+// =============================================================================
+
+type dbTracePoint struct {
+	Latitude   float64   `db:"lat"`
+	Longitude  float64   `db:"lon"`
+	RecordedAt time.Time `db:"recorded_at"`
+	Distance   float64   `db:"distance"`
+	Finished   bool      `db:"finished"`
+}
+
+func toCoreTrace(tripID uuid.UUID, dbPoints []dbTracePoint) locationws.Trace {
+	points := make([]locationws.TracePoint, len(dbPoints))
+	for i, dbPoint := range dbPoints {
+		points[i] = locationws.TracePoint{
+			Latitude:   dbPoint.Latitude,
+			Longitude:  dbPoint.Longitude,
+			RecordedAt: dbPoint.RecordedAt,
+		}
+	}
+
+	return locationws.Trace{
+		TripID:   tripID,
+		Points:   points,
+		Distance: dbPoints[0].Distance,
+		Finished: dbPoints[0].Finished,
+	}
+}
@@ -0,0 +1,44 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/TSMC-Uber/server/business/sys/validate"
+	"github.com/google/uuid"
+)
+
+// QueryFilter holds the available fields a query can be filtered on.
+type QueryFilter struct {
+	Status      *string    `validate:"omitempty,oneof=open in_review resolved dismissed"`
+	TripID      *uuid.UUID `validate:"omitempty"`
+	DefendantID *uuid.UUID `validate:"omitempty"`
+	AssigneeID  *uuid.UUID `validate:"omitempty"`
+}
+
+// Validate checks the data in the model is considered clean.
+func (qf *QueryFilter) Validate() error {
+	if err := validate.Check(qf); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+	return nil
+}
+
+// WithStatus sets the Status field of the QueryFilter value.
+func (qf *QueryFilter) WithStatus(status string) {
+	qf.Status = &status
+}
+
+// WithTripID sets the TripID field of the QueryFilter value.
+func (qf *QueryFilter) WithTripID(tripID uuid.UUID) {
+	qf.TripID = &tripID
+}
+
+// WithDefendantID sets the DefendantID field of the QueryFilter value.
+func (qf *QueryFilter) WithDefendantID(defendantID uuid.UUID) {
+	qf.DefendantID = &defendantID
+}
+
+// WithAssigneeID sets the AssigneeID field of the QueryFilter value.
+func (qf *QueryFilter) WithAssigneeID(assigneeID uuid.UUID) {
+	qf.AssigneeID = &assigneeID
+}
@@ -0,0 +1,53 @@
+package report
+
+import (
+	"context"
+
+	"github.com/TSMC-Uber/server/business/data/order"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockStorer struct {
+	mock.Mock
+}
+
+func (m *MockStorer) Create(ctx context.Context, report Report) error {
+	args := m.Called(ctx, report)
+	return args.Error(0)
+}
+
+func (m *MockStorer) Update(ctx context.Context, report Report, from string) error {
+	args := m.Called(ctx, report, from)
+	return args.Error(0)
+}
+
+func (m *MockStorer) QueryByID(ctx context.Context, reportID uuid.UUID) (Report, error) {
+	args := m.Called(ctx, reportID)
+	return args.Get(0).(Report), args.Error(1)
+}
+
+func (m *MockStorer) Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]Report, error) {
+	args := m.Called(ctx, filter, orderBy, pageNumber, rowsPerPage)
+	return args.Get(0).([]Report), args.Error(1)
+}
+
+func (m *MockStorer) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	args := m.Called(ctx, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStorer) CreateNote(ctx context.Context, note Note) error {
+	args := m.Called(ctx, note)
+	return args.Error(0)
+}
+
+func (m *MockStorer) QueryNotes(ctx context.Context, reportID uuid.UUID) ([]Note, error) {
+	args := m.Called(ctx, reportID)
+	return args.Get(0).([]Note), args.Error(1)
+}
+
+func (m *MockStorer) IsModerator(ctx context.Context, userID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,51 @@
+package report
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Report represents a complaint a participant of a trip filed against
+// another participant of the same trip.
+type Report struct {
+	ID            uuid.UUID
+	TripID        uuid.UUID
+	ComplainantID uuid.UUID
+	DefendantID   uuid.UUID
+	Comment       string
+	Status        string
+	AssigneeID    *uuid.UUID
+	Resolution    string
+	Suspended     bool
+	ResolvedBy    *uuid.UUID
+	ResolvedAt    *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// NewReport contains information needed to file a report.
+type NewReport struct {
+	ComplainantID uuid.UUID
+	DefendantID   uuid.UUID
+	Comment       string
+}
+
+// Note is a remark a moderator left on a report.
+type Note struct {
+	ID        uuid.UUID
+	ReportID  uuid.UUID
+	AuthorID  uuid.UUID
+	Note      string
+	CreatedAt time.Time
+}
+
+// Resolution contains information needed to close a report. A resolution can
+// suspend the defendant, for the given duration or indefinitely when
+// SuspendFor is nil.
+type Resolution struct {
+	Status     string
+	Resolution string
+	Suspend    bool
+	SuspendFor *time.Duration
+}
@@ -0,0 +1,13 @@
+package report
+
+import "github.com/TSMC-Uber/server/business/data/order"
+
+// DefaultOrderBy represents the default way we sort, oldest reports first so
+// the queue is worked through in order.
+var DefaultOrderBy = order.NewBy(OrderByCreatedAt, order.ASC)
+
+const (
+	OrderByCreatedAt = "created_at"
+	OrderByUpdatedAt = "updated_at"
+	OrderByStatus    = "status"
+)
@@ -0,0 +1,251 @@
+// Package report provides the core business API for the reports participants
+// of a trip file against each other, and the moderation queue working them.
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/core/user"
+	"github.com/TSMC-Uber/server/business/data/order"
+	"github.com/google/uuid"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound          = errors.New("report not found")
+	ErrNotParticipant    = errors.New("only participants of the trip can report each other")
+	ErrReportSelf        = errors.New("user cannot report themselves")
+	ErrAlreadyReported   = errors.New("user already reported this person on the trip")
+	ErrNotModerator      = errors.New("user is not a moderator")
+	ErrInvalidTransition = errors.New("report cannot move to this status")
+)
+
+var (
+	StatusOpen      = "open"
+	StatusInReview  = "in_review"
+	StatusResolved  = "resolved"
+	StatusDismissed = "dismissed"
+)
+
+// transitions lists the statuses a report can move to from each status.
+var transitions = map[string][]string{
+	StatusOpen:     {StatusInReview, StatusResolved, StatusDismissed},
+	StatusInReview: {StatusResolved, StatusDismissed},
+}
+
+// Storer interface declares the behavior this package needs to perists and
+// retrieve data.
+type Storer interface {
+	Create(ctx context.Context, report Report) error
+	Update(ctx context.Context, report Report, from string) error
+	QueryByID(ctx context.Context, reportID uuid.UUID) (Report, error)
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]Report, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	CreateNote(ctx context.Context, note Note) error
+	QueryNotes(ctx context.Context, reportID uuid.UUID) ([]Note, error)
+	IsModerator(ctx context.Context, userID uuid.UUID) (bool, error)
+}
+
+// Core manages the set of APIs for report access.
+type Core struct {
+	storer Storer
+	trip   *trip.Core
+	user   *user.Core
+}
+
+// NewCore constructs a core for report api access.
+func NewCore(storer Storer, trip *trip.Core, user *user.Core) *Core {
+	return &Core{
+		storer: storer,
+		trip:   trip,
+		user:   user,
+	}
+}
+
+// Create files a report of one participant of a trip against another.
+func (c *Core) Create(ctx context.Context, tripID uuid.UUID, nr NewReport) (Report, error) {
+	if nr.ComplainantID == nr.DefendantID {
+		return Report{}, ErrReportSelf
+	}
+
+	trp, err := c.trip.QueryByID(ctx, tripID)
+	if err != nil {
+		return Report{}, fmt.Errorf("querytrip: tripID[%s]: %w", tripID, err)
+	}
+
+	for _, userID := range []uuid.UUID{nr.ComplainantID, nr.DefendantID} {
+		ok, err := c.trip.IsParticipant(ctx, trp, userID)
+		if err != nil {
+			return Report{}, fmt.Errorf("isparticipant: userID[%s]: %w", userID, err)
+		}
+		if !ok {
+			return Report{}, ErrNotParticipant
+		}
+	}
+
+	now := time.Now()
+
+	report := Report{
+		ID:            uuid.New(),
+		TripID:        tripID,
+		ComplainantID: nr.ComplainantID,
+		DefendantID:   nr.DefendantID,
+		Comment:       nr.Comment,
+		Status:        StatusOpen,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	if err := c.storer.Create(ctx, report); err != nil {
+		return Report{}, fmt.Errorf("create: %w", err)
+	}
+
+	return report, nil
+}
+
+// IsModerator reports whether the user works the moderation queue.
+func (c *Core) IsModerator(ctx context.Context, userID uuid.UUID) (bool, error) {
+	ok, err := c.storer.IsModerator(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("ismoderator: userID[%s]: %w", userID, err)
+	}
+
+	return ok, nil
+}
+
+// Triage assigns the report to the moderator and starts the review.
+func (c *Core) Triage(ctx context.Context, report Report, moderatorID uuid.UUID, note string) (Report, error) {
+	if !canTransition(report.Status, StatusInReview) {
+		return Report{}, ErrInvalidTransition
+	}
+
+	from := report.Status
+
+	report.Status = StatusInReview
+	report.AssigneeID = &moderatorID
+	report.UpdatedAt = time.Now()
+
+	if err := c.storer.Update(ctx, report, from); err != nil {
+		return Report{}, fmt.Errorf("update: reportID[%s]: %w", report.ID, err)
+	}
+
+	if note != "" {
+		if _, err := c.AddNote(ctx, report, moderatorID, note); err != nil {
+			return Report{}, err
+		}
+	}
+
+	return report, nil
+}
+
+// Resolve closes the report, suspending the defendant when the resolution
+// asks for it.
+func (c *Core) Resolve(ctx context.Context, report Report, moderatorID uuid.UUID, res Resolution) (Report, error) {
+	if res.Status != StatusResolved && res.Status != StatusDismissed {
+		return Report{}, ErrInvalidTransition
+	}
+
+	if !canTransition(report.Status, res.Status) {
+		return Report{}, ErrInvalidTransition
+	}
+
+	from := report.Status
+	now := time.Now()
+
+	report.Status = res.Status
+	report.Resolution = res.Resolution
+	report.Suspended = res.Suspend && res.Status == StatusResolved
+	report.ResolvedBy = &moderatorID
+	report.ResolvedAt = &now
+	report.UpdatedAt = now
+
+	if err := c.storer.Update(ctx, report, from); err != nil {
+		return Report{}, fmt.Errorf("update: reportID[%s]: %w", report.ID, err)
+	}
+
+	if report.Suspended {
+		defendant, err := c.user.QueryByID(ctx, report.DefendantID)
+		if err != nil {
+			return Report{}, fmt.Errorf("querydefendant: userID[%s]: %w", report.DefendantID, err)
+		}
+
+		var until *time.Time
+		if res.SuspendFor != nil {
+			t := now.Add(*res.SuspendFor)
+			until = &t
+		}
+
+		if _, err := c.user.Suspend(ctx, defendant, until, res.Resolution); err != nil {
+			return Report{}, fmt.Errorf("suspend: userID[%s]: %w", report.DefendantID, err)
+		}
+	}
+
+	return report, nil
+}
+
+// AddNote leaves a remark on the report.
+func (c *Core) AddNote(ctx context.Context, report Report, authorID uuid.UUID, text string) (Note, error) {
+	note := Note{
+		ID:        uuid.New(),
+		ReportID:  report.ID,
+		AuthorID:  authorID,
+		Note:      text,
+		CreatedAt: time.Now(),
+	}
+
+	if err := c.storer.CreateNote(ctx, note); err != nil {
+		return Note{}, fmt.Errorf("createnote: reportID[%s]: %w", report.ID, err)
+	}
+
+	return note, nil
+}
+
+// Query retrieves a list of existing reports from the database.
+func (c *Core) Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]Report, error) {
+	reports, err := c.storer.Query(ctx, filter, orderBy, pageNumber, rowsPerPage)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return reports, nil
+}
+
+// Count returns the total number of reports in the store.
+func (c *Core) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	return c.storer.Count(ctx, filter)
+}
+
+// QueryByID gets the specified report from the database.
+func (c *Core) QueryByID(ctx context.Context, reportID uuid.UUID) (Report, error) {
+	report, err := c.storer.QueryByID(ctx, reportID)
+	if err != nil {
+		return Report{}, fmt.Errorf("query: reportID[%s]: %w", reportID, err)
+	}
+
+	return report, nil
+}
+
+// QueryNotes retrieves the notes of a report, oldest first.
+func (c *Core) QueryNotes(ctx context.Context, reportID uuid.UUID) ([]Note, error) {
+	notes, err := c.storer.QueryNotes(ctx, reportID)
+	if err != nil {
+		return nil, fmt.Errorf("query: reportID[%s]: %w", reportID, err)
+	}
+
+	return notes, nil
+}
+
+// =============================================================================
+
+func canTransition(from string, to string) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package report
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/core/user"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mocks struct {
+	storer *MockStorer
+	trip   *trip.MockStorer
+	user   *user.MockStorer
+}
+
+func newTestCore() (*Core, mocks) {
+	m := mocks{
+		storer: new(MockStorer),
+		trip:   new(trip.MockStorer),
+		user:   new(user.MockStorer),
+	}
+
+	return NewCore(m.storer, trip.NewCore(m.trip), user.NewCore(m.user)), m
+}
+
+func TestCreate(t *testing.T) {
+	core, m := newTestCore()
+
+	tripID := uuid.New()
+	driverID := uuid.New()
+	passengerID := uuid.New()
+
+	m.trip.On("QueryByID", mock.Anything, tripID).Return(trip.TripView{ID: tripID, DriverID: driverID}, nil)
+	m.trip.On("QueryPassenger", mock.Anything, tripID, passengerID).Return(trip.TripPassenger{Status: trip.StatusAccepted}, nil)
+	m.storer.On("Create", mock.Anything, mock.AnythingOfType("Report")).Return(nil)
+
+	report, err := core.Create(context.Background(), tripID, NewReport{ComplainantID: passengerID, DefendantID: driverID, Comment: "reckless driving"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusOpen, report.Status)
+	assert.Equal(t, driverID, report.DefendantID)
+	m.storer.AssertExpectations(t)
+}
+
+func TestCreateRejected(t *testing.T) {
+	tripID := uuid.New()
+	driverID := uuid.New()
+	passengerID := uuid.New()
+	strangerID := uuid.New()
+
+	tests := []struct {
+		name string
+		nr   NewReport
+		want error
+	}{
+		{"self", NewReport{ComplainantID: driverID, DefendantID: driverID}, ErrReportSelf},
+		{"complainant not on trip", NewReport{ComplainantID: strangerID, DefendantID: driverID}, ErrNotParticipant},
+		{"defendant not on trip", NewReport{ComplainantID: driverID, DefendantID: strangerID}, ErrNotParticipant},
+		{"defendant not accepted", NewReport{ComplainantID: driverID, DefendantID: passengerID}, ErrNotParticipant},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, m := newTestCore()
+
+			m.trip.On("QueryByID", mock.Anything, tripID).Return(trip.TripView{ID: tripID, DriverID: driverID}, nil)
+			m.trip.On("QueryPassenger", mock.Anything, tripID, passengerID).Return(trip.TripPassenger{Status: trip.StatusPending}, nil)
+			m.trip.On("QueryPassenger", mock.Anything, tripID, strangerID).Return(trip.TripPassenger{}, trip.ErrPassengerNotFound)
+
+			_, err := core.Create(context.Background(), tripID, tt.nr)
+
+			assert.ErrorIs(t, err, tt.want)
+			m.storer.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestTriage(t *testing.T) {
+	core, m := newTestCore()
+
+	moderatorID := uuid.New()
+	rpt := Report{ID: uuid.New(), Status: StatusOpen}
+
+	m.storer.On("Update", mock.Anything, mock.AnythingOfType("Report"), StatusOpen).Return(nil)
+	m.storer.On("CreateNote", mock.Anything, mock.AnythingOfType("Note")).Return(nil)
+
+	report, err := core.Triage(context.Background(), rpt, moderatorID, "looking into it")
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusInReview, report.Status)
+	assert.Equal(t, &moderatorID, report.AssigneeID)
+	m.storer.AssertExpectations(t)
+}
+
+func TestTriageClosed(t *testing.T) {
+	core, m := newTestCore()
+
+	_, err := core.Triage(context.Background(), Report{Status: StatusResolved}, uuid.New(), "")
+
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	m.storer.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestResolveWithSuspension(t *testing.T) {
+	core, m := newTestCore()
+
+	moderatorID := uuid.New()
+	defendantID := uuid.New()
+	rpt := Report{ID: uuid.New(), DefendantID: defendantID, Status: StatusInReview}
+	week := 7 * 24 * time.Hour
+
+	m.storer.On("Update", mock.Anything, mock.AnythingOfType("Report"), StatusInReview).Return(nil)
+	m.user.On("QueryByID", mock.Anything, defendantID).Return(user.User{ID: defendantID}, nil)
+	m.user.On("UpdateSuspension", mock.Anything, mock.MatchedBy(func(usr user.User) bool {
+		return usr.ID == defendantID && usr.SuspendedUntil != nil && usr.SuspensionReason == "harassment"
+	})).Return(nil)
+
+	report, err := core.Resolve(context.Background(), rpt, moderatorID, Resolution{
+		Status:     StatusResolved,
+		Resolution: "harassment",
+		Suspend:    true,
+		SuspendFor: &week,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusResolved, report.Status)
+	assert.True(t, report.Suspended)
+	assert.Equal(t, &moderatorID, report.ResolvedBy)
+	m.user.AssertExpectations(t)
+}
+
+func TestResolveDismissed(t *testing.T) {
+	core, m := newTestCore()
+
+	rpt := Report{ID: uuid.New(), DefendantID: uuid.New(), Status: StatusOpen}
+
+	m.storer.On("Update", mock.Anything, mock.AnythingOfType("Report"), StatusOpen).Return(nil)
+
+	report, err := core.Resolve(context.Background(), rpt, uuid.New(), Resolution{Status: StatusDismissed, Suspend: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusDismissed, report.Status)
+	assert.False(t, report.Suspended)
+	m.user.AssertNotCalled(t, "UpdateSuspension", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,26 @@
+package reportdb
+
+import (
+	"github.com/Masterminds/squirrel"
+	"github.com/TSMC-Uber/server/business/core/report"
+)
+
+func (s *Store) applyFilter(builder squirrel.SelectBuilder, filter report.QueryFilter) squirrel.SelectBuilder {
+	if filter.Status != nil {
+		builder = builder.Where(squirrel.Eq{"status": *filter.Status})
+	}
+
+	if filter.TripID != nil {
+		builder = builder.Where(squirrel.Eq{"trip_id": *filter.TripID})
+	}
+
+	if filter.DefendantID != nil {
+		builder = builder.Where(squirrel.Eq{"defendant": *filter.DefendantID})
+	}
+
+	if filter.AssigneeID != nil {
+		builder = builder.Where(squirrel.Eq{"assignee_id": *filter.AssigneeID})
+	}
+
+	return builder
+}
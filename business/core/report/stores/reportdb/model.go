@@ -0,0 +1,122 @@
+package reportdb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/core/report"
+	"github.com/google/uuid"
+)
+
+type dbReport struct {
+	ID          uuid.UUID      `db:"id"`
+	TripID      uuid.UUID      `db:"trip_id"`
+	Complainant uuid.UUID      `db:"complainant"`
+	Defendant   uuid.UUID      `db:"defendant"`
+	Comment     sql.NullString `db:"comment"`
+	Status      string         `db:"status"`
+	AssigneeID  uuid.NullUUID  `db:"assignee_id"`
+	Resolution  sql.NullString `db:"resolution"`
+	Suspended   bool           `db:"suspended"`
+	ResolvedBy  uuid.NullUUID  `db:"resolved_by"`
+	ResolvedAt  sql.NullTime   `db:"resolved_at"`
+	CreatedAt   time.Time      `db:"created_at"`
+	UpdatedAt   time.Time      `db:"updated_at"`
+}
+
+func toDBReport(rpt report.Report) dbReport {
+	dbRpt := dbReport{
+		ID:          rpt.ID,
+		TripID:      rpt.TripID,
+		Complainant: rpt.ComplainantID,
+		Defendant:   rpt.DefendantID,
+		Comment:     sql.NullString{String: rpt.Comment, Valid: rpt.Comment != ""},
+		Status:      rpt.Status,
+		Resolution:  sql.NullString{String: rpt.Resolution, Valid: rpt.Resolution != ""},
+		Suspended:   rpt.Suspended,
+		CreatedAt:   rpt.CreatedAt.UTC(),
+		UpdatedAt:   rpt.UpdatedAt.UTC(),
+	}
+
+	if rpt.AssigneeID != nil {
+		dbRpt.AssigneeID = uuid.NullUUID{UUID: *rpt.AssigneeID, Valid: true}
+	}
+	if rpt.ResolvedBy != nil {
+		dbRpt.ResolvedBy = uuid.NullUUID{UUID: *rpt.ResolvedBy, Valid: true}
+	}
+	if rpt.ResolvedAt != nil {
+		dbRpt.ResolvedAt = sql.NullTime{Time: rpt.ResolvedAt.UTC(), Valid: true}
+	}
+
+	return dbRpt
+}
+
+func toCoreReport(dbRpt dbReport) report.Report {
+	rpt := report.Report{
+		ID:            dbRpt.ID,
+		TripID:        dbRpt.TripID,
+		ComplainantID: dbRpt.Complainant,
+		DefendantID:   dbRpt.Defendant,
+		Comment:       dbRpt.Comment.String,
+		Status:        dbRpt.Status,
+		Resolution:    dbRpt.Resolution.String,
+		Suspended:     dbRpt.Suspended,
+		CreatedAt:     dbRpt.CreatedAt.In(time.Local),
+		UpdatedAt:     dbRpt.UpdatedAt.In(time.Local),
+	}
+
+	if dbRpt.AssigneeID.Valid {
+		rpt.AssigneeID = &dbRpt.AssigneeID.UUID
+	}
+	if dbRpt.ResolvedBy.Valid {
+		rpt.ResolvedBy = &dbRpt.ResolvedBy.UUID
+	}
+	if dbRpt.ResolvedAt.Valid {
+		resolvedAt := dbRpt.ResolvedAt.Time.In(time.Local)
+		rpt.ResolvedAt = &resolvedAt
+	}
+
+	return rpt
+}
+
+func toCoreReportSlice(dbReports []dbReport) []report.Report {
+	reports := make([]report.Report, len(dbReports))
+	for i, dbRpt := range dbReports {
+		reports[i] = toCoreReport(dbRpt)
+	}
+	return reports
+}
+
+// =============================================================================
+
+type dbNote struct {
+	ID        uuid.UUID `db:"id"`
+	ReportID  uuid.UUID `db:"report_id"`
+	AuthorID  uuid.UUID `db:"author_id"`
+	Note      string    `db:"note"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func toDBNote(note report.Note) dbNote {
+	return dbNote{
+		ID:        note.ID,
+		ReportID:  note.ReportID,
+		AuthorID:  note.AuthorID,
+		Note:      note.Note,
+		CreatedAt: note.CreatedAt.UTC(),
+	}
+}
+
+func toCoreNoteSlice(dbNotes []dbNote) []report.Note {
+	notes := make([]report.Note, len(dbNotes))
+	for i, dbNote := range dbNotes {
+		notes[i] = report.Note{
+			ID:        dbNote.ID,
+			ReportID:  dbNote.ReportID,
+			AuthorID:  dbNote.AuthorID,
+			Note:      dbNote.Note,
+			CreatedAt: dbNote.CreatedAt.In(time.Local),
+		}
+	}
+	return notes
+}
@@ -0,0 +1,23 @@
+package reportdb
+
+import (
+	"fmt"
+
+	"github.com/TSMC-Uber/server/business/core/report"
+	"github.com/TSMC-Uber/server/business/data/order"
+)
+
+var orderByFields = map[string]string{
+	report.OrderByCreatedAt: "created_at",
+	report.OrderByUpdatedAt: "updated_at",
+	report.OrderByStatus:    "status",
+}
+
+func orderByClause(orderBy order.By) (string, error) {
+	by, exists := orderByFields[orderBy.Field]
+	if !exists {
+		return "", fmt.Errorf("field %q does not exist", orderBy.Field)
+	}
+
+	return fmt.Sprintf("%s %s", by, orderBy.Direction), nil
+}
@@ -0,0 +1,239 @@
+// Package reportdb contains report related CRUD functionality.
+package reportdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+
+	"github.com/TSMC-Uber/server/business/core/report"
+	"github.com/TSMC-Uber/server/business/data/order"
+	"github.com/TSMC-Uber/server/business/sys/database"
+	"github.com/TSMC-Uber/server/foundation/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// reportColumns are the columns selected when reading reports.
+var reportColumns = []string{
+	"id",
+	"trip_id",
+	"complainant",
+	"defendant",
+	"comment",
+	"status",
+	"assignee_id",
+	"resolution",
+	"suspended",
+	"resolved_by",
+	"resolved_at",
+	"created_at",
+	"updated_at",
+}
+
+// Store manages the set of APIs for report database access.
+type Store struct {
+	log *logger.Logger
+	db  *sqlx.DB
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create inserts a new report into the database.
+func (s *Store) Create(ctx context.Context, rpt report.Report) error {
+	dbRpt := toDBReport(rpt)
+
+	sql, args, err := sq.
+		Insert("report").
+		Columns("id", "trip_id", "complainant", "defendant", "comment", "status", "created_at", "updated_at").
+		Values(dbRpt.ID, dbRpt.TripID, dbRpt.Complainant, dbRpt.Defendant, dbRpt.Comment, dbRpt.Status, dbRpt.CreatedAt, dbRpt.UpdatedAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	if err := database.ExecContext(ctx, s.log, s.db, sql, args); err != nil {
+		if errors.Is(err, database.ErrDBDuplicatedEntry) {
+			return report.ErrAlreadyReported
+		}
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	return nil
+}
+
+// Update writes the moderation state of the report, as long as nobody moved
+// it away from the status it was read in.
+func (s *Store) Update(ctx context.Context, rpt report.Report, from string) error {
+	dbRpt := toDBReport(rpt)
+
+	sql, args, err := sq.
+		Update("report").
+		Set("status", dbRpt.Status).
+		Set("assignee_id", dbRpt.AssigneeID).
+		Set("resolution", dbRpt.Resolution).
+		Set("suspended", dbRpt.Suspended).
+		Set("resolved_by", dbRpt.ResolvedBy).
+		Set("resolved_at", dbRpt.ResolvedAt).
+		Set("updated_at", dbRpt.UpdatedAt).
+		Where(sq.Eq{"id": dbRpt.ID}).
+		Where(sq.Eq{"status": from}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rowsaffected: %w", err)
+	}
+	if rows == 0 {
+		return report.ErrInvalidTransition
+	}
+
+	return nil
+}
+
+// QueryByID gets the specified report from the database.
+func (s *Store) QueryByID(ctx context.Context, reportID uuid.UUID) (report.Report, error) {
+	sql, args, err := sq.Select(reportColumns...).
+		From("report").
+		Where(sq.Eq{"id": reportID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return report.Report{}, fmt.Errorf("tosql: %w", err)
+	}
+
+	var dbRpt dbReport
+	if err := database.GetContext(ctx, s.log, s.db, sql, args, &dbRpt); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return report.Report{}, fmt.Errorf("namedquerystruct: %w", report.ErrNotFound)
+		}
+		return report.Report{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreReport(dbRpt), nil
+}
+
+// Query retrieves a list of existing reports from the database.
+func (s *Store) Query(ctx context.Context, filter report.QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]report.Report, error) {
+	builder := sq.Select(reportColumns...).From("report")
+
+	builder = s.applyFilter(builder, filter)
+
+	orderByClause, err := orderByClause(orderBy)
+	if err != nil {
+		return nil, err
+	}
+	builder = builder.OrderBy(orderByClause)
+
+	// add paging
+	builder = builder.Limit(uint64(rowsPerPage)).Offset(uint64((pageNumber - 1) * rowsPerPage))
+
+	sql, args, err := builder.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("tosql: %w", err)
+	}
+
+	var dbReports []dbReport
+	if err := database.QueryContext(ctx, s.log, s.db, sql, args, &dbReports); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreReportSlice(dbReports), nil
+}
+
+// Count returns the total number of reports in the DB.
+func (s *Store) Count(ctx context.Context, filter report.QueryFilter) (int, error) {
+	builder := sq.Select("COUNT(*) AS count").From("report")
+
+	builder = s.applyFilter(builder, filter)
+
+	sql, args, err := builder.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("tosql: %w", err)
+	}
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := database.GetContext(ctx, s.log, s.db, sql, args, &count); err != nil {
+		return 0, fmt.Errorf("getcontext: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// CreateNote inserts a new note on a report into the database.
+func (s *Store) CreateNote(ctx context.Context, note report.Note) error {
+	dbNote := toDBNote(note)
+
+	sql, args, err := sq.
+		Insert("report_note").
+		Columns("id", "report_id", "author_id", "note", "created_at").
+		Values(dbNote.ID, dbNote.ReportID, dbNote.AuthorID, dbNote.Note, dbNote.CreatedAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	if err := database.ExecContext(ctx, s.log, s.db, sql, args); err != nil {
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	return nil
+}
+
+// QueryNotes retrieves the notes of a report from the database, oldest
+// first.
+func (s *Store) QueryNotes(ctx context.Context, reportID uuid.UUID) ([]report.Note, error) {
+	sql, args, err := sq.Select("id", "report_id", "author_id", "note", "created_at").
+		From("report_note").
+		Where(sq.Eq{"report_id": reportID}).
+		OrderBy("created_at ASC").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("tosql: %w", err)
+	}
+
+	var dbNotes []dbNote
+	if err := database.QueryContext(ctx, s.log, s.db, sql, args, &dbNotes); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreNoteSlice(dbNotes), nil
+}
+
+// IsModerator reports whether the user is listed as a moderator.
+func (s *Store) IsModerator(ctx context.Context, userID uuid.UUID) (bool, error) {
+	const q = `
+	SELECT
+		EXISTS (SELECT 1 FROM moderator WHERE user_id = $1) AS moderator`
+
+	var result struct {
+		Moderator bool `db:"moderator"`
+	}
+	if err := database.GetContext(ctx, s.log, s.db, q, []interface{}{userID}, &result); err != nil {
+		return false, fmt.Errorf("getcontext: %w", err)
+	}
+
+	return result.Moderator, nil
+}
@@ -0,0 +1,55 @@
+package trip
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Set of the lifecycle events of a trip.
+const (
+	EventPassengerAccepted = "passenger_accepted"
+	EventStarted           = "trip_started"
+	EventFinished          = "trip_finished"
+	EventCancelled         = "trip_cancelled"
+	EventStartTimeChanged  = "start_time_changed"
+)
+
+// statusEvents maps the statuses a trip moves to to the events they emit.
+var statusEvents = map[string]string{
+	TripStatusIn:        EventStarted,
+	TripStatusFinished:  EventFinished,
+	TripStatusCancelled: EventCancelled,
+}
+
+// Event is a change in the lifecycle of a trip. PassengerID is set on the
+// events of a passenger and StartTime, the new start time, on
+// EventStartTimeChanged.
+type Event struct {
+	Type        string
+	TripID      uuid.UUID
+	ChangedBy   uuid.UUID
+	PassengerID uuid.UUID
+	StartTime   time.Time
+	CreatedAt   time.Time
+}
+
+// EventHandler is called with the events of the trips the core changes, once
+// the change is stored. A handler cannot fail the change, it deals with its
+// own errors.
+type EventHandler func(ctx context.Context, evt Event)
+
+// Subscribe registers the handler for the events of the trips the core
+// changes. Handlers are registered while the core is set up, before it is
+// used.
+func (c *Core) Subscribe(handler EventHandler) {
+	c.handlers = append(c.handlers, handler)
+}
+
+// emit calls the handlers with the event, in the order they subscribed.
+func (c *Core) emit(ctx context.Context, evt Event) {
+	for _, handler := range c.handlers {
+		handler(ctx, evt)
+	}
+}
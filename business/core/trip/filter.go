@@ -20,6 +20,22 @@ type QueryFilter struct {
 
 	// in another table
 	UserID *uuid.UUID `validate:"omitempty"`
+
+	// geospatial search, a trip matches when its route passes within Radius
+	// meters of the pickup point and then of the drop-off point
+	Pickup  *GeoPoint `validate:"omitempty"`
+	Dropoff *GeoPoint `validate:"omitempty"`
+	Radius  *float64  `validate:"omitempty,gt=0,lte=50000"`
+}
+
+// DefaultSearchRadius is the radius in meters used by a geospatial search
+// when the caller does not provide one.
+const DefaultSearchRadius = 1000
+
+// GeoPoint represents a coordinate a trip can be searched by.
+type GeoPoint struct {
+	Lat float64 `validate:"gte=-90,lte=90"`
+	Lon float64 `validate:"gte=-180,lte=180"`
 }
 
 // Validate checks the data in the model is considered clean.
@@ -69,6 +85,26 @@ func (qf *QueryFilter) WithEndStartDate(endDate time.Time) {
 	qf.EndStartDate = &d
 }
 
+// WithPickup sets the Pickup field of the QueryFilter value.
+func (qf *QueryFilter) WithPickup(lat float64, lon float64) {
+	qf.Pickup = &GeoPoint{Lat: lat, Lon: lon}
+}
+
+// WithDropoff sets the Dropoff field of the QueryFilter value.
+func (qf *QueryFilter) WithDropoff(lat float64, lon float64) {
+	qf.Dropoff = &GeoPoint{Lat: lat, Lon: lon}
+}
+
+// WithRadius sets the Radius field of the QueryFilter value in meters.
+func (qf *QueryFilter) WithRadius(radius float64) {
+	qf.Radius = &radius
+}
+
+// IsGeoSearch reports whether the filter searches trips by coordinates.
+func (qf *QueryFilter) IsGeoSearch() bool {
+	return qf.Pickup != nil || qf.Dropoff != nil
+}
+
 // QueryFilterByUser
 type QueryFilterByUser struct {
 	UserID   uuid.UUID
@@ -23,6 +23,11 @@ func (m *MockStorer) Update(ctx context.Context, trip Trip) error {
 	return args.Error(0)
 }
 
+func (m *MockStorer) UpdateStatus(ctx context.Context, trip Trip, change StatusChange) error {
+	args := m.Called(ctx, trip, change)
+	return args.Error(0)
+}
+
 func (m *MockStorer) Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]TripView, error) {
 	args := m.Called(ctx, filter, orderBy, pageNumber, rowsPerPage)
 	return args.Get(0).([]TripView), args.Error(1)
@@ -48,11 +53,26 @@ func (m *MockStorer) Join(ctx context.Context, tripPassenger TripPassenger) erro
 	return args.Error(0)
 }
 
-func (m *MockStorer) UpdatePassengerStatus(ctx context.Context, tripPassenger TripPassenger) error {
+func (m *MockStorer) UpdatePassengerStatus(ctx context.Context, tripPassenger TripPassenger, from []string) error {
+	args := m.Called(ctx, tripPassenger, from)
+	return args.Error(0)
+}
+
+func (m *MockStorer) AcceptPassenger(ctx context.Context, tripPassenger TripPassenger) error {
 	args := m.Called(ctx, tripPassenger)
 	return args.Error(0)
 }
 
+func (m *MockStorer) QueryPassenger(ctx context.Context, tripID uuid.UUID, passengerID uuid.UUID) (TripPassenger, error) {
+	args := m.Called(ctx, tripID, passengerID)
+	return args.Get(0).(TripPassenger), args.Error(1)
+}
+
+func (m *MockStorer) QueryRoute(ctx context.Context, tripID uuid.UUID) (Route, error) {
+	args := m.Called(ctx, tripID)
+	return args.Get(0).(Route), args.Error(1)
+}
+
 func (m *MockStorer) QueryPassengers(ctx context.Context, tripID uuid.UUID) (TripDetails, error) {
 	fmt.Println("MockStorer.QueryPassengers", tripID)
 	args := m.Called(ctx, tripID)
@@ -63,3 +83,23 @@ func (m *MockStorer) CreateRating(ctx context.Context, rating Rating) error {
 	args := m.Called(ctx, rating)
 	return args.Error(0)
 }
+
+func (m *MockStorer) QueryRatings(ctx context.Context, tripID uuid.UUID) ([]Rating, error) {
+	args := m.Called(ctx, tripID)
+	return args.Get(0).([]Rating), args.Error(1)
+}
+
+func (m *MockStorer) QueryRatingsByDriver(ctx context.Context, driverID uuid.UUID, pageNumber int, rowsPerPage int) ([]Rating, error) {
+	args := m.Called(ctx, driverID, pageNumber, rowsPerPage)
+	return args.Get(0).([]Rating), args.Error(1)
+}
+
+func (m *MockStorer) CountRatingsByDriver(ctx context.Context, driverID uuid.UUID) (int, error) {
+	args := m.Called(ctx, driverID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStorer) QueryReputation(ctx context.Context, userID uuid.UUID) (Reputation, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(Reputation), args.Error(1)
+}
@@ -21,11 +21,12 @@ type Trip struct {
 }
 
 type TripLocation struct {
-	ID      uuid.UUID
-	Name    string
-	PlaceID string
-	Lat     float64
-	Lon     float64
+	ID             uuid.UUID
+	Name           string
+	PlaceID        string
+	Lat            float64
+	Lon            float64
+	PlannedArrival *time.Time
 }
 
 type NewTrip struct {
@@ -40,6 +41,7 @@ type NewTrip struct {
 type UpdateTrip struct {
 	PassengerLimit *int
 	Status         *string
+	StartTime      *time.Time
 }
 
 type TripPassenger struct {
@@ -48,6 +50,7 @@ type TripPassenger struct {
 	SourceID      uuid.UUID
 	DestinationID uuid.UUID
 	Status        string
+	StatusReason  string
 	CreatedAt     time.Time
 }
 type NewTripPassenger struct {
@@ -64,6 +67,7 @@ type UserTrip struct {
 	MySourceID           uuid.UUID
 	MyDestinationID      uuid.UUID
 	MyStatus             string
+	MyStatusReason       string
 	DriverID             uuid.UUID
 	DriverName           string
 	DriverImageURL       string
@@ -118,6 +122,8 @@ type PassengerDetails struct {
 	PassengerName        string
 	PassengerImageURL    string
 	PassengerStatus      string
+	PassengerReason      string
+	PassengerReputation  Reputation
 	SourceName           string
 	SourcePlaceID        string
 	SourceLatitude       float64
@@ -149,17 +155,30 @@ type TripDetails struct {
 }
 
 type Rating struct {
-	ID          uuid.UUID
-	CommenterID uuid.UUID
-	TripID      uuid.UUID
-	Rating      int
-	Comment     string
-	CreatedAt   time.Time
+	ID                uuid.UUID
+	RateeID           uuid.UUID
+	CommenterID       uuid.UUID
+	CommenterName     string
+	CommenterImageURL string
+	TripID            uuid.UUID
+	Rating            int
+	Comment           string
+	CreatedAt         time.Time
 }
 
 type NewRating struct {
+	RateeID     uuid.UUID
 	CommenterID uuid.UUID
 	TripID      uuid.UUID
 	Rating      int
 	Comment     string
 }
+
+// Reputation summarizes how a user has done as a passenger: the ratings drivers
+// left for them and the number of finished trips they rode along.
+type Reputation struct {
+	UserID         uuid.UUID
+	RatingAverage  float64
+	RatingCount    int
+	CompletedTrips int
+}
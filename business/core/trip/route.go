@@ -0,0 +1,105 @@
+package trip
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Set of kinds a stop of the trip itinerary can be.
+var (
+	StopKindSource      = "source"
+	StopKindWaypoint    = "waypoint"
+	StopKindPickup      = "pickup"
+	StopKindDropoff     = "dropoff"
+	StopKindDestination = "destination"
+)
+
+// Stop represents a point of the ordered trip itinerary. The ID is uuid.Nil
+// for the source and the destination of the trip.
+type Stop struct {
+	ID             uuid.UUID
+	Sequence       int
+	Kind           string
+	PassengerID    *uuid.UUID
+	Location       TripLocation
+	PlannedArrival *time.Time
+	Distance       float64 // cumulative distance from the source in meters
+}
+
+// Route represents the full ordered itinerary of a trip.
+type Route struct {
+	TripID        uuid.UUID
+	Stops         []Stop
+	TotalDistance float64
+}
+
+// PlanPassengerStops inserts the pickup and drop-off stops of a passenger into
+// an itinerary that starts with the source and ends with the destination. The
+// stops are placed where they add the least distance to the route, with the
+// pickup always before the drop-off. Sequences are renumbered from zero.
+func PlanPassengerStops(stops []Stop, pickup Stop, dropoff Stop) []Stop {
+	if len(stops) < 2 {
+		return stops
+	}
+
+	bestPickup, bestDropoff := 1, 1
+	bestCost := math.Inf(1)
+
+	// i is the index the pickup is inserted at, j the index the drop-off is
+	// inserted at in the original itinerary, j == i keeps them adjacent.
+	for i := 1; i < len(stops); i++ {
+		for j := i; j < len(stops); j++ {
+			var cost float64
+			if i == j {
+				cost = distance(stops[i-1].Location, pickup.Location) +
+					distance(pickup.Location, dropoff.Location) +
+					distance(dropoff.Location, stops[i].Location) -
+					distance(stops[i-1].Location, stops[i].Location)
+			} else {
+				cost = distance(stops[i-1].Location, pickup.Location) +
+					distance(pickup.Location, stops[i].Location) -
+					distance(stops[i-1].Location, stops[i].Location) +
+					distance(stops[j-1].Location, dropoff.Location) +
+					distance(dropoff.Location, stops[j].Location) -
+					distance(stops[j-1].Location, stops[j].Location)
+			}
+
+			if cost < bestCost {
+				bestCost = cost
+				bestPickup, bestDropoff = i, j
+			}
+		}
+	}
+
+	planned := make([]Stop, 0, len(stops)+2)
+	for i, stop := range stops {
+		if i == bestPickup {
+			planned = append(planned, pickup)
+		}
+		if i == bestDropoff {
+			planned = append(planned, dropoff)
+		}
+		planned = append(planned, stop)
+	}
+
+	for i := range planned {
+		planned[i].Sequence = i
+	}
+
+	return planned
+}
+
+// earthRadius is the mean radius of the earth in meters.
+const earthRadius = 6371008.8
+
+// distance returns the great-circle distance between two locations in meters.
+func distance(a TripLocation, b TripLocation) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,53 @@
+package trip
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// transitions lists, for every trip status, the statuses it can move to.
+var transitions = map[string][]string{
+	TripStatusNotStarted: {TripStatusIn, TripStatusCancelled},
+	TripStatusIn:         {TripStatusFinished},
+}
+
+// CanTransition reports whether a trip can move from one status to another.
+func CanTransition(from string, to string) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusTransitionError is used when a trip cannot move from its current
+// status to the requested one.
+type StatusTransitionError struct {
+	From string
+	To   string
+}
+
+// Error implements the error interface.
+func (e *StatusTransitionError) Error() string {
+	return fmt.Sprintf("trip status cannot change from %q to %q", e.From, e.To)
+}
+
+// IsStatusTransitionError checks if an error of type StatusTransitionError exists.
+func IsStatusTransitionError(err error) bool {
+	var se *StatusTransitionError
+	return errors.As(err, &se)
+}
+
+// StatusChange records who moved a trip from one status to another and when.
+type StatusChange struct {
+	ID        uuid.UUID
+	TripID    uuid.UUID
+	From      string
+	To        string
+	ChangedBy uuid.UUID
+	CreatedAt time.Time
+}
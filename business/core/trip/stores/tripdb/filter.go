@@ -1,10 +1,32 @@
 package tripdb
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/Masterminds/squirrel"
 	"github.com/TSMC-Uber/server/business/core/trip"
 )
 
+// routePoints selects every stop on the route of the trip_view row being
+// filtered with its place in the route, numbered as routeQuery does: the
+// source first, then the stops of trip_location, then the destination.
+const routePoints = `SELECT -1 AS sequence, trip_view.source_lat_lon AS lat_lon
+	UNION ALL SELECT 2147483647, trip_view.destination_lat_lon
+	UNION ALL SELECT trip_location.sequence, locations.lat_lon FROM trip_location
+		JOIN locations ON trip_location.location_id = locations.id
+		WHERE trip_location.trip_id = trip_view.id`
+
+// geoPoint builds a geography value from a longitude and latitude pair.
+const geoPoint = "ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography"
+
+// nearestStop selects the place in the route of the stop nearest to the
+// point, among the stops within the radius, or NULL when there is none. Its
+// arguments are the point, the radius and the point again.
+var nearestStop = fmt.Sprintf(`(SELECT route.sequence FROM (%s) AS route
+	WHERE ST_DWithin(route.lat_lon, %s, ?)
+	ORDER BY ST_Distance(route.lat_lon, %s), route.sequence LIMIT 1)`, routePoints, geoPoint, geoPoint)
+
 func (s *Store) applyFilter(builder squirrel.SelectBuilder, filter trip.QueryFilter) squirrel.SelectBuilder {
 	if filter.ID != nil {
 		builder = builder.Where(squirrel.Eq{"id": *filter.ID})
@@ -34,9 +56,53 @@ func (s *Store) applyFilter(builder squirrel.SelectBuilder, filter trip.QueryFil
 		builder = builder.Where(squirrel.LtOrEq{"start_time": *filter.EndStartDate})
 	}
 
+	radius := float64(trip.DefaultSearchRadius)
+	if filter.Radius != nil {
+		radius = *filter.Radius
+	}
+
+	// The route has to pass the pickup point before the drop-off point, a
+	// trip going the other way does not take the passenger there.
+	switch pickup, dropoff := filter.Pickup, filter.Dropoff; {
+	case pickup != nil && dropoff != nil:
+		builder = builder.Where(
+			nearestStop+" < "+nearestStop,
+			pickup.Lon, pickup.Lat, radius, pickup.Lon, pickup.Lat,
+			dropoff.Lon, dropoff.Lat, radius, dropoff.Lon, dropoff.Lat,
+		)
+	case pickup != nil:
+		builder = builder.Where(nearestStop+" IS NOT NULL", pickup.Lon, pickup.Lat, radius, pickup.Lon, pickup.Lat)
+	case dropoff != nil:
+		builder = builder.Where(nearestStop+" IS NOT NULL", dropoff.Lon, dropoff.Lat, radius, dropoff.Lon, dropoff.Lat)
+	}
+
 	return builder
 }
 
+// applyDetourOrder sorts the trips of a geospatial search by the distance a
+// passenger has to cover to reach the route from the pickup and drop-off
+// points, so the trips needing the smallest detour come first.
+func (s *Store) applyDetourOrder(builder squirrel.SelectBuilder, filter trip.QueryFilter) squirrel.SelectBuilder {
+	var (
+		exprs []string
+		args  []any
+	)
+
+	for _, point := range []*trip.GeoPoint{filter.Pickup, filter.Dropoff} {
+		if point == nil {
+			continue
+		}
+		exprs = append(exprs, fmt.Sprintf("(SELECT MIN(ST_Distance(route.lat_lon, %s)) FROM (%s) AS route)", geoPoint, routePoints))
+		args = append(args, point.Lon, point.Lat)
+	}
+
+	if len(exprs) == 0 {
+		return builder
+	}
+
+	return builder.OrderByClause(strings.Join(exprs, " + ")+" ASC", args...)
+}
+
 func (s *Store) applyFilterByUser(builder squirrel.SelectBuilder, filter trip.QueryFilterByUser) squirrel.SelectBuilder {
 	if filter.Status != nil {
 		builder = builder.Where(squirrel.Eq{"trip.status": *filter.Status})
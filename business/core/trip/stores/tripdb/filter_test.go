@@ -0,0 +1,56 @@
+//go:build integration
+
+package tripdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryRouteDirection(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	mid := trip.TripLocation{Name: "M", PlaceID: "m", Lat: 25.05, Lon: 121.50}
+
+	forward := f.createTrip(3, testSource, testDestination, mid)
+	f.createTrip(3, testDestination, testSource, mid)
+
+	radius := 1000.0
+	tests := []struct {
+		name    string
+		pickup  trip.TripLocation
+		dropoff trip.TripLocation
+		want    []uuid.UUID
+	}{
+		{"source to destination", testSource, testDestination, []uuid.UUID{forward.ID}},
+		{"waypoint to destination", mid, testDestination, []uuid.UUID{forward.ID}},
+		{"destination to source", testDestination, testSource, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := trip.QueryFilter{
+				DriverID: &f.driverID,
+				Pickup:   &trip.GeoPoint{Lat: tt.pickup.Lat, Lon: tt.pickup.Lon},
+				Dropoff:  &trip.GeoPoint{Lat: tt.dropoff.Lat, Lon: tt.dropoff.Lon},
+				Radius:   &radius,
+			}
+
+			trips, err := f.store.Query(ctx, filter, trip.DefaultOrderBy, 1, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []uuid.UUID
+			for _, trp := range trips {
+				got = append(got, trp.ID)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
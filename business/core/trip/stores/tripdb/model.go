@@ -41,6 +41,7 @@ type dbUserTrip struct {
 	MySourceID           uuid.UUID      `db:"my_source_id"`
 	MyDestinationID      uuid.UUID      `db:"my_destination_id"`
 	MyStatus             string         `db:"my_status"`
+	MyStatusReason       string         `db:"my_status_reason"`
 	DriverID             uuid.UUID      `db:"driver_id"`
 	DriverName           string         `db:"driver_name"`
 	DriverImageURL       string         `db:"driver_image_url"`
@@ -80,6 +81,7 @@ func toCoreUserTrip(dbUserTrip dbUserTrip) trip.UserTrip {
 		MySourceID:           dbUserTrip.MySourceID,
 		MyDestinationID:      dbUserTrip.MyDestinationID,
 		MyStatus:             dbUserTrip.MyStatus,
+		MyStatusReason:       dbUserTrip.MyStatusReason,
 		DriverID:             dbUserTrip.DriverID,
 		DriverName:           dbUserTrip.DriverName,
 		DriverImageURL:       dbUserTrip.DriverImageURL,
@@ -120,6 +122,7 @@ type dbTripPassenger struct {
 	SourceID      uuid.UUID `db:"source_id"`
 	DestinationID uuid.UUID `db:"destination_id"`
 	Status        string    `db:"tp_status"`
+	StatusReason  string    `db:"status_reason"`
 	CreatedAt     time.Time `db:"created_at"`
 }
 
@@ -130,10 +133,23 @@ func toDBTripPassenger(tripPassenger trip.TripPassenger) dbTripPassenger {
 		SourceID:      tripPassenger.SourceID,
 		DestinationID: tripPassenger.DestinationID,
 		Status:        tripPassenger.Status,
+		StatusReason:  tripPassenger.StatusReason,
 		CreatedAt:     tripPassenger.CreatedAt.UTC(),
 	}
 }
 
+func toCoreTripPassenger(dbTripPassenger dbTripPassenger) trip.TripPassenger {
+	return trip.TripPassenger{
+		TripID:        dbTripPassenger.TripID,
+		PassengerID:   dbTripPassenger.PassengerID,
+		SourceID:      dbTripPassenger.SourceID,
+		DestinationID: dbTripPassenger.DestinationID,
+		Status:        dbTripPassenger.Status,
+		StatusReason:  dbTripPassenger.StatusReason,
+		CreatedAt:     dbTripPassenger.CreatedAt.In(time.Local),
+	}
+}
+
 // ------------------------------------------------------------
 type dbTripView struct {
 	ID                   uuid.UUID `db:"id"`
@@ -173,6 +189,10 @@ func toCoreTripView(dbTripView dbTripView) trip.TripView {
 			Lat:     dbMid.Lat,
 			Lon:     dbMid.Lon,
 		}
+		if dbMid.PlannedArrival.Valid {
+			plannedArrival := dbMid.PlannedArrival.Time.In(time.Local)
+			mid[i].PlannedArrival = &plannedArrival
+		}
 	}
 
 	trip := trip.TripView{
@@ -215,21 +235,28 @@ func toCoreTripViewSlice(dbTripViews []dbTripView) []trip.TripView {
 
 // ------------------------------------------------------------
 type dbLocation struct {
-	ID      uuid.UUID `db:"id"`
-	Name    string    `db:"name"`
-	PlaceID string    `db:"place_id"`
-	Lat     float64   `db:"lat"`
-	Lon     float64   `db:"lon"`
+	ID             uuid.UUID    `db:"id"`
+	Name           string       `db:"name"`
+	PlaceID        string       `db:"place_id"`
+	Lat            float64      `db:"lat"`
+	Lon            float64      `db:"lon"`
+	PlannedArrival sql.NullTime `db:"planned_arrival"`
 }
 
 func toDBLocation(location trip.TripLocation) dbLocation {
-	return dbLocation{
+	dbLocation := dbLocation{
 		ID:      location.ID,
 		Name:    location.Name,
 		PlaceID: location.PlaceID,
 		Lat:     location.Lat,
 		Lon:     location.Lon,
 	}
+
+	if location.PlannedArrival != nil {
+		dbLocation.PlannedArrival = sql.NullTime{Time: location.PlannedArrival.UTC(), Valid: true}
+	}
+
+	return dbLocation
 }
 
 func toCoreTripDetails(tripDetails trip.TripDetails) trip.TripDetails {
@@ -257,21 +284,140 @@ func toCoreTripDetails(tripDetails trip.TripDetails) trip.TripDetails {
 }
 
 type dbRating struct {
-	ID          uuid.UUID `db:"id"`
-	TripID      uuid.UUID `db:"trip_id"`
-	CommenterID uuid.UUID `db:"commenter_id"`
-	Comment     string    `db:"comment"`
-	Rating      int       `db:"rating"`
-	CreatedAt   time.Time `db:"created_at"`
+	ID                uuid.UUID `db:"id"`
+	TripID            uuid.UUID `db:"trip_id"`
+	RateeID           uuid.UUID `db:"ratee_id"`
+	CommenterID       uuid.UUID `db:"commenter_id"`
+	CommenterName     string    `db:"commenter_name"`
+	CommenterImageURL string    `db:"commenter_image_url"`
+	Comment           string    `db:"comment"`
+	Rating            int       `db:"rating"`
+	CreatedAt         time.Time `db:"created_at"`
 }
 
 func toDBRating(rating trip.Rating) dbRating {
 	return dbRating{
 		ID:          rating.ID,
 		TripID:      rating.TripID,
+		RateeID:     rating.RateeID,
 		CommenterID: rating.CommenterID,
 		Comment:     rating.Comment,
 		Rating:      rating.Rating,
 		CreatedAt:   rating.CreatedAt.UTC(),
 	}
 }
+
+func toCoreRating(dbRating dbRating) trip.Rating {
+	return trip.Rating{
+		ID:                dbRating.ID,
+		TripID:            dbRating.TripID,
+		RateeID:           dbRating.RateeID,
+		CommenterID:       dbRating.CommenterID,
+		CommenterName:     dbRating.CommenterName,
+		CommenterImageURL: dbRating.CommenterImageURL,
+		Comment:           dbRating.Comment,
+		Rating:            dbRating.Rating,
+		CreatedAt:         dbRating.CreatedAt.In(time.Local),
+	}
+}
+
+func toCoreRatingSlice(dbRatings []dbRating) []trip.Rating {
+	ratings := make([]trip.Rating, len(dbRatings))
+	for i, dbRating := range dbRatings {
+		ratings[i] = toCoreRating(dbRating)
+	}
+	return ratings
+}
+
+// ------------------------------------------------------------
+type dbReputation struct {
+	UserID         uuid.UUID `db:"user_id"`
+	RatingAverage  float64   `db:"rating_average"`
+	RatingCount    int       `db:"rating_count"`
+	CompletedTrips int       `db:"completed_trips"`
+}
+
+func toCoreReputation(dbReputation dbReputation) trip.Reputation {
+	return trip.Reputation{
+		UserID:         dbReputation.UserID,
+		RatingAverage:  dbReputation.RatingAverage,
+		RatingCount:    dbReputation.RatingCount,
+		CompletedTrips: dbReputation.CompletedTrips,
+	}
+}
+
+// ------------------------------------------------------------
+type dbStatusChange struct {
+	ID         uuid.UUID `db:"id"`
+	TripID     uuid.UUID `db:"trip_id"`
+	FromStatus string    `db:"from_status"`
+	ToStatus   string    `db:"to_status"`
+	ChangedBy  uuid.UUID `db:"changed_by"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+func toDBStatusChange(change trip.StatusChange) dbStatusChange {
+	return dbStatusChange{
+		ID:         change.ID,
+		TripID:     change.TripID,
+		FromStatus: change.From,
+		ToStatus:   change.To,
+		ChangedBy:  change.ChangedBy,
+		CreatedAt:  change.CreatedAt.UTC(),
+	}
+}
+
+// ------------------------------------------------------------
+type dbStop struct {
+	ID             uuid.NullUUID `db:"id"`
+	Sequence       int           `db:"sequence"`
+	Kind           string        `db:"kind"`
+	PassengerID    uuid.NullUUID `db:"passenger_id"`
+	LocationID     uuid.UUID     `db:"location_id"`
+	Name           string        `db:"name"`
+	PlaceID        string        `db:"place_id"`
+	Lat            float64       `db:"lat"`
+	Lon            float64       `db:"lon"`
+	PlannedArrival sql.NullTime  `db:"planned_arrival"`
+	Distance       float64       `db:"distance"`
+}
+
+func toCoreStop(dbStop dbStop) trip.Stop {
+	stop := trip.Stop{
+		ID:       dbStop.ID.UUID,
+		Sequence: dbStop.Sequence,
+		Kind:     dbStop.Kind,
+		Location: trip.TripLocation{
+			ID:      dbStop.LocationID,
+			Name:    dbStop.Name,
+			PlaceID: dbStop.PlaceID,
+			Lat:     dbStop.Lat,
+			Lon:     dbStop.Lon,
+		},
+		Distance: dbStop.Distance,
+	}
+
+	if dbStop.PassengerID.Valid {
+		passengerID := dbStop.PassengerID.UUID
+		stop.PassengerID = &passengerID
+	}
+
+	if dbStop.PlannedArrival.Valid {
+		plannedArrival := dbStop.PlannedArrival.Time.In(time.Local)
+		stop.PlannedArrival = &plannedArrival
+		stop.Location.PlannedArrival = &plannedArrival
+	}
+
+	return stop
+}
+
+// toCoreStopSlice converts the stops in itinerary order and numbers them
+// from zero, since the stored sequences can have gaps.
+func toCoreStopSlice(dbStops []dbStop) []trip.Stop {
+	stops := make([]trip.Stop, len(dbStops))
+	for i, dbStop := range dbStops {
+		stops[i] = toCoreStop(dbStop)
+		stops[i].Sequence = i
+	}
+	return stops
+}
@@ -0,0 +1,183 @@
+package tripdb
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/sys/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// routeQuery selects the itinerary of the trip $1 in order: the source, the
+// waypoints and passenger stops of trip_location, then the destination. Every
+// stop carries the distance travelled along the route from the source.
+const routeQuery = `
+SELECT id, sequence, kind, passenger_id, location_id, name, place_id,
+	ST_Y(lat_lon::geometry) AS lat,
+	ST_X(lat_lon::geometry) AS lon,
+	planned_arrival,
+	COALESCE(SUM(ST_Distance(previous_lat_lon, lat_lon)) OVER (ORDER BY sequence), 0) AS distance
+FROM (
+	SELECT stops.*, locations.name, locations.place_id, locations.lat_lon,
+		LAG(locations.lat_lon) OVER (ORDER BY stops.sequence) AS previous_lat_lon
+	FROM (
+		SELECT NULL::UUID AS id, -1 AS sequence, 'source' AS kind, NULL::UUID AS passenger_id,
+			trip.source_id AS location_id, trip.start_time AS planned_arrival
+		FROM trip WHERE trip.id = $1
+		UNION ALL
+		SELECT trip_location.id, trip_location.sequence, trip_location.kind, trip_location.passenger_id,
+			trip_location.location_id, trip_location.planned_arrival
+		FROM trip_location WHERE trip_location.trip_id = $1
+		UNION ALL
+		SELECT NULL::UUID, 2147483647, 'destination', NULL::UUID,
+			trip.destination_id, NULL::TIMESTAMP
+		FROM trip WHERE trip.id = $1
+	) AS stops
+	JOIN locations ON locations.id = stops.location_id
+) AS legs
+ORDER BY sequence`
+
+// QueryRoute retrieves the ordered itinerary of the trip.
+func (s *Store) QueryRoute(ctx context.Context, tripID uuid.UUID) (trip.Route, error) {
+	var dbStops []dbStop
+	if err := database.QueryContext(ctx, s.log, s.db, routeQuery, []any{tripID}, &dbStops); err != nil {
+		return trip.Route{}, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	if len(dbStops) == 0 {
+		return trip.Route{}, trip.ErrNotFound
+	}
+
+	stops := toCoreStopSlice(dbStops)
+
+	route := trip.Route{
+		TripID:        tripID,
+		Stops:         stops,
+		TotalDistance: stops[len(stops)-1].Distance,
+	}
+
+	return route, nil
+}
+
+// mergePassengerStops places the pickup and drop-off points of an accepted
+// passenger into the ordered stops of the trip and renumbers the stops.
+func (s *Store) mergePassengerStops(ctx context.Context, tx *sqlx.Tx, tripID uuid.UUID, passengerID uuid.UUID) error {
+	// drop the stops planned for an earlier accept of the same passenger
+	sql, args, err := sq.
+		Delete("trip_location").
+		Where(sq.Eq{"trip_id": tripID}).
+		Where(sq.Eq{"passenger_id": passengerID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	var dbStops []dbStop
+	if err := tx.SelectContext(ctx, &dbStops, routeQuery, tripID); err != nil {
+		return fmt.Errorf("selectcontext: %w", err)
+	}
+
+	sql, args, err = sq.Select(
+		"pickup.id AS pickup_id",
+		"ST_Y(pickup.lat_lon::geometry) AS pickup_lat",
+		"ST_X(pickup.lat_lon::geometry) AS pickup_lon",
+		"dropoff.id AS dropoff_id",
+		"ST_Y(dropoff.lat_lon::geometry) AS dropoff_lat",
+		"ST_X(dropoff.lat_lon::geometry) AS dropoff_lon",
+	).
+		From("trip_passenger").
+		Join("locations AS pickup ON trip_passenger.source_id = pickup.id").
+		Join("locations AS dropoff ON trip_passenger.destination_id = dropoff.id").
+		Where(sq.Eq{"trip_passenger.trip_id": tripID}).
+		Where(sq.Eq{"trip_passenger.passenger_id": passengerID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	var points struct {
+		PickupID   uuid.UUID `db:"pickup_id"`
+		PickupLat  float64   `db:"pickup_lat"`
+		PickupLon  float64   `db:"pickup_lon"`
+		DropoffID  uuid.UUID `db:"dropoff_id"`
+		DropoffLat float64   `db:"dropoff_lat"`
+		DropoffLon float64   `db:"dropoff_lon"`
+	}
+	if err := tx.GetContext(ctx, &points, sql, args...); err != nil {
+		return fmt.Errorf("getcontext: %w", err)
+	}
+
+	pickup := trip.Stop{
+		ID:          uuid.New(),
+		Kind:        trip.StopKindPickup,
+		PassengerID: &passengerID,
+		Location:    trip.TripLocation{ID: points.PickupID, Lat: points.PickupLat, Lon: points.PickupLon},
+	}
+
+	dropoff := trip.Stop{
+		ID:          uuid.New(),
+		Kind:        trip.StopKindDropoff,
+		PassengerID: &passengerID,
+		Location:    trip.TripLocation{ID: points.DropoffID, Lat: points.DropoffLat, Lon: points.DropoffLon},
+	}
+
+	stops := trip.PlanPassengerStops(toCoreStopSlice(dbStops), pickup, dropoff)
+
+	// the source and the destination are not stored in trip_location
+	for _, stop := range stops[1 : len(stops)-1] {
+		switch stop.ID {
+		case pickup.ID, dropoff.ID:
+			sql, args, err = sq.
+				Insert("trip_location").
+				Columns("id", "trip_id", "location_id", "sequence", "kind", "passenger_id").
+				Values(stop.ID, tripID, stop.Location.ID, stop.Sequence, stop.Kind, passengerID).
+				PlaceholderFormat(sq.Dollar).
+				ToSql()
+		default:
+			sql, args, err = sq.
+				Update("trip_location").
+				Set("sequence", stop.Sequence).
+				Where(sq.Eq{"id": stop.ID}).
+				PlaceholderFormat(sq.Dollar).
+				ToSql()
+		}
+		if err != nil {
+			return fmt.Errorf("tosql: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, sql, args...); err != nil {
+			return fmt.Errorf("execcontext: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// removePassengerStops removes the pickup and drop-off points of a passenger
+// from the stops of the trip, within the transaction of the status change.
+func (s *Store) removePassengerStops(ctx context.Context, tx *sqlx.Tx, tripID uuid.UUID, passengerID uuid.UUID) error {
+	sql, args, err := sq.
+		Delete("trip_location").
+		Where(sq.Eq{"trip_id": tripID}).
+		Where(sq.Eq{"passenger_id": passengerID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	return nil
+}
@@ -9,7 +9,6 @@ import (
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/TSMC-Uber/server/business/core/trip"
-	"github.com/TSMC-Uber/server/business/core/user"
 	"github.com/TSMC-Uber/server/business/data/order"
 	"github.com/TSMC-Uber/server/business/sys/database"
 	"github.com/TSMC-Uber/server/foundation/logger"
@@ -56,7 +55,7 @@ func insertLocationAndGetID(ctx context.Context, tx *sql.Tx, loc dbLocation) (uu
 }
 
 // Create inserts a new trip into the database.
-func (s *Store) Create(ctx context.Context, trip trip.Trip) error {
+func (s *Store) Create(ctx context.Context, trp trip.Trip) error {
 	// Start a transaction
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -75,12 +74,12 @@ func (s *Store) Create(ctx context.Context, trip trip.Trip) error {
 		}
 	}()
 	// Insert source and destination locations and get their IDs
-	sourceID, err := insertLocationAndGetID(ctx, tx, toDBLocation(trip.Source))
+	sourceID, err := insertLocationAndGetID(ctx, tx, toDBLocation(trp.Source))
 	if err != nil {
 		return fmt.Errorf("insert source: %w", err)
 	}
 
-	destinationID, err := insertLocationAndGetID(ctx, tx, toDBLocation(trip.Destination))
+	destinationID, err := insertLocationAndGetID(ctx, tx, toDBLocation(trp.Destination))
 	if err != nil {
 		return fmt.Errorf("insert destination: %w", err)
 	}
@@ -89,7 +88,7 @@ func (s *Store) Create(ctx context.Context, trip trip.Trip) error {
 	sql, args, err := sq.
 		Insert("trip").
 		Columns("id", "driver_id", "passenger_limit", "source_id", "destination_id", "status", "start_time", "created_at", "updated_at").
-		Values(trip.ID, trip.DriverID, trip.PassengerLimit, sourceID, destinationID, trip.Status, trip.StartTime, trip.CreatedAt, trip.UpdatedAt).
+		Values(trp.ID, trp.DriverID, trp.PassengerLimit, sourceID, destinationID, trp.Status, trp.StartTime, trp.CreatedAt, trp.UpdatedAt).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
 	if err != nil {
@@ -103,7 +102,7 @@ func (s *Store) Create(ctx context.Context, trip trip.Trip) error {
 	sql, args, err = sq.
 		Insert("trip_passenger").
 		Columns("trip_id", "passenger_id", "source_id", "destination_id", "status", "created_at", "roles").
-		Values(trip.ID, trip.DriverID, sourceID, destinationID, "pending", trip.CreatedAt, tripDriverRole).
+		Values(trp.ID, trp.DriverID, sourceID, destinationID, "pending", trp.CreatedAt, tripDriverRole).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
 	if err != nil {
@@ -114,18 +113,19 @@ func (s *Store) Create(ctx context.Context, trip trip.Trip) error {
 		return fmt.Errorf("execcontext:create trip_passenger: %w", err)
 	}
 
-	// Insert mid locations and associate them with the trip
-	for _, mid := range trip.Mid {
-		midID, err := insertLocationAndGetID(ctx, tx, toDBLocation(mid))
+	// Insert mid locations and associate them with the trip in order
+	for i, mid := range trp.Mid {
+		dbMid := toDBLocation(mid)
+		midID, err := insertLocationAndGetID(ctx, tx, dbMid)
 		if err != nil {
 			return fmt.Errorf("insert mid: %w", err)
 		}
 
-		// Insert into trip_location table
+		// Insert into trip_location table, the source is stop 0
 		sql, args, err := sq.
 			Insert("trip_location").
-			Columns("trip_id", "location_id").
-			Values(trip.ID, midID).
+			Columns("trip_id", "location_id", "sequence", "kind", "planned_arrival").
+			Values(trp.ID, midID, i+1, trip.StopKindWaypoint, dbMid.PlannedArrival).
 			PlaceholderFormat(sq.Dollar).
 			ToSql()
 		if err != nil {
@@ -139,9 +139,28 @@ func (s *Store) Create(ctx context.Context, trip trip.Trip) error {
 	return nil
 }
 
-// Update replaces a trip document in the database.
-func (s *Store) Update(ctx context.Context, trip trip.Trip) error {
-	dbTrip := toDBTrip(trip)
+// Update replaces a trip document in the database. The passenger limit is
+// checked against the accepted passengers while the trip is locked, see
+// lockPassengerLimit.
+func (s *Store) Update(ctx context.Context, trp trip.Trip) (err error) {
+	dbTrip := toDBTrip(trp)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if err := s.lockPassengerLimit(ctx, tx, dbTrip.ID, dbTrip.PassengerLimit); err != nil {
+		return err
+	}
 
 	sql, args, err := sq.
 		Update("trip").
@@ -157,14 +176,147 @@ func (s *Store) Update(ctx context.Context, trip trip.Trip) error {
 		return fmt.Errorf("tosql: %w", err)
 	}
 
-	// execute the sql
-	if err := database.ExecContext(ctx, s.log, s.db, sql, args); err != nil {
-		if errors.Is(err, database.ErrDBDuplicatedEntry) {
-			return user.ErrUniqueEmail
+	if _, err := tx.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	return nil
+}
+
+// lockPassengerLimit locks the trip row until the transaction ends and checks
+// the passenger limit leaves a seat for every accepted passenger, so no
+// passenger is accepted meanwhile, see AcceptPassenger.
+func (s *Store) lockPassengerLimit(ctx context.Context, tx *sqlx.Tx, tripID uuid.UUID, passengerLimit int) error {
+	sql, args, err := sq.
+		Select("id").
+		From("trip").
+		Where(sq.Eq{"id": tripID}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	var id uuid.UUID
+	if err := tx.GetContext(ctx, &id, sql, args...); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return trip.ErrNotFound
+		}
+		return fmt.Errorf("getcontext: %w", err)
+	}
+
+	// the driver row does not take a seat
+	sql, args, err = sq.
+		Select("COUNT(*)").
+		From("trip_passenger").
+		Where(sq.Eq{"trip_id": tripID}).
+		Where(sq.Eq{"status": trip.StatusAccepted}).
+		Where(sq.NotEq{"roles": tripDriverRole}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	var accepted int
+	if err := tx.GetContext(ctx, &accepted, sql, args...); err != nil {
+		return fmt.Errorf("getcontext: %w", err)
+	}
+
+	if accepted > passengerLimit {
+		return trip.ErrPassengerLimitTooLow
+	}
+
+	return nil
+}
+
+// UpdateStatus moves a trip to a new status and records the change in the
+// status history. Cancelling a trip cancels every passenger of the trip.
+func (s *Store) UpdateStatus(ctx context.Context, trp trip.Trip, change trip.StatusChange) (err error) {
+	dbTrip := toDBTrip(trp)
+	dbChange := toDBStatusChange(change)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
 		}
+		err = tx.Commit()
+	}()
+
+	if err := s.lockPassengerLimit(ctx, tx, dbTrip.ID, dbTrip.PassengerLimit); err != nil {
+		return err
+	}
+
+	// only move the trip if nobody changed its status in the meantime
+	sql, args, err := sq.
+		Update("trip").
+		Set("passenger_limit", dbTrip.PassengerLimit).
+		Set("status", dbChange.ToStatus).
+		Set("start_time", dbTrip.StartTime).
+		Set("updated_at", dbTrip.UpdatedAt).
+		Where(sq.Eq{"id": dbTrip.ID}).
+		Where(sq.Eq{"status": dbChange.FromStatus}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rowsaffected: %w", err)
+	}
+	if rows == 0 {
+		return &trip.StatusTransitionError{From: dbChange.FromStatus, To: dbChange.ToStatus}
+	}
+
+	sql, args, err = sq.
+		Insert("trip_status_history").
+		Columns("id", "trip_id", "from_status", "to_status", "changed_by", "created_at").
+		Values(dbChange.ID, dbChange.TripID, dbChange.FromStatus, dbChange.ToStatus, dbChange.ChangedBy, dbChange.CreatedAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, sql, args...); err != nil {
 		return fmt.Errorf("execcontext: %w", err)
 	}
 
+	// only the passengers still on the trip are cancelled, the ones who left
+	// keep the status and reason they left with
+	if dbChange.ToStatus == trip.TripStatusCancelled {
+		sql, args, err = sq.
+			Update("trip_passenger").
+			Set("status", trip.StatusCancelled).
+			Set("status_reason", "trip cancelled").
+			Where(sq.Eq{"trip_id": dbTrip.ID}).
+			Where(sq.Eq{"status": []string{trip.StatusPending, trip.StatusAccepted}}).
+			Where(sq.NotEq{"roles": tripDriverRole}).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("tosql: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, sql, args...); err != nil {
+			return fmt.Errorf("execcontext: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -217,6 +369,7 @@ func (s *Store) Query(ctx context.Context, filter trip.QueryFilter, orderBy orde
 	).From("trip_view")
 
 	builder = s.applyFilter(builder, filter)
+	builder = s.applyDetourOrder(builder, filter)
 
 	orderByClause, err := orderByClause(orderBy)
 	if err != nil {
@@ -228,13 +381,13 @@ func (s *Store) Query(ctx context.Context, filter trip.QueryFilter, orderBy orde
 	builder = builder.Limit(uint64(rowsPerPage)).Offset(uint64((pageNumber - 1) * rowsPerPage))
 
 	// Convert the builder to SQL and args
-	sql, _, err := builder.ToSql()
+	sql, args, err := builder.PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("tosql: %w", err)
 	}
 
 	var dbTripViews []dbTripView
-	if err := database.QueryContext(ctx, s.log, s.db, sql, nil, &dbTripViews); err != nil {
+	if err := database.QueryContext(ctx, s.log, s.db, sql, args, &dbTripViews); err != nil {
 		return nil, fmt.Errorf("namedqueryslice: %w", err)
 	}
 
@@ -248,6 +401,7 @@ func (s *Store) QueryMyTrip(ctx context.Context, userID uuid.UUID, filter trip.Q
 		"trip_passenger.source_id AS my_source_id",
 		"trip_passenger.destination_id AS my_destination_id",
 		"trip_passenger.status AS my_status",
+		"COALESCE(trip_passenger.status_reason, '') AS my_status_reason",
 		"trip.id AS trip_id",
 		"trip.driver_id",
 		"driver.name AS driver_name",
@@ -312,42 +466,181 @@ func (s *Store) Join(ctx context.Context, tripPassenger trip.TripPassenger) erro
 
 	// execute the sql
 	if err := database.ExecContext(ctx, s.log, s.db, sql, args); err != nil {
+		if errors.Is(err, database.ErrDBDuplicatedEntry) {
+			return trip.ErrAlreadyJoined
+		}
 		return fmt.Errorf("execcontext: %w", err)
 	}
 
 	return nil
 }
 
-func (s *Store) UpdatePassengerStatus(ctx context.Context, tripPassenger trip.TripPassenger) error {
+// UpdatePassengerStatus moves a passenger to a new status. With from
+// statuses only a passenger in one of them is moved, it fails with
+// ErrPassengerNotActive otherwise. The stops of a passenger who is no longer
+// accepted are removed in the same transaction.
+func (s *Store) UpdatePassengerStatus(ctx context.Context, tripPassenger trip.TripPassenger, from []string) (err error) {
 	dbTripPassenger := toDBTripPassenger(tripPassenger)
-	sql, args, err := sq.
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	builder := sq.
 		Update("trip_passenger").
 		Set("status", dbTripPassenger.Status).
+		Set("status_reason", dbTripPassenger.StatusReason).
 		Where(sq.Eq{"trip_id": dbTripPassenger.TripID}).
 		Where(sq.Eq{"passenger_id": dbTripPassenger.PassengerID}).
+		Where(sq.Eq{"roles": tripPassengerRole})
+
+	if len(from) > 0 {
+		builder = builder.Where(sq.Eq{"status": from})
+	}
+
+	sql, args, err := builder.
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rowsaffected: %w", err)
+	}
+	if rows == 0 {
+		if len(from) > 0 {
+			return trip.ErrPassengerNotActive
+		}
+		return trip.ErrPassengerNotFound
+	}
+
+	// only accepted passengers have stops on the route
+	if dbTripPassenger.Status != trip.StatusAccepted {
+		if err := s.removePassengerStops(ctx, tx, dbTripPassenger.TripID, dbTripPassenger.PassengerID); err != nil {
+			return fmt.Errorf("removepassengerstops: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// AcceptPassenger accepts a passenger request while holding a lock on the trip
+// row, so concurrent accepts cannot take more seats than the passenger limit.
+func (s *Store) AcceptPassenger(ctx context.Context, tripPassenger trip.TripPassenger) (err error) {
+	dbTripPassenger := toDBTripPassenger(tripPassenger)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	// lock the trip row until the transaction ends
+	sql, args, err := sq.
+		Select("passenger_limit").
+		From("trip").
+		Where(sq.Eq{"id": dbTripPassenger.TripID}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	var passengerLimit int
+	if err := tx.GetContext(ctx, &passengerLimit, sql, args...); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return trip.ErrNotFound
+		}
+		return fmt.Errorf("getcontext: %w", err)
+	}
+
+	// count the seats already taken, the driver row does not take a seat
+	sql, args, err = sq.
+		Select("COUNT(*)").
+		From("trip_passenger").
+		Where(sq.Eq{"trip_id": dbTripPassenger.TripID}).
+		Where(sq.Eq{"status": trip.StatusAccepted}).
+		Where(sq.NotEq{"roles": tripDriverRole}).
+		Where(sq.NotEq{"passenger_id": dbTripPassenger.PassengerID}).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	var accepted int
+	if err := tx.GetContext(ctx, &accepted, sql, args...); err != nil {
+		return fmt.Errorf("getcontext: %w", err)
+	}
+
+	if accepted >= passengerLimit {
+		return trip.ErrTripFull
+	}
 
+	sql, args, err = sq.
+		Update("trip_passenger").
+		Set("status", trip.StatusAccepted).
+		Where(sq.Eq{"trip_id": dbTripPassenger.TripID}).
+		Where(sq.Eq{"passenger_id": dbTripPassenger.PassengerID}).
+		Where(sq.Eq{"roles": tripPassengerRole}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
 	if err != nil {
 		return fmt.Errorf("tosql: %w", err)
 	}
 
-	// execute the sql
-	if err := database.ExecContext(ctx, s.log, s.db, sql, args); err != nil {
+	result, err := tx.ExecContext(ctx, sql, args...)
+	if err != nil {
 		return fmt.Errorf("execcontext: %w", err)
 	}
 
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rowsaffected: %w", err)
+	}
+	if rows == 0 {
+		return trip.ErrPassengerNotFound
+	}
+
+	if err := s.mergePassengerStops(ctx, tx, dbTripPassenger.TripID, dbTripPassenger.PassengerID); err != nil {
+		return fmt.Errorf("mergepassengerstops: %w", err)
+	}
+
 	return nil
 }
 
 // // Count returns the total number of trips in the DB.
 func (s *Store) Count(ctx context.Context, filter trip.QueryFilter) (int, error) {
-	builder := sq.Select("COUNT(*) AS count").From("trip")
+	builder := sq.Select("COUNT(*) AS count").From("trip_view")
 
 	builder = s.applyFilter(builder, filter)
 
 	// Convert the builder to SQL and args
-	sql, _, err := builder.ToSql()
+	sql, args, err := builder.PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
 		return 0, fmt.Errorf("tosql: %w", err)
 	}
@@ -355,7 +648,7 @@ func (s *Store) Count(ctx context.Context, filter trip.QueryFilter) (int, error)
 	var count struct {
 		Count int `db:"count"`
 	}
-	if err := database.GetContext(ctx, s.log, s.db, sql, nil, &count); err != nil {
+	if err := database.GetContext(ctx, s.log, s.db, sql, args, &count); err != nil {
 		return 0, fmt.Errorf("namedquerystruct: %w", err)
 	}
 
@@ -412,9 +705,12 @@ func (s *Store) QueryByID(ctx context.Context, tripID uuid.UUID) (trip.TripView,
 		"locations.place_id",
 		"ST_Y(locations.lat_lon::geometry) AS lat",
 		"ST_X(locations.lat_lon::geometry) AS lon",
+		"trip_location.planned_arrival",
 	).From("trip_location").
 		Join("locations ON trip_location.location_id = locations.id").
 		Where(sq.Eq{"trip_location.trip_id": tripID}).
+		Where(sq.Eq{"trip_location.kind": trip.StopKindWaypoint}).
+		OrderBy("trip_location.sequence").
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
 
@@ -433,10 +729,44 @@ func (s *Store) QueryByID(ctx context.Context, tripID uuid.UUID) (trip.TripView,
 	return toCoreTripView(dbTrip), nil
 }
 
+// QueryPassenger gets a passenger of the specified trip from the database.
+func (s *Store) QueryPassenger(ctx context.Context, tripID uuid.UUID, passengerID uuid.UUID) (trip.TripPassenger, error) {
+	sql, args, err := sq.Select(
+		"trip_id",
+		"passenger_id",
+		"source_id",
+		"destination_id",
+		"status AS tp_status",
+		"COALESCE(status_reason, '') AS status_reason",
+		"created_at",
+	).
+		From("trip_passenger").
+		Where(sq.Eq{"trip_id": tripID}).
+		Where(sq.Eq{"passenger_id": passengerID}).
+		Where(sq.Eq{"roles": tripPassengerRole}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+
+	if err != nil {
+		return trip.TripPassenger{}, fmt.Errorf("tosql: %w", err)
+	}
+
+	var dbTripPassenger dbTripPassenger
+	if err := database.GetContext(ctx, s.log, s.db, sql, args, &dbTripPassenger); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return trip.TripPassenger{}, fmt.Errorf("namedquerystruct: %w", trip.ErrPassengerNotFound)
+		}
+		return trip.TripPassenger{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreTripPassenger(dbTripPassenger), nil
+}
+
 func (s *Store) QueryPassengers(ctx context.Context, tripID uuid.UUID) (trip.TripDetails, error) {
 	sql, args, err := sq.Select(
 		"trip_id",
-		"passenger_id", "passenger_name", "passenger_image_url", "passenger_status",
+		"passenger_id", "passenger_name", "passenger_image_url", "passenger_status", "COALESCE(passenger_status_reason, '') AS passenger_status_reason",
+		"passenger_reputation_view.rating_average", "passenger_reputation_view.rating_count", "passenger_reputation_view.completed_trips",
 		"driver_id", "driver_name", "driver_image_url", "driver_brand", "driver_model", "driver_color", "driver_plate",
 		"source_name", "source_place_id", "ST_Y(source_lat_lon::geometry) AS source_latitude", "ST_X(source_lat_lon::geometry) AS source_longitude",
 		"destination_name", "destination_place_id", "ST_Y(destination_lat_lon::geometry) AS destination_latitude", "ST_X(destination_lat_lon::geometry) AS destination_longitude",
@@ -444,6 +774,7 @@ func (s *Store) QueryPassengers(ctx context.Context, tripID uuid.UUID) (trip.Tri
 		"passenger_location_destination_name", "passenger_location_destination_place_id", "ST_Y(passenger_location_destination_lat_lon::geometry) AS passenger_location_destination_latitude", "ST_X(passenger_location_destination_lat_lon::geometry) AS passenger_location_destination_longitude",
 	).
 		From("trip_passenger_view").
+		Join("passenger_reputation_view ON passenger_reputation_view.user_id = trip_passenger_view.passenger_id").
 		Where(sq.Eq{"trip_id": tripID}).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
@@ -455,7 +786,7 @@ func (s *Store) QueryPassengers(ctx context.Context, tripID uuid.UUID) (trip.Tri
 	// Execute the query
 	rows, err := s.db.QueryContext(ctx, sql, args...)
 	if err != nil {
-		panic(err)
+		return trip.TripDetails{}, fmt.Errorf("querycontext: %w", err)
 	}
 	defer rows.Close()
 
@@ -465,7 +796,8 @@ func (s *Store) QueryPassengers(ctx context.Context, tripID uuid.UUID) (trip.Tri
 		var passenger trip.PassengerDetails
 		if err := rows.Scan(
 			&ttrip.TripID,
-			&passenger.PassengerID, &passenger.PassengerName, &passenger.PassengerImageURL, &passenger.PassengerStatus,
+			&passenger.PassengerID, &passenger.PassengerName, &passenger.PassengerImageURL, &passenger.PassengerStatus, &passenger.PassengerReason,
+			&passenger.PassengerReputation.RatingAverage, &passenger.PassengerReputation.RatingCount, &passenger.PassengerReputation.CompletedTrips,
 			&ttrip.DriverID, &ttrip.DriverName, &ttrip.DriverImageURL, &ttrip.DriverBrand, &ttrip.DriverModel, &ttrip.DriverColor, &ttrip.DriverPlate,
 			&ttrip.SourceName, &ttrip.SourcePlaceID, &ttrip.SourceLatitude, &ttrip.SourceLongitude,
 			&ttrip.DestinationName, &ttrip.DestinationPlaceID, &ttrip.DestinationLatitude, &ttrip.DestinationLongitude,
@@ -474,6 +806,7 @@ func (s *Store) QueryPassengers(ctx context.Context, tripID uuid.UUID) (trip.Tri
 			return trip.TripDetails{}, fmt.Errorf("scan: %w", err)
 		}
 
+		passenger.PassengerReputation.UserID = passenger.PassengerID
 		ttrip.PassengerDetails = append(ttrip.PassengerDetails, passenger)
 	}
 
@@ -489,8 +822,8 @@ func (s *Store) CreateRating(ctx context.Context, rating trip.Rating) error {
 
 	sql, args, err := sq.
 		Insert("rating").
-		Columns("id", "trip_id", "commenter_id", "comment", "rating", "created_at").
-		Values(dbRating.ID, dbRating.TripID, dbRating.CommenterID, dbRating.Comment, dbRating.Rating, dbRating.CreatedAt).
+		Columns("id", "trip_id", "ratee_id", "commenter_id", "comment", "rating", "created_at").
+		Values(dbRating.ID, dbRating.TripID, dbRating.RateeID, dbRating.CommenterID, dbRating.Comment, dbRating.Rating, dbRating.CreatedAt).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
 
@@ -500,8 +833,113 @@ func (s *Store) CreateRating(ctx context.Context, rating trip.Rating) error {
 
 	// execute the sql
 	if err := database.ExecContext(ctx, s.log, s.db, sql, args); err != nil {
+		if errors.Is(err, database.ErrDBDuplicatedEntry) {
+			return trip.ErrAlreadyRated
+		}
 		return fmt.Errorf("execcontext: %w", err)
 	}
 
 	return nil
 }
+
+// ratingColumns are the columns selected when reading ratings.
+var ratingColumns = []string{
+	"rating.id",
+	"rating.trip_id",
+	"rating.ratee_id",
+	"rating.commenter_id",
+	"commenter.name AS commenter_name",
+	"COALESCE(commenter.image_url, '') AS commenter_image_url",
+	"COALESCE(rating.comment, '') AS comment",
+	"rating.rating",
+	"rating.created_at",
+}
+
+// QueryRatings retrieves the ratings of a trip from the database.
+func (s *Store) QueryRatings(ctx context.Context, tripID uuid.UUID) ([]trip.Rating, error) {
+	sql, args, err := sq.Select(ratingColumns...).
+		From("rating").
+		Join("users AS commenter ON rating.commenter_id = commenter.id").
+		Where(sq.Eq{"rating.trip_id": tripID}).
+		OrderBy("rating.created_at DESC").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("tosql: %w", err)
+	}
+
+	var dbRatings []dbRating
+	if err := database.QueryContext(ctx, s.log, s.db, sql, args, &dbRatings); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreRatingSlice(dbRatings), nil
+}
+
+// QueryRatingsByDriver retrieves the ratings of the trips of a driver from
+// the database.
+func (s *Store) QueryRatingsByDriver(ctx context.Context, driverID uuid.UUID, pageNumber int, rowsPerPage int) ([]trip.Rating, error) {
+	sql, args, err := sq.Select(ratingColumns...).
+		From("rating").
+		Join("trip ON rating.trip_id = trip.id").
+		Join("users AS commenter ON rating.commenter_id = commenter.id").
+		Where(sq.Eq{"rating.ratee_id": driverID}).
+		Where("rating.ratee_id = trip.driver_id").
+		OrderBy("rating.created_at DESC").
+		Limit(uint64(rowsPerPage)).
+		Offset(uint64((pageNumber - 1) * rowsPerPage)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("tosql: %w", err)
+	}
+
+	var dbRatings []dbRating
+	if err := database.QueryContext(ctx, s.log, s.db, sql, args, &dbRatings); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreRatingSlice(dbRatings), nil
+}
+
+// CountRatingsByDriver returns the total number of ratings of a driver.
+func (s *Store) CountRatingsByDriver(ctx context.Context, driverID uuid.UUID) (int, error) {
+	sql, args, err := sq.Select("COUNT(*) AS count").
+		From("rating").
+		Join("trip ON rating.trip_id = trip.id").
+		Where(sq.Eq{"rating.ratee_id": driverID}).
+		Where("rating.ratee_id = trip.driver_id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("tosql: %w", err)
+	}
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := database.GetContext(ctx, s.log, s.db, sql, args, &count); err != nil {
+		return 0, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+// QueryReputation retrieves the reputation of a user as a passenger.
+func (s *Store) QueryReputation(ctx context.Context, userID uuid.UUID) (trip.Reputation, error) {
+	sql, args, err := sq.Select("user_id", "rating_average", "rating_count", "completed_trips").
+		From("passenger_reputation_view").
+		Where(sq.Eq{"user_id": userID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return trip.Reputation{}, fmt.Errorf("tosql: %w", err)
+	}
+
+	var dbReputation dbReputation
+	if err := database.GetContext(ctx, s.log, s.db, sql, args, &dbReputation); err != nil {
+		return trip.Reputation{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreReputation(dbReputation), nil
+}
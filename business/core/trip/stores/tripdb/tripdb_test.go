@@ -0,0 +1,288 @@
+//go:build integration
+
+package tripdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/sys/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// fixture is a store on the migrated database named by TEST_DATABASE_URL
+// with a driver. The trips of the driver and the users created are removed
+// once the test is done.
+type fixture struct {
+	t        *testing.T
+	db       *sqlx.DB
+	store    *Store
+	driverID uuid.UUID
+	users    []uuid.UUID
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db, err := sqlx.Open("pgx", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.StatusCheck(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	f := fixture{
+		t:     t,
+		db:    db,
+		store: NewStore(nil, db),
+	}
+	t.Cleanup(f.cleanup)
+
+	f.driverID = f.createUser()
+	f.exec(`INSERT INTO driver (user_id, license, brand, model, color, plate) VALUES ($1, 'license', 'brand', 'model', 'color', 'plate')`, f.driverID)
+
+	return &f
+}
+
+func (f *fixture) exec(query string, args ...any) {
+	f.t.Helper()
+
+	if _, err := f.db.ExecContext(context.Background(), query, args...); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// createUser inserts a user and returns its id.
+func (f *fixture) createUser() uuid.UUID {
+	f.t.Helper()
+
+	id := uuid.New()
+	f.exec(`INSERT INTO users (id, name, email, image_url) VALUES ($1, 'user', $2, '')`, id, id.String()+"@example.com")
+	f.users = append(f.users, id)
+
+	return id
+}
+
+// createTrip stores a trip of the driver from the source to the destination
+// through the mid locations.
+func (f *fixture) createTrip(passengerLimit int, source trip.TripLocation, destination trip.TripLocation, mid ...trip.TripLocation) trip.TripView {
+	f.t.Helper()
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	trp := trip.Trip{
+		ID:             uuid.New(),
+		DriverID:       f.driverID,
+		PassengerLimit: passengerLimit,
+		Source:         source,
+		Destination:    destination,
+		Mid:            mid,
+		Status:         trip.TripStatusNotStarted,
+		StartTime:      now.Add(time.Hour),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := f.store.Create(ctx, trp); err != nil {
+		f.t.Fatal(err)
+	}
+
+	view, err := f.store.QueryByID(ctx, trp.ID)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+
+	return view
+}
+
+// join adds a new user to the trip as a passenger in the status, riding from
+// the source to the destination of the trip.
+func (f *fixture) join(trp trip.TripView, status string) uuid.UUID {
+	f.t.Helper()
+	ctx := context.Background()
+
+	passengerID := f.createUser()
+	tripPassenger := trip.TripPassenger{
+		TripID:        trp.ID,
+		PassengerID:   passengerID,
+		SourceID:      trp.SourceID,
+		DestinationID: trp.DestinationID,
+		Status:        trip.StatusPending,
+		CreatedAt:     time.Now().UTC(),
+	}
+	if err := f.store.Join(ctx, tripPassenger); err != nil {
+		f.t.Fatal(err)
+	}
+
+	switch status {
+	case trip.StatusPending:
+	case trip.StatusAccepted:
+		if err := f.store.AcceptPassenger(ctx, tripPassenger); err != nil {
+			f.t.Fatal(err)
+		}
+	default:
+		f.exec(`UPDATE trip_passenger SET status = $1 WHERE trip_id = $2 AND passenger_id = $3`, status, trp.ID, passengerID)
+	}
+
+	return passengerID
+}
+
+// passengerStatus returns the status of the user on the trip.
+func (f *fixture) passengerStatus(tripID uuid.UUID, userID uuid.UUID) string {
+	f.t.Helper()
+
+	var status string
+	if err := f.db.GetContext(context.Background(), &status, `SELECT status FROM trip_passenger WHERE trip_id = $1 AND passenger_id = $2`, tripID, userID); err != nil {
+		f.t.Fatal(err)
+	}
+
+	return status
+}
+
+func (f *fixture) cleanup() {
+	ctx := context.Background()
+
+	trips := `SELECT id FROM trip WHERE driver_id = $1`
+	for _, q := range []string{
+		`DELETE FROM trip_location WHERE trip_id IN (` + trips + `)`,
+		`DELETE FROM trip_passenger WHERE trip_id IN (` + trips + `)`,
+		`DELETE FROM trip_status_history WHERE trip_id IN (` + trips + `)`,
+		`DELETE FROM trip WHERE driver_id = $1`,
+		`DELETE FROM driver WHERE user_id = $1`,
+	} {
+		if _, err := f.db.ExecContext(ctx, q, f.driverID); err != nil {
+			f.t.Error(err)
+		}
+	}
+
+	for _, id := range f.users {
+		if _, err := f.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id); err != nil {
+			f.t.Error(err)
+		}
+	}
+}
+
+var (
+	testSource      = trip.TripLocation{Name: "A", PlaceID: "a", Lat: 25.00, Lon: 121.50}
+	testDestination = trip.TripLocation{Name: "B", PlaceID: "b", Lat: 25.10, Lon: 121.50}
+)
+
+func TestUpdatePassengerLimit(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	trp := f.createTrip(3, testSource, testDestination)
+	f.join(trp, trip.StatusAccepted)
+	f.join(trp, trip.StatusAccepted)
+	f.join(trp, trip.StatusPending)
+
+	update := func(limit int) error {
+		return f.store.Update(ctx, trip.Trip{
+			ID:             trp.ID,
+			PassengerLimit: limit,
+			Status:         trp.Status,
+			StartTime:      trp.StartTime,
+			UpdatedAt:      time.Now().UTC(),
+		})
+	}
+
+	assert.ErrorIs(t, update(1), trip.ErrPassengerLimitTooLow)
+	assert.NoError(t, update(2))
+
+	got, err := f.store.QueryByID(ctx, trp.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, got.PassengerLimit)
+}
+
+func TestCancelTrip(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	trp := f.createTrip(3, testSource, testDestination)
+	pending := f.join(trp, trip.StatusPending)
+	accepted := f.join(trp, trip.StatusAccepted)
+	rejected := f.join(trp, trip.StatusRejected)
+	removed := f.join(trp, trip.StatusRemoved)
+
+	now := time.Now().UTC()
+	err := f.store.UpdateStatus(ctx, trip.Trip{
+		ID:             trp.ID,
+		PassengerLimit: trp.PassengerLimit,
+		Status:         trip.TripStatusCancelled,
+		StartTime:      trp.StartTime,
+		UpdatedAt:      now,
+	}, trip.StatusChange{
+		ID:        uuid.New(),
+		TripID:    trp.ID,
+		From:      trp.Status,
+		To:        trip.TripStatusCancelled,
+		ChangedBy: f.driverID,
+		CreatedAt: now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, trip.StatusCancelled, f.passengerStatus(trp.ID, pending))
+	assert.Equal(t, trip.StatusCancelled, f.passengerStatus(trp.ID, accepted))
+	assert.Equal(t, trip.StatusRejected, f.passengerStatus(trp.ID, rejected))
+	assert.Equal(t, trip.StatusRemoved, f.passengerStatus(trp.ID, removed))
+	assert.Equal(t, trip.StatusPending, f.passengerStatus(trp.ID, f.driverID))
+}
+
+func TestUpdatePassengerStatusFrom(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	trp := f.createTrip(3, testSource, testDestination)
+	passengerID := f.join(trp, trip.StatusRemoved)
+
+	// a passenger removed meanwhile cannot withdraw anymore
+	withdraw := trip.TripPassenger{TripID: trp.ID, PassengerID: passengerID, Status: trip.StatusCancelled}
+	err := f.store.UpdatePassengerStatus(ctx, withdraw, []string{trip.StatusPending, trip.StatusAccepted})
+
+	assert.ErrorIs(t, err, trip.ErrPassengerNotActive)
+	assert.Equal(t, trip.StatusRemoved, f.passengerStatus(trp.ID, passengerID))
+
+	unknown := trip.TripPassenger{TripID: trp.ID, PassengerID: uuid.New(), Status: trip.StatusRejected}
+	assert.ErrorIs(t, f.store.UpdatePassengerStatus(ctx, unknown, nil), trip.ErrPassengerNotFound)
+}
+
+func TestRejectRemovesStops(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	trp := f.createTrip(3, testSource, testDestination)
+	passengerID := f.join(trp, trip.StatusAccepted)
+
+	stops := func() int {
+		var n int
+		if err := f.db.GetContext(ctx, &n, `SELECT COUNT(*) FROM trip_location WHERE trip_id = $1 AND passenger_id = $2`, trp.ID, passengerID); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	assert.NotZero(t, stops())
+
+	reject := trip.TripPassenger{TripID: trp.ID, PassengerID: passengerID, Status: trip.StatusRejected}
+	if err := f.store.UpdatePassengerStatus(ctx, reject, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, trip.StatusRejected, f.passengerStatus(trp.ID, passengerID))
+	assert.Zero(t, stops())
+}
@@ -18,18 +18,49 @@ var (
 	ErrNotFound              = errors.New("trip not found")
 	ErrUniqueEmail           = errors.New("email is not unique")
 	ErrAuthenticationFailure = errors.New("authentication failed")
+	ErrPassengerNotFound     = errors.New("trip passenger not found")
+	ErrTripFull              = errors.New("trip has no seats left")
+	ErrInvalidPassengerLimit = errors.New("passenger limit must be at least 1")
+	ErrPassengerLimitTooLow  = errors.New("passenger limit is below the accepted passengers")
+	ErrTripNotJoinable       = errors.New("trip can no longer be joined")
+	ErrAlreadyJoined         = errors.New("passenger already joined the trip")
+	ErrJoinOwnTrip           = errors.New("driver cannot join their own trip")
+	ErrCutoffPassed          = errors.New("cut-off time for this change has passed")
+	ErrPassengerNotActive    = errors.New("passenger is not active on the trip")
+	ErrInvalidRating         = errors.New("rating must be between 1 and 5")
+	ErrTripNotFinished       = errors.New("trip is not finished")
+	ErrNotParticipant        = errors.New("user is not a participant of the trip")
+	ErrAlreadyRated          = errors.New("user already rated this person on the trip")
+	ErrInvalidRatee          = errors.New("ratee cannot be rated by this user on the trip")
+	ErrTripStarted           = errors.New("trip has already started")
+)
+
+// Range of the score of a rating.
+const (
+	MinRating = 1
+	MaxRating = 5
 )
 
 var (
 	TripStatusNotStarted = "not_start"
 	TripStatusIn         = "in_trip"
 	TripStatusFinished   = "finished"
+	TripStatusCancelled  = "cancelled"
+)
+
+var (
+	StatusPending   = "pending"
+	StatusAccepted  = "accepted"
+	StatusRejected  = "rejected"
+	StatusCancelled = "cancelled"
+	StatusRemoved   = "removed"
 )
 
+// Cut-off times before the trip starts after which a passenger can no longer
+// withdraw and a driver can no longer remove a rider.
 var (
-	StatusPending  = "pending"
-	StatusAccepted = "accepted"
-	StatusRejected = "rejected"
+	WithdrawCutoff = 30 * time.Minute
+	RemoveCutoff   = time.Hour
 )
 
 // Storer interface declares the behavior this package needs to perists and
@@ -37,6 +68,7 @@ var (
 type Storer interface {
 	Create(ctx context.Context, trip Trip) error
 	Update(ctx context.Context, trip Trip) error
+	UpdateStatus(ctx context.Context, trip Trip, change StatusChange) error
 	// Delete(ctx context.Context, trip Trip) error
 	Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]TripView, error)
 	QueryByID(ctx context.Context, tripID uuid.UUID) (TripView, error)
@@ -44,14 +76,22 @@ type Storer interface {
 	QueryMyTrip(ctx context.Context, userID uuid.UUID, filter QueryFilterByUser, orderBy order.By, pageNumber int, rowsPerPage int) ([]UserTrip, error)
 	Join(ctx context.Context, tripPassenger TripPassenger) error
 
+	QueryPassenger(ctx context.Context, tripID uuid.UUID, passengerID uuid.UUID) (TripPassenger, error)
+	QueryRoute(ctx context.Context, tripID uuid.UUID) (Route, error)
 	QueryPassengers(ctx context.Context, tripID uuid.UUID) (TripDetails, error)
-	UpdatePassengerStatus(ctx context.Context, tripPassenger TripPassenger) error
+	UpdatePassengerStatus(ctx context.Context, tripPassenger TripPassenger, from []string) error
+	AcceptPassenger(ctx context.Context, tripPassenger TripPassenger) error
 	CreateRating(ctx context.Context, rating Rating) error
+	QueryRatings(ctx context.Context, tripID uuid.UUID) ([]Rating, error)
+	QueryRatingsByDriver(ctx context.Context, driverID uuid.UUID, pageNumber int, rowsPerPage int) ([]Rating, error)
+	CountRatingsByDriver(ctx context.Context, driverID uuid.UUID) (int, error)
+	QueryReputation(ctx context.Context, userID uuid.UUID) (Reputation, error)
 }
 
 // Core manages the set of APIs for user access.
 type Core struct {
-	storer Storer
+	storer   Storer
+	handlers []EventHandler
 }
 
 // NewCore constructs a core for user api access.
@@ -147,17 +187,47 @@ func (c *Core) Create(ctx context.Context, nt NewTrip) (Trip, error) {
 	return trip, nil
 }
 
-// Update replaces a user document in the database.
-func (c *Core) Update(ctx context.Context, trip TripView, ut UpdateTrip) (Trip, error) {
+// Update modifies a trip in the database. A status change must be a legal
+// transition, it is recorded in the status history on behalf of userID. The
+// start time can only change before the trip starts. The passenger limit
+// cannot go below the passengers already accepted.
+func (c *Core) Update(ctx context.Context, userID uuid.UUID, trip TripView, ut UpdateTrip) (Trip, error) {
+	now := time.Now()
+
 	if ut.PassengerLimit != nil {
+		if *ut.PassengerLimit < 1 {
+			return Trip{}, ErrInvalidPassengerLimit
+		}
 		trip.PassengerLimit = *ut.PassengerLimit
 	}
 
-	if ut.Status != nil {
+	startTimeChanged := false
+	if ut.StartTime != nil && !ut.StartTime.Equal(trip.StartTime) {
+		if trip.Status != TripStatusNotStarted {
+			return Trip{}, ErrTripStarted
+		}
+		trip.StartTime = *ut.StartTime
+		startTimeChanged = true
+	}
+
+	var change *StatusChange
+	if ut.Status != nil && *ut.Status != trip.Status {
+		if !CanTransition(trip.Status, *ut.Status) {
+			return Trip{}, &StatusTransitionError{From: trip.Status, To: *ut.Status}
+		}
+
+		change = &StatusChange{
+			ID:        uuid.New(),
+			TripID:    trip.ID,
+			From:      trip.Status,
+			To:        *ut.Status,
+			ChangedBy: userID,
+			CreatedAt: now,
+		}
 		trip.Status = *ut.Status
 	}
 
-	trip.UpdatedAt = time.Now()
+	trip.UpdatedAt = now
 
 	buildTrip := Trip{
 		ID:             trip.ID,
@@ -175,8 +245,35 @@ func (c *Core) Update(ctx context.Context, trip TripView, ut UpdateTrip) (Trip,
 		UpdatedAt: trip.UpdatedAt,
 	}
 
-	if err := c.storer.Update(ctx, buildTrip); err != nil {
-		return Trip{}, fmt.Errorf("update: %w", err)
+	if change != nil {
+		if err := c.storer.UpdateStatus(ctx, buildTrip, *change); err != nil {
+			return Trip{}, fmt.Errorf("updatestatus: %w", err)
+		}
+	} else {
+		if err := c.storer.Update(ctx, buildTrip); err != nil {
+			return Trip{}, fmt.Errorf("update: %w", err)
+		}
+	}
+
+	if startTimeChanged {
+		c.emit(ctx, Event{
+			Type:      EventStartTimeChanged,
+			TripID:    trip.ID,
+			ChangedBy: userID,
+			StartTime: trip.StartTime,
+			CreatedAt: now,
+		})
+	}
+
+	if change != nil {
+		if typ, ok := statusEvents[change.To]; ok {
+			c.emit(ctx, Event{
+				Type:      typ,
+				TripID:    trip.ID,
+				ChangedBy: userID,
+				CreatedAt: now,
+			})
+		}
 	}
 
 	return buildTrip, nil
@@ -225,8 +322,22 @@ func (c *Core) QueryByID(ctx context.Context, tripID uuid.UUID) (TripView, error
 	return trip, nil
 }
 
-// CreateTripPassenger inserts a new trip into the database.
+// Join adds the passenger to the trip as a pending request. Only trips that
+// have not started can be joined and the driver cannot join their own trip.
 func (c *Core) Join(ctx context.Context, tripID uuid.UUID, ntp NewTripPassenger) (TripPassenger, error) {
+	trip, err := c.storer.QueryByID(ctx, tripID)
+	if err != nil {
+		return TripPassenger{}, fmt.Errorf("query: tripID[%s]: %w", tripID, err)
+	}
+
+	if trip.DriverID == ntp.PassengerID {
+		return TripPassenger{}, ErrJoinOwnTrip
+	}
+
+	if trip.Status != TripStatusNotStarted {
+		return TripPassenger{}, ErrTripNotJoinable
+	}
+
 	now := time.Now()
 
 	tripPassenger := TripPassenger{
@@ -245,6 +356,8 @@ func (c *Core) Join(ctx context.Context, tripID uuid.UUID, ntp NewTripPassenger)
 	return tripPassenger, nil
 }
 
+// UpdatePassengerStatus changes the status of a passenger request. Accepting
+// a passenger fails with ErrTripFull once the passenger limit is reached.
 func (c *Core) UpdatePassengerStatus(ctx context.Context, tripID uuid.UUID, passengerID uuid.UUID, status string) (TripPassenger, error) {
 	tripPassenger := TripPassenger{
 		TripID:      tripID,
@@ -252,13 +365,108 @@ func (c *Core) UpdatePassengerStatus(ctx context.Context, tripID uuid.UUID, pass
 		Status:      status,
 	}
 
-	if err := c.storer.UpdatePassengerStatus(ctx, tripPassenger); err != nil {
+	if status == StatusAccepted {
+		if err := c.storer.AcceptPassenger(ctx, tripPassenger); err != nil {
+			return TripPassenger{}, fmt.Errorf("accept: %w", err)
+		}
+
+		c.emit(ctx, Event{
+			Type:        EventPassengerAccepted,
+			TripID:      tripID,
+			PassengerID: passengerID,
+			CreatedAt:   time.Now(),
+		})
+
+		return tripPassenger, nil
+	}
+
+	if err := c.storer.UpdatePassengerStatus(ctx, tripPassenger, nil); err != nil {
 		return TripPassenger{}, fmt.Errorf("create: %w", err)
 	}
 
 	return tripPassenger, nil
 }
 
+// Withdraw lets a passenger cancel a pending join request or give up an
+// accepted seat, up to WithdrawCutoff before the trip starts.
+func (c *Core) Withdraw(ctx context.Context, tripID uuid.UUID, passengerID uuid.UUID, reason string) (TripPassenger, error) {
+	return c.leave(ctx, tripID, passengerID, StatusCancelled, reason, WithdrawCutoff, StatusPending, StatusAccepted)
+}
+
+// RemovePassenger lets the driver remove an accepted rider, up to
+// RemoveCutoff before the trip starts.
+func (c *Core) RemovePassenger(ctx context.Context, tripID uuid.UUID, passengerID uuid.UUID, reason string) (TripPassenger, error) {
+	return c.leave(ctx, tripID, passengerID, StatusRemoved, reason, RemoveCutoff, StatusAccepted)
+}
+
+// leave moves a passenger out of a trip that has not started yet, provided
+// the cut-off has not passed and the passenger is in one of the from statuses.
+func (c *Core) leave(ctx context.Context, tripID uuid.UUID, passengerID uuid.UUID, status string, reason string, cutoff time.Duration, from ...string) (TripPassenger, error) {
+	trip, err := c.storer.QueryByID(ctx, tripID)
+	if err != nil {
+		return TripPassenger{}, fmt.Errorf("query: tripID[%s]: %w", tripID, err)
+	}
+
+	if trip.Status != TripStatusNotStarted || time.Now().After(trip.StartTime.Add(-cutoff)) {
+		return TripPassenger{}, ErrCutoffPassed
+	}
+
+	tripPassenger, err := c.storer.QueryPassenger(ctx, tripID, passengerID)
+	if err != nil {
+		return TripPassenger{}, fmt.Errorf("querypassenger: passengerID[%s]: %w", passengerID, err)
+	}
+
+	active := false
+	for _, s := range from {
+		if tripPassenger.Status == s {
+			active = true
+			break
+		}
+	}
+	if !active {
+		return TripPassenger{}, ErrPassengerNotActive
+	}
+
+	tripPassenger.Status = status
+	tripPassenger.StatusReason = reason
+
+	// the store checks the status again, in case it changed meanwhile
+	if err := c.storer.UpdatePassengerStatus(ctx, tripPassenger, from); err != nil {
+		return TripPassenger{}, fmt.Errorf("update: %w", err)
+	}
+
+	return tripPassenger, nil
+}
+
+// IsParticipant reports whether the user is the driver or an accepted
+// passenger of the trip.
+func (c *Core) IsParticipant(ctx context.Context, trip TripView, userID uuid.UUID) (bool, error) {
+	if trip.DriverID == userID {
+		return true, nil
+	}
+
+	tripPassenger, err := c.storer.QueryPassenger(ctx, trip.ID, userID)
+	if err != nil {
+		if errors.Is(err, ErrPassengerNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("querypassenger: userID[%s]: %w", userID, err)
+	}
+
+	return tripPassenger.Status == StatusAccepted, nil
+}
+
+// QueryRoute retrieves the ordered itinerary of the trip with the cumulative
+// distance of every stop.
+func (c *Core) QueryRoute(ctx context.Context, tripID uuid.UUID) (Route, error) {
+	route, err := c.storer.QueryRoute(ctx, tripID)
+	if err != nil {
+		return Route{}, fmt.Errorf("query: tripID[%s]: %w", tripID, err)
+	}
+
+	return route, nil
+}
+
 // QueryPassengers retrieves a list of existing trips from the database.
 func (c *Core) QueryPassengers(ctx context.Context, tripID uuid.UUID) (TripDetails, error) {
 	tripDetails, err := c.storer.QueryPassengers(ctx, tripID)
@@ -269,13 +477,53 @@ func (c *Core) QueryPassengers(ctx context.Context, tripID uuid.UUID) (TripDetai
 	return tripDetails, nil
 }
 
-// CreateRating inserts a new rating into the database.
+// CreateRating inserts a new rating into the database. Passengers rate the
+// driver and the driver rates the passengers, once each per finished trip.
+// Only the driver and the accepted passengers take part in the ratings.
 func (c *Core) CreateRating(ctx context.Context, tripID uuid.UUID, nr NewRating) (Rating, error) {
+	if nr.Rating < MinRating || nr.Rating > MaxRating {
+		return Rating{}, ErrInvalidRating
+	}
+
+	trip, err := c.storer.QueryByID(ctx, tripID)
+	if err != nil {
+		return Rating{}, fmt.Errorf("query: tripID[%s]: %w", tripID, err)
+	}
+
+	if trip.Status != TripStatusFinished {
+		return Rating{}, ErrTripNotFinished
+	}
+
+	switch nr.CommenterID {
+	case trip.DriverID:
+		if nr.RateeID == uuid.Nil || nr.RateeID == trip.DriverID {
+			return Rating{}, ErrInvalidRatee
+		}
+		if err := c.requireAccepted(ctx, tripID, nr.RateeID); err != nil {
+			if errors.Is(err, ErrNotParticipant) {
+				return Rating{}, ErrInvalidRatee
+			}
+			return Rating{}, err
+		}
+
+	default:
+		if nr.RateeID == uuid.Nil {
+			nr.RateeID = trip.DriverID
+		}
+		if nr.RateeID != trip.DriverID {
+			return Rating{}, ErrInvalidRatee
+		}
+		if err := c.requireAccepted(ctx, tripID, nr.CommenterID); err != nil {
+			return Rating{}, err
+		}
+	}
+
 	now := time.Now()
 
 	rating := Rating{
 		ID:          uuid.New(),
 		TripID:      tripID,
+		RateeID:     nr.RateeID,
 		CommenterID: nr.CommenterID,
 		Comment:     nr.Comment,
 		Rating:      nr.Rating,
@@ -288,3 +536,57 @@ func (c *Core) CreateRating(ctx context.Context, tripID uuid.UUID, nr NewRating)
 
 	return rating, nil
 }
+
+// requireAccepted returns ErrNotParticipant unless the user rode along the
+// trip as an accepted passenger.
+func (c *Core) requireAccepted(ctx context.Context, tripID uuid.UUID, userID uuid.UUID) error {
+	tripPassenger, err := c.storer.QueryPassenger(ctx, tripID, userID)
+	if err != nil {
+		if errors.Is(err, ErrPassengerNotFound) {
+			return ErrNotParticipant
+		}
+		return fmt.Errorf("querypassenger: userID[%s]: %w", userID, err)
+	}
+
+	if tripPassenger.Status != StatusAccepted {
+		return ErrNotParticipant
+	}
+
+	return nil
+}
+
+// QueryRatings retrieves the ratings of a trip from the database.
+func (c *Core) QueryRatings(ctx context.Context, tripID uuid.UUID) ([]Rating, error) {
+	ratings, err := c.storer.QueryRatings(ctx, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("query: tripID[%s]: %w", tripID, err)
+	}
+
+	return ratings, nil
+}
+
+// QueryReputation retrieves the reputation of a user as a passenger.
+func (c *Core) QueryReputation(ctx context.Context, userID uuid.UUID) (Reputation, error) {
+	reputation, err := c.storer.QueryReputation(ctx, userID)
+	if err != nil {
+		return Reputation{}, fmt.Errorf("query: userID[%s]: %w", userID, err)
+	}
+
+	return reputation, nil
+}
+
+// QueryRatingsByDriver retrieves the ratings of the trips of a driver from
+// the database, newest first.
+func (c *Core) QueryRatingsByDriver(ctx context.Context, driverID uuid.UUID, pageNumber int, rowsPerPage int) ([]Rating, error) {
+	ratings, err := c.storer.QueryRatingsByDriver(ctx, driverID, pageNumber, rowsPerPage)
+	if err != nil {
+		return nil, fmt.Errorf("query: driverID[%s]: %w", driverID, err)
+	}
+
+	return ratings, nil
+}
+
+// CountRatingsByDriver returns the total number of ratings of a driver.
+func (c *Core) CountRatingsByDriver(ctx context.Context, driverID uuid.UUID) (int, error) {
+	return c.storer.CountRatingsByDriver(ctx, driverID)
+}
@@ -130,7 +130,7 @@ func TestUpdate(t *testing.T) {
 		Status:         updatedStatus,
 	}
 	mockStorer.On("Update", mock.Anything, mock.AnythingOfType("Trip")).Return(nil)
-	updatedTrip, err := core.Update(context.Background(), trip, updateTrip)
+	updatedTrip, err := core.Update(context.Background(), trip.DriverID, trip, updateTrip)
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedTrip.PassengerLimit, updatedTrip.PassengerLimit)
@@ -138,6 +138,141 @@ func TestUpdate(t *testing.T) {
 	mockStorer.AssertExpectations(t)
 }
 
+func TestUpdateStatusTransition(t *testing.T) {
+	mockStorer := new(MockStorer)
+	core := NewCore(mockStorer)
+
+	driverID := uuid.New()
+	trip := TripView{
+		ID:       uuid.New(),
+		DriverID: driverID,
+		Status:   TripStatusNotStarted,
+	}
+
+	status := TripStatusCancelled
+	mockStorer.On("UpdateStatus", mock.Anything, mock.AnythingOfType("Trip"), mock.MatchedBy(func(change StatusChange) bool {
+		return change.TripID == trip.ID &&
+			change.From == TripStatusNotStarted &&
+			change.To == TripStatusCancelled &&
+			change.ChangedBy == driverID
+	})).Return(nil)
+
+	updatedTrip, err := core.Update(context.Background(), driverID, trip, UpdateTrip{Status: &status})
+
+	assert.NoError(t, err)
+	assert.Equal(t, TripStatusCancelled, updatedTrip.Status)
+	mockStorer.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	mockStorer.AssertExpectations(t)
+}
+
+func TestUpdateEvents(t *testing.T) {
+	mockStorer := new(MockStorer)
+	core := NewCore(mockStorer)
+
+	var events []Event
+	core.Subscribe(func(ctx context.Context, evt Event) {
+		events = append(events, evt)
+	})
+
+	driverID := uuid.New()
+	trip := TripView{
+		ID:        uuid.New(),
+		DriverID:  driverID,
+		Status:    TripStatusNotStarted,
+		StartTime: time.Now().Add(time.Hour),
+	}
+
+	startTime := trip.StartTime.Add(time.Hour)
+	status := TripStatusIn
+	mockStorer.On("UpdateStatus", mock.Anything, mock.MatchedBy(func(trp Trip) bool {
+		return trp.StartTime.Equal(startTime)
+	}), mock.AnythingOfType("StatusChange")).Return(nil)
+
+	_, err := core.Update(context.Background(), driverID, trip, UpdateTrip{StartTime: &startTime, Status: &status})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	assert.Equal(t, EventStartTimeChanged, events[0].Type)
+	assert.True(t, startTime.Equal(events[0].StartTime))
+	assert.Equal(t, EventStarted, events[1].Type)
+	assert.Equal(t, trip.ID, events[1].TripID)
+	assert.Equal(t, driverID, events[1].ChangedBy)
+	mockStorer.AssertExpectations(t)
+}
+
+func TestUpdateStartTimeOfStartedTrip(t *testing.T) {
+	mockStorer := new(MockStorer)
+	core := NewCore(mockStorer)
+
+	core.Subscribe(func(ctx context.Context, evt Event) {
+		t.Errorf("unexpected event %s", evt.Type)
+	})
+
+	trip := TripView{ID: uuid.New(), Status: TripStatusIn, StartTime: time.Now()}
+	startTime := trip.StartTime.Add(time.Hour)
+
+	_, err := core.Update(context.Background(), uuid.New(), trip, UpdateTrip{StartTime: &startTime})
+
+	assert.ErrorIs(t, err, ErrTripStarted)
+	mockStorer.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestUpdateInvalidPassengerLimit(t *testing.T) {
+	for _, limit := range []int{0, -1} {
+		mockStorer := new(MockStorer)
+		core := NewCore(mockStorer)
+
+		_, err := core.Update(context.Background(), uuid.New(), TripView{ID: uuid.New(), PassengerLimit: 4}, UpdateTrip{PassengerLimit: &limit})
+
+		assert.ErrorIs(t, err, ErrInvalidPassengerLimit)
+		mockStorer.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	}
+}
+
+func TestUpdatePassengerLimitTooLow(t *testing.T) {
+	mockStorer := new(MockStorer)
+	core := NewCore(mockStorer)
+
+	limit := 1
+	mockStorer.On("Update", mock.Anything, mock.AnythingOfType("Trip")).Return(ErrPassengerLimitTooLow)
+	_, err := core.Update(context.Background(), uuid.New(), TripView{ID: uuid.New(), PassengerLimit: 4}, UpdateTrip{PassengerLimit: &limit})
+
+	assert.ErrorIs(t, err, ErrPassengerLimitTooLow)
+	mockStorer.AssertExpectations(t)
+}
+
+func TestUpdateIllegalStatusTransition(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+	}{
+		{TripStatusNotStarted, TripStatusFinished},
+		{TripStatusIn, TripStatusNotStarted},
+		{TripStatusIn, TripStatusCancelled},
+		{TripStatusFinished, TripStatusIn},
+		{TripStatusCancelled, TripStatusNotStarted},
+		{TripStatusNotStarted, "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			mockStorer := new(MockStorer)
+			core := NewCore(mockStorer)
+
+			to := tt.to
+			_, err := core.Update(context.Background(), uuid.New(), TripView{ID: uuid.New(), Status: tt.from}, UpdateTrip{Status: &to})
+
+			assert.True(t, IsStatusTransitionError(err))
+			mockStorer.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+			mockStorer.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
 func TestQuery(t *testing.T) {
 	mockStorer := new(MockStorer)
 	core := NewCore(mockStorer)
@@ -331,6 +466,25 @@ func TestCountError(t *testing.T) {
 	mockStorer.AssertExpectations(t)
 }
 
+func TestQueryFilterGeoSearch(t *testing.T) {
+	var filter QueryFilter
+	assert.False(t, filter.IsGeoSearch())
+
+	filter.WithPickup(25.0330, 121.5654)
+	filter.WithDropoff(24.8138, 120.9675)
+	filter.WithRadius(DefaultSearchRadius)
+
+	assert.True(t, filter.IsGeoSearch())
+	assert.NoError(t, filter.Validate())
+
+	filter.WithPickup(91, 121.5654)
+	assert.Error(t, filter.Validate())
+
+	filter.WithPickup(25.0330, 121.5654)
+	filter.WithRadius(-1)
+	assert.Error(t, filter.Validate())
+}
+
 func TestQueryMyTrip(t *testing.T) {
 	mockStorer := new(MockStorer)
 	core := NewCore(mockStorer)
@@ -418,12 +572,16 @@ func TestCreateRating(t *testing.T) {
 	mockStorer := new(MockStorer)
 	core := NewCore(mockStorer)
 
+	driverID := uuid.New()
 	newRating := NewRating{
-		TripID:  uuid.New(),
-		Rating:  5.0,
-		Comment: "some comment",
+		TripID:      uuid.New(),
+		CommenterID: uuid.New(),
+		Rating:      5.0,
+		Comment:     "some comment",
 	}
 
+	mockStorer.On("QueryByID", mock.Anything, newRating.TripID).Return(TripView{ID: newRating.TripID, DriverID: driverID, Status: TripStatusFinished}, nil)
+	mockStorer.On("QueryPassenger", mock.Anything, newRating.TripID, newRating.CommenterID).Return(TripPassenger{TripID: newRating.TripID, PassengerID: newRating.CommenterID, Status: StatusAccepted}, nil)
 	mockStorer.On("CreateRating", mock.Anything, mock.AnythingOfType("Rating")).Return(nil)
 
 	rating, err := core.CreateRating(context.Background(), newRating.TripID, newRating)
@@ -432,6 +590,27 @@ func TestCreateRating(t *testing.T) {
 	assert.Equal(t, newRating.TripID, rating.TripID)
 	assert.Equal(t, newRating.Rating, rating.Rating)
 	assert.Equal(t, newRating.Comment, rating.Comment)
+	assert.Equal(t, driverID, rating.RateeID)
+	mockStorer.AssertExpectations(t)
+}
+
+func TestCreateRatingByDriver(t *testing.T) {
+	mockStorer := new(MockStorer)
+	core := NewCore(mockStorer)
+
+	tripID := uuid.New()
+	driverID := uuid.New()
+	passengerID := uuid.New()
+
+	mockStorer.On("QueryByID", mock.Anything, tripID).Return(TripView{ID: tripID, DriverID: driverID, Status: TripStatusFinished}, nil)
+	mockStorer.On("QueryPassenger", mock.Anything, tripID, passengerID).Return(TripPassenger{TripID: tripID, PassengerID: passengerID, Status: StatusAccepted}, nil)
+	mockStorer.On("CreateRating", mock.Anything, mock.AnythingOfType("Rating")).Return(nil)
+
+	rating, err := core.CreateRating(context.Background(), tripID, NewRating{CommenterID: driverID, RateeID: passengerID, Rating: 4})
+
+	assert.NoError(t, err)
+	assert.Equal(t, passengerID, rating.RateeID)
+	assert.Equal(t, driverID, rating.CommenterID)
 	mockStorer.AssertExpectations(t)
 }
 
@@ -439,12 +618,16 @@ func TestCreateRatingError(t *testing.T) {
 	mockStorer := new(MockStorer)
 	core := NewCore(mockStorer)
 
+	driverID := uuid.New()
 	newRating := NewRating{
-		TripID:  uuid.New(),
-		Rating:  5.0,
-		Comment: "some comment",
+		TripID:      uuid.New(),
+		CommenterID: uuid.New(),
+		Rating:      5.0,
+		Comment:     "some comment",
 	}
 
+	mockStorer.On("QueryByID", mock.Anything, newRating.TripID).Return(TripView{ID: newRating.TripID, DriverID: driverID, Status: TripStatusFinished}, nil)
+	mockStorer.On("QueryPassenger", mock.Anything, newRating.TripID, newRating.CommenterID).Return(TripPassenger{TripID: newRating.TripID, PassengerID: newRating.CommenterID, Status: StatusAccepted}, nil)
 	mockStorer.On("CreateRating", mock.Anything, mock.AnythingOfType("Rating")).Return(errors.New("create rating error"))
 
 	_, err := core.CreateRating(context.Background(), newRating.TripID, newRating)
@@ -454,6 +637,55 @@ func TestCreateRatingError(t *testing.T) {
 	mockStorer.AssertExpectations(t)
 }
 
+func TestCreateRatingRejected(t *testing.T) {
+	tripID := uuid.New()
+	commenterID := uuid.New()
+
+	driverID := uuid.New()
+
+	tests := []struct {
+		name      string
+		commenter uuid.UUID
+		ratee     uuid.UUID
+		rating    int
+		status    string
+		passenger *TripPassenger
+		want      error
+	}{
+		{name: "out of range", rating: 6, want: ErrInvalidRating},
+		{name: "not finished", rating: 4, status: TripStatusIn, want: ErrTripNotFinished},
+		{name: "not joined", rating: 4, status: TripStatusFinished, want: ErrNotParticipant},
+		{name: "not accepted", rating: 4, status: TripStatusFinished, passenger: &TripPassenger{Status: StatusRejected}, want: ErrNotParticipant},
+		{name: "passenger rates passenger", ratee: uuid.New(), rating: 4, status: TripStatusFinished, want: ErrInvalidRatee},
+		{name: "driver without ratee", commenter: driverID, rating: 4, status: TripStatusFinished, want: ErrInvalidRatee},
+		{name: "driver rates stranger", commenter: driverID, ratee: commenterID, rating: 4, status: TripStatusFinished, want: ErrInvalidRatee},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorer := new(MockStorer)
+			core := NewCore(mockStorer)
+
+			commenter := tt.commenter
+			if commenter == uuid.Nil {
+				commenter = commenterID
+			}
+
+			mockStorer.On("QueryByID", mock.Anything, tripID).Return(TripView{ID: tripID, DriverID: driverID, Status: tt.status}, nil)
+			if tt.passenger != nil {
+				mockStorer.On("QueryPassenger", mock.Anything, tripID, commenterID).Return(*tt.passenger, nil)
+			} else {
+				mockStorer.On("QueryPassenger", mock.Anything, tripID, commenterID).Return(TripPassenger{}, ErrPassengerNotFound)
+			}
+
+			_, err := core.CreateRating(context.Background(), tripID, NewRating{CommenterID: commenter, RateeID: tt.ratee, Rating: tt.rating})
+
+			assert.ErrorIs(t, err, tt.want)
+			mockStorer.AssertNotCalled(t, "CreateRating", mock.Anything, mock.Anything)
+		})
+	}
+}
+
 func TestCreateTripPassenger(t *testing.T) {
 	mockStorer := new(MockStorer)
 	core := NewCore(mockStorer)
@@ -473,6 +705,7 @@ func TestCreateTripPassenger(t *testing.T) {
 		CreatedAt:     time.Now(),
 	}
 
+	mockStorer.On("QueryByID", mock.Anything, expectedTripPassenger.TripID).Return(TripView{ID: expectedTripPassenger.TripID, DriverID: uuid.New(), Status: TripStatusNotStarted}, nil)
 	mockStorer.On("Join", mock.Anything, mock.AnythingOfType("TripPassenger")).Return(nil)
 
 	tripPassenger, err := core.Join(context.Background(), expectedTripPassenger.TripID, newTripPassenger)
@@ -496,15 +729,219 @@ func TestCreateTripPassengerError(t *testing.T) {
 		DestinationID: uuid.New(),
 	}
 
+	tripID := uuid.New()
+
+	mockStorer.On("QueryByID", mock.Anything, tripID).Return(TripView{ID: tripID, DriverID: uuid.New(), Status: TripStatusNotStarted}, nil)
 	mockStorer.On("Join", mock.Anything, mock.AnythingOfType("TripPassenger")).Return(errors.New("create trip passenger error"))
 
-	_, err := core.Join(context.Background(), uuid.New(), newTripPassenger)
+	_, err := core.Join(context.Background(), tripID, newTripPassenger)
 	assert.Error(t, err)
 	fmt.Println(err.Error())
 	assert.Contains(t, err.Error(), "create trip passenger error")
 	mockStorer.AssertExpectations(t)
 }
 
+func TestJoinRejected(t *testing.T) {
+	driverID := uuid.New()
+
+	tests := []struct {
+		name        string
+		passengerID uuid.UUID
+		status      string
+		expectedErr error
+	}{
+		{"own trip", driverID, TripStatusNotStarted, ErrJoinOwnTrip},
+		{"in trip", uuid.New(), TripStatusIn, ErrTripNotJoinable},
+		{"finished", uuid.New(), TripStatusFinished, ErrTripNotJoinable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorer := new(MockStorer)
+			core := NewCore(mockStorer)
+
+			tripID := uuid.New()
+			mockStorer.On("QueryByID", mock.Anything, tripID).Return(TripView{ID: tripID, DriverID: driverID, Status: tt.status}, nil)
+
+			_, err := core.Join(context.Background(), tripID, NewTripPassenger{PassengerID: tt.passengerID})
+			assert.ErrorIs(t, err, tt.expectedErr)
+			mockStorer.AssertNotCalled(t, "Join", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestAcceptPassenger(t *testing.T) {
+	mockStorer := new(MockStorer)
+	core := NewCore(mockStorer)
+
+	tripID := uuid.New()
+	passengerID := uuid.New()
+
+	expected := TripPassenger{TripID: tripID, PassengerID: passengerID, Status: StatusAccepted}
+	mockStorer.On("AcceptPassenger", mock.Anything, expected).Return(nil)
+
+	var events []Event
+	core.Subscribe(func(ctx context.Context, evt Event) {
+		events = append(events, evt)
+	})
+
+	tripPassenger, err := core.UpdatePassengerStatus(context.Background(), tripID, passengerID, StatusAccepted)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, tripPassenger)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, EventPassengerAccepted, events[0].Type)
+		assert.Equal(t, passengerID, events[0].PassengerID)
+	}
+	mockStorer.AssertNotCalled(t, "UpdatePassengerStatus", mock.Anything, mock.Anything, mock.Anything)
+	mockStorer.AssertExpectations(t)
+}
+
+func TestAcceptPassengerTripFull(t *testing.T) {
+	mockStorer := new(MockStorer)
+	core := NewCore(mockStorer)
+
+	mockStorer.On("AcceptPassenger", mock.Anything, mock.AnythingOfType("TripPassenger")).Return(ErrTripFull)
+
+	_, err := core.UpdatePassengerStatus(context.Background(), uuid.New(), uuid.New(), StatusAccepted)
+
+	assert.ErrorIs(t, err, ErrTripFull)
+	mockStorer.AssertExpectations(t)
+}
+
+func TestWithdraw(t *testing.T) {
+	mockStorer := new(MockStorer)
+	core := NewCore(mockStorer)
+
+	tripID := uuid.New()
+	passengerID := uuid.New()
+
+	mockStorer.On("QueryByID", mock.Anything, tripID).Return(TripView{ID: tripID, Status: TripStatusNotStarted, StartTime: time.Now().Add(2 * time.Hour)}, nil)
+	mockStorer.On("QueryPassenger", mock.Anything, tripID, passengerID).Return(TripPassenger{TripID: tripID, PassengerID: passengerID, Status: StatusAccepted}, nil)
+	mockStorer.On("UpdatePassengerStatus", mock.Anything, TripPassenger{TripID: tripID, PassengerID: passengerID, Status: StatusCancelled, StatusReason: "plans changed"}, []string{StatusPending, StatusAccepted}).Return(nil)
+
+	tripPassenger, err := core.Withdraw(context.Background(), tripID, passengerID, "plans changed")
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCancelled, tripPassenger.Status)
+	assert.Equal(t, "plans changed", tripPassenger.StatusReason)
+	mockStorer.AssertExpectations(t)
+}
+
+func TestWithdrawStatusChanged(t *testing.T) {
+	mockStorer := new(MockStorer)
+	core := NewCore(mockStorer)
+
+	tripID := uuid.New()
+	passengerID := uuid.New()
+
+	// the driver removes the passenger between the read and the update
+	mockStorer.On("QueryByID", mock.Anything, tripID).Return(TripView{ID: tripID, Status: TripStatusNotStarted, StartTime: time.Now().Add(2 * time.Hour)}, nil)
+	mockStorer.On("QueryPassenger", mock.Anything, tripID, passengerID).Return(TripPassenger{TripID: tripID, PassengerID: passengerID, Status: StatusAccepted}, nil)
+	mockStorer.On("UpdatePassengerStatus", mock.Anything, mock.AnythingOfType("TripPassenger"), []string{StatusPending, StatusAccepted}).Return(ErrPassengerNotActive)
+
+	_, err := core.Withdraw(context.Background(), tripID, passengerID, "")
+
+	assert.ErrorIs(t, err, ErrPassengerNotActive)
+	mockStorer.AssertExpectations(t)
+}
+
+func TestWithdrawAfterCutoff(t *testing.T) {
+	mockStorer := new(MockStorer)
+	core := NewCore(mockStorer)
+
+	tripID := uuid.New()
+
+	mockStorer.On("QueryByID", mock.Anything, tripID).Return(TripView{ID: tripID, Status: TripStatusNotStarted, StartTime: time.Now().Add(WithdrawCutoff / 2)}, nil)
+
+	_, err := core.Withdraw(context.Background(), tripID, uuid.New(), "")
+
+	assert.ErrorIs(t, err, ErrCutoffPassed)
+	mockStorer.AssertNotCalled(t, "UpdatePassengerStatus", mock.Anything, mock.Anything, mock.Anything)
+	mockStorer.AssertExpectations(t)
+}
+
+func TestRemovePassengerNotAccepted(t *testing.T) {
+	mockStorer := new(MockStorer)
+	core := NewCore(mockStorer)
+
+	tripID := uuid.New()
+	passengerID := uuid.New()
+
+	mockStorer.On("QueryByID", mock.Anything, tripID).Return(TripView{ID: tripID, Status: TripStatusNotStarted, StartTime: time.Now().Add(2 * RemoveCutoff)}, nil)
+	mockStorer.On("QueryPassenger", mock.Anything, tripID, passengerID).Return(TripPassenger{TripID: tripID, PassengerID: passengerID, Status: StatusPending}, nil)
+
+	_, err := core.RemovePassenger(context.Background(), tripID, passengerID, "")
+
+	assert.ErrorIs(t, err, ErrPassengerNotActive)
+	mockStorer.AssertNotCalled(t, "UpdatePassengerStatus", mock.Anything, mock.Anything, mock.Anything)
+	mockStorer.AssertExpectations(t)
+}
+
+func TestPlanPassengerStops(t *testing.T) {
+	// a route driving north along a meridian
+	stops := []Stop{
+		{Kind: StopKindSource, Location: TripLocation{Lat: 24.0, Lon: 121.0}},
+		{ID: uuid.New(), Kind: StopKindWaypoint, Location: TripLocation{Lat: 24.5, Lon: 121.0}},
+		{Kind: StopKindDestination, Location: TripLocation{Lat: 25.0, Lon: 121.0}},
+	}
+
+	passengerID := uuid.New()
+	pickup := Stop{ID: uuid.New(), Kind: StopKindPickup, PassengerID: &passengerID, Location: TripLocation{Lat: 24.2, Lon: 121.0}}
+	dropoff := Stop{ID: uuid.New(), Kind: StopKindDropoff, PassengerID: &passengerID, Location: TripLocation{Lat: 24.8, Lon: 121.0}}
+
+	planned := PlanPassengerStops(stops, pickup, dropoff)
+
+	kinds := make([]string, len(planned))
+	for i, stop := range planned {
+		kinds[i] = stop.Kind
+		assert.Equal(t, i, stop.Sequence)
+	}
+	assert.Equal(t, []string{StopKindSource, StopKindPickup, StopKindWaypoint, StopKindDropoff, StopKindDestination}, kinds)
+}
+
+func TestPlanPassengerStopsAdjacent(t *testing.T) {
+	stops := []Stop{
+		{Kind: StopKindSource, Location: TripLocation{Lat: 24.0, Lon: 121.0}},
+		{ID: uuid.New(), Kind: StopKindWaypoint, Location: TripLocation{Lat: 24.5, Lon: 121.0}},
+		{Kind: StopKindDestination, Location: TripLocation{Lat: 25.0, Lon: 121.0}},
+	}
+
+	pickup := Stop{ID: uuid.New(), Kind: StopKindPickup, Location: TripLocation{Lat: 24.1, Lon: 121.0}}
+	dropoff := Stop{ID: uuid.New(), Kind: StopKindDropoff, Location: TripLocation{Lat: 24.2, Lon: 121.0}}
+
+	planned := PlanPassengerStops(stops, pickup, dropoff)
+
+	assert.Len(t, planned, 5)
+	assert.Equal(t, pickup.ID, planned[1].ID)
+	assert.Equal(t, dropoff.ID, planned[2].ID)
+}
+
+func TestIsParticipant(t *testing.T) {
+	mockStorer := new(MockStorer)
+	core := NewCore(mockStorer)
+
+	tripView := TripView{ID: uuid.New(), DriverID: uuid.New()}
+	accepted := uuid.New()
+	pending := uuid.New()
+	stranger := uuid.New()
+
+	mockStorer.On("QueryPassenger", mock.Anything, tripView.ID, accepted).Return(TripPassenger{Status: StatusAccepted}, nil)
+	mockStorer.On("QueryPassenger", mock.Anything, tripView.ID, pending).Return(TripPassenger{Status: StatusPending}, nil)
+	mockStorer.On("QueryPassenger", mock.Anything, tripView.ID, stranger).Return(TripPassenger{}, ErrPassengerNotFound)
+
+	for userID, expected := range map[uuid.UUID]bool{
+		tripView.DriverID: true,
+		accepted:          true,
+		pending:           false,
+		stranger:          false,
+	} {
+		ok, err := core.IsParticipant(context.Background(), tripView, userID)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, ok)
+	}
+}
+
 func TestQueryPassengers(t *testing.T) {
 	mockStorer := new(MockStorer)
 	core := NewCore(mockStorer)
@@ -26,6 +26,16 @@ func (m *MockStorer) Update(ctx context.Context, usr User) error {
 	return args.Error(0)
 }
 
+func (m *MockStorer) UpdateSuspension(ctx context.Context, usr User) error {
+	args := m.Called(ctx, usr)
+	return args.Error(0)
+}
+
+func (m *MockStorer) UpdateRoles(ctx context.Context, usr User) error {
+	args := m.Called(ctx, usr)
+	return args.Error(0)
+}
+
 func (m *MockStorer) Delete(ctx context.Context, usr User) error {
 	fmt.Println("MockStorer.Delete", usr)
 	args := m.Called(ctx, usr)
@@ -62,8 +72,12 @@ func (m *MockStorer) QueryByEmail(ctx context.Context, email mail.Address) (User
 	return args.Get(0).(User), args.Error(1)
 }
 
-func (m *MockStorer) QueryByGoogleID(ctx context.Context, googleID string) (User, error) {
-	fmt.Println("MockStorer.QueryByGoogleID", googleID)
-	args := m.Called(ctx, googleID)
+func (m *MockStorer) QueryByIdentity(ctx context.Context, provider string, subject string) (User, error) {
+	args := m.Called(ctx, provider, subject)
 	return args.Get(0).(User), args.Error(1)
 }
+
+func (m *MockStorer) CreateIdentity(ctx context.Context, identity Identity) error {
+	args := m.Called(ctx, identity)
+	return args.Error(0)
+}
@@ -16,10 +16,23 @@ type User struct {
 	Bio                string
 	AcceptNotification bool
 	Sub                string
+	Roles              []Role
+	SuspendedAt        *time.Time
+	SuspendedUntil     *time.Time
+	SuspensionReason   string
 	CreatedAt          time.Time
 	UpdatedAt          time.Time
 }
 
+// IsSuspended reports whether the user is suspended at the given time. A
+// suspension without an end never expires.
+func (u User) IsSuspended(now time.Time) bool {
+	if u.SuspendedAt == nil {
+		return false
+	}
+	return u.SuspendedUntil == nil || now.Before(*u.SuspendedUntil)
+}
+
 // NewUser contains information needed to create a new user.
 type NewUser struct {
 	Name               string
@@ -30,6 +43,23 @@ type NewUser struct {
 	Sub                string
 }
 
+// Identity links a user to the subject of an identity provider.
+type Identity struct {
+	Provider  string
+	Subject   string
+	UserID    uuid.UUID
+	Email     string
+	CreatedAt time.Time
+}
+
+// NewIdentity contains information needed to link an identity to a user.
+type NewIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
 // UpdateUser contains information needed to update a user.
 type UpdateUser struct {
 	Name               *string
@@ -0,0 +1,95 @@
+package user
+
+import "fmt"
+
+// Set of possible roles for a user.
+var (
+	RoleRider  = Role{"rider"}
+	RoleDriver = Role{"driver"}
+	RoleAdmin  = Role{"admin"}
+)
+
+// Set of known roles.
+var roles = map[string]Role{
+	RoleRider.name:  RoleRider,
+	RoleDriver.name: RoleDriver,
+	RoleAdmin.name:  RoleAdmin,
+}
+
+// Role represents a role in the system.
+type Role struct {
+	name string
+}
+
+// ParseRole parses the string value and returns a role if one exists.
+func ParseRole(value string) (Role, error) {
+	role, exists := roles[value]
+	if !exists {
+		return Role{}, fmt.Errorf("invalid role %q", value)
+	}
+
+	return role, nil
+}
+
+// MustParseRole parses the string value and returns a role if one exists. If
+// an error occurs the function panics.
+func MustParseRole(value string) Role {
+	role, err := ParseRole(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return role
+}
+
+// ParseRoles parses the string values and returns the roles.
+func ParseRoles(values []string) ([]Role, error) {
+	rls := make([]Role, len(values))
+	for i, value := range values {
+		role, err := ParseRole(value)
+		if err != nil {
+			return nil, err
+		}
+		rls[i] = role
+	}
+
+	return rls, nil
+}
+
+// Name returns the name of the role.
+func (r Role) Name() string {
+	return r.name
+}
+
+// UnmarshalText implement the unmarshal interface for JSON conversions.
+func (r *Role) UnmarshalText(data []byte) error {
+	role, err := ParseRole(string(data))
+	if err != nil {
+		return err
+	}
+
+	r.name = role.name
+	return nil
+}
+
+// MarshalText implement the marshal interface for JSON conversions.
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.name), nil
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (r Role) Equal(r2 Role) bool {
+	return r.name == r2.name
+}
+
+// HasRole reports whether any of the wanted roles is in the list.
+func HasRole(have []Role, want ...Role) bool {
+	for _, h := range have {
+		for _, w := range want {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/TSMC-Uber/server/business/core/user"
+	"github.com/TSMC-Uber/server/business/sys/database/dbarray"
 	"github.com/google/uuid"
 )
 
@@ -19,12 +20,16 @@ type dbUser struct {
 	Bio                string         `db:"bio"`
 	AcceptNotification bool           `db:"accept_notification"`
 	Sub                sql.NullString `db:"sub"`
+	Roles              dbarray.String `db:"roles"`
+	SuspendedAt        sql.NullTime   `db:"suspended_at"`
+	SuspendedUntil     sql.NullTime   `db:"suspended_until"`
+	SuspensionReason   sql.NullString `db:"suspension_reason"`
 	CreatedAt          time.Time      `db:"created_at"`
 	UpdatedAt          time.Time      `db:"updated_at"`
 }
 
 func toDBUser(usr user.User) dbUser {
-	return dbUser{
+	dbUsr := dbUser{
 		ID:                 usr.ID,
 		Name:               usr.Name,
 		Email:              usr.Email.Address,
@@ -32,9 +37,20 @@ func toDBUser(usr user.User) dbUser {
 		Bio:                usr.Bio,
 		AcceptNotification: usr.AcceptNotification,
 		Sub:                sql.NullString{String: usr.Sub, Valid: usr.Sub != ""},
+		Roles:              toDBRoles(usr.Roles),
+		SuspensionReason:   sql.NullString{String: usr.SuspensionReason, Valid: usr.SuspensionReason != ""},
 		CreatedAt:          usr.CreatedAt.UTC(),
 		UpdatedAt:          usr.UpdatedAt.UTC(),
 	}
+
+	if usr.SuspendedAt != nil {
+		dbUsr.SuspendedAt = sql.NullTime{Time: usr.SuspendedAt.UTC(), Valid: true}
+	}
+	if usr.SuspendedUntil != nil {
+		dbUsr.SuspendedUntil = sql.NullTime{Time: usr.SuspendedUntil.UTC(), Valid: true}
+	}
+
+	return dbUsr
 }
 
 func toCoreUser(dbUsr dbUser) user.User {
@@ -49,10 +65,21 @@ func toCoreUser(dbUsr dbUser) user.User {
 		ImageURL:           dbUsr.ImageURL,
 		Bio:                dbUsr.Bio,
 		AcceptNotification: dbUsr.AcceptNotification,
+		Roles:              toCoreRoles(dbUsr.Roles),
+		SuspensionReason:   dbUsr.SuspensionReason.String,
 		CreatedAt:          dbUsr.CreatedAt.In(time.Local),
 		UpdatedAt:          dbUsr.UpdatedAt.In(time.Local),
 	}
 
+	if dbUsr.SuspendedAt.Valid {
+		suspendedAt := dbUsr.SuspendedAt.Time.In(time.Local)
+		usr.SuspendedAt = &suspendedAt
+	}
+	if dbUsr.SuspendedUntil.Valid {
+		suspendedUntil := dbUsr.SuspendedUntil.Time.In(time.Local)
+		usr.SuspendedUntil = &suspendedUntil
+	}
+
 	return usr
 }
 
@@ -63,3 +90,42 @@ func toCoreUserSlice(dbUsers []dbUser) []user.User {
 	}
 	return usrs
 }
+
+// toDBRoles converts the roles to their names for storage.
+func toDBRoles(roles []user.Role) dbarray.String {
+	names := make(dbarray.String, len(roles))
+	for i, role := range roles {
+		names[i] = role.Name()
+	}
+	return names
+}
+
+// toCoreRoles converts the stored role names back into roles. The column is
+// constrained to the known roles, so parsing cannot fail.
+func toCoreRoles(names dbarray.String) []user.Role {
+	roles := make([]user.Role, len(names))
+	for i, name := range names {
+		roles[i] = user.MustParseRole(name)
+	}
+	return roles
+}
+
+// =============================================================================
+
+type dbIdentity struct {
+	Provider  string         `db:"provider"`
+	Subject   string         `db:"subject"`
+	UserID    uuid.UUID      `db:"user_id"`
+	Email     sql.NullString `db:"email"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+func toDBIdentity(identity user.Identity) dbIdentity {
+	return dbIdentity{
+		Provider:  identity.Provider,
+		Subject:   identity.Subject,
+		UserID:    identity.UserID,
+		Email:     sql.NullString{String: identity.Email, Valid: identity.Email != ""},
+		CreatedAt: identity.CreatedAt.UTC(),
+	}
+}
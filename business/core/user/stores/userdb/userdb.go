@@ -41,8 +41,8 @@ func (s *Store) Create(ctx context.Context, usr user.User) error {
 
 	sql, args, err := sq.
 		Insert("users").
-		Columns("id", "name", "email", "image_url", "bio", "accept_notification", "sub").
-		Values(dbUser.ID, dbUser.Name, dbUser.Email, dbUser.ImageURL, dbUser.Bio, dbUser.AcceptNotification, dbUser.Sub).
+		Columns("id", "name", "email", "image_url", "bio", "accept_notification", "sub", "roles").
+		Values(dbUser.ID, dbUser.Name, dbUser.Email, dbUser.ImageURL, dbUser.Bio, dbUser.AcceptNotification, dbUser.Sub, dbUser.Roles).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
 
@@ -92,6 +92,52 @@ func (s *Store) Update(ctx context.Context, usr user.User) error {
 	return nil
 }
 
+// UpdateSuspension writes the suspension of a user to the database.
+func (s *Store) UpdateSuspension(ctx context.Context, usr user.User) error {
+	dbUser := toDBUser(usr)
+
+	sql, args, err := sq.
+		Update("users").
+		Set("suspended_at", dbUser.SuspendedAt).
+		Set("suspended_until", dbUser.SuspendedUntil).
+		Set("suspension_reason", dbUser.SuspensionReason).
+		Set("updated_at", dbUser.UpdatedAt).
+		Where(sq.Eq{"id": dbUser.ID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	if err := database.ExecContext(ctx, s.log, s.db, sql, args); err != nil {
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateRoles writes the roles of a user to the database.
+func (s *Store) UpdateRoles(ctx context.Context, usr user.User) error {
+	dbUser := toDBUser(usr)
+
+	sql, args, err := sq.
+		Update("users").
+		Set("roles", dbUser.Roles).
+		Set("updated_at", dbUser.UpdatedAt).
+		Where(sq.Eq{"id": dbUser.ID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	if err := database.ExecContext(ctx, s.log, s.db, sql, args); err != nil {
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	return nil
+}
+
 // Delete removes a user from the database.
 func (s *Store) Delete(ctx context.Context, usr user.User) error {
 	sql, args, err := sq.
@@ -211,7 +257,7 @@ func (s *Store) QueryByIDs(ctx context.Context, userIDs []uuid.UUID) ([]user.Use
 	FROM
 		users
 	WHERE
-		user_id = ANY(:user_id)`
+		id = ANY(:user_id)`
 
 	var usrs []dbUser
 	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &usrs); err != nil {
@@ -251,12 +297,13 @@ func (s *Store) QueryByEmail(ctx context.Context, email mail.Address) (user.User
 	return toCoreUser(dbUsr), nil
 }
 
-// QueryByGoogleID gets the specified user from the database by googleID.
-func (s *Store) QueryByGoogleID(ctx context.Context, googleID string) (user.User, error) {
+// QueryByIdentity gets the user linked to the identity from the database.
+func (s *Store) QueryByIdentity(ctx context.Context, provider string, subject string) (user.User, error) {
 	sql, args, err := sq.
-		Select("*").
+		Select("users.*").
 		From("users").
-		Where(sq.Eq{"sub": googleID}).
+		Join("user_identity ON user_identity.user_id = users.id").
+		Where(sq.Eq{"user_identity.provider": provider, "user_identity.subject": subject}).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
 
@@ -267,10 +314,35 @@ func (s *Store) QueryByGoogleID(ctx context.Context, googleID string) (user.User
 	var dbUsr dbUser
 	if err := database.GetContext(ctx, s.log, s.db, sql, args, &dbUsr); err != nil {
 		if errors.Is(err, database.ErrDBNotFound) {
-			return user.User{}, fmt.Errorf("namedquerystruct: %w", database.ErrDBNotFound)
+			return user.User{}, fmt.Errorf("namedquerystruct: %w", user.ErrNotFound)
 		}
 		return user.User{}, fmt.Errorf("namedquerystruct: %w", err)
 	}
 
 	return toCoreUser(dbUsr), nil
 }
+
+// CreateIdentity links an identity to a user in the database.
+func (s *Store) CreateIdentity(ctx context.Context, identity user.Identity) error {
+	dbIdentity := toDBIdentity(identity)
+
+	sql, args, err := sq.
+		Insert("user_identity").
+		Columns("provider", "subject", "user_id", "email", "created_at").
+		Values(dbIdentity.Provider, dbIdentity.Subject, dbIdentity.UserID, dbIdentity.Email, dbIdentity.CreatedAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	if err := database.ExecContext(ctx, s.log, s.db, sql, args); err != nil {
+		if errors.Is(err, database.ErrDBDuplicatedEntry) {
+			return user.ErrIdentityLinked
+		}
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	return nil
+}
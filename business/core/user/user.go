@@ -11,7 +11,6 @@ import (
 	"time"
 
 	"github.com/TSMC-Uber/server/business/data/order"
-	"github.com/TSMC-Uber/server/business/sys/database"
 	"github.com/google/uuid"
 )
 
@@ -20,6 +19,8 @@ var (
 	ErrNotFound              = errors.New("user not found")
 	ErrUniqueEmail           = errors.New("email is not unique")
 	ErrAuthenticationFailure = errors.New("authentication failed")
+	ErrSuspended             = errors.New("user is suspended")
+	ErrIdentityLinked        = errors.New("identity is linked to another user")
 )
 
 // Storer interface declares the behavior this package needs to perists and
@@ -27,13 +28,16 @@ var (
 type Storer interface {
 	Create(ctx context.Context, usr User) error
 	Update(ctx context.Context, usr User) error
+	UpdateSuspension(ctx context.Context, usr User) error
+	UpdateRoles(ctx context.Context, usr User) error
 	Delete(ctx context.Context, usr User) error
 	Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]User, error)
 	Count(ctx context.Context, filter QueryFilter) (int, error)
 	QueryByID(ctx context.Context, userID uuid.UUID) (User, error)
 	QueryByIDs(ctx context.Context, userID []uuid.UUID) ([]User, error)
 	QueryByEmail(ctx context.Context, email mail.Address) (User, error)
-	QueryByGoogleID(ctx context.Context, googleID string) (User, error)
+	QueryByIdentity(ctx context.Context, provider string, subject string) (User, error)
+	CreateIdentity(ctx context.Context, identity Identity) error
 }
 
 // Core manages the set of APIs for user access.
@@ -60,6 +64,7 @@ func (c *Core) Create(ctx context.Context, nu NewUser) (User, error) {
 		Bio:                nu.Bio,
 		AcceptNotification: nu.AcceptNotification,
 		Sub:                nu.Sub,
+		Roles:              []Role{RoleRider},
 		CreatedAt:          now,
 		UpdatedAt:          now,
 	}
@@ -98,6 +103,40 @@ func (c *Core) Update(ctx context.Context, usr User, uu UpdateUser) (User, error
 	return usr, nil
 }
 
+// AddRole grants the role to the user, doing nothing when the user already
+// has it.
+func (c *Core) AddRole(ctx context.Context, usr User, role Role) (User, error) {
+	if HasRole(usr.Roles, role) {
+		return usr, nil
+	}
+
+	usr.Roles = append(usr.Roles, role)
+	usr.UpdatedAt = time.Now()
+
+	if err := c.storer.UpdateRoles(ctx, usr); err != nil {
+		return User{}, fmt.Errorf("updateroles: userID[%s]: %w", usr.ID, err)
+	}
+
+	return usr, nil
+}
+
+// Suspend keeps the user from signing in and from taking part in trips until
+// the given time, or indefinitely when until is nil.
+func (c *Core) Suspend(ctx context.Context, usr User, until *time.Time, reason string) (User, error) {
+	now := time.Now()
+
+	usr.SuspendedAt = &now
+	usr.SuspendedUntil = until
+	usr.SuspensionReason = reason
+	usr.UpdatedAt = now
+
+	if err := c.storer.UpdateSuspension(ctx, usr); err != nil {
+		return User{}, fmt.Errorf("updatesuspension: userID[%s]: %w", usr.ID, err)
+	}
+
+	return usr, nil
+}
+
 // Delete removes a user from the database.
 func (c *Core) Delete(ctx context.Context, usr User) error {
 	if err := c.storer.Delete(ctx, usr); err != nil {
@@ -152,20 +191,80 @@ func (c *Core) QueryByEmail(ctx context.Context, email mail.Address) (User, erro
 	return user, nil
 }
 
-// UpsertByGoogleID gets the specified user from the database by email.
-func (c *Core) UpsertByGoogleID(ctx context.Context, googleID string, nu NewUser) (User, error) {
-	usr, err := c.storer.QueryByGoogleID(ctx, googleID)
+// UpsertByIdentity gets the user linked to the identity. An identity seen for
+// the first time is linked to the user owning its email when the provider
+// verified that email, otherwise a new user is created for it.
+func (c *Core) UpsertByIdentity(ctx context.Context, ni NewIdentity, nu NewUser) (User, error) {
+	usr, err := c.storer.QueryByIdentity(ctx, ni.Provider, ni.Subject)
+	if err == nil {
+		return usr, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return User{}, fmt.Errorf("query: provider[%s] subject[%s]: %w", ni.Provider, ni.Subject, err)
+	}
+
+	usr, err = c.userForIdentity(ctx, ni, nu)
 	if err != nil {
+		return User{}, err
+	}
+
+	if err := c.createIdentity(ctx, usr, ni); err != nil {
+		return User{}, err
+	}
+
+	return usr, nil
+}
+
+// LinkIdentity links another identity to the user, so the user can sign in
+// with that provider as well.
+func (c *Core) LinkIdentity(ctx context.Context, usr User, ni NewIdentity) error {
+	linked, err := c.storer.QueryByIdentity(ctx, ni.Provider, ni.Subject)
+	switch {
+	case err == nil:
+		if linked.ID != usr.ID {
+			return ErrIdentityLinked
+		}
+		return nil
+	case !errors.Is(err, ErrNotFound):
+		return fmt.Errorf("query: provider[%s] subject[%s]: %w", ni.Provider, ni.Subject, err)
+	}
+
+	return c.createIdentity(ctx, usr, ni)
+}
+
+// userForIdentity finds the user a new identity belongs to, creating one if
+// there is none.
+func (c *Core) userForIdentity(ctx context.Context, ni NewIdentity, nu NewUser) (User, error) {
+	if ni.EmailVerified {
+		usr, err := c.storer.QueryByEmail(ctx, nu.Email)
 		switch {
-		case errors.Is(err, database.ErrDBNotFound):
-			usr, err = c.Create(ctx, nu)
-			if err != nil {
-				return User{}, fmt.Errorf("create: %w", err)
-			}
-		default:
-			return User{}, fmt.Errorf("query: googleID[%s]: %w", googleID, err)
+		case err == nil:
+			return usr, nil
+		case !errors.Is(err, ErrNotFound):
+			return User{}, fmt.Errorf("query: email[%s]: %w", nu.Email, err)
 		}
 	}
 
+	usr, err := c.Create(ctx, nu)
+	if err != nil {
+		return User{}, fmt.Errorf("create: %w", err)
+	}
+
 	return usr, nil
 }
+
+func (c *Core) createIdentity(ctx context.Context, usr User, ni NewIdentity) error {
+	identity := Identity{
+		Provider:  ni.Provider,
+		Subject:   ni.Subject,
+		UserID:    usr.ID,
+		Email:     ni.Email,
+		CreatedAt: time.Now(),
+	}
+
+	if err := c.storer.CreateIdentity(ctx, identity); err != nil {
+		return fmt.Errorf("createidentity: provider[%s] userID[%s]: %w", ni.Provider, usr.ID, err)
+	}
+
+	return nil
+}
@@ -183,22 +183,119 @@ func strPtr(s string) *string {
 	return &s
 }
 
-func TestUpsertByGoogleID(t *testing.T) {
+func TestUpsertByIdentity(t *testing.T) {
+	mockStorer := new(MockStorer)
+	core := NewCore(mockStorer)
+
+	existing := User{ID: uuid.New()}
+	ni := NewIdentity{Provider: "google", Subject: "1234567890"}
+
+	mockStorer.On("QueryByIdentity", context.Background(), "google", "1234567890").Return(existing, nil)
+	usr, err := core.UpsertByIdentity(context.Background(), ni, NewUser{})
+	assert.NoError(t, err)
+	assert.Equal(t, existing.ID, usr.ID)
+
+	mockStorer.AssertExpectations(t)
+	mockStorer.AssertNotCalled(t, "CreateIdentity", mock.Anything, mock.Anything)
+}
+
+func TestUpsertByIdentityLinksVerifiedEmail(t *testing.T) {
+	mockStorer := new(MockStorer)
+	core := NewCore(mockStorer)
+
+	existing := User{ID: uuid.New()}
+	email := mail.Address{Address: "rider@example.com"}
+	ni := NewIdentity{Provider: "oidc", Subject: "abc", Email: email.Address, EmailVerified: true}
+
+	mockStorer.On("QueryByIdentity", context.Background(), "oidc", "abc").Return(User{}, ErrNotFound)
+	mockStorer.On("QueryByEmail", context.Background(), email).Return(existing, nil)
+	mockStorer.On("CreateIdentity", context.Background(), mock.MatchedBy(func(identity Identity) bool {
+		return identity.UserID == existing.ID && identity.Provider == "oidc" && identity.Subject == "abc"
+	})).Return(nil)
+
+	usr, err := core.UpsertByIdentity(context.Background(), ni, NewUser{Email: email})
+	assert.NoError(t, err)
+	assert.Equal(t, existing.ID, usr.ID)
+
+	mockStorer.AssertExpectations(t)
+	mockStorer.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestUpsertByIdentityCreatesUser(t *testing.T) {
 	mockStorer := new(MockStorer)
 	core := NewCore(mockStorer)
 
 	newUser := NewUser{
-		Name:               "Test User",
-		Email:              mail.Address{Address: "000.gmail.com"},
-		ImageURL:           "https://example.com/image.png",
-		Bio:                "This is a test user.",
-		AcceptNotification: true,
-		Sub:                "1234567890",
+		Name:  "Test User",
+		Email: mail.Address{Address: "000.gmail.com"},
 	}
-	googleID := "1234567890"
+	ni := NewIdentity{Provider: "google", Subject: "1234567890", Email: newUser.Email.Address}
+
+	mockStorer.On("QueryByIdentity", context.Background(), "google", "1234567890").Return(User{}, ErrNotFound)
+	mockStorer.On("Create", context.Background(), mock.AnythingOfType("User")).Return(nil)
+	mockStorer.On("CreateIdentity", context.Background(), mock.AnythingOfType("Identity")).Return(nil)
 
-	mockStorer.On("QueryByGoogleID", context.Background(), googleID).Return(User{}, nil)
-	_, err := core.UpsertByGoogleID(context.Background(), googleID, newUser)
+	usr, err := core.UpsertByIdentity(context.Background(), ni, newUser)
 	assert.NoError(t, err)
+	assert.Equal(t, newUser.Name, usr.Name)
+
+	// an unverified email is never used to find an existing user
+	mockStorer.AssertNotCalled(t, "QueryByEmail", mock.Anything, mock.Anything)
+	mockStorer.AssertExpectations(t)
+}
+
+func TestLinkIdentityLinkedToAnotherUser(t *testing.T) {
+	mockStorer := new(MockStorer)
+	core := NewCore(mockStorer)
+
+	ni := NewIdentity{Provider: "oidc", Subject: "abc"}
+
+	mockStorer.On("QueryByIdentity", context.Background(), "oidc", "abc").Return(User{ID: uuid.New()}, nil)
+	err := core.LinkIdentity(context.Background(), User{ID: uuid.New()}, ni)
+	assert.ErrorIs(t, err, ErrIdentityLinked)
+
+	mockStorer.AssertNotCalled(t, "CreateIdentity", mock.Anything, mock.Anything)
+}
+
+func TestSuspend(t *testing.T) {
+	mockStorer := new(MockStorer)
+	core := NewCore(mockStorer)
+
+	until := time.Now().Add(24 * time.Hour)
+
+	mockStorer.On("UpdateSuspension", context.Background(), mock.AnythingOfType("User")).Return(nil)
+	usr, err := core.Suspend(context.Background(), User{ID: uuid.New()}, &until, "no show")
+	assert.NoError(t, err)
+	assert.True(t, usr.IsSuspended(time.Now()))
+	assert.False(t, usr.IsSuspended(until.Add(time.Minute)))
+
+	mockStorer.AssertExpectations(t)
+}
+
+func TestParseRole(t *testing.T) {
+	role, err := ParseRole("driver")
+	assert.NoError(t, err)
+	assert.Equal(t, RoleDriver, role)
+
+	_, err = ParseRole("superuser")
+	assert.Error(t, err)
+}
+
+func TestAddRole(t *testing.T) {
+	mockStorer := new(MockStorer)
+	core := NewCore(mockStorer)
+
+	usr := User{ID: uuid.New(), Roles: []Role{RoleRider}}
+
+	mockStorer.On("UpdateRoles", context.Background(), mock.AnythingOfType("User")).Return(nil).Once()
+	usr, err := core.AddRole(context.Background(), usr, RoleDriver)
+	assert.NoError(t, err)
+	assert.Equal(t, []Role{RoleRider, RoleDriver}, usr.Roles)
+
+	// granting a role the user already has does not touch the store
+	usr, err = core.AddRole(context.Background(), usr, RoleDriver)
+	assert.NoError(t, err)
+	assert.Len(t, usr.Roles, 2)
+
 	mockStorer.AssertExpectations(t)
 }
@@ -0,0 +1,52 @@
+package ws
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockStorer struct {
+	mock.Mock
+}
+
+func (m *MockStorer) Create(ctx context.Context, msg ChatMessage) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
+
+func (m *MockStorer) QueryByID(ctx context.Context, msgID uuid.UUID) (ChatMessage, error) {
+	args := m.Called(ctx, msgID)
+	return args.Get(0).(ChatMessage), args.Error(1)
+}
+
+func (m *MockStorer) QueryByRoom(ctx context.Context, roomID uuid.UUID, filter QueryFilter, limit int) ([]ChatMessage, error) {
+	args := m.Called(ctx, roomID, filter, limit)
+	return args.Get(0).([]ChatMessage), args.Error(1)
+}
+
+func (m *MockStorer) CountUnread(ctx context.Context, userID uuid.UUID, pointers []ReadPointer) (map[uuid.UUID]int, error) {
+	args := m.Called(ctx, userID, pointers)
+	return args.Get(0).(map[uuid.UUID]int), args.Error(1)
+}
+
+func (m *MockStorer) CreateRoom(ctx context.Context, room Room) error {
+	args := m.Called(ctx, room)
+	return args.Error(0)
+}
+
+func (m *MockStorer) QueryRoomByID(ctx context.Context, roomID uuid.UUID) (Room, error) {
+	args := m.Called(ctx, roomID)
+	return args.Get(0).(Room), args.Error(1)
+}
+
+func (m *MockStorer) QueryRoomByPassenger(ctx context.Context, tripID uuid.UUID, passengerID uuid.UUID) (Room, error) {
+	args := m.Called(ctx, tripID, passengerID)
+	return args.Get(0).(Room), args.Error(1)
+}
+
+func (m *MockStorer) QueryRoomsByTrip(ctx context.Context, tripID uuid.UUID) ([]Room, error) {
+	args := m.Called(ctx, tripID)
+	return args.Get(0).([]Room), args.Error(1)
+}
@@ -1,10 +1,69 @@
 package ws
 
-import "github.com/google/uuid"
+import (
+	"bytes"
+	"time"
 
+	"github.com/google/uuid"
+)
+
+// ChatMessage is a message sent to a room. Type is TypeMessage for the
+// messages of users and TypeSystem for the ones the server posts about the
+// trip, which have no user and carry the trip Event they are about. ClientID
+// is the id the client gave the message and StreamID the id of its entry in
+// the stream of the room, neither is kept in the database.
 type ChatMessage struct {
-	UserID   uuid.UUID
-	Username string
-	ImageURL string
-	Message  string
+	ID        uuid.UUID
+	Type      string
+	Event     string
+	RoomID    uuid.UUID
+	TripID    uuid.UUID
+	UserID    uuid.UUID
+	Username  string
+	ImageURL  string
+	Message   string
+	ClientID  string
+	StreamID  string
+	CreatedAt time.Time
+}
+
+// QueryFilter holds the available fields a query of the messages of a room
+// can be filtered on. Before and After are the ids of messages, only the
+// messages sent before or after them are returned.
+type QueryFilter struct {
+	Before *uuid.UUID
+	After  *uuid.UUID
+}
+
+// Room is a chat room of a trip. Every trip has a room for its driver and
+// accepted passengers, whose id is the id of the trip. A prospective
+// passenger gets a room of its own with the driver to ask questions before
+// joining, PassengerID is set on those.
+type Room struct {
+	ID          uuid.UUID
+	TripID      uuid.UUID
+	PassengerID *uuid.UUID
+	CreatedAt   time.Time
+}
+
+// IsTripRoom reports whether the room is the room of the whole trip.
+func (r Room) IsTripRoom() bool {
+	return r.PassengerID == nil
+}
+
+// ReadPointer is the last message of a room a user has read, the messages
+// sent after it are unread. A zero pointer has read nothing.
+type ReadPointer struct {
+	RoomID    uuid.UUID
+	MessageID uuid.UUID
+	CreatedAt time.Time
+}
+
+// Before reports whether the message of the pointer was sent before the
+// message of the other one, in the order the messages of a room are sent.
+func (p ReadPointer) Before(other ReadPointer) bool {
+	if !p.CreatedAt.Equal(other.CreatedAt) {
+		return p.CreatedAt.Before(other.CreatedAt)
+	}
+	return bytes.Compare(p.MessageID[:], other.MessageID[:]) < 0
 }
@@ -0,0 +1,225 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Version is the version of the chat protocol, every frame carries it.
+const Version = 1
+
+// Set of frame types of the chat protocol. Clients send message, typing and
+// read frames, the server sends all of them.
+const (
+	TypeMessage = "message"
+	TypeTyping  = "typing"
+	TypeRead    = "read"
+	TypeSystem  = "system"
+	TypeError   = "error"
+	TypeAck     = "ack"
+)
+
+// Set of errors sent back to the client in an error frame.
+var (
+	ErrInvalidFrame       = errors.New("frame is invalid")
+	ErrUnsupportedVersion = errors.New("protocol version is not supported")
+	ErrMessageNotFound    = errors.New("chat message not found")
+)
+
+const (
+	// maxMessageLen is how many characters a message can have.
+	maxMessageLen = 2000
+
+	// maxClientIDLen is how long the id a client gives a message can be.
+	maxClientIDLen = 64
+)
+
+// Envelope is a frame of the chat protocol. A message sent with a client id
+// is acked with the ids of the stored message and of its stream entry, and
+// sending it again with the same client id does not store it twice.
+type Envelope struct {
+	Version  int             `json:"v"`
+	Type     string          `json:"type"`
+	ClientID string          `json:"client_id,omitempty"`
+	StreamID string          `json:"stream_id,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+// MessageData is the data of a message frame. Clients only send the text.
+type MessageData struct {
+	ID        uuid.UUID `json:"id"`
+	RoomID    uuid.UUID `json:"room_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	ImageURL  string    `json:"image_url"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TypingData is the data of a typing frame, clients send none.
+type TypingData struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+}
+
+// ReadData is the data of a read frame, the last message the user has read.
+// Clients only send the message id.
+type ReadData struct {
+	UserID    uuid.UUID `json:"user_id"`
+	MessageID uuid.UUID `json:"message_id"`
+}
+
+// SystemData is the data of a system frame, a message the server posted
+// about the trip. Event is the trip event it is about.
+type SystemData struct {
+	ID        uuid.UUID `json:"id"`
+	RoomID    uuid.UUID `json:"room_id"`
+	Event     string    `json:"event"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AckData is the data of an ack frame, the id of the stored message.
+type AckData struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// ErrorData is the data of an error frame.
+type ErrorData struct {
+	Error string `json:"error"`
+}
+
+// newEnvelope returns a frame of the type carrying the data.
+func newEnvelope(typ string, data any) (Envelope, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("json marshal: %w", err)
+	}
+
+	env := Envelope{
+		Version: Version,
+		Type:    typ,
+		Data:    raw,
+	}
+
+	return env, nil
+}
+
+// messageEnvelope returns the message frame of the message, or the system
+// frame of a system message.
+func messageEnvelope(msg ChatMessage) (Envelope, error) {
+	var env Envelope
+	var err error
+
+	switch msg.Type {
+	case TypeSystem:
+		env, err = newEnvelope(TypeSystem, SystemData{
+			ID:        msg.ID,
+			RoomID:    msg.RoomID,
+			Event:     msg.Event,
+			Text:      msg.Message,
+			CreatedAt: msg.CreatedAt,
+		})
+	default:
+		env, err = newEnvelope(TypeMessage, MessageData{
+			ID:        msg.ID,
+			RoomID:    msg.RoomID,
+			UserID:    msg.UserID,
+			Username:  msg.Username,
+			ImageURL:  msg.ImageURL,
+			Text:      msg.Message,
+			CreatedAt: msg.CreatedAt,
+		})
+	}
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	env.ClientID = msg.ClientID
+	env.StreamID = msg.StreamID
+
+	return env, nil
+}
+
+// ackEnvelope returns the frame acking the message.
+func ackEnvelope(msg ChatMessage) (Envelope, error) {
+	env, err := newEnvelope(TypeAck, AckData{ID: msg.ID})
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	env.ClientID = msg.ClientID
+	env.StreamID = msg.StreamID
+
+	return env, nil
+}
+
+// errorEnvelope returns the frame telling the client its frame was refused.
+func errorEnvelope(clientID string, err error) Envelope {
+	data, _ := json.Marshal(ErrorData{Error: err.Error()})
+
+	return Envelope{
+		Version:  Version,
+		Type:     TypeError,
+		ClientID: clientID,
+		Data:     data,
+	}
+}
+
+// decodeFrame decodes and validates a frame sent by a client.
+func decodeFrame(frame []byte) (Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(frame, &env); err != nil {
+		return Envelope{}, fmt.Errorf("%w: not a json object", ErrInvalidFrame)
+	}
+
+	if env.Version != Version {
+		return env, ErrUnsupportedVersion
+	}
+
+	if utf8.RuneCountInString(env.ClientID) > maxClientIDLen {
+		return env, fmt.Errorf("%w: client_id is longer than %d characters", ErrInvalidFrame, maxClientIDLen)
+	}
+
+	switch env.Type {
+	case TypeMessage, TypeTyping, TypeRead:
+	default:
+		return env, fmt.Errorf("%w: type %q cannot be sent", ErrInvalidFrame, env.Type)
+	}
+
+	return env, nil
+}
+
+// decodeText returns the text of a message frame.
+func decodeText(env Envelope) (string, error) {
+	var data MessageData
+	if err := json.Unmarshal(env.Data, &data); err != nil {
+		return "", fmt.Errorf("%w: data is not a message", ErrInvalidFrame)
+	}
+
+	text := strings.TrimSpace(data.Text)
+	switch {
+	case text == "":
+		return "", fmt.Errorf("%w: text is empty", ErrInvalidFrame)
+	case utf8.RuneCountInString(text) > maxMessageLen:
+		return "", fmt.Errorf("%w: text is longer than %d characters", ErrInvalidFrame, maxMessageLen)
+	}
+
+	return text, nil
+}
+
+// decodeRead returns the message id of a read frame.
+func decodeRead(env Envelope) (uuid.UUID, error) {
+	var data ReadData
+	if err := json.Unmarshal(env.Data, &data); err != nil || data.MessageID == uuid.Nil {
+		return uuid.Nil, fmt.Errorf("%w: message_id is missing", ErrInvalidFrame)
+	}
+
+	return data.MessageID, nil
+}
@@ -0,0 +1,114 @@
+package wsdb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/core/ws"
+	"github.com/google/uuid"
+)
+
+type dbChatMessage struct {
+	ID        uuid.UUID      `db:"id"`
+	Type      string         `db:"msg_type"`
+	Event     sql.NullString `db:"event"`
+	RoomID    uuid.UUID      `db:"room_id"`
+	TripID    uuid.UUID      `db:"trip_id"`
+	SenderID  uuid.NullUUID  `db:"sender_id"`
+	Content   string         `db:"msg_content"`
+	CreatedAt time.Time      `db:"created_at"`
+	Name      sql.NullString `db:"name"`
+	ImageURL  sql.NullString `db:"image_url"`
+}
+
+func toDBChatMessage(msg ws.ChatMessage) dbChatMessage {
+	dbMsg := dbChatMessage{
+		ID:        msg.ID,
+		Type:      msg.Type,
+		Event:     sql.NullString{String: msg.Event, Valid: msg.Event != ""},
+		RoomID:    msg.RoomID,
+		TripID:    msg.TripID,
+		Content:   msg.Message,
+		CreatedAt: msg.CreatedAt.UTC(),
+	}
+
+	if msg.UserID != uuid.Nil {
+		dbMsg.SenderID = uuid.NullUUID{UUID: msg.UserID, Valid: true}
+	}
+
+	return dbMsg
+}
+
+func toCoreChatMessage(dbMsg dbChatMessage) ws.ChatMessage {
+	return ws.ChatMessage{
+		ID:        dbMsg.ID,
+		Type:      dbMsg.Type,
+		Event:     dbMsg.Event.String,
+		RoomID:    dbMsg.RoomID,
+		TripID:    dbMsg.TripID,
+		UserID:    dbMsg.SenderID.UUID,
+		Username:  dbMsg.Name.String,
+		ImageURL:  dbMsg.ImageURL.String,
+		Message:   dbMsg.Content,
+		CreatedAt: dbMsg.CreatedAt.In(time.Local),
+	}
+}
+
+func toCoreChatMessages(dbMsgs []dbChatMessage) []ws.ChatMessage {
+	msgs := make([]ws.ChatMessage, len(dbMsgs))
+	for i, dbMsg := range dbMsgs {
+		msgs[i] = toCoreChatMessage(dbMsg)
+	}
+	return msgs
+}
+
+type dbUnread struct {
+	RoomID uuid.UUID `db:"room_id"`
+	Unread int       `db:"unread"`
+}
+
+// =============================================================================
+
+type dbRoom struct {
+	ID          uuid.UUID     `db:"id"`
+	TripID      uuid.UUID     `db:"trip_id"`
+	PassengerID uuid.NullUUID `db:"passenger_id"`
+	CreatedAt   time.Time     `db:"created_at"`
+}
+
+func toDBRoom(room ws.Room) dbRoom {
+	dbRoom := dbRoom{
+		ID:        room.ID,
+		TripID:    room.TripID,
+		CreatedAt: room.CreatedAt.UTC(),
+	}
+
+	if room.PassengerID != nil {
+		dbRoom.PassengerID = uuid.NullUUID{UUID: *room.PassengerID, Valid: true}
+	}
+
+	return dbRoom
+}
+
+func toCoreRoom(dbRoom dbRoom) ws.Room {
+	room := ws.Room{
+		ID:        dbRoom.ID,
+		TripID:    dbRoom.TripID,
+		CreatedAt: dbRoom.CreatedAt.In(time.Local),
+	}
+
+	if dbRoom.PassengerID.Valid {
+		passengerID := dbRoom.PassengerID.UUID
+		room.PassengerID = &passengerID
+	}
+
+	return room
+}
+
+func toCoreRooms(dbRooms []dbRoom) []ws.Room {
+	rooms := make([]ws.Room, len(dbRooms))
+	for i, dbRoom := range dbRooms {
+		rooms[i] = toCoreRoom(dbRoom)
+	}
+	return rooms
+}
@@ -1,9 +1,18 @@
-// Package wsdb contains user related CRUD functionality.
+// Package wsdb contains chat message related CRUD functionality.
 package wsdb
 
 import (
-	"github.com/TSMC-Uber/server/foundation/logger"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
 
+	"github.com/TSMC-Uber/server/business/core/ws"
+	"github.com/TSMC-Uber/server/business/sys/database"
+	"github.com/TSMC-Uber/server/foundation/logger"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -20,3 +29,222 @@ func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
 		db:  db,
 	}
 }
+
+// Create inserts a new chat message into the database.
+func (s *Store) Create(ctx context.Context, msg ws.ChatMessage) error {
+	dbMsg := toDBChatMessage(msg)
+
+	sql, args, err := sq.
+		Insert("chat_history").
+		Columns("id", "msg_type", "event", "room_id", "trip_id", "sender_id", "msg_content", "created_at").
+		Values(dbMsg.ID, dbMsg.Type, dbMsg.Event, dbMsg.RoomID, dbMsg.TripID, dbMsg.SenderID, dbMsg.Content, dbMsg.CreatedAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	if err := database.ExecContext(ctx, s.log, s.db, sql, args); err != nil {
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	return nil
+}
+
+// QueryByID gets the specified message from the database.
+func (s *Store) QueryByID(ctx context.Context, msgID uuid.UUID) (ws.ChatMessage, error) {
+	sql, args, err := selectChatMessages().
+		Where(sq.Eq{"chat_history.id": msgID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return ws.ChatMessage{}, fmt.Errorf("tosql: %w", err)
+	}
+
+	var dbMsg dbChatMessage
+	if err := database.GetContext(ctx, s.log, s.db, sql, args, &dbMsg); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return ws.ChatMessage{}, fmt.Errorf("namedquerystruct: %w", ws.ErrMessageNotFound)
+		}
+		return ws.ChatMessage{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreChatMessage(dbMsg), nil
+}
+
+// QueryByRoom retrieves the latest messages of the room matching the filter,
+// in the order they were sent. Messages are ordered by the time they were
+// sent and then by id, the ids in the filter are cursors into that order.
+// With only After set the oldest messages after it are retrieved instead, so
+// the messages can be paged forward.
+func (s *Store) QueryByRoom(ctx context.Context, roomID uuid.UUID, filter ws.QueryFilter, limit int) ([]ws.ChatMessage, error) {
+	builder := selectChatMessages().
+		Where(sq.Eq{"chat_history.room_id": roomID})
+
+	if filter.Before != nil {
+		builder = builder.Where("(chat_history.created_at, chat_history.id) < (SELECT created_at, id FROM chat_history WHERE id = ?)", *filter.Before)
+	}
+
+	if filter.After != nil {
+		builder = builder.Where("(chat_history.created_at, chat_history.id) > (SELECT created_at, id FROM chat_history WHERE id = ?)", *filter.After)
+	}
+
+	forward := filter.After != nil && filter.Before == nil
+	if forward {
+		builder = builder.OrderBy("chat_history.created_at", "chat_history.id")
+	} else {
+		builder = builder.OrderBy("chat_history.created_at DESC", "chat_history.id DESC")
+	}
+
+	sql, args, err := builder.
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("tosql: %w", err)
+	}
+
+	var dbMsgs []dbChatMessage
+	if err := database.QueryContext(ctx, s.log, s.db, sql, args, &dbMsgs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	// the latest messages are selected, they are returned oldest first
+	if !forward {
+		slices.Reverse(dbMsgs)
+	}
+
+	return toCoreChatMessages(dbMsgs), nil
+}
+
+// CountUnread counts the messages the other members and the server sent to
+// the rooms after the read pointers, by room id. Rooms without unread messages
+// are left out.
+func (s *Store) CountUnread(ctx context.Context, userID uuid.UUID, pointers []ws.ReadPointer) (map[uuid.UUID]int, error) {
+	rooms := make(sq.Or, len(pointers))
+	for i, p := range pointers {
+		rooms[i] = sq.And{
+			sq.Eq{"room_id": p.RoomID},
+			sq.Expr("(created_at, id) > (?, ?)", p.CreatedAt.UTC(), p.MessageID),
+		}
+	}
+
+	sql, args, err := sq.Select("room_id", "COUNT(*) AS unread").
+		From("chat_history").
+		Where(rooms).
+		Where("sender_id IS DISTINCT FROM ?", userID).
+		GroupBy("room_id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("tosql: %w", err)
+	}
+
+	var dbCounts []dbUnread
+	if err := database.QueryContext(ctx, s.log, s.db, sql, args, &dbCounts); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	counts := make(map[uuid.UUID]int, len(dbCounts))
+	for _, c := range dbCounts {
+		counts[c.RoomID] = c.Unread
+	}
+
+	return counts, nil
+}
+
+// CreateRoom inserts a new chat room into the database. A room the trip or
+// the passenger already has is left as it is.
+func (s *Store) CreateRoom(ctx context.Context, room ws.Room) error {
+	dbRoom := toDBRoom(room)
+
+	sql, args, err := sq.
+		Insert("chat_room").
+		Columns("id", "trip_id", "passenger_id", "created_at").
+		Values(dbRoom.ID, dbRoom.TripID, dbRoom.PassengerID, dbRoom.CreatedAt).
+		Suffix("ON CONFLICT DO NOTHING").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("tosql: %w", err)
+	}
+
+	if err := database.ExecContext(ctx, s.log, s.db, sql, args); err != nil {
+		return fmt.Errorf("execcontext: %w", err)
+	}
+
+	return nil
+}
+
+// QueryRoomByID gets the specified room from the database.
+func (s *Store) QueryRoomByID(ctx context.Context, roomID uuid.UUID) (ws.Room, error) {
+	return s.queryRoom(ctx, sq.Eq{"id": roomID})
+}
+
+// QueryRoomByPassenger gets the room of the prospective passenger of the trip
+// from the database.
+func (s *Store) QueryRoomByPassenger(ctx context.Context, tripID uuid.UUID, passengerID uuid.UUID) (ws.Room, error) {
+	return s.queryRoom(ctx, sq.Eq{"trip_id": tripID, "passenger_id": passengerID})
+}
+
+// QueryRoomsByTrip retrieves the rooms of the prospective passengers of the
+// trip from the database, newest first.
+func (s *Store) QueryRoomsByTrip(ctx context.Context, tripID uuid.UUID) ([]ws.Room, error) {
+	sql, args, err := sq.Select("id", "trip_id", "passenger_id", "created_at").
+		From("chat_room").
+		Where(sq.Eq{"trip_id": tripID}).
+		Where(sq.NotEq{"passenger_id": nil}).
+		OrderBy("created_at DESC").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("tosql: %w", err)
+	}
+
+	var dbRooms []dbRoom
+	if err := database.QueryContext(ctx, s.log, s.db, sql, args, &dbRooms); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreRooms(dbRooms), nil
+}
+
+func (s *Store) queryRoom(ctx context.Context, where sq.Eq) (ws.Room, error) {
+	sql, args, err := sq.Select("id", "trip_id", "passenger_id", "created_at").
+		From("chat_room").
+		Where(where).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return ws.Room{}, fmt.Errorf("tosql: %w", err)
+	}
+
+	var dbRoom dbRoom
+	if err := database.GetContext(ctx, s.log, s.db, sql, args, &dbRoom); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return ws.Room{}, fmt.Errorf("namedquerystruct: %w", ws.ErrRoomNotFound)
+		}
+		return ws.Room{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreRoom(dbRoom), nil
+}
+
+// selectChatMessages selects the messages with the name and image of their
+// senders, system messages have none.
+func selectChatMessages() sq.SelectBuilder {
+	return sq.Select(
+		"chat_history.id",
+		"chat_history.msg_type",
+		"chat_history.event",
+		"chat_history.room_id",
+		"chat_history.trip_id",
+		"chat_history.sender_id",
+		"chat_history.msg_content",
+		"chat_history.created_at",
+		"users.name",
+		"users.image_url",
+	).
+		From("chat_history").
+		LeftJoin("users ON users.id = chat_history.sender_id")
+}
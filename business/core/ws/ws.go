@@ -3,80 +3,838 @@ package ws
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/TSMC-Uber/server/business/core/trip"
 	"github.com/TSMC-Uber/server/business/core/user"
 	"github.com/TSMC-Uber/server/business/sys/cachedb"
+	"github.com/TSMC-Uber/server/business/sys/ratelimit"
+	"github.com/TSMC-Uber/server/foundation/logger"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/redis/go-redis/v9"
 )
 
+// Set of error variables for CRUD operations.
+var (
+	ErrRoomNotFound = errors.New("chat room not found")
+	ErrNotMember    = errors.New("user is not a member of the chat room")
+)
+
+const (
+	// ReplayCount is how many of the latest messages are replayed to a
+	// client when it connects.
+	ReplayCount = 50
+
+	// replayPage is how many messages after the last one a client has seen
+	// are read at a time when it reconnects.
+	replayPage = 500
+
+	// streamMaxLen is about how many messages the stream of a room keeps as
+	// a hot cache, the database keeps them all.
+	streamMaxLen = 200
+
+	// clientIDTTL is how long the id a client gave a message is remembered,
+	// a message sent again within it is not stored twice.
+	clientIDTTL = 24 * time.Hour
+
+	// replyQueue is how many acks and errors can wait to be written to a
+	// client.
+	replyQueue = 16
+)
+
 // Storer interface declares the behavior this package needs to perists and
 // retrieve data.
 type Storer interface {
-	// SendChatHistory(ctx context.Context, streamName string, channelName string, conn *websocket.Conn) error
-	// Disconnect(ctx context.Context, userID uuid.UUID) error
+	Create(ctx context.Context, msg ChatMessage) error
+	QueryByID(ctx context.Context, msgID uuid.UUID) (ChatMessage, error)
+	QueryByRoom(ctx context.Context, roomID uuid.UUID, filter QueryFilter, limit int) ([]ChatMessage, error)
+	CountUnread(ctx context.Context, userID uuid.UUID, pointers []ReadPointer) (map[uuid.UUID]int, error)
+	CreateRoom(ctx context.Context, room Room) error
+	QueryRoomByID(ctx context.Context, roomID uuid.UUID) (Room, error)
+	QueryRoomByPassenger(ctx context.Context, tripID uuid.UUID, passengerID uuid.UUID) (Room, error)
+	QueryRoomsByTrip(ctx context.Context, tripID uuid.UUID) ([]Room, error)
 }
 
 // Core manages the set of APIs for user access.
 type Core struct {
 	storer Storer
+	trip   *trip.Core
+	user   *user.Core
+	limit  ratelimit.Policy
 }
 
-// NewCore constructs a core for user api access.
-func NewCore(storer Storer) *Core {
+// NewCore constructs a core for user api access. The messages a user sends
+// are limited by the policy.
+func NewCore(storer Storer, trip *trip.Core, user *user.Core, limit ratelimit.Policy) *Core {
 	return &Core{
 		storer: storer,
+		trip:   trip,
+		user:   user,
+		limit:  limit,
 	}
 }
 
-func (c *Core) SendChatHistory(ctx context.Context, streamName string, channelName string, conn *websocket.Conn) error {
-	messages, err := cachedb.XRange(ctx, streamName, "-", "+")
+// CreateTripRoom creates the room of the trip, its id is the id of the trip.
+func (c *Core) CreateTripRoom(ctx context.Context, tripID uuid.UUID) (Room, error) {
+	room := Room{
+		ID:        tripID,
+		TripID:    tripID,
+		CreatedAt: time.Now(),
+	}
+
+	if err := c.storer.CreateRoom(ctx, room); err != nil {
+		return Room{}, fmt.Errorf("create: %w", err)
+	}
+
+	return room, nil
+}
+
+// OpenRoom returns the room of a prospective passenger with the driver of
+// the trip, creating it the first time.
+func (c *Core) OpenRoom(ctx context.Context, tripID uuid.UUID, passengerID uuid.UUID) (Room, error) {
+	trp, err := c.trip.QueryByID(ctx, tripID)
 	if err != nil {
-		return fmt.Errorf("xrange: %w", err)
+		return Room{}, fmt.Errorf("query trip: tripID[%s]: %w", tripID, err)
 	}
 
-	for _, xMessage := range messages {
-		jsonMsg, ok := xMessage.Values["message"].(string)
-		if !ok {
-			continue
+	if trp.DriverID == passengerID {
+		return Room{}, trip.ErrJoinOwnTrip
+	}
+
+	room, err := c.storer.QueryRoomByPassenger(ctx, tripID, passengerID)
+	if err == nil {
+		return room, nil
+	}
+	if !errors.Is(err, ErrRoomNotFound) {
+		return Room{}, fmt.Errorf("query room: tripID[%s]: %w", tripID, err)
+	}
+
+	if trp.Status != trip.TripStatusNotStarted {
+		return Room{}, trip.ErrTripNotJoinable
+	}
+
+	room = Room{
+		ID:          uuid.New(),
+		TripID:      tripID,
+		PassengerID: &passengerID,
+		CreatedAt:   time.Now(),
+	}
+
+	// Creating a room that was opened in the meantime does nothing, the room
+	// is read back so both get the same one.
+	if err := c.storer.CreateRoom(ctx, room); err != nil {
+		return Room{}, fmt.Errorf("create: %w", err)
+	}
+
+	room, err = c.storer.QueryRoomByPassenger(ctx, tripID, passengerID)
+	if err != nil {
+		return Room{}, fmt.Errorf("query room: tripID[%s]: %w", tripID, err)
+	}
+
+	return room, nil
+}
+
+// QueryRoomByID gets the specified room.
+func (c *Core) QueryRoomByID(ctx context.Context, roomID uuid.UUID) (Room, error) {
+	room, err := c.queryRoom(ctx, roomID)
+	if err != nil {
+		return Room{}, fmt.Errorf("query: roomID[%s]: %w", roomID, err)
+	}
+
+	return room, nil
+}
+
+// queryRoom gets the specified room, creating the room of a trip when the
+// trip has none. The room is created after the trip, so creating it can fail
+// once the trip is stored.
+func (c *Core) queryRoom(ctx context.Context, roomID uuid.UUID) (Room, error) {
+	room, err := c.storer.QueryRoomByID(ctx, roomID)
+	if err == nil || !errors.Is(err, ErrRoomNotFound) {
+		return room, err
+	}
+
+	if _, terr := c.trip.QueryByID(ctx, roomID); terr != nil {
+		if errors.Is(terr, trip.ErrNotFound) {
+			return Room{}, err
+		}
+		return Room{}, fmt.Errorf("query trip: tripID[%s]: %w", roomID, terr)
+	}
+
+	room, err = c.CreateTripRoom(ctx, roomID)
+	if err != nil {
+		return Room{}, fmt.Errorf("create trip room: tripID[%s]: %w", roomID, err)
+	}
+
+	return room, nil
+}
+
+// QueryRooms retrieves the rooms the user has with prospective passengers of
+// the trip. The driver gets all of them, anybody else only their own.
+func (c *Core) QueryRooms(ctx context.Context, tripID uuid.UUID, userID uuid.UUID) ([]Room, error) {
+	trp, err := c.trip.QueryByID(ctx, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("query trip: tripID[%s]: %w", tripID, err)
+	}
+
+	if trp.DriverID == userID {
+		rooms, err := c.storer.QueryRoomsByTrip(ctx, tripID)
+		if err != nil {
+			return nil, fmt.Errorf("query: tripID[%s]: %w", tripID, err)
+		}
+		return rooms, nil
+	}
+
+	room, err := c.storer.QueryRoomByPassenger(ctx, tripID, userID)
+	if err != nil {
+		if errors.Is(err, ErrRoomNotFound) {
+			return []Room{}, nil
+		}
+		return nil, fmt.Errorf("query: tripID[%s]: %w", tripID, err)
+	}
+
+	return []Room{room}, nil
+}
+
+// Authorize returns the room when the user is a member of it. The members of
+// the room of a trip are its driver and accepted passengers, the members of
+// the room of a prospective passenger are the passenger and the driver.
+func (c *Core) Authorize(ctx context.Context, roomID uuid.UUID, userID uuid.UUID) (Room, error) {
+	room, err := c.queryRoom(ctx, roomID)
+	if err != nil {
+		return Room{}, fmt.Errorf("query: roomID[%s]: %w", roomID, err)
+	}
+
+	trp, err := c.trip.QueryByID(ctx, room.TripID)
+	if err != nil {
+		return Room{}, fmt.Errorf("query trip: tripID[%s]: %w", room.TripID, err)
+	}
+
+	if !room.IsTripRoom() {
+		if userID != trp.DriverID && userID != *room.PassengerID {
+			return Room{}, ErrNotMember
+		}
+		return room, nil
+	}
+
+	ok, err := c.trip.IsParticipant(ctx, trp, userID)
+	if err != nil {
+		return Room{}, fmt.Errorf("isparticipant: tripID[%s]: %w", room.TripID, err)
+	}
+	if !ok {
+		return Room{}, ErrNotMember
+	}
+
+	return room, nil
+}
+
+// Kick disconnects the user from the room on every instance.
+func (c *Core) Kick(ctx context.Context, roomID uuid.UUID, userID uuid.UUID) error {
+	if err := cachedb.Publish(ctx, kickChannelName(roomID), userID.String()); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	return nil
+}
+
+// Serve joins the user to the room until the connection closes or the user is
+// kicked. The latest messages are replayed first, see SendChatHistory.
+func (c *Core) Serve(ctx context.Context, usr user.User, room Room, lastSeen *uuid.UUID, conn *websocket.Conn) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Subscribe before the history is sent so no message falls in between,
+	// the client drops the messages it gets twice by their id.
+	pubsub := cachedb.Subscribe(ctx, channelName(room.ID), kickChannelName(room.ID))
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("subscribe: %w", err)
+	}
+	ch := pubsub.Channel()
+
+	if err := c.SendChatHistory(ctx, room.ID, lastSeen, conn); err != nil {
+		return fmt.Errorf("send chat history: %w", err)
+	}
+
+	// Receive frames from WebSocket, the acks and errors for them come back
+	// on replies since only this goroutine writes to the connection.
+	replies := make(chan Envelope, replyQueue)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.ReceiveChatMessages(ctx, usr, room, conn, replies)
+	}()
+
+	// Receive real-time frames from the Redis channel and send to WebSocket
+	for {
+		select {
+		case <-done:
+			// the client went away
+			return nil
+
+		case env := <-replies:
+			if err := conn.WriteJSON(env); err != nil {
+				return fmt.Errorf("write reply: %w", err)
+			}
+
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+
+			if msg.Channel == kickChannelName(room.ID) {
+				if msg.Payload != usr.ID.String() {
+					continue
+				}
+				closeMsg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "removed from the trip")
+				conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+				return nil
+			}
+
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(msg.Payload)); err != nil {
+				return fmt.Errorf("write message: %w", err)
+			}
+		}
+	}
+}
+
+// Create stores a message sent to the room and publishes it to the connected
+// clients. The client id is the id the client gave the message, if any.
+func (c *Core) Create(ctx context.Context, usr user.User, room Room, text string, clientID string) (ChatMessage, error) {
+	msg := ChatMessage{
+		ID:        uuid.New(),
+		Type:      TypeMessage,
+		RoomID:    room.ID,
+		TripID:    room.TripID,
+		UserID:    usr.ID,
+		Username:  usr.Name,
+		ImageURL:  usr.ImageURL,
+		Message:   text,
+		ClientID:  clientID,
+		CreatedAt: time.Now(),
+	}
+
+	return c.append(ctx, msg)
+}
+
+// CreateSystem posts a system message about the trip event to the room of
+// the trip.
+func (c *Core) CreateSystem(ctx context.Context, evt trip.Event) (ChatMessage, error) {
+	text, err := c.systemText(ctx, evt)
+	if err != nil {
+		return ChatMessage{}, fmt.Errorf("text: %w", err)
+	}
+
+	if _, err := c.queryRoom(ctx, evt.TripID); err != nil {
+		return ChatMessage{}, fmt.Errorf("query room: tripID[%s]: %w", evt.TripID, err)
+	}
+
+	msg := ChatMessage{
+		ID:        uuid.New(),
+		Type:      TypeSystem,
+		Event:     evt.Type,
+		RoomID:    evt.TripID,
+		TripID:    evt.TripID,
+		Message:   text,
+		CreatedAt: time.Now(),
+	}
+
+	return c.append(ctx, msg)
+}
+
+// TripEvents returns the handler posting the events of trips to their rooms,
+// see trip.Core.Subscribe. A message that cannot be posted is logged, the
+// change of the trip stands.
+func (c *Core) TripEvents(log *logger.Logger) trip.EventHandler {
+	return func(ctx context.Context, evt trip.Event) {
+		if _, err := c.CreateSystem(ctx, evt); err != nil {
+			log.Error(ctx, "chat: trip event", "tripID", evt.TripID, "event", evt.Type, "ERROR", err)
+		}
+	}
+}
+
+// MarkRead moves the read pointer of the user in the room to the message,
+// reporting whether it moved. The pointer never moves back to an older
+// message.
+func (c *Core) MarkRead(ctx context.Context, userID uuid.UUID, room Room, msgID uuid.UUID) (bool, error) {
+	msg, err := c.storer.QueryByID(ctx, msgID)
+	if err != nil {
+		return false, fmt.Errorf("query: msgID[%s]: %w", msgID, err)
+	}
+
+	if msg.RoomID != room.ID {
+		return false, fmt.Errorf("query: msgID[%s]: %w", msgID, ErrMessageNotFound)
+	}
+
+	pointer := ReadPointer{
+		RoomID:    room.ID,
+		MessageID: msg.ID,
+		CreatedAt: msg.CreatedAt,
+	}
+
+	val, err := cachedb.HGet(ctx, readKey(userID), room.ID.String())
+	switch {
+	case err == nil:
+		var current ReadPointer
+		if err := json.Unmarshal([]byte(val), &current); err == nil && !current.Before(pointer) {
+			return false, nil
+		}
+	case !errors.Is(err, redis.Nil):
+		return false, fmt.Errorf("hget: %w", err)
+	}
+
+	data, err := json.Marshal(pointer)
+	if err != nil {
+		return false, fmt.Errorf("json marshal: %w", err)
+	}
+
+	if err := cachedb.HSet(ctx, readKey(userID), room.ID.String(), data); err != nil {
+		return false, fmt.Errorf("hset: %w", err)
+	}
+
+	return true, nil
+}
+
+// CountUnread returns how many messages the other members sent to the rooms
+// of the trips since the user last read them, by trip id. Only the trips the
+// user drives or was accepted on are counted.
+func (c *Core) CountUnread(ctx context.Context, userID uuid.UUID, trips []trip.UserTrip) (map[uuid.UUID]int, error) {
+	stored, err := cachedb.HGetAll(ctx, readKey(userID))
+	if err != nil {
+		return nil, fmt.Errorf("hgetall: %w", err)
+	}
+
+	pointers := readPointers(userID, trips, stored)
+	if len(pointers) == 0 {
+		return map[uuid.UUID]int{}, nil
+	}
+
+	counts, err := c.storer.CountUnread(ctx, userID, pointers)
+	if err != nil {
+		return nil, fmt.Errorf("count: userID[%s]: %w", userID, err)
+	}
+
+	unread := make(map[uuid.UUID]int, len(pointers))
+	for _, p := range pointers {
+		unread[p.RoomID] = counts[p.RoomID]
+	}
+
+	return unread, nil
+}
+
+// QueryByRoom retrieves the latest messages of the room matching the filter,
+// in the order they were sent.
+func (c *Core) QueryByRoom(ctx context.Context, roomID uuid.UUID, filter QueryFilter, limit int) ([]ChatMessage, error) {
+	msgs, err := c.storer.QueryByRoom(ctx, roomID, filter, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query: roomID[%s]: %w", roomID, err)
+	}
+
+	return msgs, nil
+}
+
+// SendChatHistory replays the latest messages of the room to the client. A
+// client that saw lastSeen before also gets every message sent after it.
+func (c *Core) SendChatHistory(ctx context.Context, roomID uuid.UUID, lastSeen *uuid.UUID, conn *websocket.Conn) error {
+	msgs, err := c.recent(ctx, roomID)
+	if err != nil {
+		return fmt.Errorf("recent: %w", err)
+	}
+
+	if lastSeen != nil && !contains(msgs, *lastSeen) {
+		after, err := c.queryAfter(ctx, roomID, *lastSeen)
+		if err != nil {
+			return fmt.Errorf("query after: %w", err)
+		}
+		if len(after) > len(msgs) {
+			msgs = after
+		}
+	}
+
+	for _, msg := range msgs {
+		env, err := messageEnvelope(msg)
+		if err != nil {
+			return fmt.Errorf("envelope: %w", err)
 		}
 
-		conn.WriteMessage(websocket.TextMessage, []byte(jsonMsg))
+		if err := conn.WriteJSON(env); err != nil {
+			return fmt.Errorf("write message: %w", err)
+		}
 	}
 
 	return nil
 }
 
-func (c *Core) ReceiveChatMessages(ctx context.Context, user user.User, streamName string, channelName string, conn *websocket.Conn, ch <-chan *redis.Message) error {
+// queryAfter retrieves every message of the room sent after the message,
+// paging forward until the latest one.
+func (c *Core) queryAfter(ctx context.Context, roomID uuid.UUID, msgID uuid.UUID) ([]ChatMessage, error) {
+	var msgs []ChatMessage
+	for cursor := msgID; ; {
+		page, err := c.storer.QueryByRoom(ctx, roomID, QueryFilter{After: &cursor}, replayPage)
+		if err != nil {
+			return nil, err
+		}
+
+		msgs = append(msgs, page...)
+		if len(page) < replayPage {
+			return msgs, nil
+		}
+		cursor = page[len(page)-1].ID
+	}
+}
+
+// ReceiveChatMessages handles the frames the user sends to the room until the
+// connection closes. The acks and errors for them are sent on replies.
+func (c *Core) ReceiveChatMessages(ctx context.Context, usr user.User, room Room, conn *websocket.Conn, replies chan<- Envelope) error {
 	for {
-		_, message, err := conn.ReadMessage()
+		_, frame, err := conn.ReadMessage()
 		if err != nil {
 			return fmt.Errorf("read message: %w", err)
 		}
 
-		chatMessage := ChatMessage{
-			UserID:   user.ID,
-			Username: user.Name,
-			ImageURL: user.ImageURL,
-			Message:  string(message),
+		reply, err := c.receive(ctx, usr, room, frame)
+		if err != nil {
+			return err
+		}
+		if reply.Type == "" {
+			continue
+		}
+
+		select {
+		case replies <- reply:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// =============================================================================
+
+// receive handles a frame sent by the user, returning the frame to reply
+// with, if any. Frames the client got wrong are replied to with an error
+// frame, only the errors that should close the connection are returned.
+func (c *Core) receive(ctx context.Context, usr user.User, room Room, frame []byte) (Envelope, error) {
+	env, err := decodeFrame(frame)
+	if err != nil {
+		return errorEnvelope(env.ClientID, err), nil
+	}
+
+	var reply Envelope
+	switch env.Type {
+	case TypeMessage:
+		reply, err = c.receiveMessage(ctx, usr, room, env)
+	case TypeTyping:
+		err = c.receiveTyping(ctx, usr, room)
+	case TypeRead:
+		err = c.receiveRead(ctx, usr, room, env)
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidFrame), errors.Is(err, ratelimit.ErrLimited):
+			return errorEnvelope(env.ClientID, err), nil
+		case errors.Is(err, ErrMessageNotFound):
+			return errorEnvelope(env.ClientID, ErrMessageNotFound), nil
+		default:
+			return Envelope{}, fmt.Errorf("receive %s: %w", env.Type, err)
 		}
+	}
+
+	return reply, nil
+}
+
+// receiveMessage stores the message and returns the ack for it. A message
+// whose client id was seen before is acked again without being stored.
+func (c *Core) receiveMessage(ctx context.Context, usr user.User, room Room, env Envelope) (Envelope, error) {
+	text, err := decodeText(env)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	res, err := ratelimit.Allow(ctx, "chat:user:"+usr.ID.String(), c.limit)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("ratelimit: %w", err)
+	}
+	if !res.Allowed {
+		return Envelope{}, ratelimit.ErrLimited
+	}
+
+	if env.ClientID != "" {
+		key := clientIDKey(usr.ID, env.ClientID)
 
-		jsonMessage, err := json.Marshal(chatMessage)
+		claimed, err := cachedb.SetNX(ctx, key, "", clientIDTTL)
 		if err != nil {
-			return fmt.Errorf("json marshal: %w", err)
+			return Envelope{}, fmt.Errorf("setnx: %w", err)
 		}
 
-		// Add message to the Stream
-		_, err = cachedb.XAdd(ctx, streamName, map[string]interface{}{"message": jsonMessage})
+		if !claimed {
+			// The ack of the message is kept once it is stored, while it is
+			// being stored the first ack is on its way.
+			val, err := cachedb.GetPrimary(ctx, key)
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return Envelope{}, fmt.Errorf("get: %w", err)
+			}
+
+			var ack Envelope
+			if val != "" && json.Unmarshal([]byte(val), &ack) == nil {
+				return ack, nil
+			}
+			return Envelope{}, nil
+		}
+	}
+
+	msg, err := c.Create(ctx, usr, room, text, env.ClientID)
+	if err != nil {
+		if env.ClientID != "" {
+			cachedb.Remove(ctx, clientIDKey(usr.ID, env.ClientID))
+		}
+		return Envelope{}, fmt.Errorf("create: %w", err)
+	}
+
+	ack, err := ackEnvelope(msg)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("ack: %w", err)
+	}
+
+	if env.ClientID != "" {
+		data, err := json.Marshal(ack)
 		if err != nil {
-			return fmt.Errorf("xadd: %w", err)
+			return Envelope{}, fmt.Errorf("json marshal: %w", err)
+		}
+
+		if _, err := cachedb.SetXX(ctx, clientIDKey(usr.ID, env.ClientID), data, clientIDTTL); err != nil {
+			return Envelope{}, fmt.Errorf("setxx: %w", err)
 		}
+	}
+
+	return ack, nil
+}
+
+// receiveTyping tells the other members the user is typing. Typing frames
+// over the limit are dropped.
+func (c *Core) receiveTyping(ctx context.Context, usr user.User, room Room) error {
+	res, err := ratelimit.Allow(ctx, "chat-typing:user:"+usr.ID.String(), c.limit)
+	if err != nil {
+		return fmt.Errorf("ratelimit: %w", err)
+	}
+	if !res.Allowed {
+		return nil
+	}
+
+	env, err := newEnvelope(TypeTyping, TypingData{
+		UserID:   usr.ID,
+		Username: usr.Name,
+	})
+	if err != nil {
+		return fmt.Errorf("envelope: %w", err)
+	}
+
+	if err := c.publish(ctx, room.ID, env); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	return nil
+}
+
+// receiveRead moves the read pointer of the user and tells the other members
+// when it moved.
+func (c *Core) receiveRead(ctx context.Context, usr user.User, room Room, env Envelope) error {
+	msgID, err := decodeRead(env)
+	if err != nil {
+		return err
+	}
+
+	res, err := ratelimit.Allow(ctx, "chat-read:user:"+usr.ID.String(), c.limit)
+	if err != nil {
+		return fmt.Errorf("ratelimit: %w", err)
+	}
+	if !res.Allowed {
+		return ratelimit.ErrLimited
+	}
+
+	moved, err := c.MarkRead(ctx, usr.ID, room, msgID)
+	if err != nil {
+		return fmt.Errorf("markread: %w", err)
+	}
+	if !moved {
+		return nil
+	}
+
+	read, err := newEnvelope(TypeRead, ReadData{
+		UserID:    usr.ID,
+		MessageID: msgID,
+	})
+	if err != nil {
+		return fmt.Errorf("envelope: %w", err)
+	}
+
+	if err := c.publish(ctx, room.ID, read); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	return nil
+}
+
+// append stores the message, adds it to the stream of the room and publishes
+// it to the connected clients.
+func (c *Core) append(ctx context.Context, msg ChatMessage) (ChatMessage, error) {
+	if err := c.storer.Create(ctx, msg); err != nil {
+		return ChatMessage{}, fmt.Errorf("create: %w", err)
+	}
+
+	jsonMessage, err := json.Marshal(msg)
+	if err != nil {
+		return ChatMessage{}, fmt.Errorf("json marshal: %w", err)
+	}
+
+	// Add message to the Stream
+	streamID, err := cachedb.XAddMaxLen(ctx, streamName(msg.RoomID), streamMaxLen, map[string]interface{}{"message": jsonMessage})
+	if err != nil {
+		return ChatMessage{}, fmt.Errorf("xadd: %w", err)
+	}
+	msg.StreamID = streamID
+
+	env, err := messageEnvelope(msg)
+	if err != nil {
+		return ChatMessage{}, fmt.Errorf("envelope: %w", err)
+	}
+
+	// Publish message for real-time updates
+	if err := c.publish(ctx, msg.RoomID, env); err != nil {
+		return ChatMessage{}, fmt.Errorf("publish: %w", err)
+	}
+
+	return msg, nil
+}
 
-		// Publish message for real-time updates
-		err = cachedb.Publish(ctx, channelName, string(jsonMessage))
+// systemText returns the text of the system message about the trip event.
+func (c *Core) systemText(ctx context.Context, evt trip.Event) (string, error) {
+	switch evt.Type {
+	case trip.EventPassengerAccepted:
+		usr, err := c.user.QueryByID(ctx, evt.PassengerID)
 		if err != nil {
-			return fmt.Errorf("publish: %w", err)
+			return "", fmt.Errorf("query user: userID[%s]: %w", evt.PassengerID, err)
+		}
+		return usr.Name + " joined the trip", nil
+	case trip.EventStarted:
+		return "The trip has started", nil
+	case trip.EventFinished:
+		return "The trip has finished", nil
+	case trip.EventCancelled:
+		return "The trip was cancelled", nil
+	case trip.EventStartTimeChanged:
+		return "The start time was changed to " + evt.StartTime.Format("Mon, 02 Jan 2006 15:04 MST"), nil
+	default:
+		return "", fmt.Errorf("unknown event %q", evt.Type)
+	}
+}
+
+// publish sends the frame to the members of the room connected to any
+// instance.
+func (c *Core) publish(ctx context.Context, roomID uuid.UUID, env Envelope) error {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("json marshal: %w", err)
+	}
+
+	if err := cachedb.Publish(ctx, channelName(roomID), string(data)); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	return nil
+}
+
+// recent returns the latest messages of the room from the stream, or from
+// the database when the stream does not hold enough of them.
+func (c *Core) recent(ctx context.Context, roomID uuid.UUID) ([]ChatMessage, error) {
+	xMessages, err := cachedb.XRevRangeN(ctx, streamName(roomID), "+", "-", ReplayCount)
+	if err != nil {
+		return nil, fmt.Errorf("xrevrange: %w", err)
+	}
+
+	if len(xMessages) == ReplayCount {
+		return fromStream(xMessages), nil
+	}
+
+	msgs, err := c.storer.QueryByRoom(ctx, roomID, QueryFilter{}, ReplayCount)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return msgs, nil
+}
+
+// fromStream decodes the messages of the stream, given newest first, into
+// the order they were sent.
+func fromStream(xMessages []redis.XMessage) []ChatMessage {
+	msgs := make([]ChatMessage, 0, len(xMessages))
+	for i := len(xMessages) - 1; i >= 0; i-- {
+		jsonMsg, ok := xMessages[i].Values["message"].(string)
+		if !ok {
+			continue
+		}
+
+		var msg ChatMessage
+		if err := json.Unmarshal([]byte(jsonMsg), &msg); err != nil {
+			continue
+		}
+		msg.StreamID = xMessages[i].ID
+
+		msgs = append(msgs, msg)
+	}
+
+	return msgs
+}
+
+// readPointers returns the read pointers of the rooms of the trips the user
+// drives or was accepted on, from the ones stored by room id.
+func readPointers(userID uuid.UUID, trips []trip.UserTrip, stored map[string]string) []ReadPointer {
+	pointers := make([]ReadPointer, 0, len(trips))
+	for _, trp := range trips {
+		if trp.DriverID != userID && trp.MyStatus != trip.StatusAccepted {
+			continue
+		}
+
+		pointer := ReadPointer{RoomID: trp.TripID}
+		if val, ok := stored[trp.TripID.String()]; ok {
+			var p ReadPointer
+			if err := json.Unmarshal([]byte(val), &p); err == nil {
+				pointer = p
+			}
+		}
+
+		pointers = append(pointers, pointer)
+	}
+
+	return pointers
+}
+
+func contains(msgs []ChatMessage, id uuid.UUID) bool {
+	for _, msg := range msgs {
+		if msg.ID == id {
+			return true
 		}
 	}
+	return false
+}
+
+func streamName(roomID uuid.UUID) string {
+	return "chatstream:" + roomID.String()
+}
+
+func channelName(roomID uuid.UUID) string {
+	return "chatroom:" + roomID.String()
+}
+
+func kickChannelName(roomID uuid.UUID) string {
+	return "chatroom:kick:" + roomID.String()
+}
+
+func readKey(userID uuid.UUID) string {
+	return "chat:read:" + userID.String()
+}
+
+func clientIDKey(userID uuid.UUID, clientID string) string {
+	return "chat:client:" + userID.String() + ":" + clientID
 }
@@ -0,0 +1,393 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/core/trip"
+	"github.com/TSMC-Uber/server/business/core/user"
+	"github.com/TSMC-Uber/server/business/sys/ratelimit"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mocks struct {
+	storer *MockStorer
+	trip   *trip.MockStorer
+	user   *user.MockStorer
+}
+
+func newTestCore() (*Core, mocks) {
+	m := mocks{
+		storer: new(MockStorer),
+		trip:   new(trip.MockStorer),
+		user:   new(user.MockStorer),
+	}
+
+	return NewCore(m.storer, trip.NewCore(m.trip), user.NewCore(m.user), ratelimit.Policy{}), m
+}
+
+func TestOpenRoom(t *testing.T) {
+	core, m := newTestCore()
+
+	tripID := uuid.New()
+	driverID := uuid.New()
+	passengerID := uuid.New()
+
+	m.trip.On("QueryByID", mock.Anything, tripID).Return(trip.TripView{ID: tripID, DriverID: driverID, Status: trip.TripStatusNotStarted}, nil)
+	m.storer.On("QueryRoomByPassenger", mock.Anything, tripID, passengerID).Return(Room{}, ErrRoomNotFound).Once()
+	m.storer.On("CreateRoom", mock.Anything, mock.MatchedBy(func(room Room) bool {
+		return room.TripID == tripID && room.PassengerID != nil && *room.PassengerID == passengerID
+	})).Return(nil)
+
+	stored := Room{ID: uuid.New(), TripID: tripID, PassengerID: &passengerID}
+	m.storer.On("QueryRoomByPassenger", mock.Anything, tripID, passengerID).Return(stored, nil)
+
+	room, err := core.OpenRoom(context.Background(), tripID, passengerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, stored, room)
+	assert.False(t, room.IsTripRoom())
+
+	// the driver cannot open a room with themselves
+	_, err = core.OpenRoom(context.Background(), tripID, driverID)
+	assert.ErrorIs(t, err, trip.ErrJoinOwnTrip)
+
+	m.storer.AssertNumberOfCalls(t, "CreateRoom", 1)
+}
+
+func TestOpenRoomNotJoinable(t *testing.T) {
+	core, m := newTestCore()
+
+	tripID := uuid.New()
+	passengerID := uuid.New()
+
+	m.trip.On("QueryByID", mock.Anything, tripID).Return(trip.TripView{ID: tripID, DriverID: uuid.New(), Status: trip.TripStatusIn}, nil)
+	m.storer.On("QueryRoomByPassenger", mock.Anything, tripID, passengerID).Return(Room{}, ErrRoomNotFound)
+
+	_, err := core.OpenRoom(context.Background(), tripID, passengerID)
+	assert.ErrorIs(t, err, trip.ErrTripNotJoinable)
+	m.storer.AssertNotCalled(t, "CreateRoom", mock.Anything, mock.Anything)
+}
+
+func TestQueryAfter(t *testing.T) {
+	core, m := newTestCore()
+
+	roomID := uuid.New()
+	lastSeen := uuid.New()
+
+	messages := func(n int) []ChatMessage {
+		msgs := make([]ChatMessage, n)
+		for i := range msgs {
+			msgs[i] = ChatMessage{ID: uuid.New(), RoomID: roomID}
+		}
+		return msgs
+	}
+	first := messages(replayPage)
+	second := messages(3)
+
+	m.storer.On("QueryByRoom", mock.Anything, roomID, QueryFilter{After: &lastSeen}, replayPage).Return(first, nil)
+	m.storer.On("QueryByRoom", mock.Anything, roomID, QueryFilter{After: &first[replayPage-1].ID}, replayPage).Return(second, nil)
+
+	msgs, err := core.queryAfter(context.Background(), roomID, lastSeen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, append(first, second...), msgs)
+}
+
+func TestAuthorize(t *testing.T) {
+	core, m := newTestCore()
+
+	tripID := uuid.New()
+	driverID := uuid.New()
+	acceptedID := uuid.New()
+	pendingID := uuid.New()
+	prospectID := uuid.New()
+
+	tripRoom := Room{ID: tripID, TripID: tripID}
+	directRoom := Room{ID: uuid.New(), TripID: tripID, PassengerID: &prospectID}
+
+	m.storer.On("QueryRoomByID", mock.Anything, tripRoom.ID).Return(tripRoom, nil)
+	m.storer.On("QueryRoomByID", mock.Anything, directRoom.ID).Return(directRoom, nil)
+	m.trip.On("QueryByID", mock.Anything, tripID).Return(trip.TripView{ID: tripID, DriverID: driverID}, nil)
+	m.trip.On("QueryPassenger", mock.Anything, tripID, acceptedID).Return(trip.TripPassenger{Status: trip.StatusAccepted}, nil)
+	m.trip.On("QueryPassenger", mock.Anything, tripID, pendingID).Return(trip.TripPassenger{Status: trip.StatusPending}, nil)
+	m.trip.On("QueryPassenger", mock.Anything, tripID, prospectID).Return(trip.TripPassenger{}, trip.ErrPassengerNotFound)
+
+	tests := []struct {
+		name   string
+		room   Room
+		userID uuid.UUID
+		want   error
+	}{
+		{"trip room driver", tripRoom, driverID, nil},
+		{"trip room accepted passenger", tripRoom, acceptedID, nil},
+		{"trip room pending passenger", tripRoom, pendingID, ErrNotMember},
+		{"trip room prospective passenger", tripRoom, prospectID, ErrNotMember},
+		{"direct room driver", directRoom, driverID, nil},
+		{"direct room its passenger", directRoom, prospectID, nil},
+		{"direct room somebody else", directRoom, acceptedID, ErrNotMember},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room, err := core.Authorize(context.Background(), tt.room.ID, tt.userID)
+			if tt.want != nil {
+				assert.ErrorIs(t, err, tt.want)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.room.ID, room.ID)
+		})
+	}
+}
+
+func TestAuthorizeMissingTripRoom(t *testing.T) {
+	core, m := newTestCore()
+
+	tripID := uuid.New()
+	driverID := uuid.New()
+	unknownID := uuid.New()
+
+	m.storer.On("QueryRoomByID", mock.Anything, tripID).Return(Room{}, ErrRoomNotFound)
+	m.storer.On("QueryRoomByID", mock.Anything, unknownID).Return(Room{}, ErrRoomNotFound)
+	m.storer.On("CreateRoom", mock.Anything, mock.MatchedBy(func(room Room) bool {
+		return room.ID == tripID && room.TripID == tripID && room.IsTripRoom()
+	})).Return(nil)
+	m.trip.On("QueryByID", mock.Anything, tripID).Return(trip.TripView{ID: tripID, DriverID: driverID}, nil)
+	m.trip.On("QueryByID", mock.Anything, unknownID).Return(trip.TripView{}, trip.ErrNotFound)
+
+	// the room of the trip is created on first use
+	room, err := core.Authorize(context.Background(), tripID, driverID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, tripID, room.ID)
+	assert.True(t, room.IsTripRoom())
+	m.storer.AssertNumberOfCalls(t, "CreateRoom", 1)
+
+	// a room that is no trip is not found
+	_, err = core.Authorize(context.Background(), unknownID, driverID)
+	assert.ErrorIs(t, err, ErrRoomNotFound)
+	m.storer.AssertNumberOfCalls(t, "CreateRoom", 1)
+}
+
+func TestFromStream(t *testing.T) {
+	first := ChatMessage{ID: uuid.New(), Message: "first"}
+	second := ChatMessage{ID: uuid.New(), Message: "second"}
+
+	entry := func(id string, msg ChatMessage) redis.XMessage {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return redis.XMessage{ID: id, Values: map[string]interface{}{"message": string(data)}}
+	}
+
+	// the stream is read newest first
+	xMessages := []redis.XMessage{
+		entry("2-0", second),
+		{ID: "1-1", Values: map[string]interface{}{"message": "not json"}},
+		entry("1-0", first),
+	}
+
+	msgs := fromStream(xMessages)
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages, want 2", len(msgs))
+	}
+	assert.Equal(t, first.ID, msgs[0].ID)
+	assert.Equal(t, second.ID, msgs[1].ID)
+	assert.Equal(t, "1-0", msgs[0].StreamID)
+	assert.Equal(t, "2-0", msgs[1].StreamID)
+
+	assert.True(t, contains(msgs, second.ID))
+	assert.False(t, contains(msgs, uuid.New()))
+}
+
+func TestDecodeFrame(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame string
+		want  error
+	}{
+		{"message", `{"v":1,"type":"message","client_id":"c1","data":{"text":"hi"}}`, nil},
+		{"typing", `{"v":1,"type":"typing"}`, nil},
+		{"read", `{"v":1,"type":"read","data":{"message_id":"` + uuid.NewString() + `"}}`, nil},
+		{"not json", `hello`, ErrInvalidFrame},
+		{"old version", `{"type":"message","data":{"text":"hi"}}`, ErrUnsupportedVersion},
+		{"system from client", `{"v":1,"type":"system","data":{"text":"hi"}}`, ErrInvalidFrame},
+		{"unknown type", `{"v":1,"type":"shout"}`, ErrInvalidFrame},
+		{"long client id", `{"v":1,"type":"message","client_id":"` + strings.Repeat("x", maxClientIDLen+1) + `"}`, ErrInvalidFrame},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeFrame([]byte(tt.frame))
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	env := func(data string) Envelope {
+		return Envelope{Version: Version, Type: TypeMessage, Data: json.RawMessage(data)}
+	}
+
+	text, err := decodeText(env(`{"text":"  hello  "}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "hello", text)
+
+	_, err = decodeText(env(`{"text":"   "}`))
+	assert.ErrorIs(t, err, ErrInvalidFrame)
+
+	_, err = decodeText(env(`{"text":"` + strings.Repeat("字", maxMessageLen+1) + `"}`))
+	assert.ErrorIs(t, err, ErrInvalidFrame)
+
+	_, err = decodeText(env(`"hello"`))
+	assert.ErrorIs(t, err, ErrInvalidFrame)
+}
+
+func TestMessageEnvelope(t *testing.T) {
+	msg := ChatMessage{
+		ID:       uuid.New(),
+		RoomID:   uuid.New(),
+		UserID:   uuid.New(),
+		Message:  "hello",
+		ClientID: "c1",
+		StreamID: "1-0",
+	}
+
+	env, err := messageEnvelope(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Version, env.Version)
+	assert.Equal(t, TypeMessage, env.Type)
+	assert.Equal(t, "c1", env.ClientID)
+	assert.Equal(t, "1-0", env.StreamID)
+
+	var data MessageData
+	if err := json.Unmarshal(env.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, msg.ID, data.ID)
+	assert.Equal(t, "hello", data.Text)
+
+	ack, err := ackEnvelope(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, TypeAck, ack.Type)
+	assert.Equal(t, "c1", ack.ClientID)
+	assert.Equal(t, "1-0", ack.StreamID)
+}
+
+func TestSystemEnvelope(t *testing.T) {
+	msg := ChatMessage{
+		ID:      uuid.New(),
+		Type:    TypeSystem,
+		Event:   trip.EventStarted,
+		RoomID:  uuid.New(),
+		Message: "The trip has started",
+	}
+
+	env, err := messageEnvelope(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, TypeSystem, env.Type)
+
+	var data SystemData
+	if err := json.Unmarshal(env.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, msg.ID, data.ID)
+	assert.Equal(t, trip.EventStarted, data.Event)
+	assert.Equal(t, "The trip has started", data.Text)
+}
+
+func TestSystemText(t *testing.T) {
+	core, m := newTestCore()
+
+	passengerID := uuid.New()
+	m.user.On("QueryByID", mock.Anything, passengerID).Return(user.User{ID: passengerID, Name: "Alice"}, nil)
+
+	startTime := time.Date(2024, 5, 3, 8, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		evt  trip.Event
+		want string
+	}{
+		{trip.Event{Type: trip.EventPassengerAccepted, PassengerID: passengerID}, "Alice joined the trip"},
+		{trip.Event{Type: trip.EventStarted}, "The trip has started"},
+		{trip.Event{Type: trip.EventFinished}, "The trip has finished"},
+		{trip.Event{Type: trip.EventCancelled}, "The trip was cancelled"},
+		{trip.Event{Type: trip.EventStartTimeChanged, StartTime: startTime}, "The start time was changed to Fri, 03 May 2024 08:30 UTC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.evt.Type, func(t *testing.T) {
+			text, err := core.systemText(context.Background(), tt.evt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.want, text)
+		})
+	}
+
+	_, err := core.systemText(context.Background(), trip.Event{Type: "unknown"})
+	assert.Error(t, err)
+}
+
+func TestReadPointerBefore(t *testing.T) {
+	now := time.Now()
+	low := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	high := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+
+	assert.True(t, ReadPointer{}.Before(ReadPointer{MessageID: low, CreatedAt: now}))
+	assert.True(t, ReadPointer{MessageID: high, CreatedAt: now}.Before(ReadPointer{MessageID: low, CreatedAt: now.Add(time.Microsecond)}))
+	assert.True(t, ReadPointer{MessageID: low, CreatedAt: now}.Before(ReadPointer{MessageID: high, CreatedAt: now}))
+	assert.False(t, ReadPointer{MessageID: high, CreatedAt: now}.Before(ReadPointer{MessageID: high, CreatedAt: now}))
+}
+
+func TestReadPointers(t *testing.T) {
+	userID := uuid.New()
+
+	driving := trip.UserTrip{TripID: uuid.New(), DriverID: userID, MyStatus: trip.StatusAccepted}
+	accepted := trip.UserTrip{TripID: uuid.New(), DriverID: uuid.New(), MyStatus: trip.StatusAccepted}
+	pending := trip.UserTrip{TripID: uuid.New(), DriverID: uuid.New(), MyStatus: trip.StatusPending}
+
+	read := ReadPointer{RoomID: accepted.TripID, MessageID: uuid.New(), CreatedAt: time.Now().UTC()}
+	data, err := json.Marshal(read)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := map[string]string{
+		accepted.TripID.String(): string(data),
+		pending.TripID.String():  string(data),
+	}
+
+	pointers := readPointers(userID, []trip.UserTrip{driving, accepted, pending}, stored)
+	if len(pointers) != 2 {
+		t.Fatalf("got %d pointers, want 2", len(pointers))
+	}
+	assert.Equal(t, ReadPointer{RoomID: driving.TripID}, pointers[0])
+	assert.Equal(t, read.MessageID, pointers[1].MessageID)
+	assert.True(t, read.CreatedAt.Equal(pointers[1].CreatedAt))
+}
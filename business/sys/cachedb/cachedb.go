@@ -103,8 +103,8 @@ func XRange(ctx context.Context, streamName string, start string, stop string) (
 	return val, nil
 }
 
-func Subscribe(ctx context.Context, channelName string) *redis.PubSub {
-	return cachedb.Replica.Subscribe(ctx, channelName)
+func Subscribe(ctx context.Context, channelNames ...string) *redis.PubSub {
+	return cachedb.Replica.Subscribe(ctx, channelNames...)
 }
 
 func XAdd(ctx context.Context, streamName string, values map[string]interface{}) (string, error) {
@@ -127,3 +127,140 @@ func Publish(ctx context.Context, channelName string, message string) error {
 
 	return nil
 }
+
+// GetPrimary reads the key from the master, for reads that cannot tolerate
+// the replica lagging behind.
+func GetPrimary(ctx context.Context, key string) (string, error) {
+	val, err := cachedb.Master.Get(ctx, key).Result()
+	if err != nil {
+		return "", fmt.Errorf("get: %w", err)
+	}
+
+	return val, nil
+}
+
+// SetXX sets the key only when it exists, reporting whether it was set.
+func SetXX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	ok, err := cachedb.Master.SetXX(ctx, key, value, expiration).Result()
+	if err != nil {
+		return false, fmt.Errorf("setxx: %w", err)
+	}
+
+	return ok, nil
+}
+
+// MGet reads the keys from the master. Missing keys are returned as nil.
+func MGet(ctx context.Context, keys ...string) ([]interface{}, error) {
+	vals, err := cachedb.Master.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("mget: %w", err)
+	}
+
+	return vals, nil
+}
+
+// HGet reads a field of the hash from the master.
+func HGet(ctx context.Context, key string, field string) (string, error) {
+	val, err := cachedb.Master.HGet(ctx, key, field).Result()
+	if err != nil {
+		return "", fmt.Errorf("hget: %w", err)
+	}
+
+	return val, nil
+}
+
+// HGetAll reads the hash from the master.
+func HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	vals, err := cachedb.Master.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("hgetall: %w", err)
+	}
+
+	return vals, nil
+}
+
+// HDel removes the fields from the hash.
+func HDel(ctx context.Context, key string, fields ...string) error {
+	if err := cachedb.Master.HDel(ctx, key, fields...).Err(); err != nil {
+		return fmt.Errorf("hdel: %w", err)
+	}
+
+	return nil
+}
+
+// TxPipelined runs the commands queued by fn on the master in a transaction.
+func TxPipelined(ctx context.Context, fn func(redis.Pipeliner) error) error {
+	if _, err := cachedb.Master.TxPipelined(ctx, fn); err != nil {
+		return fmt.Errorf("txpipelined: %w", err)
+	}
+
+	return nil
+}
+
+// GetDel reads the key and removes it in one step, so the value can be read
+// only once.
+func GetDel(ctx context.Context, key string) (string, error) {
+	val, err := cachedb.Master.GetDel(ctx, key).Result()
+	if err != nil {
+		return "", fmt.Errorf("getdel: %w", err)
+	}
+
+	return val, nil
+}
+
+// RunScript runs the Lua script on the master, loading it when the master
+// does not have it cached yet.
+func RunScript(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	val, err := script.Run(ctx, cachedb.Master, keys, args...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("run script: %w", err)
+	}
+
+	return val, nil
+}
+
+// XAddMaxLen adds the entry to the stream and trims the stream down to about
+// maxLen entries, so it can be used as a capped cache.
+func XAddMaxLen(ctx context.Context, streamName string, maxLen int64, values map[string]interface{}) (string, error) {
+	val, err := cachedb.Master.XAdd(ctx, &redis.XAddArgs{
+		Stream: streamName,
+		MaxLen: maxLen,
+		Approx: true,
+		Values: values,
+	}).Result()
+	if err != nil {
+		return "", fmt.Errorf("xadd: %w", err)
+	}
+
+	return val, nil
+}
+
+// XRevRangeN reads up to count entries of the stream, newest first.
+func XRevRangeN(ctx context.Context, streamName string, start string, stop string, count int64) ([]redis.XMessage, error) {
+	val, err := cachedb.Replica.XRevRangeN(ctx, streamName, start, stop, count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("xrevrange: %w", err)
+	}
+
+	return val, nil
+}
+
+// SetNX sets the key only when it does not exist, reporting whether it was
+// set.
+func SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	ok, err := cachedb.Master.SetNX(ctx, key, value, expiration).Result()
+	if err != nil {
+		return false, fmt.Errorf("setnx: %w", err)
+	}
+
+	return ok, nil
+}
+
+// HSet sets the fields of the hash, given as field and value pairs.
+func HSet(ctx context.Context, key string, values ...interface{}) error {
+	if err := cachedb.Master.HSet(ctx, key, values...).Err(); err != nil {
+		return fmt.Errorf("hset: %w", err)
+	}
+
+	return nil
+}
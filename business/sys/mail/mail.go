@@ -1,6 +1,7 @@
 package mail
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/smtp"
@@ -10,6 +11,28 @@ import (
 	"github.com/streadway/amqp"
 )
 
+// Email is a message queued for the email worker. A message that is a bare
+// address instead is a trip reminder.
+type Email struct {
+	To      []string `json:"to"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+}
+
+// Enqueue queues the email for the email worker to send right away.
+func Enqueue(email Email) error {
+	msg, err := json.Marshal(email)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := mq.SendDelayMsg(msg, 0); err != nil {
+		return fmt.Errorf("senddelaymsg: %w", err)
+	}
+
+	return nil
+}
+
 func StartSendEmailWorker() {
 	msgsReceiver := mq.NewDelayMsgsReceiver()
 	for msg := range msgsReceiver {
@@ -25,6 +48,13 @@ func sendEmail(msg amqp.Delivery) {
 	to := string(msg.Body)            // recipient email address
 	subject := "Hello"
 	body := "Your trip is going to start!"
+
+	var email Email
+	if err := json.Unmarshal(msg.Body, &email); err == nil {
+		to = strings.Join(email.To, ",")
+		subject = email.Subject
+		body = email.Body
+	}
 	// SMTP server configuration.
 	smtpHost := "smtp.gmail.com"
 	smtpPort := "587"
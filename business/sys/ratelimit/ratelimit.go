@@ -0,0 +1,159 @@
+// Package ratelimit provides a sliding window rate limiter over Redis, so the
+// limits hold across every instance of the service.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/sys/cachedb"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrLimited is returned when a key went over its limit.
+var ErrLimited = errors.New("rate limit exceeded")
+
+// Policy allows Limit events within any Window. The zero Policy allows any
+// number of events.
+type Policy struct {
+	Limit  int
+	Window time.Duration
+}
+
+// Enabled reports whether the policy limits anything.
+func (p Policy) Enabled() bool {
+	return p.Limit > 0 && p.Window > 0
+}
+
+// String returns the policy in the form ParsePolicy accepts.
+func (p Policy) String() string {
+	return fmt.Sprintf("%d/%s", p.Limit, p.Window)
+}
+
+// Result describes the state of a key after an event.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+
+	// Reset is how long until the oldest event in the window expires, that
+	// is when the next event is allowed again once the limit is reached.
+	Reset time.Duration
+}
+
+// ParsePolicy parses a policy of the form "<limit>/<window>", like "10/1m".
+func ParsePolicy(value string) (Policy, error) {
+	limit, window, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("invalid policy %q, expected <limit>/<window>", value)
+	}
+
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 0 {
+		return Policy{}, fmt.Errorf("invalid policy %q: limit", value)
+	}
+
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return Policy{}, fmt.Errorf("invalid policy %q: window", value)
+	}
+
+	return Policy{Limit: n, Window: d}, nil
+}
+
+// ParsePolicies parses a comma separated list of named policies, like
+// "login=10/1m,chat=30/10s".
+func ParsePolicies(value string) (map[string]Policy, error) {
+	policies := make(map[string]Policy)
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, policy, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid policy %q, expected <name>=<limit>/<window>", item)
+		}
+
+		p, err := ParsePolicy(policy)
+		if err != nil {
+			return nil, err
+		}
+
+		policies[strings.TrimSpace(name)] = p
+	}
+
+	return policies, nil
+}
+
+// slidingWindow keeps the events of the window in a sorted set scored by
+// their time in milliseconds. An event is only recorded when it is allowed.
+var slidingWindow = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	redis.call('PEXPIRE', key, window)
+	count = count + 1
+	allowed = 1
+end
+
+local reset = window
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+
+return {allowed, limit - count, reset}
+`)
+
+// runScript runs the sliding window script, tests replace it to run without
+// Redis.
+var runScript = cachedb.RunScript
+
+// Allow records an event for the key when the policy allows it.
+func Allow(ctx context.Context, key string, p Policy) (Result, error) {
+	if !p.Enabled() {
+		return Result{Allowed: true}, nil
+	}
+
+	now := time.Now().UnixMilli()
+	member := fmt.Sprintf("%d-%s", now, uuid.NewString())
+
+	val, err := runScript(ctx, slidingWindow, []string{"ratelimit:" + key}, now, p.Window.Milliseconds(), p.Limit, member)
+	if err != nil {
+		return Result{}, fmt.Errorf("sliding window: %w", err)
+	}
+
+	vals, ok := val.([]interface{})
+	if !ok || len(vals) != 3 {
+		return Result{}, fmt.Errorf("sliding window: unexpected reply %v", val)
+	}
+
+	allowed, _ := vals[0].(int64)
+	remaining, _ := vals[1].(int64)
+	reset, _ := vals[2].(int64)
+
+	res := Result{
+		Allowed:   allowed == 1,
+		Limit:     p.Limit,
+		Remaining: int(remaining),
+		Reset:     time.Duration(reset) * time.Millisecond,
+	}
+
+	return res, nil
+}
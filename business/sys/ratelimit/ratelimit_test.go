@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/sys/cachedb"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies("login=10/1m, chat=30/10s,,location=5/1s")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, map[string]Policy{
+		"login":    {Limit: 10, Window: time.Minute},
+		"chat":     {Limit: 30, Window: 10 * time.Second},
+		"location": {Limit: 5, Window: time.Second},
+	}, policies)
+
+	for _, value := range []string{"login", "login=10", "login=x/1m", "login=10/x", "login=10/0s", "=10/1m"} {
+		_, err := ParsePolicies(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestPolicyEnabled(t *testing.T) {
+	assert.False(t, Policy{}.Enabled())
+	assert.False(t, Policy{Limit: 0, Window: time.Minute}.Enabled())
+	assert.True(t, Policy{Limit: 1, Window: time.Minute}.Enabled())
+}
+
+func TestAllowDisabled(t *testing.T) {
+	t.Cleanup(func() { runScript = cachedb.RunScript })
+
+	runScript = func(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+		t.Error("script run for a disabled policy")
+		return nil, errors.New("disabled")
+	}
+
+	res, err := Allow(context.Background(), "login:ip:10.0.0.1", Policy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, res.Allowed)
+}
+
+func TestAllowError(t *testing.T) {
+	t.Cleanup(func() { runScript = cachedb.RunScript })
+
+	runScript = func(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+		return nil, errors.New("connection refused")
+	}
+	_, err := Allow(context.Background(), "login:ip:10.0.0.1", Policy{Limit: 1, Window: time.Minute})
+	assert.Error(t, err)
+
+	runScript = func(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+		return "OK", nil
+	}
+	_, err = Allow(context.Background(), "login:ip:10.0.0.1", Policy{Limit: 1, Window: time.Minute})
+	assert.Error(t, err)
+}
@@ -0,0 +1,114 @@
+//go:build integration
+
+package ratelimit
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/sys/cachedb"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// openRedis connects cachedb to the Redis named by TEST_REDIS_ADDR and
+// returns a key prefix of the test, its windows are removed once the test is
+// done.
+func openRedis(t *testing.T) string {
+	t.Helper()
+
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR not set")
+	}
+
+	db, err := cachedb.Open(cachedb.Config{MasterHost: addr, ReplicaHost: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefix := "test:" + uuid.NewString()
+	t.Cleanup(func() {
+		ctx := context.Background()
+
+		keys, err := db.Master.Keys(ctx, "ratelimit:"+prefix+":*").Result()
+		if err != nil {
+			t.Error(err)
+		}
+		if len(keys) > 0 {
+			if err := db.Master.Del(ctx, keys...).Err(); err != nil {
+				t.Error(err)
+			}
+		}
+		db.Master.Close()
+		db.Replica.Close()
+	})
+
+	return prefix
+}
+
+func TestAllow(t *testing.T) {
+	prefix := openRedis(t)
+	ctx := context.Background()
+	p := Policy{Limit: 2, Window: time.Minute}
+
+	res, err := Allow(ctx, prefix+":ip:10.0.0.1", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Limit)
+	assert.Equal(t, 1, res.Remaining)
+
+	res, err = Allow(ctx, prefix+":ip:10.0.0.1", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	res, err = Allow(ctx, prefix+":ip:10.0.0.1", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.True(t, res.Reset > 0 && res.Reset <= time.Minute, res.Reset)
+
+	// Every key has a window of its own.
+	res, err = Allow(ctx, prefix+":ip:10.0.0.2", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, res.Allowed)
+}
+
+func TestAllowWindowSlides(t *testing.T) {
+	prefix := openRedis(t)
+	ctx := context.Background()
+	p := Policy{Limit: 1, Window: 200 * time.Millisecond}
+
+	res, err := Allow(ctx, prefix+":ip:10.0.0.1", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, res.Allowed)
+
+	res, err = Allow(ctx, prefix+":ip:10.0.0.1", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, res.Allowed)
+
+	// The event leaves the window once it is older than it.
+	time.Sleep(p.Window + 50*time.Millisecond)
+
+	res, err = Allow(ctx, prefix+":ip:10.0.0.1", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+}
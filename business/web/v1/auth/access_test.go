@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	aauth "github.com/TSMC-Uber/server/business/core/auth"
+	"github.com/TSMC-Uber/server/business/core/user"
+	"github.com/TSMC-Uber/server/foundation/keystore"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestKeyStore(t *testing.T, kids ...string) *keystore.KeyStore {
+	fsys := fstest.MapFS{}
+	for _, kid := range kids {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fsys[kid+".pem"] = &fstest.MapFile{
+			Data: pem.EncodeToMemory(&pem.Block{
+				Type:  "RSA PRIVATE KEY",
+				Bytes: x509.MarshalPKCS1PrivateKey(key),
+			}),
+		}
+	}
+
+	ks, err := keystore.NewFS(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ks
+}
+
+func newTestAuth(t *testing.T, ks KeyLookup, activeKID string) *Auth {
+	a, err := New(Config{
+		KeyLookup: ks,
+		ActiveKID: activeKID,
+		Issuer:    "tuber project",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a
+}
+
+func TestAccessToken(t *testing.T) {
+	ks := newTestKeyStore(t, "key-1", "key-2")
+	a := newTestAuth(t, ks, "key-1")
+
+	sess := aauth.Session{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		Roles:     []user.Role{user.RoleRider, user.RoleDriver},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	token, expiresAt, err := a.AccessToken(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.WithinDuration(t, time.Now().Add(DefaultAccessTokenTTL), expiresAt, time.Second)
+
+	claims, err := a.Authenticate(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, sess.ID.String(), claims.ID)
+	assert.Equal(t, sess.UserID.String(), claims.Subject)
+	assert.True(t, claims.HasRole(user.RoleDriver))
+
+	// After rotating to key-2, tokens signed with key-1 remain valid until
+	// they expire.
+	rotated := newTestAuth(t, ks, "key-2")
+
+	if _, err := rotated.Authenticate(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+
+	token2, _, err := rotated.AccessToken(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(token2, &Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "key-2", parsed.Header["kid"])
+}
+
+func TestAccessTokenRejected(t *testing.T) {
+	ks := newTestKeyStore(t, "key-1")
+	a := newTestAuth(t, ks, "key-1")
+
+	now := time.Now()
+	valid := func() Claims {
+		return Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "tuber project",
+				Subject:   uuid.NewString(),
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			},
+			Roles: []user.Role{user.RoleRider},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		kid    string
+		claims func(c *Claims)
+	}{
+		{"expired", "key-1", func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute)) }},
+		{"no expiry", "key-1", func(c *Claims) { c.ExpiresAt = nil }},
+		{"wrong issuer", "key-1", func(c *Claims) { c.Issuer = "someone else" }},
+		{"unknown kid", "key-9", func(c *Claims) {}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.claims(&claims)
+
+			signer := a
+			if tt.kid != "key-1" {
+				// sign with a key the verifier does not know
+				signer = newTestAuth(t, newTestKeyStore(t, tt.kid), tt.kid)
+			}
+
+			token, err := signer.GenerateToken(tt.kid, claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = a.Authenticate(context.Background(), token)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAccessTokensDisabled(t *testing.T) {
+	a := newTestAuth(t, nil, "")
+
+	_, _, err := a.AccessToken(aauth.Session{})
+	assert.ErrorIs(t, err, ErrAccessTokensDisabled)
+}
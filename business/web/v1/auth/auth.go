@@ -5,27 +5,44 @@ package auth
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
+	"crypto/rsa"
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 	"sync"
+	"time"
 
-	"github.com/TSMC-Uber/server/business/sys/cachedb"
+	aauth "github.com/TSMC-Uber/server/business/core/auth"
+	"github.com/TSMC-Uber/server/business/core/auth/stores/authredisdb"
+	"github.com/TSMC-Uber/server/business/core/user"
+	"github.com/TSMC-Uber/server/business/core/user/stores/userdb"
 	"github.com/TSMC-Uber/server/foundation/logger"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
-	"google.golang.org/api/idtoken"
 )
 
-// ErrForbidden is returned when a auth issue is identified.
+// Set of error variables for authentication and authorization.
+var (
+	ErrForbidden            = errors.New("attempted action is not allowed")
+	ErrAccessTokensDisabled = errors.New("access tokens are not enabled")
+)
+
+// DefaultAccessTokenTTL is how long an access token is valid when the config
+// does not say otherwise. Access tokens are not checked against the session
+// store, so a revoked session keeps working until its access tokens expire.
+const DefaultAccessTokenTTL = 5 * time.Minute
 
 // Claims represents the authorization claims transmitted via a JWT.
 type Claims struct {
 	jwt.RegisteredClaims
-	// Roles []user.Role `json:"roles"`
+	Roles []user.Role `json:"roles"`
+}
+
+// HasRole reports whether any of the given roles is within the claims.
+func (c Claims) HasRole(roles ...user.Role) bool {
+	return user.HasRole(c.Roles, roles...)
 }
 
 // KeyLookup declares a method set of behavior for looking up
@@ -36,141 +53,268 @@ type KeyLookup interface {
 	PublicKey(kid string) (key string, err error)
 }
 
-type Cache interface {
-	Get(key string) (string, error)
-	Set(key string, value string) error
-}
-
-// Config represents information required to initialize auth.
+// Config represents information required to initialize auth. Access tokens
+// are enabled when a KeyLookup is given, and signed with the ActiveKID key.
 type Config struct {
-	Log       *logger.Logger
-	DB        *sqlx.DB
-	KeyLookup KeyLookup
-	Issuer    string
-	Cache     Cache
+	Log            *logger.Logger
+	DB             *sqlx.DB
+	KeyLookup      KeyLookup
+	ActiveKID      string
+	AccessTokenTTL time.Duration
+	Issuer         string
+	Providers      []Provider
+	Cookie         CookieConfig
 }
 
 // Auth is used to authenticate clients. It can generate a token for a
 // set of user claims and recreate the claims by parsing the token.
 type Auth struct {
 	log       *logger.Logger
+	userCore  *user.Core
+	sessions  *aauth.Core
 	keyLookup KeyLookup
+	activeKID string
+	accessTTL time.Duration
 	method    jwt.SigningMethod
-	audience  string
-	// parser    *jwt.Parser
-	issuer string
-	mu     sync.RWMutex
-	cache  Cache
-}
-
-type userSessionInfo struct {
-	ID       string
-	Name     string
-	ImageURL string
+	verifier  *Verifier
+	cookieCfg CookieConfig
+	parser    *jwt.Parser
+	issuer    string
+	mu        sync.RWMutex
+	cache     map[string]*rsa.PublicKey
 }
 
 // New creates an Auth to support authentication/authorization.
 func New(cfg Config) (*Auth, error) {
+	// If a database connection is not provided, we won't perform the
+	// user suspension check.
+	var usrCore *user.Core
+	if cfg.DB != nil {
+		usrCore = user.NewCore(userdb.NewStore(cfg.Log, cfg.DB))
+	}
+
+	if cfg.KeyLookup != nil && cfg.ActiveKID == "" {
+		return nil, errors.New("active kid is required to sign access tokens")
+	}
+
+	accessTTL := cfg.AccessTokenTTL
+	if accessTTL == 0 {
+		accessTTL = DefaultAccessTokenTTL
+	}
+
 	a := Auth{
 		log:       cfg.Log,
+		userCore:  usrCore,
+		sessions:  aauth.NewCore(authredisdb.NewStore(cfg.Log)),
 		keyLookup: cfg.KeyLookup,
+		activeKID: cfg.ActiveKID,
+		accessTTL: accessTTL,
 		method:    jwt.GetSigningMethod(jwt.SigningMethodRS256.Name),
-		// parser:    jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name})),
-		issuer: cfg.Issuer,
-		// cache:  cfg.Cache,
+		verifier:  NewVerifier(&http.Client{Timeout: 10 * time.Second}, cfg.Providers...),
+		cookieCfg: cfg.Cookie,
+		parser:    jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name})),
+		issuer:    cfg.Issuer,
+		cache:     make(map[string]*rsa.PublicKey),
 	}
 
 	return &a, nil
 }
 
-// Authenticate processes the token to validate the sender's token is valid.
-func (a *Auth) Authenticate(ctx context.Context, token string) (uuid.UUID, error) {
+// Authenticate processes the token to validate the sender's token is valid
+// and returns the claims of the session it belongs to. The token is either
+// a signed access token or the opaque token of a session.
+func (a *Auth) Authenticate(ctx context.Context, token string) (Claims, error) {
+	if isAccessToken(token) {
+		return a.validateAccessToken(token)
+	}
+
 	if a.validateTokenPlaintext(token) != nil {
-		return uuid.Nil, wrapError(errors.New("invalid plaintext token"))
+		return Claims{}, wrapError(errors.New("invalid plaintext token"))
+	}
+
+	sess, err := a.sessions.Authenticate(ctx, token)
+	if err != nil {
+		return Claims{}, wrapError(fmt.Errorf("authenticate session: %w", err))
+	}
+
+	if err := a.isUserActive(ctx, sess.UserID); err != nil {
+		return Claims{}, wrapError(err)
+	}
+
+	return sessionClaims(sess), nil
+}
+
+// Refresh authenticates the opaque token of a session, which acts as the
+// refresh token, and issues a new access token for the session.
+func (a *Auth) Refresh(ctx context.Context, refreshToken string) (string, time.Time, error) {
+	if a.keyLookup == nil {
+		return "", time.Time{}, ErrAccessTokensDisabled
 	}
 
-	// TODO: get userID from redis
-	hash := sha256.Sum256([]byte(token))
-	hashToken := hex.EncodeToString(hash[:])
+	if a.validateTokenPlaintext(refreshToken) != nil {
+		return "", time.Time{}, wrapError(errors.New("invalid refresh token"))
+	}
 
-	user, err := cachedb.Get(ctx, hashToken)
+	sess, err := a.sessions.Authenticate(ctx, refreshToken)
 	if err != nil {
-		return uuid.Nil, wrapError(fmt.Errorf("get token from cache: %w", err))
+		return "", time.Time{}, wrapError(fmt.Errorf("authenticate session: %w", err))
 	}
 
-	var userSessionInfo userSessionInfo
-	if err := json.Unmarshal([]byte(user), &userSessionInfo); err != nil {
-		return uuid.Nil, wrapError(fmt.Errorf("json unmarshal: %w", err))
+	if err := a.isUserActive(ctx, sess.UserID); err != nil {
+		return "", time.Time{}, wrapError(err)
+	}
+
+	return a.AccessToken(sess)
+}
+
+// AccessToken issues a short lived access token for the session, signed with
+// the active key.
+func (a *Auth) AccessToken(sess aauth.Session) (string, time.Time, error) {
+	if a.keyLookup == nil {
+		return "", time.Time{}, ErrAccessTokensDisabled
 	}
 
-	if userSessionInfo.ID == "" {
-		return uuid.Nil, wrapError(errors.New("invalid token"))
+	now := time.Now()
+	expiresAt := now.Add(a.accessTTL)
+	if sess.ExpiresAt.Before(expiresAt) {
+		expiresAt = sess.ExpiresAt
 	}
 
-	// to uuid
-	usrID, err := uuid.Parse(userSessionInfo.ID)
+	claims := sessionClaims(sess)
+	claims.Issuer = a.issuer
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(expiresAt)
+
+	token, err := a.GenerateToken(a.activeKID, claims)
 	if err != nil {
-		return uuid.Nil, wrapError(fmt.Errorf("parse userID: %w", err))
+		return "", time.Time{}, err
 	}
 
-	return usrID, nil
+	return token, expiresAt, nil
 }
 
-func (a *Auth) ValidateIDToken(idToken string) error {
-	_, err := idtoken.Validate(context.Background(), idToken, a.audience)
+// GenerateToken generates a signed JWT token string representing the user Claims.
+func (a *Auth) GenerateToken(kid string, claims Claims) (string, error) {
+	token := jwt.NewWithClaims(a.method, claims)
+	token.Header["kid"] = kid
+
+	privateKeyPEM, err := a.keyLookup.PrivateKey(kid)
 	if err != nil {
-		return fmt.Errorf("validate idtoken: %w", err)
+		return "", fmt.Errorf("private key: %w", err)
 	}
 
-	return nil
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(privateKeyPEM))
+	if err != nil {
+		return "", fmt.Errorf("parsing private pem: %w", err)
+	}
+
+	str, err := token.SignedString(privateKey)
+	if err != nil {
+		return "", fmt.Errorf("signing token: %w", err)
+	}
+
+	return str, nil
+}
+
+// VerifyIDToken verifies an ID token issued by one of the configured
+// providers and returns the identity it carries. A nonce has to be one we
+// issued, it is used up whether the token is valid or not.
+func (a *Auth) VerifyIDToken(ctx context.Context, idToken string, nonce string) (IDToken, error) {
+	if nonce != "" {
+		if err := a.consumeNonce(ctx, nonce); err != nil {
+			return IDToken{}, fmt.Errorf("consume nonce: %w", err)
+		}
+	}
+
+	token, err := a.verifier.Verify(ctx, idToken, nonce)
+	if err != nil {
+		return IDToken{}, fmt.Errorf("verify idtoken: %w", err)
+	}
+
+	return token, nil
 }
 
 // Authorize attempts to authorize the user with the provided input roles, if
 // none of the input roles are within the user's claims, we return an error
 // otherwise the user is authorized.
-func (a *Auth) Authorize(ctx context.Context, claims Claims, rule string) error {
-	// input := map[string]any{
-	// 	"Roles":   claims.Roles,
-	// 	"Subject": claims.Subject,
-	// 	"UserID":  claims.Subject,
-	// }
-
-	// if err := a.opaPolicyEvaluation(ctx, opaAuthorization, rule, input); err != nil {
-	// 	return fmt.Errorf("rego evaluation failed : %w", err)
-	// }
+func (a *Auth) Authorize(ctx context.Context, claims Claims, roles ...user.Role) error {
+	if !claims.HasRole(roles...) {
+		return ErrForbidden
+	}
 
 	return nil
 }
 
+// AuthorizeUser authorizes the user to act on the given user, which is
+// allowed to the user themselves and to admins.
+func (a *Auth) AuthorizeUser(ctx context.Context, claims Claims, userID uuid.UUID) error {
+	if claims.Subject == userID.String() {
+		return nil
+	}
+
+	return a.Authorize(ctx, claims, user.RoleAdmin)
+}
+
 // =============================================================================
 
-// publicKeyLookup performs a lookup for the public pem for the specified kid.
-// func (a *Auth) publicKeyLookup(kid string) (string, error) {
-// 	pem, err := func() (string, error) {
-// 		a.mu.RLock()
-// 		defer a.mu.RUnlock()
-
-// 		pem, exists := a.cache[kid]
-// 		if !exists {
-// 			return "", errors.New("not found")
-// 		}
-// 		return pem, nil
-// 	}()
-// 	if err == nil {
-// 		return pem, nil
-// 	}
+// validateAccessToken verifies the signature, expiry and issuer of an access
+// token and returns its claims.
+func (a *Auth) validateAccessToken(token string) (Claims, error) {
+	if a.keyLookup == nil {
+		return Claims{}, wrapError(ErrAccessTokensDisabled)
+	}
 
-// 	pem, err = a.keyLookup.PublicKey(kid)
-// 	if err != nil {
-// 		return "", fmt.Errorf("fetching public key: %w", err)
-// 	}
+	var claims Claims
+	keyFunc := func(t *jwt.Token) (interface{}, error) {
+		kid, ok := t.Header["kid"].(string)
+		if !ok {
+			return nil, errors.New("missing kid in token header")
+		}
+		return a.publicKeyLookup(kid)
+	}
 
-// 	a.mu.Lock()
-// 	defer a.mu.Unlock()
-// 	a.cache[kid] = pem
+	if _, err := a.parser.ParseWithClaims(token, &claims, keyFunc); err != nil {
+		return Claims{}, wrapError(fmt.Errorf("parse access token: %w", err))
+	}
 
-// 	return pem, nil
-// }
+	if !claims.VerifyIssuer(a.issuer, true) {
+		return Claims{}, wrapError(fmt.Errorf("issuer[%s]: not accepted", claims.Issuer))
+	}
+
+	if claims.ExpiresAt == nil {
+		return Claims{}, wrapError(errors.New("access token has no expiry"))
+	}
+
+	return claims, nil
+}
+
+// publicKeyLookup performs a lookup for the public key for the specified
+// kid. Keys never change for a kid, so they are cached for good.
+func (a *Auth) publicKeyLookup(kid string) (*rsa.PublicKey, error) {
+	a.mu.RLock()
+	key, exists := a.cache[kid]
+	a.mu.RUnlock()
+
+	if exists {
+		return key, nil
+	}
+
+	pem, err := a.keyLookup.PublicKey(kid)
+	if err != nil {
+		return nil, fmt.Errorf("fetching public key: %w", err)
+	}
+
+	key, err = jwt.ParseRSAPublicKeyFromPEM([]byte(pem))
+	if err != nil {
+		return nil, fmt.Errorf("parsing public pem: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cache[kid] = key
+
+	return key, nil
+}
 
 // opaPolicyEvaluation asks opa to evaulate the token against the specified token
 // policy and public key.
@@ -202,6 +346,44 @@ func (a *Auth) Authorize(ctx context.Context, claims Claims, rule string) error
 // 	return nil
 // }
 
+// isUserActive checks the user is not suspended, so a suspension takes
+// effect on the sessions the user already has.
+func (a *Auth) isUserActive(ctx context.Context, userID uuid.UUID) error {
+	if a.userCore == nil {
+		return nil
+	}
+
+	usr, err := a.userCore.QueryByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("query user: %w", err)
+	}
+
+	if usr.IsSuspended(time.Now()) {
+		return user.ErrSuspended
+	}
+
+	return nil
+}
+
+// sessionClaims returns the claims of the session, the id of the claims is
+// the id of the session.
+func sessionClaims(sess aauth.Session) Claims {
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sess.ID.String(),
+			Subject:   sess.UserID.String(),
+			ExpiresAt: jwt.NewNumericDate(sess.ExpiresAt),
+		},
+		Roles: sess.Roles,
+	}
+}
+
+// isAccessToken reports whether the token is a JWT rather than the opaque
+// token of a session.
+func isAccessToken(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
 func (a *Auth) validateTokenPlaintext(tokenPlaintext string) error {
 	if tokenPlaintext == "" {
 		return fmt.Errorf("token must be provided")
@@ -9,9 +9,6 @@ import (
 // ctxKey represents the type of value for the context key.
 type ctxKey int
 
-// key is used to store/retrieve a Claims value from a context.Context.
-// const claimKey ctxKey = 1
-
 // key is used to store/retrieve a user value from a context.Context.
 const userKey ctxKey = 1
 
@@ -21,6 +18,9 @@ const sessionTokenKey ctxKey = 3
 
 const audienceKey ctxKey = 4
 
+// claimKey is used to store/retrieve a Claims value from a context.Context.
+const claimKey ctxKey = 5
+
 // =============================================================================
 
 // SetUserID stores the user id from the request in the context.
@@ -37,14 +37,30 @@ func GetUserID(ctx context.Context) uuid.UUID {
 	return v
 }
 
-func SetIDToken(ctx context.Context, idToken string) context.Context {
+// SetClaims stores the claims in the context.
+func SetClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimKey, claims)
+}
+
+// GetClaims returns the claims from the context.
+func GetClaims(ctx context.Context) Claims {
+	v, ok := ctx.Value(claimKey).(Claims)
+	if !ok {
+		return Claims{}
+	}
+	return v
+}
+
+// SetIDToken stores the verified ID token in the context.
+func SetIDToken(ctx context.Context, idToken IDToken) context.Context {
 	return context.WithValue(ctx, idTokenKey, idToken)
 }
 
-func GetIDToken(ctx context.Context) string {
-	v, ok := ctx.Value(idTokenKey).(string)
+// GetIDToken returns the verified ID token from the context.
+func GetIDToken(ctx context.Context) IDToken {
+	v, ok := ctx.Value(idTokenKey).(IDToken)
 	if !ok {
-		return ""
+		return IDToken{}
 	}
 	return v
 }
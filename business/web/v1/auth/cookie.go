@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrCSRF is returned when a request authenticated by cookie does not carry
+// the CSRF token.
+var ErrCSRF = errors.New("csrf token is missing or does not match")
+
+// Names of the cookies and header carrying the session and CSRF tokens.
+const (
+	SessionCookie = "token"
+	CSRFCookie    = "csrf_token"
+	CSRFHeader    = "X-CSRF-Token"
+)
+
+// CookieConfig holds the attributes of the cookies we set.
+type CookieConfig struct {
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+}
+
+// ParseSameSite parses the SameSite attribute from its name.
+func ParseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(value) {
+	case "", "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+
+	return 0, fmt.Errorf("invalid same site %q", value)
+}
+
+// SetSessionCookies sets the session cookie together with the CSRF cookie the
+// client has to echo in the CSRF header. The CSRF cookie is readable by
+// scripts, the session cookie is not.
+func (a *Auth) SetSessionCookies(w http.ResponseWriter, token string, expiresAt time.Time) error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Errorf("generate csrf token: %w", err)
+	}
+
+	maxAge := int(time.Until(expiresAt).Seconds())
+
+	http.SetCookie(w, a.cookie(SessionCookie, token, maxAge, true))
+	http.SetCookie(w, a.cookie(CSRFCookie, base64.RawURLEncoding.EncodeToString(b), maxAge, false))
+
+	return nil
+}
+
+// ClearSessionCookies removes the session and CSRF cookies.
+func (a *Auth) ClearSessionCookies(w http.ResponseWriter) {
+	http.SetCookie(w, a.cookie(SessionCookie, "", -1, true))
+	http.SetCookie(w, a.cookie(CSRFCookie, "", -1, false))
+}
+
+// VerifyCSRF checks the CSRF header of the request matches its CSRF cookie.
+func (a *Auth) VerifyCSRF(r *http.Request) error {
+	cookie, err := r.Cookie(CSRFCookie)
+	if err != nil || cookie.Value == "" {
+		return ErrCSRF
+	}
+
+	header := r.Header.Get(CSRFHeader)
+	if subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) != 1 {
+		return ErrCSRF
+	}
+
+	return nil
+}
+
+func (a *Auth) cookie(name string, value string, maxAge int, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   a.cookieCfg.Domain,
+		MaxAge:   maxAge,
+		Secure:   a.cookieCfg.Secure,
+		HttpOnly: httpOnly,
+		SameSite: a.cookieCfg.SameSite,
+	}
+}
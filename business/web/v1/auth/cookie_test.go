@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionCookies(t *testing.T) {
+	a := Auth{cookieCfg: CookieConfig{Domain: "tuber.example.com", Secure: true, SameSite: http.SameSiteStrictMode}}
+
+	w := httptest.NewRecorder()
+	if err := a.SetSessionCookies(w, "Y3QMGX3PJ3WLRL2YRTQGQ6KRHU", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range w.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+
+	session := cookies[SessionCookie]
+	if session == nil {
+		t.Fatal("session cookie not set")
+	}
+	assert.Equal(t, "Y3QMGX3PJ3WLRL2YRTQGQ6KRHU", session.Value)
+	assert.Equal(t, "tuber.example.com", session.Domain)
+	assert.True(t, session.Secure)
+	assert.True(t, session.HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, session.SameSite)
+
+	csrf := cookies[CSRFCookie]
+	if csrf == nil {
+		t.Fatal("csrf cookie not set")
+	}
+	assert.False(t, csrf.HttpOnly)
+	assert.NotEmpty(t, csrf.Value)
+
+	tests := []struct {
+		name   string
+		header string
+		want   error
+	}{
+		{"matching header", csrf.Value, nil},
+		{"missing header", "", ErrCSRF},
+		{"wrong header", csrf.Value + "x", ErrCSRF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/trips", nil)
+			r.AddCookie(session)
+			r.AddCookie(csrf)
+			if tt.header != "" {
+				r.Header.Set(CSRFHeader, tt.header)
+			}
+
+			assert.Equal(t, tt.want, a.VerifyCSRF(r))
+		})
+	}
+}
+
+func TestParseSameSite(t *testing.T) {
+	tests := []struct {
+		value string
+		want  http.SameSite
+	}{
+		{"", http.SameSiteLaxMode},
+		{"Lax", http.SameSiteLaxMode},
+		{"strict", http.SameSiteStrictMode},
+		{"none", http.SameSiteNoneMode},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSameSite(tt.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, tt.want, got)
+	}
+
+	_, err := ParseSameSite("sometimes")
+	assert.Error(t, err)
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/sys/cachedb"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrInvalidNonce is returned when a nonce was not issued by us, expired or
+// has already been used.
+var ErrInvalidNonce = errors.New("nonce is invalid or has already been used")
+
+// NonceTTL is how long a nonce can be used to sign in after it is issued.
+const NonceTTL = 10 * time.Minute
+
+// IssueNonce issues a single use nonce. The client asks the provider for an
+// ID token carrying it and signs in with both, so a token cannot be replayed.
+func (a *Auth) IssueNonce(ctx context.Context) (string, time.Time, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, fmt.Errorf("generate nonce: %w", err)
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(b)
+
+	if err := cachedb.Set(ctx, nonceKey(nonce), 1, NonceTTL); err != nil {
+		return "", time.Time{}, fmt.Errorf("set: %w", err)
+	}
+
+	return nonce, time.Now().Add(NonceTTL), nil
+}
+
+// consumeNonce removes the nonce, so it signs in only once.
+func (a *Auth) consumeNonce(ctx context.Context, nonce string) error {
+	if _, err := cachedb.GetDel(ctx, nonceKey(nonce)); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ErrInvalidNonce
+		}
+		return fmt.Errorf("getdel: %w", err)
+	}
+
+	return nil
+}
+
+// nonceKey returns the key of the nonce, only a hash of the nonce is kept.
+func nonceKey(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return "auth:nonce:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,400 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Set of errors returned when verifying an ID token.
+var (
+	ErrUnknownIssuer     = errors.New("id token issuer is not accepted")
+	ErrInvalidAudience   = errors.New("id token was not issued for this service")
+	ErrIDTokenExpired    = errors.New("id token is expired")
+	ErrNonceMismatch     = errors.New("id token nonce does not match")
+	ErrNonceRequired     = errors.New("id token nonce is required")
+	ErrUnknownSigningKey = errors.New("id token is signed with an unknown key")
+)
+
+const (
+	// clockSkew is the leeway allowed between our clock and the provider's
+	// when checking the validity window of a token.
+	clockSkew = time.Minute
+
+	// defaultKeysTTL is how long keys are cached when the provider does not
+	// say otherwise.
+	defaultKeysTTL = time.Hour
+
+	// minRefreshInterval bounds how often an unknown key id can make us
+	// fetch the keys again, so made up key ids cannot hammer the provider.
+	minRefreshInterval = time.Minute
+)
+
+// Provider describes an OpenID Connect identity provider we accept ID tokens
+// from. Without a JWKSURL the keys are located through the discovery document
+// of the first issuer. With RequireNonce the tokens have to carry a nonce we
+// issued, see Auth.IssueNonce.
+type Provider struct {
+	Name         string
+	Issuers      []string
+	Audiences    []string
+	JWKSURL      string
+	RequireNonce bool
+}
+
+// GoogleProvider returns the provider for Sign in with Google, accepting
+// tokens issued to the given OAuth client ids.
+func GoogleProvider(audiences ...string) Provider {
+	return Provider{
+		Name:      "google",
+		Issuers:   []string{"https://accounts.google.com", "accounts.google.com"},
+		Audiences: audiences,
+		JWKSURL:   "https://www.googleapis.com/oauth2/v3/certs",
+	}
+}
+
+// IDToken is the identity carried by an ID token whose signature and claims
+// were verified.
+type IDToken struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+	Expiry        time.Time
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+	Nonce         string `json:"nonce"`
+}
+
+// =============================================================================
+
+// Verifier verifies ID tokens against the providers it was configured with.
+type Verifier struct {
+	providers []oidcProvider
+	now       func() time.Time
+}
+
+type oidcProvider struct {
+	Provider
+	keys *keySet
+}
+
+// NewVerifier constructs a verifier for the given providers. Keys are fetched
+// with the client on first use.
+func NewVerifier(client *http.Client, providers ...Provider) *Verifier {
+	v := Verifier{
+		now: time.Now,
+	}
+
+	for _, p := range providers {
+		ks := keySet{
+			url:    p.JWKSURL,
+			client: client,
+			now:    func() time.Time { return v.now() },
+		}
+		if len(p.Issuers) > 0 {
+			ks.issuer = p.Issuers[0]
+		}
+
+		v.providers = append(v.providers, oidcProvider{Provider: p, keys: &ks})
+	}
+
+	return &v
+}
+
+// Verify checks the signature, issuer, audience and expiry of the ID token.
+// The token has to carry the nonce given, and no nonce when none is given.
+func (v *Verifier) Verify(ctx context.Context, rawToken string, nonce string) (IDToken, error) {
+	var claims idTokenClaims
+
+	// Peek at the issuer to pick the provider, the token is verified below.
+	if _, _, err := jwt.NewParser().ParseUnverified(rawToken, &claims); err != nil {
+		return IDToken{}, fmt.Errorf("parse: %w", err)
+	}
+
+	p, exists := v.provider(claims.Issuer)
+	if !exists {
+		return IDToken{}, fmt.Errorf("issuer[%s]: %w", claims.Issuer, ErrUnknownIssuer)
+	}
+
+	keyFunc := func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.keys.key(ctx, kid)
+	}
+
+	claims = idTokenClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name}), jwt.WithoutClaimsValidation())
+	if _, err := parser.ParseWithClaims(rawToken, &claims, keyFunc); err != nil {
+		return IDToken{}, fmt.Errorf("verify: %w", err)
+	}
+
+	if err := v.validate(p, claims, nonce); err != nil {
+		return IDToken{}, err
+	}
+
+	token := IDToken{
+		Provider:      p.Name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		Picture:       claims.Picture,
+		Expiry:        claims.ExpiresAt.Time,
+	}
+
+	return token, nil
+}
+
+// validate checks the claims of a token whose signature was verified.
+func (v *Verifier) validate(p oidcProvider, claims idTokenClaims, nonce string) error {
+	now := v.now()
+
+	if claims.Subject == "" {
+		return errors.New("id token has no subject")
+	}
+
+	if !audienceAccepted(p.Audiences, claims.Audience) {
+		return fmt.Errorf("audience%v: %w", []string(claims.Audience), ErrInvalidAudience)
+	}
+
+	if claims.ExpiresAt == nil || !now.Before(claims.ExpiresAt.Add(clockSkew)) {
+		return ErrIDTokenExpired
+	}
+
+	if claims.IssuedAt != nil && now.Add(clockSkew).Before(claims.IssuedAt.Time) {
+		return errors.New("id token is issued in the future")
+	}
+
+	if nonce == "" && p.RequireNonce {
+		return ErrNonceRequired
+	}
+
+	if claims.Nonce != nonce {
+		return ErrNonceMismatch
+	}
+
+	return nil
+}
+
+// provider returns the provider issuing tokens as the given issuer.
+func (v *Verifier) provider(issuer string) (oidcProvider, bool) {
+	for _, p := range v.providers {
+		for _, iss := range p.Issuers {
+			if iss == issuer {
+				return p, true
+			}
+		}
+	}
+	return oidcProvider{}, false
+}
+
+// audienceAccepted reports whether the token was issued to one of the
+// accepted audiences. A provider without audiences accepts nothing.
+func audienceAccepted(accepted []string, audience jwt.ClaimStrings) bool {
+	for _, want := range accepted {
+		for _, aud := range audience {
+			if aud == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// =============================================================================
+
+// keySet caches the signing keys of a provider. The keys are fetched again
+// when they expire, or when a token names a key we do not know yet because
+// the provider rotated its keys.
+type keySet struct {
+	url    string
+	issuer string
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	expiry    time.Time
+	lastFetch time.Time
+}
+
+// key returns the public key with the given key id.
+func (ks *keySet) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	now := ks.now()
+
+	key, exists := ks.keys[kid]
+	if exists && now.Before(ks.expiry) {
+		return key, nil
+	}
+
+	if ks.keys == nil || !now.Before(ks.expiry) || now.Sub(ks.lastFetch) >= minRefreshInterval {
+		if err := ks.refresh(ctx); err != nil {
+			// Keep serving a known key when the provider is unreachable.
+			if exists {
+				return key, nil
+			}
+			return nil, err
+		}
+	}
+
+	key, exists = ks.keys[kid]
+	if !exists {
+		return nil, fmt.Errorf("kid[%s]: %w", kid, ErrUnknownSigningKey)
+	}
+
+	return key, nil
+}
+
+// refresh fetches the keys of the provider.
+func (ks *keySet) refresh(ctx context.Context) error {
+	ks.lastFetch = ks.now()
+
+	if ks.url == "" {
+		url, err := ks.discover(ctx)
+		if err != nil {
+			return err
+		}
+		ks.url = url
+	}
+
+	var doc struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+
+	header, err := ks.get(ctx, ks.url, &doc)
+	if err != nil {
+		return fmt.Errorf("fetch keys: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+
+		key, err := rsaPublicKey(k.N, k.E)
+		if err != nil {
+			return fmt.Errorf("kid[%s]: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+
+	ks.keys = keys
+	ks.expiry = ks.now().Add(maxAge(header))
+
+	return nil
+}
+
+// discover locates the keys through the discovery document of the issuer.
+func (ks *keySet) discover(ctx context.Context) (string, error) {
+	if ks.issuer == "" {
+		return "", errors.New("provider has neither a jwks url nor an issuer")
+	}
+
+	var doc struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+
+	url := strings.TrimSuffix(ks.issuer, "/") + "/.well-known/openid-configuration"
+	if _, err := ks.get(ctx, url, &doc); err != nil {
+		return "", fmt.Errorf("discover: %w", err)
+	}
+
+	if doc.JWKSURI == "" {
+		return "", errors.New("discover: document has no jwks_uri")
+	}
+
+	return doc.JWKSURI, nil
+}
+
+// get fetches the JSON document at url.
+func (ks *keySet) get(ctx context.Context, url string, doc any) (http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("url[%s]: unexpected status %d", url, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(doc); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+
+	return resp.Header, nil
+}
+
+// maxAge returns how long the response can be cached for.
+func maxAge(header http.Header) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.TrimSpace(directive)
+		if !strings.HasPrefix(directive, "max-age=") {
+			continue
+		}
+
+		seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+		if err != nil || seconds <= 0 {
+			break
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	return defaultKeysTTL
+}
+
+// rsaPublicKey builds a public key from the base64url encoded modulus and
+// exponent of a JWK.
+func rsaPublicKey(n string, e string) (*rsa.PublicKey, error) {
+	nb, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return nil, fmt.Errorf("decode modulus: %w", err)
+	}
+
+	eb, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, fmt.Errorf("decode exponent: %w", err)
+	}
+
+	key := rsa.PublicKey{
+		N: new(big.Int).SetBytes(nb),
+		E: int(new(big.Int).SetBytes(eb).Int64()),
+	}
+
+	return &key, nil
+}
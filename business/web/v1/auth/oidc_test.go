@@ -0,0 +1,266 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "tuber-client"
+)
+
+// jwksServer stands in for the keys endpoint of a provider.
+type jwksServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	fetches int
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	s := jwksServer{keys: map[string]*rsa.PrivateKey{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   s.URL,
+			"jwks_uri": s.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.fetches++
+
+		var doc struct {
+			Keys []map[string]string `json:"keys"`
+		}
+		for kid, key := range s.keys {
+			doc.Keys = append(doc.Keys, map[string]string{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": "RS256",
+				"kid": kid,
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		json.NewEncoder(w).Encode(doc)
+	})
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	return &s
+}
+
+// rotate publishes a new signing key.
+func (s *jwksServer) rotate(t *testing.T, kid string) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[kid] = key
+
+	return key
+}
+
+func (s *jwksServer) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, kid string, claims idTokenClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return raw
+}
+
+func validClaims(now time.Time) idTokenClaims {
+	return idTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			Subject:   "subject-1",
+			Audience:  jwt.ClaimStrings{testAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+		Email:         "rider@example.com",
+		EmailVerified: true,
+		Name:          "Rider",
+	}
+}
+
+func newTestVerifier(srv *jwksServer, jwksURL string) *Verifier {
+	return NewVerifier(srv.Client(), Provider{
+		Name:      "test",
+		Issuers:   []string{testIssuer},
+		Audiences: []string{testAudience},
+		JWKSURL:   jwksURL,
+	})
+}
+
+func TestVerify(t *testing.T) {
+	srv := newJWKSServer(t)
+	key := srv.rotate(t, "key-1")
+	v := newTestVerifier(srv, srv.URL+"/keys")
+
+	claims := validClaims(time.Now())
+	claims.Nonce = "nonce-1"
+	raw := sign(t, key, "key-1", claims)
+
+	token, err := v.Verify(context.Background(), raw, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "test", token.Provider)
+	assert.Equal(t, "subject-1", token.Subject)
+	assert.Equal(t, "rider@example.com", token.Email)
+	assert.True(t, token.EmailVerified)
+
+	// the keys are cached
+	_, err = v.Verify(context.Background(), raw, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, srv.fetchCount())
+}
+
+func TestVerifyRejected(t *testing.T) {
+	srv := newJWKSServer(t)
+	key := srv.rotate(t, "key-1")
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		key    *rsa.PrivateKey
+		claims func(c *idTokenClaims)
+		nonce  string
+		want   error
+	}{
+		{"unknown issuer", key, func(c *idTokenClaims) { c.Issuer = "https://evil.example.com" }, "", ErrUnknownIssuer},
+		{"wrong audience", key, func(c *idTokenClaims) { c.Audience = jwt.ClaimStrings{"someone-else"} }, "", ErrInvalidAudience},
+		{"expired", key, func(c *idTokenClaims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Hour)) }, "", ErrIDTokenExpired},
+		{"no expiry", key, func(c *idTokenClaims) { c.ExpiresAt = nil }, "", ErrIDTokenExpired},
+		{"nonce mismatch", key, func(c *idTokenClaims) { c.Nonce = "nonce-1" }, "nonce-2", ErrNonceMismatch},
+		{"nonce not sent", key, func(c *idTokenClaims) { c.Nonce = "nonce-1" }, "", ErrNonceMismatch},
+		{"nonce not in token", key, func(c *idTokenClaims) {}, "nonce-1", ErrNonceMismatch},
+		{"bad signature", other, func(c *idTokenClaims) {}, "", rsa.ErrVerification},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVerifier(srv, srv.URL+"/keys")
+
+			claims := validClaims(now)
+			tt.claims(&claims)
+
+			_, err := v.Verify(context.Background(), sign(t, tt.key, "key-1", claims), tt.nonce)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestVerifyKeyRotation(t *testing.T) {
+	srv := newJWKSServer(t)
+	key1 := srv.rotate(t, "key-1")
+
+	now := time.Now()
+	v := newTestVerifier(srv, srv.URL+"/keys")
+	v.now = func() time.Time { return now }
+
+	_, err := v.Verify(context.Background(), sign(t, key1, "key-1", validClaims(now)), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A token signed with a key published after our fetch makes us fetch
+	// the keys again.
+	key2 := srv.rotate(t, "key-2")
+	now = now.Add(2 * minRefreshInterval)
+
+	_, err = v.Verify(context.Background(), sign(t, key2, "key-2", validClaims(now)), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, srv.fetchCount())
+
+	// Unknown key ids do not make us fetch more than once per interval.
+	_, err = v.Verify(context.Background(), sign(t, key2, "key-3", validClaims(now)), "")
+	assert.ErrorIs(t, err, ErrUnknownSigningKey)
+	assert.Equal(t, 2, srv.fetchCount())
+}
+
+func TestVerifyDiscovery(t *testing.T) {
+	srv := newJWKSServer(t)
+	key := srv.rotate(t, "key-1")
+
+	v := NewVerifier(srv.Client(), Provider{
+		Name:      "test",
+		Issuers:   []string{srv.URL},
+		Audiences: []string{testAudience},
+	})
+
+	claims := validClaims(time.Now())
+	claims.Issuer = srv.URL
+
+	_, err := v.Verify(context.Background(), sign(t, key, "key-1", claims), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyRequireNonce(t *testing.T) {
+	srv := newJWKSServer(t)
+	key := srv.rotate(t, "key-1")
+
+	v := NewVerifier(srv.Client(), Provider{
+		Name:         "test",
+		Issuers:      []string{testIssuer},
+		Audiences:    []string{testAudience},
+		JWKSURL:      srv.URL + "/keys",
+		RequireNonce: true,
+	})
+
+	_, err := v.Verify(context.Background(), sign(t, key, "key-1", validClaims(time.Now())), "")
+	assert.ErrorIs(t, err, ErrNonceRequired)
+
+	claims := validClaims(time.Now())
+	claims.Nonce = "nonce-1"
+
+	_, err = v.Verify(context.Background(), sign(t, key, "key-1", claims), "nonce-1")
+	assert.NoError(t, err)
+}
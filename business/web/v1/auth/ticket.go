@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/sys/cachedb"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrInvalidTicket is returned when a websocket ticket is unknown, expired or
+// has already been used.
+var ErrInvalidTicket = errors.New("ticket is invalid or has already been used")
+
+// TicketTTL is how long a websocket ticket can be redeemed after it is issued.
+const TicketTTL = 30 * time.Second
+
+// IssueTicket issues a single use ticket carrying the claims. Browsers cannot
+// set headers on a websocket handshake, so they pass the ticket in the query
+// string instead of the session token.
+func (a *Auth) IssueTicket(ctx context.Context, claims Claims) (string, time.Time, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, fmt.Errorf("generate ticket: %w", err)
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(b)
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := cachedb.Set(ctx, ticketKey(ticket), data, TicketTTL); err != nil {
+		return "", time.Time{}, fmt.Errorf("set: %w", err)
+	}
+
+	return ticket, time.Now().Add(TicketTTL), nil
+}
+
+// RedeemTicket returns the claims of the ticket and removes it, so a ticket
+// can open only one connection.
+func (a *Auth) RedeemTicket(ctx context.Context, ticket string) (Claims, error) {
+	data, err := cachedb.GetDel(ctx, ticketKey(ticket))
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return Claims{}, ErrInvalidTicket
+		}
+		return Claims{}, fmt.Errorf("getdel: %w", err)
+	}
+
+	var claims Claims
+	if err := json.Unmarshal([]byte(data), &claims); err != nil {
+		return Claims{}, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return Claims{}, fmt.Errorf("parse subject: %w", err)
+	}
+
+	if err := a.isUserActive(ctx, userID); err != nil {
+		return Claims{}, err
+	}
+
+	return claims, nil
+}
+
+// ticketKey returns the key of the ticket, only a hash of the ticket is kept.
+func ticketKey(ticket string) string {
+	sum := sha256.Sum256([]byte(ticket))
+	return "ws:ticket:" + hex.EncodeToString(sum[:])
+}
@@ -2,28 +2,66 @@ package mid
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"strings"
 
-	aauth "github.com/TSMC-Uber/server/business/core/auth"
+	"github.com/TSMC-Uber/server/business/core/user"
 	"github.com/TSMC-Uber/server/business/web/v1/auth"
 
+	"github.com/TSMC-Uber/server/business/web/v1/response"
 	"github.com/TSMC-Uber/server/foundation/web"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
 )
 
-// Authenticate validates a JWT from the `Authorization` header.
+// Authenticate validates the session token from the `Authorization: Bearer`
+// header, or else from the `token` cookie. A state-changing request
+// authenticated by cookie has to carry the CSRF token as well. A websocket
+// handshake may instead carry a single use ticket in the `ticket` query
+// parameter.
 func Authenticate(a *auth.Auth) web.Middleware {
 	m := func(handler web.Handler) web.Handler {
 		h := func(ctx context.Context, c *gin.Context) error {
-			// get token from cookie
-			token, err := c.Cookie("token")
+			if ticket := c.Query("ticket"); ticket != "" && websocket.IsWebSocketUpgrade(c.Request) {
+				claims, err := a.RedeemTicket(ctx, ticket)
+				if err != nil {
+					return auth.NewAuthError("authenticate: ticket: %s", err)
+				}
+
+				userID, err := uuid.Parse(claims.Subject)
+				if err != nil {
+					return auth.NewAuthError("authenticate: parse subject: %s", err)
+				}
+
+				ctx = auth.SetClaims(ctx, claims)
+				ctx = auth.SetUserID(ctx, userID)
+
+				return handler(ctx, c)
+			}
+
+			token, fromCookie, err := sessionToken(c.Request)
 			if err != nil {
 				return auth.NewAuthError("authenticate: failed: %s", err)
 			}
-			userID, err := a.Authenticate(ctx, token)
+			claims, err := a.Authenticate(ctx, token)
 			if err != nil {
 				return auth.NewAuthError("authenticate: failed: %s", err)
 			}
 
+			if fromCookie && !safeMethod(c.Request.Method) {
+				if err := a.VerifyCSRF(c.Request); err != nil {
+					return response.NewError(err, http.StatusForbidden)
+				}
+			}
+
+			userID, err := uuid.Parse(claims.Subject)
+			if err != nil {
+				return auth.NewAuthError("authenticate: parse subject: %s", err)
+			}
+
+			ctx = auth.SetClaims(ctx, claims)
 			ctx = auth.SetUserID(ctx, userID)
 			ctx = auth.SetSessionToken(ctx, token)
 
@@ -36,15 +74,20 @@ func Authenticate(a *auth.Auth) web.Middleware {
 	return m
 }
 
-func AuthGoogle(a *auth.Auth, authCore *aauth.Core) web.Middleware {
+// AuthIDToken verifies the OpenID Connect ID token from the `id_token` header.
+// The `nonce` header carries the nonce we issued for the token, when the
+// token has one.
+func AuthIDToken(a *auth.Auth) web.Middleware {
 	m := func(handler web.Handler) web.Handler {
 		h := func(ctx context.Context, c *gin.Context) error {
 			idToken := c.Request.Header.Get("id_token")
-			err := a.ValidateIDToken(idToken)
+			nonce := c.Request.Header.Get("nonce")
+
+			token, err := a.VerifyIDToken(ctx, idToken, nonce)
 			if err != nil {
-				return auth.NewAuthError("google authenticate: failed: %s", err)
+				return auth.NewAuthError("id token authenticate: failed: %s", err)
 			}
-			ctx = auth.SetIDToken(ctx, idToken)
+			ctx = auth.SetIDToken(ctx, token)
 
 			return handler(ctx, c)
 		}
@@ -57,23 +100,80 @@ func AuthGoogle(a *auth.Auth, authCore *aauth.Core) web.Middleware {
 
 // Authorize validates that an authenticated user has at least one role from a
 // specified list. This method constructs the actual function that is used.
-// func Authorize(a *auth.Auth, rule string) web.Middleware {
-// 	m := func(handler web.Handler) web.Handler {
-// 		h := func(ctx context.Context, c *gin.Context) error {
-// 			claims := auth.GetClaims(ctx)
-// 			if claims.Subject == "" {
-// 				return auth.NewAuthError("authorize: you are not authorized for that action, no claims")
-// 			}
-
-// 			if err := a.Authorize(ctx, claims, rule); err != nil {
-// 				return auth.NewAuthError("authorize: you are not authorized for that action, claims[%v] rule[%v]: %s", nil, rule, err)
-// 			}
-
-// 			return handler(ctx, c)
-// 		}
-
-// 		return h
-// 	}
-
-// 	return m
-// }
+func Authorize(a *auth.Auth, roles ...user.Role) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, c *gin.Context) error {
+			claims := auth.GetClaims(ctx)
+			if claims.Subject == "" {
+				return auth.NewAuthError("authorize: you are not authorized for that action, no claims")
+			}
+
+			if err := a.Authorize(ctx, claims, roles...); err != nil {
+				return response.NewError(err, http.StatusForbidden)
+			}
+
+			return handler(ctx, c)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// AuthorizeUser validates that an authenticated user is the user named by the
+// id in the path, or an admin.
+func AuthorizeUser(a *auth.Auth) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, c *gin.Context) error {
+			claims := auth.GetClaims(ctx)
+			if claims.Subject == "" {
+				return auth.NewAuthError("authorize: you are not authorized for that action, no claims")
+			}
+
+			userID, err := uuid.Parse(c.Param("id"))
+			if err != nil {
+				return response.NewError(err, http.StatusBadRequest)
+			}
+
+			if err := a.AuthorizeUser(ctx, claims, userID); err != nil {
+				return response.NewError(err, http.StatusForbidden)
+			}
+
+			return handler(ctx, c)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// sessionToken returns the session token of the request and whether it came
+// from the cookie.
+func sessionToken(r *http.Request) (string, bool, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return "", false, errors.New("expected authorization header format: Bearer <token>")
+		}
+		return strings.TrimSpace(token), false, nil
+	}
+
+	cookie, err := r.Cookie(auth.SessionCookie)
+	if err != nil {
+		return "", false, err
+	}
+
+	return cookie.Value, true, nil
+}
+
+// safeMethod reports whether the method does not change state, so it does
+// not need CSRF protection.
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
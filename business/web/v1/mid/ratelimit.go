@@ -0,0 +1,68 @@
+package mid
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/sys/ratelimit"
+	"github.com/TSMC-Uber/server/business/web/v1/auth"
+	"github.com/TSMC-Uber/server/business/web/v1/response"
+	"github.com/TSMC-Uber/server/foundation/logger"
+	"github.com/TSMC-Uber/server/foundation/web"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// allow records an event for the key under the policy, tests replace it to
+// run without Redis.
+var allow = ratelimit.Allow
+
+// RateLimit limits the requests to a route under the named policy. Requests
+// are counted per user once authenticated, so it should come after
+// Authenticate, and per client IP otherwise, which is only taken from the
+// X-Forwarded-For header of the trusted proxies, see web.NewApp. When Redis
+// cannot be reached the request is let through.
+func RateLimit(log *logger.Logger, name string, policy ratelimit.Policy) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, c *gin.Context) error {
+			if !policy.Enabled() {
+				return handler(ctx, c)
+			}
+
+			key := name + ":ip:" + c.ClientIP()
+			if userID := auth.GetUserID(ctx); userID != uuid.Nil {
+				key = name + ":user:" + userID.String()
+			}
+
+			res, err := allow(ctx, key, policy)
+			if err != nil {
+				log.Error(ctx, "ratelimit", "policy", name, "ERROR", err)
+				return handler(ctx, c)
+			}
+
+			header := c.Writer.Header()
+			header.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+			header.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			header.Set("X-RateLimit-Reset", seconds(res.Reset))
+
+			if !res.Allowed {
+				header.Set("Retry-After", seconds(res.Reset))
+				return response.NewError(ratelimit.ErrLimited, http.StatusTooManyRequests)
+			}
+
+			return handler(ctx, c)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// seconds formats the duration in whole seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
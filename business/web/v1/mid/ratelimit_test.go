@@ -0,0 +1,116 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TSMC-Uber/server/business/sys/ratelimit"
+	"github.com/TSMC-Uber/server/business/web/v1/auth"
+	"github.com/TSMC-Uber/server/business/web/v1/response"
+	"github.com/TSMC-Uber/server/foundation/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// useAllow replaces the rate limiter for the test, recording the keys it is
+// asked about.
+func useAllow(t *testing.T, res ratelimit.Result, err error) *[]string {
+	var keys []string
+
+	allow = func(ctx context.Context, key string, p ratelimit.Policy) (ratelimit.Result, error) {
+		keys = append(keys, key)
+		return res, err
+	}
+	t.Cleanup(func() { allow = ratelimit.Allow })
+
+	return &keys
+}
+
+// serve runs the request through the rate limit middleware, reporting whether
+// the handler was called.
+func serve(t *testing.T, ctx context.Context, r *http.Request) (*httptest.ResponseRecorder, bool, error) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = r
+
+	var called bool
+	handler := func(ctx context.Context, c *gin.Context) error {
+		called = true
+		return nil
+	}
+
+	log := logger.New(io.Discard, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+	policy := ratelimit.Policy{Limit: 10, Window: time.Minute}
+	err := RateLimit(log, "login", policy)(handler)(ctx, c)
+
+	return w, called, err
+}
+
+func TestRateLimitAllowed(t *testing.T) {
+	keys := useAllow(t, ratelimit.Result{Allowed: true, Limit: 10, Remaining: 9, Reset: 1500 * time.Millisecond}, nil)
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/auth", nil)
+	r.RemoteAddr = "10.0.0.1:4321"
+
+	w, called, err := serve(t, context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, called)
+	assert.Equal(t, []string{"login:ip:10.0.0.1"}, *keys)
+	assert.Equal(t, "10", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "9", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Reset"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+}
+
+func TestRateLimitLimited(t *testing.T) {
+	useAllow(t, ratelimit.Result{Allowed: false, Limit: 10, Remaining: 0, Reset: 30 * time.Second}, nil)
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/auth", nil)
+
+	w, called, err := serve(t, context.Background(), r)
+
+	assert.False(t, called)
+	if !response.IsError(err) {
+		t.Fatalf("expected a response error, got %v", err)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, response.GetError(err).Status)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Equal(t, "10", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("X-RateLimit-Reset"))
+}
+
+func TestRateLimitUser(t *testing.T) {
+	keys := useAllow(t, ratelimit.Result{Allowed: true, Limit: 10, Remaining: 9}, nil)
+
+	userID := uuid.New()
+	ctx := auth.SetUserID(context.Background(), userID)
+
+	if _, _, err := serve(t, ctx, httptest.NewRequest(http.MethodPost, "/v1/trips/1/join", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"login:user:" + userID.String()}, *keys)
+}
+
+func TestRateLimitRedisDown(t *testing.T) {
+	useAllow(t, ratelimit.Result{}, errors.New("connection refused"))
+
+	_, called, err := serve(t, context.Background(), httptest.NewRequest(http.MethodPost, "/v1/auth", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, called)
+}
@@ -2,7 +2,10 @@ package v1
 
 import (
 	"os"
+	"time"
 
+	"github.com/TSMC-Uber/server/business/core/locationws"
+	"github.com/TSMC-Uber/server/business/sys/ratelimit"
 	"github.com/TSMC-Uber/server/business/web/v1/auth"
 	"github.com/TSMC-Uber/server/business/web/v1/mid"
 	"github.com/TSMC-Uber/server/foundation/logger"
@@ -18,6 +21,27 @@ type APIMuxConfig struct {
 	Auth     *auth.Auth
 	DB       *sqlx.DB
 	Tracer   trace.Tracer
+	Origins  []string
+
+	// TrustedProxies are the IPs or CIDRs of the proxies the client IP is
+	// taken from the X-Forwarded-For header for.
+	TrustedProxies []string
+
+	// RateLimits holds the named rate limit policies of the routes, a route
+	// without a policy is not limited.
+	RateLimits map[string]ratelimit.Policy
+
+	// LocationDispatcher keeps the rooms of the location streams served by
+	// this instance.
+	LocationDispatcher *locationws.RoomsDispatcher
+
+	// LocationRecorder stores the locations of the drivers as the traces of
+	// their trips.
+	LocationRecorder *locationws.Recorder
+
+	// LocationStreamRetention is how long the locations of a trip are kept
+	// on its stream, zero keeps no stream.
+	LocationStreamRetention time.Duration
 }
 
 // RouteAdder defines behavior that sets the routes to bind for an instance
@@ -31,6 +55,8 @@ func APIMux(cfg APIMuxConfig, routeAddr RouteAdder) *web.App {
 	app := web.NewApp(
 		cfg.Shutdown,
 		cfg.Tracer,
+		cfg.Origins,
+		cfg.TrustedProxies,
 		mid.Logger(cfg.Log),
 		mid.Errors(cfg.Log),
 		mid.Metrics(),
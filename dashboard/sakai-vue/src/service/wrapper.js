@@ -5,6 +5,23 @@ const client = axios.create({
     withCredentials: true
 });
 
+/**
+ * echo the csrf_token cookie in the X-CSRF-Token header, the server refuses
+ * the writes authenticated by the session cookie without it. axios only does
+ * this for same-origin requests, the API is usually on another origin.
+ */
+client.interceptors.request.use((config) => {
+    const method = (config.method || 'get').toLowerCase();
+    if (['get', 'head', 'options'].includes(method)) {
+        return config;
+    }
+    const match = document.cookie.match(/(?:^|;\s*)csrf_token=([^;]*)/);
+    if (match) {
+        config.headers['X-CSRF-Token'] = decodeURIComponent(match[1]);
+    }
+    return config;
+});
+
 /**
  * axios api wrapper, for success and error handler
  * @param {*} options - options passed to axios
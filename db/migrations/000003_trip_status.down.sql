@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS trip_status_history;
+ALTER TABLE trip DROP CONSTRAINT IF EXISTS trip_status_check;
+ALTER TABLE trip
+ADD CONSTRAINT trip_status_check CHECK (status IN ('not_start', 'in_trip', 'finished'));
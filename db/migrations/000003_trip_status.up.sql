@@ -0,0 +1,16 @@
+ALTER TABLE trip DROP CONSTRAINT IF EXISTS trip_status_check;
+ALTER TABLE trip
+ADD CONSTRAINT trip_status_check CHECK (
+    status IN ('not_start', 'in_trip', 'finished', 'cancelled')
+  );
+CREATE TABLE trip_status_history (
+  id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+  trip_id UUID NOT NULL,
+  from_status TEXT NOT NULL,
+  to_status TEXT NOT NULL,
+  changed_by UUID NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (trip_id) REFERENCES trip(id),
+  FOREIGN KEY (changed_by) REFERENCES users(id)
+);
+CREATE INDEX trip_status_history_trip_id_idx ON trip_status_history (trip_id);
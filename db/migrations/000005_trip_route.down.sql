@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS trip_location_trip_id_sequence_idx;
+DELETE FROM trip_location
+WHERE kind <> 'waypoint';
+ALTER TABLE trip_location DROP COLUMN IF EXISTS planned_arrival,
+  DROP COLUMN IF EXISTS passenger_id,
+  DROP COLUMN IF EXISTS kind,
+  DROP COLUMN IF EXISTS sequence;
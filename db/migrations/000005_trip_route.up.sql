@@ -0,0 +1,18 @@
+ALTER TABLE trip_location
+ADD COLUMN sequence INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN kind TEXT NOT NULL DEFAULT 'waypoint' CHECK (kind IN ('waypoint', 'pickup', 'dropoff')),
+  ADD COLUMN passenger_id UUID REFERENCES users(id),
+  ADD COLUMN planned_arrival TIMESTAMP;
+-- number the existing waypoints, the source of the trip is stop 0
+UPDATE trip_location
+SET sequence = ordered.sequence
+FROM (
+    SELECT id,
+      ROW_NUMBER() OVER (
+        PARTITION BY trip_id
+        ORDER BY id
+      ) AS sequence
+    FROM trip_location
+  ) AS ordered
+WHERE trip_location.id = ordered.id;
+CREATE INDEX trip_location_trip_id_sequence_idx ON trip_location (trip_id, sequence);
@@ -0,0 +1,14 @@
+DROP VIEW IF EXISTS driver_view;
+CREATE VIEW driver_view AS
+SELECT users.*,
+  driver.license,
+  driver.verified,
+  driver.brand,
+  driver.model,
+  driver.color,
+  driver.plate,
+  driver.created_at AS driver_created_at
+FROM driver
+  JOIN users ON users.id = driver.user_id;
+ALTER TABLE rating DROP CONSTRAINT IF EXISTS rating_trip_id_commenter_id_key,
+  DROP CONSTRAINT IF EXISTS rating_rating_check;
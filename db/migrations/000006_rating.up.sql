@@ -0,0 +1,32 @@
+-- keep the first rating of a commenter on a trip
+DELETE FROM rating AS duplicate USING rating
+WHERE duplicate.trip_id = rating.trip_id
+  AND duplicate.commenter_id = rating.commenter_id
+  AND (duplicate.created_at, duplicate.id) > (rating.created_at, rating.id);
+ALTER TABLE rating
+ADD CONSTRAINT rating_rating_check CHECK (
+    rating BETWEEN 1 AND 5
+  ) NOT VALID,
+  ADD CONSTRAINT rating_trip_id_commenter_id_key UNIQUE (trip_id, commenter_id);
+DROP VIEW IF EXISTS driver_view;
+CREATE VIEW driver_view AS
+SELECT users.*,
+  driver.license,
+  driver.verified,
+  driver.brand,
+  driver.model,
+  driver.color,
+  driver.plate,
+  driver.created_at AS driver_created_at,
+  COALESCE(driver_rating.rating_average, 0) AS rating_average,
+  COALESCE(driver_rating.rating_count, 0) AS rating_count
+FROM driver
+  JOIN users ON users.id = driver.user_id
+  LEFT JOIN (
+    SELECT trip.driver_id,
+      AVG(rating.rating)::DOUBLE PRECISION AS rating_average,
+      COUNT(*) AS rating_count
+    FROM rating
+      JOIN trip ON rating.trip_id = trip.id
+    GROUP BY trip.driver_id
+  ) AS driver_rating ON driver_rating.driver_id = driver.user_id;
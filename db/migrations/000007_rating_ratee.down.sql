@@ -0,0 +1,32 @@
+DROP VIEW IF EXISTS passenger_reputation_view;
+DROP VIEW IF EXISTS driver_view;
+CREATE VIEW driver_view AS
+SELECT users.*,
+  driver.license,
+  driver.verified,
+  driver.brand,
+  driver.model,
+  driver.color,
+  driver.plate,
+  driver.created_at AS driver_created_at,
+  COALESCE(driver_rating.rating_average, 0) AS rating_average,
+  COALESCE(driver_rating.rating_count, 0) AS rating_count
+FROM driver
+  JOIN users ON users.id = driver.user_id
+  LEFT JOIN (
+    SELECT trip.driver_id,
+      AVG(rating.rating)::DOUBLE PRECISION AS rating_average,
+      COUNT(*) AS rating_count
+    FROM rating
+      JOIN trip ON rating.trip_id = trip.id
+    GROUP BY trip.driver_id
+  ) AS driver_rating ON driver_rating.driver_id = driver.user_id;
+-- only the ratings left for drivers fit the old unique key
+DELETE FROM rating
+USING trip
+WHERE rating.trip_id = trip.id
+  AND rating.ratee_id <> trip.driver_id;
+DROP INDEX IF EXISTS rating_ratee_id_idx;
+ALTER TABLE rating DROP CONSTRAINT IF EXISTS rating_trip_id_commenter_id_ratee_id_key,
+  ADD CONSTRAINT rating_trip_id_commenter_id_key UNIQUE (trip_id, commenter_id),
+  DROP COLUMN ratee_id;
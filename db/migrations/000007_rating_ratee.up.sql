@@ -0,0 +1,62 @@
+-- ratings written before ratee_id existed were all left for the driver
+ALTER TABLE rating
+ADD COLUMN ratee_id UUID REFERENCES users(id);
+UPDATE rating
+SET ratee_id = trip.driver_id
+FROM trip
+WHERE rating.trip_id = trip.id;
+ALTER TABLE rating
+ALTER COLUMN ratee_id
+SET NOT NULL,
+  DROP CONSTRAINT rating_trip_id_commenter_id_key,
+  ADD CONSTRAINT rating_trip_id_commenter_id_ratee_id_key UNIQUE (trip_id, commenter_id, ratee_id);
+CREATE INDEX rating_ratee_id_idx ON rating (ratee_id);
+DROP VIEW IF EXISTS driver_view;
+CREATE VIEW driver_view AS
+SELECT users.*,
+  driver.license,
+  driver.verified,
+  driver.brand,
+  driver.model,
+  driver.color,
+  driver.plate,
+  driver.created_at AS driver_created_at,
+  COALESCE(driver_rating.rating_average, 0) AS rating_average,
+  COALESCE(driver_rating.rating_count, 0) AS rating_count
+FROM driver
+  JOIN users ON users.id = driver.user_id
+  LEFT JOIN (
+    SELECT rating.ratee_id,
+      AVG(rating.rating)::DOUBLE PRECISION AS rating_average,
+      COUNT(*) AS rating_count
+    FROM rating
+      JOIN trip ON rating.trip_id = trip.id
+    WHERE rating.ratee_id = trip.driver_id
+    GROUP BY rating.ratee_id
+  ) AS driver_rating ON driver_rating.ratee_id = driver.user_id;
+-- passenger_reputation_view
+CREATE VIEW passenger_reputation_view AS
+SELECT users.id AS user_id,
+  COALESCE(passenger_rating.rating_average, 0) AS rating_average,
+  COALESCE(passenger_rating.rating_count, 0) AS rating_count,
+  COALESCE(passenger_trip.completed_trips, 0) AS completed_trips
+FROM users
+  LEFT JOIN (
+    SELECT rating.ratee_id,
+      AVG(rating.rating)::DOUBLE PRECISION AS rating_average,
+      COUNT(*) AS rating_count
+    FROM rating
+      JOIN trip ON rating.trip_id = trip.id
+    WHERE rating.ratee_id <> trip.driver_id
+    GROUP BY rating.ratee_id
+  ) AS passenger_rating ON passenger_rating.ratee_id = users.id
+  LEFT JOIN (
+    SELECT trip_passenger.passenger_id,
+      COUNT(*) AS completed_trips
+    FROM trip_passenger
+      JOIN trip ON trip_passenger.trip_id = trip.id
+    WHERE trip.status = 'finished'
+      AND trip_passenger.status = 'accepted'
+      AND trip_passenger.roles = 'passenger'
+    GROUP BY trip_passenger.passenger_id
+  ) AS passenger_trip ON passenger_trip.passenger_id = users.id;
@@ -0,0 +1,19 @@
+-- the old table holds a single alert per trip, keep the latest one
+DELETE FROM alert AS older USING alert
+WHERE older.trip_id = alert.trip_id
+  AND (older.created_at, older.id) < (alert.created_at, alert.id);
+DROP INDEX IF EXISTS alert_trip_id_idx;
+ALTER TABLE alert DROP CONSTRAINT alert_pkey,
+  DROP COLUMN id,
+  DROP COLUMN status,
+  DROP COLUMN lat_lon,
+  DROP COLUMN located_at,
+  DROP COLUMN acknowledged_by,
+  DROP COLUMN acknowledged_at,
+  DROP COLUMN resolved_by,
+  DROP COLUMN resolved_at,
+  DROP COLUMN created_at;
+ALTER TABLE alert
+  RENAME COLUMN sender_id TO passenger_id;
+ALTER TABLE alert
+ADD PRIMARY KEY (trip_id);
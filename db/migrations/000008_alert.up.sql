@@ -0,0 +1,17 @@
+-- a trip can raise any number of alerts, from the driver or a passenger
+ALTER TABLE alert DROP CONSTRAINT alert_pkey;
+ALTER TABLE alert
+  RENAME COLUMN passenger_id TO sender_id;
+ALTER TABLE alert
+ADD COLUMN id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+  ADD COLUMN status TEXT NOT NULL DEFAULT 'open' CHECK (
+    status IN ('open', 'acknowledged', 'resolved')
+  ),
+  ADD COLUMN lat_lon GEOGRAPHY(POINT, 4326),
+  ADD COLUMN located_at TIMESTAMP,
+  ADD COLUMN acknowledged_by UUID REFERENCES users(id),
+  ADD COLUMN acknowledged_at TIMESTAMP,
+  ADD COLUMN resolved_by UUID REFERENCES users(id),
+  ADD COLUMN resolved_at TIMESTAMP,
+  ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+CREATE INDEX alert_trip_id_idx ON alert (trip_id, created_at);
@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS report_note;
+DROP INDEX IF EXISTS report_status_idx;
+ALTER TABLE report DROP CONSTRAINT report_pkey,
+  DROP CONSTRAINT report_trip_id_complainant_defendant_key,
+  DROP COLUMN id,
+  DROP COLUMN status,
+  DROP COLUMN assignee_id,
+  DROP COLUMN resolution,
+  DROP COLUMN suspended,
+  DROP COLUMN resolved_by,
+  DROP COLUMN resolved_at,
+  DROP COLUMN created_at,
+  DROP COLUMN updated_at;
+ALTER TABLE report
+ADD PRIMARY KEY (trip_id, complainant, defendant);
+DROP TABLE IF EXISTS moderator;
+ALTER TABLE users DROP COLUMN suspended_at,
+  DROP COLUMN suspended_until,
+  DROP COLUMN suspension_reason;
@@ -0,0 +1,34 @@
+ALTER TABLE users
+ADD COLUMN suspended_at TIMESTAMP,
+  ADD COLUMN suspended_until TIMESTAMP,
+  ADD COLUMN suspension_reason TEXT;
+CREATE TABLE moderator (
+  user_id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id)
+);
+ALTER TABLE report DROP CONSTRAINT report_pkey;
+ALTER TABLE report
+ADD COLUMN id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+  ADD COLUMN status TEXT NOT NULL DEFAULT 'open' CHECK (
+    status IN ('open', 'in_review', 'resolved', 'dismissed')
+  ),
+  ADD COLUMN assignee_id UUID REFERENCES users(id),
+  ADD COLUMN resolution TEXT,
+  ADD COLUMN suspended BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD COLUMN resolved_by UUID REFERENCES users(id),
+  ADD COLUMN resolved_at TIMESTAMP,
+  ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  ADD CONSTRAINT report_trip_id_complainant_defendant_key UNIQUE (trip_id, complainant, defendant);
+CREATE INDEX report_status_idx ON report (status, created_at);
+CREATE TABLE report_note (
+  id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+  report_id UUID NOT NULL,
+  author_id UUID NOT NULL,
+  note TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (report_id) REFERENCES report(id) ON DELETE CASCADE,
+  FOREIGN KEY (author_id) REFERENCES users(id)
+);
+CREATE INDEX report_note_report_id_idx ON report_note (report_id, created_at);
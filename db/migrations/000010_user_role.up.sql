@@ -0,0 +1,8 @@
+ALTER TABLE users
+ADD COLUMN roles TEXT [] NOT NULL DEFAULT '{rider}' CHECK (roles <@ ARRAY ['rider', 'driver', 'admin']);
+UPDATE users
+SET roles = array_append(roles, 'driver')
+WHERE id IN (
+    SELECT user_id
+    FROM driver
+  );
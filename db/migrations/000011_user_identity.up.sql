@@ -0,0 +1,17 @@
+CREATE TABLE user_identity (
+  provider TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  user_id UUID NOT NULL,
+  email TEXT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (provider, subject),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX user_identity_user_id_idx ON user_identity (user_id);
+INSERT INTO user_identity (provider, subject, user_id, email)
+SELECT 'google',
+  sub,
+  id,
+  email
+FROM users
+WHERE sub IS NOT NULL;
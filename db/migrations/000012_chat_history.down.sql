@@ -0,0 +1 @@
+DROP INDEX IF EXISTS chat_history_trip_id_created_at_idx;
@@ -0,0 +1 @@
+CREATE INDEX chat_history_trip_id_created_at_idx ON chat_history (trip_id, created_at, id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS chat_history_room_id_created_at_idx;
+CREATE INDEX IF NOT EXISTS chat_history_trip_id_created_at_idx ON chat_history (trip_id, created_at, id);
+ALTER TABLE chat_history DROP COLUMN IF EXISTS room_id;
+DROP TABLE IF EXISTS chat_room;
@@ -0,0 +1,27 @@
+CREATE TABLE chat_room (
+  id UUID PRIMARY KEY,
+  trip_id UUID NOT NULL,
+  passenger_id UUID,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (trip_id) REFERENCES trip(id),
+  FOREIGN KEY (passenger_id) REFERENCES users(id)
+);
+-- a trip has one room of its own, and one room with each prospective passenger
+CREATE UNIQUE INDEX chat_room_trip_id_idx ON chat_room (trip_id)
+WHERE passenger_id IS NULL;
+CREATE UNIQUE INDEX chat_room_trip_id_passenger_id_idx ON chat_room (trip_id, passenger_id)
+WHERE passenger_id IS NOT NULL;
+-- the room of a trip has the id of the trip
+INSERT INTO chat_room (id, trip_id, created_at)
+SELECT id,
+  id,
+  COALESCE(created_at, CURRENT_TIMESTAMP)
+FROM trip;
+ALTER TABLE chat_history
+ADD COLUMN room_id UUID REFERENCES chat_room(id);
+UPDATE chat_history
+SET room_id = trip_id;
+ALTER TABLE chat_history
+ALTER COLUMN room_id SET NOT NULL;
+DROP INDEX IF EXISTS chat_history_trip_id_created_at_idx;
+CREATE INDEX chat_history_room_id_created_at_idx ON chat_history (room_id, created_at, id);
@@ -0,0 +1,7 @@
+DELETE FROM chat_history
+WHERE sender_id IS NULL;
+ALTER TABLE chat_history DROP CONSTRAINT IF EXISTS chat_history_sender_id_check;
+ALTER TABLE chat_history
+ALTER COLUMN sender_id SET NOT NULL;
+ALTER TABLE chat_history DROP COLUMN IF EXISTS event;
+ALTER TABLE chat_history DROP COLUMN IF EXISTS msg_type;
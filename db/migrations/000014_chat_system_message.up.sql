@@ -0,0 +1,12 @@
+-- system messages are posted by the server about the trip, they have no sender
+ALTER TABLE chat_history
+ADD COLUMN msg_type TEXT NOT NULL DEFAULT 'message';
+ALTER TABLE chat_history
+ADD COLUMN event TEXT;
+ALTER TABLE chat_history
+ALTER COLUMN sender_id DROP NOT NULL;
+ALTER TABLE chat_history
+ADD CONSTRAINT chat_history_sender_id_check CHECK (
+    msg_type = 'system'
+    OR sender_id IS NOT NULL
+  );
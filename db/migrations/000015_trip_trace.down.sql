@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS trip_trace;
+DROP TABLE IF EXISTS trip_trace_point;
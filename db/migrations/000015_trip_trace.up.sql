@@ -0,0 +1,25 @@
+-- the locations the driver published during a trip, purged after a while
+CREATE TABLE trip_trace_point (
+  trip_id UUID NOT NULL,
+  seq BIGINT NOT NULL,
+  lat_lon GEOGRAPHY(POINT, 4326) NOT NULL,
+  accuracy DOUBLE PRECISION,
+  heading DOUBLE PRECISION,
+  speed DOUBLE PRECISION,
+  device_time TIMESTAMP NOT NULL,
+  server_time TIMESTAMP NOT NULL,
+  FOREIGN KEY (trip_id) REFERENCES trip(id) ON DELETE CASCADE
+);
+CREATE INDEX trip_trace_point_trip_id_idx ON trip_trace_point (trip_id, device_time, seq);
+CREATE INDEX trip_trace_point_server_time_idx ON trip_trace_point (server_time);
+-- the path of a finished trip, the measure of every point is its device time
+-- in seconds since the epoch
+CREATE TABLE trip_trace (
+  trip_id UUID PRIMARY KEY,
+  path GEOGRAPHY(LINESTRINGM, 4326) NOT NULL,
+  points INTEGER NOT NULL,
+  started_at TIMESTAMP NOT NULL,
+  finished_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (trip_id) REFERENCES trip(id) ON DELETE CASCADE
+);
@@ -12,6 +12,8 @@ TRUNCATE TABLE trip_location CASCADE;
 TRUNCATE TABLE rating CASCADE;
 -- Truncate 'chat_history' table
 TRUNCATE TABLE chat_history CASCADE;
+-- Truncate 'chat_room' table
+TRUNCATE TABLE chat_room CASCADE;
 -- Truncate 'trip' table
 TRUNCATE TABLE trip CASCADE;
 -- Truncate 'locations' table
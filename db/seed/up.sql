@@ -89,12 +89,21 @@ VALUES (
     ),
     '2023-01-01 08:00:00'
   );
+-- Insert data into 'chat_room', every trip has a room with the id of the trip
+INSERT INTO chat_room (id, trip_id)
+SELECT id,
+  id
+FROM trip;
 -- Insert data into 'chat_history'
-INSERT INTO chat_history (trip_id, sender_id, msg_content)
+INSERT INTO chat_history (room_id, trip_id, sender_id, msg_content)
 SELECT (
     SELECT id
     FROM trip
     LIMIT 1
+  ), (
+    SELECT id
+    FROM trip
+    LIMIT 1
   ), (
     SELECT id
     FROM users
@@ -0,0 +1,143 @@
+// Package keystore implements the auth.KeyLookup interface over the private
+// keys found in a folder. Each key is stored in a PEM file named after its
+// key id, so rotating keys means adding a file and switching the active kid.
+package keystore
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// KeyStore represents an in memory store implementation of the
+// KeyLookup interface.
+type KeyStore struct {
+	store map[string]key
+}
+
+type key struct {
+	privatePEM string
+	publicPEM  string
+}
+
+// NewFS constructs a KeyStore based on a set of PEM files rooted inside of
+// a file system. The name of the PEM file is used as the key id.
+func NewFS(fsys fs.FS) (*KeyStore, error) {
+	ks := KeyStore{
+		store: make(map[string]key),
+	}
+
+	fn := func(fileName string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("walkdir failure: %w", err)
+		}
+
+		if dirEntry.IsDir() || path.Ext(fileName) != ".pem" {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, fileName)
+		if err != nil {
+			return fmt.Errorf("reading auth private key: %w", err)
+		}
+
+		k, err := parse(data)
+		if err != nil {
+			return fmt.Errorf("file[%s]: %w", fileName, err)
+		}
+
+		ks.store[strings.TrimSuffix(dirEntry.Name(), ".pem")] = k
+
+		return nil
+	}
+
+	if err := fs.WalkDir(fsys, ".", fn); err != nil {
+		return nil, fmt.Errorf("walking directory: %w", err)
+	}
+
+	return &ks, nil
+}
+
+// PrivateKey searches the key store for a given kid and returns the private
+// key in PEM format.
+func (ks *KeyStore) PrivateKey(kid string) (string, error) {
+	k, found := ks.store[kid]
+	if !found {
+		return "", fmt.Errorf("kid[%s]: private key lookup failed", kid)
+	}
+
+	return k.privatePEM, nil
+}
+
+// PublicKey searches the key store for a given kid and returns the public
+// key in PEM format.
+func (ks *KeyStore) PublicKey(kid string) (string, error) {
+	k, found := ks.store[kid]
+	if !found {
+		return "", fmt.Errorf("kid[%s]: public key lookup failed", kid)
+	}
+
+	return k.publicPEM, nil
+}
+
+// =============================================================================
+
+// parse reads the private key and derives its public key.
+func parse(data []byte) (key, error) {
+	publicPEM, err := PublicKeyPEM(string(data))
+	if err != nil {
+		return key{}, err
+	}
+
+	return key{privatePEM: string(data), publicPEM: publicPEM}, nil
+}
+
+// PublicKeyPEM derives the public key in PEM format from a PKCS#1 or PKCS#8
+// encoded RSA private key in PEM format.
+func PublicKeyPEM(privatePEM string) (string, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return "", errors.New("no pem block found")
+	}
+
+	var privateKey *rsa.PrivateKey
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		pk, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return "", fmt.Errorf("parsing pkcs1 private key: %w", err)
+		}
+		privateKey = pk
+
+	case "PRIVATE KEY":
+		pk, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return "", fmt.Errorf("parsing pkcs8 private key: %w", err)
+		}
+		rsaKey, ok := pk.(*rsa.PrivateKey)
+		if !ok {
+			return "", errors.New("private key is not an rsa key")
+		}
+		privateKey = rsaKey
+
+	default:
+		return "", fmt.Errorf("unexpected pem block %q", block.Type)
+	}
+
+	asn1Bytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("marshaling public key: %w", err)
+	}
+
+	publicPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: asn1Bytes,
+	})
+
+	return string(publicPEM), nil
+}
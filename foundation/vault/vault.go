@@ -0,0 +1,245 @@
+// Package vault implements the auth.KeyLookup interface over the key/value
+// secrets engine (version 2) of a Vault server. Each private key is stored as
+// a secret named after its key id, with the PEM encoded key in the pem field.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TSMC-Uber/server/foundation/keystore"
+)
+
+// Set of error variables for the vault.
+var (
+	ErrNotFound   = errors.New("key not found")
+	ErrInvalidKID = errors.New("kid is invalid")
+)
+
+// kidRegex is what a key id looks like, a single path segment so a kid taken
+// from a token cannot name any other secret of the vault.
+var kidRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+const (
+	// missTTL is how long a kid the vault has no key for is remembered, so
+	// tokens with bogus kids do not each cost a request to the vault.
+	missTTL = time.Minute
+
+	// maxMisses bounds how many missing kids are remembered.
+	maxMisses = 1024
+)
+
+// Config represents the mandatory settings needed to work with Vault.
+type Config struct {
+	Address   string
+	Token     string
+	MountPath string
+	Client    *http.Client
+}
+
+// Vault provides support to access Hashicorp's Vault product for keys.
+type Vault struct {
+	address   string
+	token     string
+	mountPath string
+	client    *http.Client
+	mu        sync.RWMutex
+	store     map[string]string
+	misses    map[string]time.Time
+}
+
+// New constructs a vault for use.
+func New(cfg Config) (*Vault, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("vault address is required")
+	}
+
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	mountPath := cfg.MountPath
+	if mountPath == "" {
+		mountPath = "secret"
+	}
+
+	v := Vault{
+		address:   strings.TrimSuffix(cfg.Address, "/"),
+		token:     cfg.Token,
+		mountPath: strings.Trim(mountPath, "/"),
+		client:    client,
+		store:     make(map[string]string),
+		misses:    make(map[string]time.Time),
+	}
+
+	return &v, nil
+}
+
+// AddPrivateKey adds a new private key into vault as a PEM encoded string.
+func (v *Vault) AddPrivateKey(ctx context.Context, kid string, pem []byte) error {
+	if !kidRegex.MatchString(kid) {
+		return fmt.Errorf("kid[%q]: %w", kid, ErrInvalidKID)
+	}
+
+	body := struct {
+		Data struct {
+			PEM string `json:"pem"`
+		} `json:"data"`
+	}{}
+	body.Data.PEM = string(pem)
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := v.do(ctx, http.MethodPost, kid, bytes.NewReader(data), nil); err != nil {
+		return fmt.Errorf("kid[%s]: %w", kid, err)
+	}
+
+	v.mu.Lock()
+	delete(v.misses, kid)
+	v.mu.Unlock()
+
+	return nil
+}
+
+// PrivateKey searches the key store for a given kid and returns the private
+// key in PEM format. The kid usually comes from a token not verified yet, so
+// it is checked before the vault is asked, and a kid the vault has no key for
+// is not asked again for a while.
+func (v *Vault) PrivateKey(kid string) (string, error) {
+	if !kidRegex.MatchString(kid) {
+		return "", fmt.Errorf("kid[%q]: %w", kid, ErrInvalidKID)
+	}
+
+	v.mu.RLock()
+	privatePEM, exists := v.store[kid]
+	missed, isMiss := v.misses[kid]
+	v.mu.RUnlock()
+
+	if exists {
+		return privatePEM, nil
+	}
+
+	if isMiss && time.Since(missed) < missTTL {
+		return "", fmt.Errorf("kid[%s]: %w", kid, ErrNotFound)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var doc struct {
+		Data struct {
+			Data struct {
+				PEM string `json:"pem"`
+			} `json:"data"`
+		} `json:"data"`
+	}
+
+	if err := v.do(ctx, http.MethodGet, kid, nil, &doc); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			v.miss(kid)
+		}
+		return "", fmt.Errorf("kid[%s]: %w", kid, err)
+	}
+
+	privatePEM = doc.Data.Data.PEM
+	if privatePEM == "" {
+		v.miss(kid)
+		return "", fmt.Errorf("kid[%s]: pem field missing: %w", kid, ErrNotFound)
+	}
+
+	v.mu.Lock()
+	v.store[kid] = privatePEM
+	delete(v.misses, kid)
+	v.mu.Unlock()
+
+	return privatePEM, nil
+}
+
+// PublicKey searches the key store for a given kid and returns the public
+// key in PEM format.
+func (v *Vault) PublicKey(kid string) (string, error) {
+	privatePEM, err := v.PrivateKey(kid)
+	if err != nil {
+		return "", fmt.Errorf("private key: %w", err)
+	}
+
+	publicPEM, err := keystore.PublicKeyPEM(privatePEM)
+	if err != nil {
+		return "", fmt.Errorf("kid[%s]: %w", kid, err)
+	}
+
+	return publicPEM, nil
+}
+
+// =============================================================================
+
+// miss remembers the vault has no key for the kid. When too many kids are
+// remembered the expired ones are forgotten, or all of them if none expired.
+func (v *Vault) miss(kid string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if len(v.misses) >= maxMisses {
+		for k, missed := range v.misses {
+			if time.Since(missed) >= missTTL {
+				delete(v.misses, k)
+			}
+		}
+		if len(v.misses) >= maxMisses {
+			clear(v.misses)
+		}
+	}
+
+	v.misses[kid] = time.Now()
+}
+
+// do sends a request for the secret named kid and decodes the response into
+// doc, when given.
+func (v *Vault) do(ctx context.Context, method string, kid string, body io.Reader, doc any) error {
+	endpoint := fmt.Sprintf("%s/v1/%s/data/%s", v.address, v.mountPath, url.PathEscape(kid))
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("X-Vault-Token", v.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	if doc == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(doc); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+
+	return nil
+}
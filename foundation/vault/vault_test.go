@@ -0,0 +1,179 @@
+package vault
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// vaultServer stands in for the key/value secrets engine of Vault.
+type vaultServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	secrets map[string]string
+	reads   int
+}
+
+func newVaultServer(t *testing.T, token string) *vaultServer {
+	s := vaultServer{secrets: map[string]string{}}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		kid, ok := strings.CutPrefix(r.URL.Path, "/v1/secret/data/")
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		switch r.Method {
+		case http.MethodPost:
+			var body struct {
+				Data struct {
+					PEM string `json:"pem"`
+				} `json:"data"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			s.secrets[kid] = body.Data.PEM
+			w.WriteHeader(http.StatusOK)
+
+		case http.MethodGet:
+			s.reads++
+			secret, exists := s.secrets[kid]
+			if !exists {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"data": map[string]any{
+					"data": map[string]string{"pem": secret},
+				},
+			})
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	return &s
+}
+
+func privateKeyPEM(t *testing.T) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+}
+
+func TestVault(t *testing.T) {
+	srv := newVaultServer(t, "root")
+
+	v, err := New(Config{Address: srv.URL, Token: "root", Client: srv.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privatePEM := privateKeyPEM(t)
+	if err := v.AddPrivateKey(context.Background(), "key-1", privatePEM); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := v.PrivateKey("key-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(privatePEM), got)
+
+	publicPEM, err := v.PublicKey("key-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, publicPEM, "BEGIN PUBLIC KEY")
+
+	// the key is only read once
+	assert.Equal(t, 1, srv.reads)
+
+	_, err = v.PrivateKey("key-2")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestVaultForbidden(t *testing.T) {
+	srv := newVaultServer(t, "root")
+
+	v, err := New(Config{Address: srv.URL, Token: "wrong", Client: srv.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = v.PrivateKey("key-1")
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrNotFound))
+}
+
+func TestVaultInvalidKID(t *testing.T) {
+	srv := newVaultServer(t, "root")
+
+	v, err := New(Config{Address: srv.URL, Token: "root", Client: srv.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, kid := range []string{"", "../../sys/policy", "..", "key/1", "key%2F1", "key 1"} {
+		_, err := v.PrivateKey(kid)
+		assert.True(t, errors.Is(err, ErrInvalidKID), kid)
+
+		_, err = v.PublicKey(kid)
+		assert.True(t, errors.Is(err, ErrInvalidKID), kid)
+	}
+
+	// the vault is never asked
+	assert.Equal(t, 0, srv.reads)
+}
+
+func TestVaultMissCached(t *testing.T) {
+	srv := newVaultServer(t, "root")
+
+	v, err := New(Config{Address: srv.URL, Token: "root", Client: srv.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := v.PrivateKey("key-1")
+		assert.True(t, errors.Is(err, ErrNotFound))
+	}
+	assert.Equal(t, 1, srv.reads)
+
+	// adding the key forgets it was missing
+	if err := v.AddPrivateKey(context.Background(), "key-1", privateKeyPEM(t)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.PrivateKey("key-1"); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, srv.reads)
+}
@@ -32,7 +32,10 @@ type App struct {
 }
 
 // NewApp creates an App value that handle a set of routes for the application.
-func NewApp(shutdown chan os.Signal, tracer trace.Tracer, mw ...Middleware) *App {
+// Browsers are allowed to call it from the given origins. The client IP is
+// taken from the X-Forwarded-For header only for requests coming from the
+// trusted proxies, given as IPs or CIDRs, and from the connection otherwise.
+func NewApp(shutdown chan os.Signal, tracer trace.Tracer, origins []string, proxies []string, mw ...Middleware) *App {
 	app := &App{
 		Engine:   gin.New(),
 		shutdown: shutdown,
@@ -40,11 +43,16 @@ func NewApp(shutdown chan os.Signal, tracer trace.Tracer, mw ...Middleware) *App
 		tracer:   tracer,
 	}
 
+	// An invalid proxy trusts none, rather than every one as gin does.
+	if err := app.SetTrustedProxies(proxies); err != nil {
+		app.SetTrustedProxies(nil)
+	}
+
 	// Configure CORS
 	app.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173"},
+		AllowOrigins:     origins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "id_token"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-CSRF-Token", "id_token", "nonce", "device"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
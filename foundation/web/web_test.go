@@ -0,0 +1,44 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		proxies []string
+		remote  string
+		want    string
+	}{
+		{"no proxies", nil, "203.0.113.7:4321", "203.0.113.7"},
+		{"untrusted proxy", []string{"10.0.0.0/8"}, "203.0.113.7:4321", "203.0.113.7"},
+		{"trusted proxy", []string{"10.0.0.0/8"}, "10.1.2.3:4321", "198.51.100.1"},
+		{"invalid proxy", []string{"not-a-proxy"}, "10.1.2.3:4321", "10.1.2.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := NewApp(nil, nil, []string{"http://localhost:5173"}, tt.proxies)
+			app.GET("/ip", func(c *gin.Context) {
+				c.String(http.StatusOK, c.ClientIP())
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			r.RemoteAddr = tt.remote
+			r.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.want, w.Body.String())
+		})
+	}
+}
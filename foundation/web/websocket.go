@@ -0,0 +1,26 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CheckOrigin returns a websocket origin check accepting the given origins,
+// a "*" origin accepts any. Requests without an Origin header do not come
+// from a browser and are accepted.
+func CheckOrigin(origins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+
+		for _, allowed := range origins {
+			if allowed == "*" || strings.EqualFold(allowed, origin) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
@@ -0,0 +1,35 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		origin  string
+		want    bool
+	}{
+		{"no origin", []string{"http://localhost:5173"}, "", true},
+		{"allowed", []string{"http://localhost:5173"}, "http://localhost:5173", true},
+		{"case insensitive", []string{"https://Tuber.example.com"}, "https://tuber.example.com", true},
+		{"not allowed", []string{"http://localhost:5173"}, "https://evil.example.com", false},
+		{"any", []string{"*"}, "https://evil.example.com", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/chat/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+
+			assert.Equal(t, tt.want, CheckOrigin(tt.origins)(r))
+		})
+	}
+}